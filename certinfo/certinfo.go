@@ -0,0 +1,85 @@
+package certinfo
+
+import (
+	"crypto/ecdsa"
+	"crypto/rsa"
+	"crypto/sha256"
+	"crypto/x509"
+	"encoding/hex"
+	"encoding/pem"
+	"errors"
+	"fmt"
+	"time"
+)
+
+// Summary of a certificate
+type CertInfo struct {
+	Subject      string
+	DNSNames     []string
+	IPAddresses  []string
+	Issuer       string
+	SerialNumber string
+	// Hex-encoded SHA-256 fingerprint of DER-encoded certificate
+	Fingerprint string
+	NotBefore   time.Time
+	NotAfter    time.Time
+	// Algorithm of public key, e.g. RSA-2048 or ECDSA-P-256
+	KeyAlgorithm string
+}
+
+// Check whether certificate expires within window
+func (c CertInfo) ExpiresWithin(window time.Duration) bool {
+	return time.Until(c.NotAfter) <= window
+}
+
+// Parse first certificate found in PEM-encoded certificate or bundle.
+//
+// Blocks other than certificates, e.g. private keys, are skipped.
+func ParseLeaf(pemBytes []byte) (*x509.Certificate, error) {
+	for {
+		var block *pem.Block
+		block, pemBytes = pem.Decode(pemBytes)
+		if block == nil {
+			return nil, errors.New("No PEM-encoded certificate found")
+		}
+		if block.Type == "CERTIFICATE" {
+			return x509.ParseCertificate(block.Bytes)
+		}
+	}
+}
+
+// Summarize first certificate found in PEM-encoded certificate or bundle
+func InspectCertificate(pemBytes []byte) (CertInfo, error) {
+	leaf, err := ParseLeaf(pemBytes)
+	if err != nil {
+		return CertInfo{}, err
+	}
+	fingerprint := sha256.Sum256(leaf.Raw)
+	ips := []string{}
+	for _, ip := range leaf.IPAddresses {
+		ips = append(ips, ip.String())
+	}
+	return CertInfo{
+		Subject:      leaf.Subject.String(),
+		DNSNames:     leaf.DNSNames,
+		IPAddresses:  ips,
+		Issuer:       leaf.Issuer.String(),
+		SerialNumber: leaf.SerialNumber.Text(16),
+		Fingerprint:  hex.EncodeToString(fingerprint[:]),
+		NotBefore:    leaf.NotBefore.UTC(),
+		NotAfter:     leaf.NotAfter.UTC(),
+		KeyAlgorithm: keyAlgorithm(leaf),
+	}, nil
+}
+
+// Describe algorithm and size of certificate public key
+func keyAlgorithm(cert *x509.Certificate) string {
+	switch key := cert.PublicKey.(type) {
+	case *rsa.PublicKey:
+		return fmt.Sprintf("RSA-%d", key.N.BitLen())
+	case *ecdsa.PublicKey:
+		return fmt.Sprintf("ECDSA-%s", key.Curve.Params().Name)
+	default:
+		return cert.PublicKeyAlgorithm.String()
+	}
+}
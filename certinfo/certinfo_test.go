@@ -0,0 +1,90 @@
+package certinfo
+
+import (
+	"crypto"
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/rsa"
+	"encoding/pem"
+	"testing"
+	"time"
+
+	"github.com/charbonnierg/letsgo/internal/testcert"
+	"golang.org/x/exp/slices"
+)
+
+// Generate a PEM-encoded leaf certificate signed by a test issuer
+func newTestLeaf(t *testing.T, key crypto.Signer, notAfter time.Time) []byte {
+	return testcert.PEM(testcert.New(t, testcert.Options{
+		Names:    []string{"example.com", "*.example.com", "192.0.2.1"},
+		Serial:   0xabcdef,
+		NotAfter: notAfter,
+		Key:      key,
+		IssuerCN: "Test Issuer",
+	}))
+}
+
+// Test that RSA and ECDSA leaves with a wildcard SAN are summarized
+func TestInspectCertificate(t *testing.T) {
+	rsaKey, err := rsa.GenerateKey(rand.Reader, 2048)
+	if err != nil {
+		t.Fatalf(err.Error())
+	}
+	ecKey, err := ecdsa.GenerateKey(elliptic.P384(), rand.Reader)
+	if err != nil {
+		t.Fatalf(err.Error())
+	}
+	notAfter := time.Now().Add(30 * 24 * time.Hour).Truncate(time.Second)
+	for want, key := range map[string]crypto.Signer{"RSA-2048": rsaKey, "ECDSA-P-384": ecKey} {
+		info, err := InspectCertificate(newTestLeaf(t, key, notAfter))
+		if err != nil {
+			t.Fatalf(err.Error())
+		}
+		if info.KeyAlgorithm != want {
+			t.Errorf("Bad key algorithm. Want: %s. Got: %s", want, info.KeyAlgorithm)
+		}
+		if info.Subject != "CN=example.com" || info.Issuer != "CN=Test Issuer" {
+			t.Errorf("Bad subject or issuer: %s, %s", info.Subject, info.Issuer)
+		}
+		if !slices.Equal(info.DNSNames, []string{"example.com", "*.example.com"}) {
+			t.Errorf("Bad DNS names: %s", info.DNSNames)
+		}
+		if !slices.Equal(info.IPAddresses, []string{"192.0.2.1"}) {
+			t.Errorf("Bad IP addresses: %s", info.IPAddresses)
+		}
+		if info.SerialNumber != "abcdef" {
+			t.Errorf("Bad serial number. Want: abcdef. Got: %s", info.SerialNumber)
+		}
+		if len(info.Fingerprint) != 64 {
+			t.Errorf("Bad fingerprint: %s", info.Fingerprint)
+		}
+		if !info.NotAfter.Equal(notAfter) || info.NotAfter.Location() != time.UTC {
+			t.Errorf("Bad expiration. Want: %s. Got: %s", notAfter.UTC(), info.NotAfter)
+		}
+		if info.ExpiresWithin(7*24*time.Hour) || !info.ExpiresWithin(31*24*time.Hour) {
+			t.Errorf("Bad expiration window for certificate expiring at %s", info.NotAfter)
+		}
+	}
+}
+
+// Test that leaf is found after non-certificate blocks and that missing certificates are reported
+func TestInspectCertificateBlocks(t *testing.T) {
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if err != nil {
+		t.Fatalf(err.Error())
+	}
+	keyPEM := pem.EncodeToMemory(&pem.Block{Type: "EC PRIVATE KEY", Bytes: []byte("private key")})
+	info, err := InspectCertificate(append(keyPEM, newTestLeaf(t, key, time.Now().Add(time.Hour))...))
+	if err != nil {
+		t.Fatalf(err.Error())
+	}
+	if info.KeyAlgorithm != "ECDSA-P-256" {
+		t.Errorf("Bad key algorithm. Want: ECDSA-P-256. Got: %s", info.KeyAlgorithm)
+	}
+	_, err = InspectCertificate(keyPEM)
+	err_want := "No PEM-encoded certificate found"
+	if err == nil || err.Error() != err_want {
+		t.Errorf("Bad error. Want: %s. Got: %v", err_want, err)
+	}
+}
@@ -0,0 +1,128 @@
+package client
+
+import (
+	"bytes"
+	"crypto"
+	"crypto/x509"
+	"encoding/pem"
+	"errors"
+	"fmt"
+	"io"
+	"log"
+	"net/http"
+	"regexp"
+
+	"github.com/charbonnierg/letsgo/configuration"
+	jose "gopkg.in/square/go-jose.v2"
+)
+
+// Match URL of Link headers with alternate relation (RFC 8555 section 7.4.2)
+var alternateLinkPattern = regexp.MustCompile(`<([^>]+)>\s*;\s*rel="?alternate"?`)
+
+// Get URLs of alternate certificate chains advertised in Link headers
+func alternateLinks(header http.Header) []string {
+	links := []string{}
+	for _, value := range header.Values("Link") {
+		for _, match := range alternateLinkPattern.FindAllStringSubmatch(value, -1) {
+			links = append(links, match[1])
+		}
+	}
+	return links
+}
+
+// Get common name of the root certificate which issued the last certificate of chain
+func rootCommonName(chain []byte) (string, error) {
+	var last *x509.Certificate
+	for rest := chain; ; {
+		var block *pem.Block
+		block, rest = pem.Decode(rest)
+		if block == nil {
+			break
+		}
+		cert, err := x509.ParseCertificate(block.Bytes)
+		if err != nil {
+			return "", err
+		}
+		last = cert
+	}
+	if last == nil {
+		return "", errors.New("No PEM-encoded certificate found in chain")
+	}
+	return last.Issuer.CommonName, nil
+}
+
+// Download certificate chain using POST-as-GET request (RFC 8555 section 6.3)
+func fetchChain(httpClient *http.Client, userAgent string, newNonce string, accountURL string, key crypto.PrivateKey, url string) ([]byte, http.Header, error) {
+	nonce, err := fetchNonce(httpClient, userAgent, newNonce)
+	if err != nil {
+		return nil, nil, err
+	}
+	jws, err := signJWS(key, accountURL, map[jose.HeaderKey]interface{}{"url": url, "nonce": nonce}, []byte{})
+	if err != nil {
+		return nil, nil, err
+	}
+	request, err := http.NewRequest(http.MethodPost, url, bytes.NewReader([]byte(jws.FullSerialize())))
+	if err != nil {
+		return nil, nil, err
+	}
+	request.Header.Set("User-Agent", userAgent)
+	request.Header.Set("Content-Type", "application/jose+json")
+	request.Header.Set("Accept", "application/pem-certificate-chain")
+	response, err := httpClient.Do(request)
+	if err != nil {
+		return nil, nil, err
+	}
+	defer response.Body.Close()
+	body, err := io.ReadAll(response.Body)
+	if err != nil {
+		return nil, nil, err
+	}
+	if response.StatusCode != http.StatusOK {
+		return nil, nil, errors.New(fmt.Sprintf("Unexpected status code %d for %s: %s", response.StatusCode, url, body))
+	}
+	return body, response.Header, nil
+}
+
+// Download default and alternate certificate chains offered by CA server,
+// indexed by common name of their root certificate
+func fetchAllChains(httpClient *http.Client, userAgent string, caDir string, accountURL string, key crypto.PrivateKey, certURL string) (map[string][]byte, error) {
+	directory := struct {
+		NewNonce string `json:"newNonce"`
+	}{}
+	err := getJSON(httpClient, userAgent, caDir, &directory)
+	if err != nil {
+		return nil, err
+	}
+	chain, header, err := fetchChain(httpClient, userAgent, directory.NewNonce, accountURL, key, certURL)
+	if err != nil {
+		return nil, err
+	}
+	offered := [][]byte{chain}
+	for _, url := range alternateLinks(header) {
+		alternate, _, err := fetchChain(httpClient, userAgent, directory.NewNonce, accountURL, key, url)
+		if err != nil {
+			return nil, err
+		}
+		offered = append(offered, alternate)
+	}
+	chains := map[string][]byte{}
+	for _, chain := range offered {
+		root, err := rootCommonName(chain)
+		if err != nil {
+			return nil, err
+		}
+		chains[root] = chain
+	}
+	return chains, nil
+}
+
+// Download all certificate chains offered by CA server for certificate
+func FetchAllChains(userConfig configuration.UserConfig, certURL string) (map[string][]byte, error) {
+	_, user, err := newClient(userConfig)
+	if err != nil {
+		return nil, err
+	}
+	log.Printf("Downloading all certificate chains of %s", certURL)
+	legoConfig := newLegoConfig(user, userConfig)
+	return fetchAllChains(legoConfig.HTTPClient, legoConfig.UserAgent, userConfig.CADirURL, user.Registration.URI, userConfig.Key, certURL)
+}
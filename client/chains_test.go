@@ -0,0 +1,64 @@
+package client
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/charbonnierg/letsgo/internal/testcert"
+	"github.com/charbonnierg/letsgo/output"
+	"github.com/go-acme/lego/v4/certcrypto"
+)
+
+// Test that default and alternate chains offered by CA server are all written
+func TestFetchAllChains(t *testing.T) {
+	defaultChain := testcert.PEM(testcert.New(t, testcert.Options{CommonName: "ISRG Root X1"}))
+	alternateChain := testcert.PEM(testcert.New(t, testcert.Options{CommonName: "DST Root CA X3"}))
+	mux := http.NewServeMux()
+	server := httptest.NewServer(mux)
+	defer server.Close()
+	mux.HandleFunc("/directory", func(w http.ResponseWriter, r *http.Request) {
+		json.NewEncoder(w).Encode(map[string]string{"newNonce": server.URL + "/nonce"})
+	})
+	mux.HandleFunc("/nonce", func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Replay-Nonce", "nonce-1")
+	})
+	mux.HandleFunc("/cert/1", func(w http.ResponseWriter, r *http.Request) {
+		if r.Method != http.MethodPost {
+			t.Errorf("Bad method. Want: POST. Got: %s", r.Method)
+		}
+		w.Header().Add("Link", `<`+server.URL+`/directory>;rel="index"`)
+		w.Header().Add("Link", `<`+server.URL+`/cert/1/1>;rel="alternate"`)
+		w.Write(defaultChain)
+	})
+	mux.HandleFunc("/cert/1/1", func(w http.ResponseWriter, r *http.Request) {
+		w.Write(alternateChain)
+	})
+	key, _ := certcrypto.GeneratePrivateKey(certcrypto.EC256)
+	chains, err := fetchAllChains(server.Client(), "test", server.URL+"/directory", "https://ca.example.com/acct/1", key, server.URL+"/cert/1")
+	if err != nil {
+		t.Fatalf(err.Error())
+	}
+	if len(chains) != 2 {
+		t.Fatalf("Bad number of chains. Want: 2. Got: %d", len(chains))
+	}
+	dir := t.TempDir()
+	_, _, err = output.WriteChains(dir, "example.com", chains, false)
+	if err != nil {
+		t.Fatalf(err.Error())
+	}
+	for name, want := range map[string][]byte{
+		"example.com.chain.ISRG_Root_X1.crt":   defaultChain,
+		"example.com.chain.DST_Root_CA_X3.crt": alternateChain,
+	} {
+		got, err := os.ReadFile(filepath.Join(dir, name))
+		if err != nil {
+			t.Errorf("Expected %s to be written", name)
+		} else if string(got) != string(want) {
+			t.Errorf("Bad content of %s", name)
+		}
+	}
+}
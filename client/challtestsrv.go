@@ -0,0 +1,51 @@
+package client
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+
+	"github.com/go-acme/lego/v4/challenge/dns01"
+)
+
+// DNS provider creating challenge records through the management API
+// of pebble-challtestsrv, the mock DNS server of local Boulder test CA.
+type challtestsrvProvider struct {
+	url        string
+	httpClient *http.Client
+}
+
+func newChalltestsrvProvider(url string) *challtestsrvProvider {
+	return &challtestsrvProvider{url: url, httpClient: http.DefaultClient}
+}
+
+// Send a request to management API
+func (p *challtestsrvProvider) post(path string, body map[string]string) error {
+	content, err := json.Marshal(body)
+	if err != nil {
+		return err
+	}
+	response, err := p.httpClient.Post(p.url+path, "application/json", bytes.NewReader(content))
+	if err != nil {
+		return err
+	}
+	defer response.Body.Close()
+	if response.StatusCode != http.StatusOK {
+		return errors.New(fmt.Sprintf("Unexpected status code %d for %s", response.StatusCode, p.url+path))
+	}
+	return nil
+}
+
+// Create TXT record
+func (p *challtestsrvProvider) Present(domain, token, keyAuth string) error {
+	fqdn, value := dns01.GetRecord(domain, keyAuth)
+	return p.post("/set-txt", map[string]string{"host": fqdn, "value": value})
+}
+
+// Remove TXT record
+func (p *challtestsrvProvider) CleanUp(domain, token, keyAuth string) error {
+	fqdn, _ := dns01.GetRecord(domain, keyAuth)
+	return p.post("/clear-txt", map[string]string{"host": fqdn})
+}
@@ -0,0 +1,35 @@
+package client
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/go-acme/lego/v4/challenge/dns01"
+)
+
+// Test that challenge records are set and cleared through management API
+func TestChalltestsrvProvider(t *testing.T) {
+	requests := []string{}
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		body := map[string]string{}
+		json.NewDecoder(r.Body).Decode(&body)
+		requests = append(requests, r.URL.Path+" "+body["host"]+" "+body["value"])
+	}))
+	defer server.Close()
+	provider := newChalltestsrvProvider(server.URL)
+	err := provider.Present("example.com", "token", "keyAuth")
+	if err != nil {
+		t.Fatalf(err.Error())
+	}
+	err = provider.CleanUp("example.com", "token", "keyAuth")
+	if err != nil {
+		t.Fatalf(err.Error())
+	}
+	fqdn, value := dns01.GetRecord("example.com", "keyAuth")
+	want := []string{"/set-txt " + fqdn + " " + value, "/clear-txt " + fqdn + " "}
+	if len(requests) != 2 || requests[0] != want[0] || requests[1] != want[1] {
+		t.Errorf("Bad requests. Want: %q. Got: %q", want, requests)
+	}
+}
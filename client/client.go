@@ -2,15 +2,20 @@ package client
 
 import (
 	"crypto"
+	"errors"
+	"fmt"
 	"log"
-	"time"
+	"net/http"
+	"strings"
 
 	"github.com/charbonnierg/letsgo/configuration"
+	"github.com/charbonnierg/letsgo/constants"
+	"github.com/go-acme/lego/v4/certcrypto"
 	"github.com/go-acme/lego/v4/certificate"
 	"github.com/go-acme/lego/v4/challenge/dns01"
 	"github.com/go-acme/lego/v4/lego"
-	"github.com/go-acme/lego/v4/providers/dns/digitalocean"
 	"github.com/go-acme/lego/v4/registration"
+	"golang.org/x/exp/slices"
 )
 
 // User type that implements acme.User
@@ -35,41 +40,119 @@ func (u *User) GetPrivateKey() crypto.PrivateKey {
 	return u.Key
 }
 
+// Create lego configuration for user
+func newLegoConfig(user *User, userConfig configuration.UserConfig) *lego.Config {
+	// Generate config for user
+	legoConfig := lego.NewConfig(user)
+	// The default URL is ACME v2 staging environment
+	legoConfig.CADirURL = userConfig.CADirURL
+	legoConfig.Certificate.KeyType = userConfig.CADirKeyType
+	// Overall time to wait for the CA server to validate challenges and issue certificate
+	if userConfig.ChallengeTimeout > 0 {
+		legoConfig.Certificate.Timeout = userConfig.ChallengeTimeout
+	}
+	// Slow proxies may need more time than lego default HTTP timeout
+	if userConfig.ACMEHTTPTimeout > 0 {
+		legoConfig.HTTPClient.Timeout = userConfig.ACMEHTTPTimeout
+	}
+	// Identify letsgo to the CA server
+	legoConfig.UserAgent = userConfig.UserAgent
+	// Verify CA server using custom root certificates when configured
+	if userConfig.CARoots != nil {
+		if transport, ok := legoConfig.HTTPClient.Transport.(*http.Transport); ok {
+			transport.TLSClientConfig.RootCAs = userConfig.CARoots
+		}
+	}
+	// Send requests through configured proxy
+	if userConfig.ACMEHTTPProxy != nil {
+		if transport, ok := legoConfig.HTTPClient.Transport.(*http.Transport); ok {
+			transport.Proxy = http.ProxyURL(userConfig.ACMEHTTPProxy)
+		}
+	}
+	// Refuse to negotiate outdated TLS versions
+	if userConfig.OutboundMinTLS > 0 {
+		if transport, ok := legoConfig.HTTPClient.Transport.(*http.Transport); ok {
+			transport.TLSClientConfig.MinVersion = userConfig.OutboundMinTLS
+		}
+	}
+	// Do not verify TLS certificates of local test CA servers
+	if userConfig.Insecure {
+		if transport, ok := legoConfig.HTTPClient.Transport.(*http.Transport); ok {
+			transport.TLSClientConfig.InsecureSkipVerify = true
+		}
+	}
+	// Add extra headers expected by ACME relays
+	if len(userConfig.ACMEExtraHeaders) > 0 {
+		legoConfig.HTTPClient.Transport = &headersTransport{headers: userConfig.ACMEExtraHeaders, next: legoConfig.HTTPClient.Transport}
+	}
+	// Log ACME requests and responses
+	if userConfig.ACMEDebug {
+		legoConfig.HTTPClient.Transport = &debugTransport{next: legoConfig.HTTPClient.Transport}
+	}
+	return legoConfig
+}
+
 // Create a new client to request certificate
 func NewClient(userConfig configuration.UserConfig) (lego.Client, error) {
+	client, _, err := newClient(userConfig)
+	if err != nil {
+		return lego.Client{}, err
+	}
+	return *client, nil
+}
+
+// Create a new client along with its registered user
+func newClient(userConfig configuration.UserConfig) (*lego.Client, *User, error) {
 	// Generate user
 	user := &User{
 		Email: userConfig.Email,
 		Key:   userConfig.Key,
 	}
 	// Generate config for user
-	legoConfig := lego.NewConfig(user)
-	// The default URL is ACME v2 staging environment
-	legoConfig.CADirURL = userConfig.CADirURL
-	legoConfig.Certificate.KeyType = userConfig.CADirKeyType
+	legoConfig := newLegoConfig(user, userConfig)
+	if userConfig.Insecure {
+		log.Printf("Using plaintext CA directory %s, TLS verification is disabled", userConfig.CADirURL)
+	}
 	// A client facilitates communication with the CA server.
 	client, err := lego.NewClient(legoConfig)
 	if err != nil {
-		return lego.Client{}, err
+		return nil, nil, err
 	}
-	// Generate DigitalOcean provider configuration
-	providerConfig := digitalocean.NewDefaultConfig()
-	// Set auth token from user config
-	providerConfig.AuthToken = userConfig.AuthToken
-	// Use a propagation timeout of 1 minute and 30 seconds
-	providerConfig.PropagationTimeout = time.Duration(time.Second * 90)
-	// Create DigitalOcean DNS Provider
-	dnsProvider, err := digitalocean.NewDNSProviderConfig(providerConfig)
+	// Record new orders so that an interrupted order can be resumed.
+	// Resumed orders use a new private key, so orders are not recorded
+	// when private key is reused.
+	if !userConfig.ReuseKey {
+		err = recordOrders(legoConfig, userConfig)
+		if err != nil {
+			return nil, nil, err
+		}
+	}
+	// Create DNS Provider
+	dnsProvider, err := newDNSProvider(userConfig)
 	if err != nil {
-		return lego.Client{}, err
+		return nil, nil, err
+	}
+	// Avoid rate limits of DNS provider API, and retry calls once rate limits are lifted
+	dnsProvider = retryRateLimited(dnsProvider, userConfig.DNSAPIMaxRetries)
+	dnsProvider = limitConcurrency(dnsProvider, userConfig.DNSAPIConcurrency)
+	// Follow delegation of challenge records through CNAME records
+	err = ConfigureCNAMESupport(userConfig.FollowCNAME)
+	if err != nil {
+		return nil, nil, err
 	}
 	// Use DNS provider with some conditional options
+	check := newDNSCheck(userConfig.DNSCheckMode)
+	dnsClient := newDNSClient(userConfig.ResolverProtocol, userConfig.DNSTimeout)
+	preCheck := newPreCheck(userConfig, check, dnsClient)
 	err = client.Challenge.SetDNS01Provider(dnsProvider,
 		dns01.CondOption(
 			len(userConfig.DNSResolvers) > 0,
 			dns01.AddRecursiveNameservers(dns01.ParseNameservers(userConfig.DNSResolvers)),
 		),
-		dns01.CondOption(userConfig.DisableCP,
+		dns01.CondOption(preCheck != nil,
+			dns01.WrapPreCheck(preCheck),
+		),
+		dns01.CondOption(!check.requireCompletePropagation,
 			dns01.DisableCompletePropagationRequirement(),
 		),
 		dns01.CondOption(userConfig.DNSTimeout > 0,
@@ -77,30 +160,117 @@ func NewClient(userConfig configuration.UserConfig) (lego.Client, error) {
 		),
 	)
 	if err != nil {
-		return lego.Client{}, err
+		return nil, nil, err
 	}
 	// Perform use registration
-	reg, err := client.Registration.Register(registration.RegisterOptions{TermsOfServiceAgreed: true})
+	reg, err := register(client, user, userConfig)
 	if err != nil {
-		log.Fatal(err)
+		return nil, nil, err
 	}
 	user.Registration = reg
 	// Return client
-	return *client, err
+	return client, user, nil
+}
+
+// Register user account, or reuse existing account when registration is skipped
+func register(client *lego.Client, user *User, userConfig configuration.UserConfig) (*registration.Resource, error) {
+	if userConfig.SkipRegistration {
+		if userConfig.AccountURI == "" {
+			return nil, errors.New("Cannot skip registration without an existing account URI")
+		}
+		return &registration.Resource{URI: userConfig.AccountURI}, nil
+	}
+	// Terms of Service must be agreed before registering a new account
+	err := checkTermsOfService(client.GetToSURL(), userConfig.TermsOfServiceAgreed)
+	if err != nil {
+		return nil, err
+	}
+	return registerAccount(client.Registration, user)
+}
+
+// Log Terms of Service URL of CA server, and refuse to proceed when not agreed
+func checkTermsOfService(tosURL string, agreed bool) error {
+	if tosURL == "" {
+		tosURL = "<not provided by CA server>"
+	}
+	if !agreed {
+		return errors.New(fmt.Sprintf("Terms of Service of CA server must be agreed through %s environment variable. Review them at %s", constants.LE_TOS_AGREED, tosURL))
+	}
+	log.Printf("Agreeing to Terms of Service at %s", tosURL)
+	return nil
 }
 
 // Request certificate according to user configuration
 func RequestCertificate(config configuration.UserConfig) (*certificate.Resource, error) {
 	// Generate lego client
-	client, err := NewClient(config)
+	client, user, err := newClient(config)
 	if err != nil {
 		return &certificate.Resource{}, err
 	}
-	// Gather request
-	request := certificate.ObtainRequest{
+	// Send request, unless order of an interrupted run can be resumed
+	return obtainOrResume(config, user, func() (*certificate.Resource, error) {
+		log.Printf("Requesting certificate for %s", strings.Join(config.DisplayDomains, ","))
+		resource, err := client.Certificate.Obtain(newObtainRequest(config))
+		if err != nil {
+			return resource, joinDomainErrors(err)
+		}
+		return resource, nil
+	})
+}
+
+// Gather request. Domains are sent in A-label form.
+func newObtainRequest(config configuration.UserConfig) certificate.ObtainRequest {
+	return certificate.ObtainRequest{
 		Domains: config.Domains,
 		Bundle:  true,
 	}
-	// Send request
-	return client.Certificate.Obtain(request)
+}
+
+// Renew a previously obtained certificate according to user configuration.
+//
+// A new certificate is requested instead when configured domains
+// do not match the domains of previous certificate. When key reuse is
+// enabled, private key of previous certificate is used in both cases.
+func RenewCertificate(config configuration.UserConfig, previous certificate.Resource) (*certificate.Resource, error) {
+	// Existing private key is kept when configured
+	if config.ReuseKey {
+		return renewWithKey(config, previous)
+	}
+	if !matchDomains(previous.Certificate, config.Domains) {
+		log.Printf("Domains changed since last certificate was issued, requesting a new certificate")
+		return RequestCertificate(config)
+	}
+	// A new private key is generated on renewal
+	previous.PrivateKey = nil
+	// Generate lego client
+	client, user, err := newClient(config)
+	if err != nil {
+		return &certificate.Resource{}, err
+	}
+	// Send request, unless order of an interrupted run can be resumed
+	return obtainOrResume(config, user, func() (*certificate.Resource, error) {
+		resource, err := client.Certificate.Renew(previous, true, false, "")
+		if err != nil {
+			return resource, joinDomainErrors(err)
+		}
+		return resource, nil
+	})
+}
+
+// Check that PEM-encoded certificate holds exactly the given domains
+func matchDomains(cert []byte, domains []string) bool {
+	x509Cert, err := certcrypto.ParsePEMCertificate(cert)
+	if err != nil {
+		return false
+	}
+	certDomains := certcrypto.ExtractDomains(x509Cert)
+	if len(certDomains) != len(domains) {
+		return false
+	}
+	for _, domain := range domains {
+		if !slices.Contains(certDomains, strings.ToLower(domain)) {
+			return false
+		}
+	}
+	return true
 }
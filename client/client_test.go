@@ -0,0 +1,344 @@
+package client
+
+import (
+	"bytes"
+	"crypto/tls"
+	"crypto/x509"
+	"log"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/charbonnierg/letsgo/configuration"
+	"github.com/charbonnierg/letsgo/stores"
+	"github.com/go-acme/lego/v4/lego"
+	"github.com/go-acme/lego/v4/providers/dns/digitalocean"
+)
+
+// Test that user agent is applied to lego configuration
+func TestNewLegoConfigUserAgent(t *testing.T) {
+	want := "custom-agent/1.0"
+	userConfig := configuration.UserConfig{UserAgent: want}
+	legoConfig := newLegoConfig(&User{}, userConfig)
+	if legoConfig.UserAgent != want {
+		t.Errorf("Bad user agent. Want: %s. Got: %s", want, legoConfig.UserAgent)
+	}
+}
+
+// Test that DigitalOcean provider configuration is generated from user config
+func TestNewDigitalOceanConfig(t *testing.T) {
+	propagationTimeout := 90 * time.Second
+	userConfig := configuration.UserConfig{AuthToken: "XXXXX", PropagationTimeout: propagationTimeout}
+	providerConfig := newDigitalOceanConfig(userConfig)
+	if providerConfig.AuthToken != "XXXXX" {
+		t.Errorf("Bad auth token. Want: XXXXX. Got: %s", providerConfig.AuthToken)
+	}
+	if providerConfig.PropagationTimeout != propagationTimeout {
+		t.Errorf("Bad propagation timeout. Want: %s. Got: %s", propagationTimeout, providerConfig.PropagationTimeout)
+	}
+}
+
+// Test that Linode provider is constructed from LINODE_TOKEN or DNS auth token
+func TestNewLinodeConfig(t *testing.T) {
+	userConfig := configuration.UserConfig{AuthToken: "XXXXX", PropagationTimeout: 90 * time.Second, DNSTTL: 600}
+	providerConfig := newLinodeConfig(userConfig)
+	if providerConfig.Token != "XXXXX" || providerConfig.PropagationTimeout != 90*time.Second || providerConfig.TTL != 600 {
+		t.Errorf("Bad Linode configuration: %+v", providerConfig)
+	}
+	userConfig.ProviderTokens = map[string]string{"linode": "LINODE"}
+	providerConfig = newLinodeConfig(userConfig)
+	if providerConfig.Token != "LINODE" {
+		t.Errorf("Bad token. Want: LINODE. Got: %s", providerConfig.Token)
+	}
+	_, err := newProviderByName("linode", userConfig)
+	if err != nil {
+		t.Errorf(err.Error())
+	}
+}
+
+// Test that Vultr provider is constructed from VULTR_API_KEY or DNS auth token
+func TestNewVultrConfig(t *testing.T) {
+	userConfig := configuration.UserConfig{AuthToken: "XXXXX", PropagationTimeout: 90 * time.Second}
+	providerConfig := newVultrConfig(userConfig)
+	if providerConfig.APIKey != "XXXXX" || providerConfig.PropagationTimeout != 90*time.Second {
+		t.Errorf("Bad Vultr configuration: %+v", providerConfig)
+	}
+	userConfig.ProviderTokens = map[string]string{"vultr": "VULTR"}
+	providerConfig = newVultrConfig(userConfig)
+	if providerConfig.APIKey != "VULTR" {
+		t.Errorf("Bad API key. Want: VULTR. Got: %s", providerConfig.APIKey)
+	}
+	_, err := newProviderByName("vultr", userConfig)
+	if err != nil {
+		t.Errorf(err.Error())
+	}
+}
+
+// Test that challenge timeout and propagation timeout are applied independently
+func TestTimeouts(t *testing.T) {
+	userConfig := configuration.UserConfig{
+		PropagationTimeout: 5 * time.Minute,
+		ChallengeTimeout:   10 * time.Minute,
+		Azure:              configuration.AzureConfig{SubscriptionID: "subscription", ResourceGroup: "group"},
+	}
+	legoConfig := newLegoConfig(&User{}, userConfig)
+	if legoConfig.Certificate.Timeout != 10*time.Minute {
+		t.Errorf("Bad challenge timeout. Want: 10m0s. Got: %s", legoConfig.Certificate.Timeout)
+	}
+	if timeout := newDigitalOceanConfig(userConfig).PropagationTimeout; timeout != 5*time.Minute {
+		t.Errorf("Bad DigitalOcean propagation timeout. Want: 5m0s. Got: %s", timeout)
+	}
+	if timeout := newAzureConfig(userConfig).PropagationTimeout; timeout != 5*time.Minute {
+		t.Errorf("Bad Azure propagation timeout. Want: 5m0s. Got: %s", timeout)
+	}
+	// Lego default challenge timeout is kept when not configured
+	want := lego.NewConfig(&User{}).Certificate.Timeout
+	legoConfig = newLegoConfig(&User{}, configuration.UserConfig{PropagationTimeout: time.Minute})
+	if legoConfig.Certificate.Timeout != want {
+		t.Errorf("Bad default challenge timeout. Want: %s. Got: %s", want, legoConfig.Certificate.Timeout)
+	}
+}
+
+// Test that HTTP timeout is applied to the client used by lego, unless not configured
+func TestNewLegoConfigHTTPTimeout(t *testing.T) {
+	legoConfig := newLegoConfig(&User{}, configuration.UserConfig{ACMEHTTPTimeout: 5 * time.Minute})
+	if legoConfig.HTTPClient.Timeout != 5*time.Minute {
+		t.Errorf("Bad HTTP timeout. Want: 5m0s. Got: %s", legoConfig.HTTPClient.Timeout)
+	}
+	want := lego.NewConfig(&User{}).HTTPClient.Timeout
+	legoConfig = newLegoConfig(&User{}, configuration.UserConfig{})
+	if legoConfig.HTTPClient.Timeout != want {
+		t.Errorf("Bad default HTTP timeout. Want: %s. Got: %s", want, legoConfig.HTTPClient.Timeout)
+	}
+}
+
+// Test that TTL of challenge records flows into DigitalOcean provider configuration
+func TestNewDigitalOceanConfigTTL(t *testing.T) {
+	want := digitalocean.NewDefaultConfig().TTL
+	providerConfig := newDigitalOceanConfig(configuration.UserConfig{})
+	if providerConfig.TTL != want {
+		t.Errorf("Bad default TTL. Want: %d. Got: %d", want, providerConfig.TTL)
+	}
+	providerConfig = newDigitalOceanConfig(configuration.UserConfig{DNSTTL: 60})
+	if providerConfig.TTL != 60 {
+		t.Errorf("Bad TTL. Want: 60. Got: %d", providerConfig.TTL)
+	}
+}
+
+// Test that API base URL flows into DigitalOcean provider configuration
+func TestNewDigitalOceanConfigBaseURL(t *testing.T) {
+	want := digitalocean.NewDefaultConfig().BaseURL
+	providerConfig := newDigitalOceanConfig(configuration.UserConfig{})
+	if providerConfig.BaseURL != want {
+		t.Errorf("Bad default base URL. Want: %s. Got: %s", want, providerConfig.BaseURL)
+	}
+	want = "http://localhost:8080"
+	providerConfig = newDigitalOceanConfig(configuration.UserConfig{DigitalOceanAPIURL: want})
+	if providerConfig.BaseURL != want {
+		t.Errorf("Bad base URL. Want: %s. Got: %s", want, providerConfig.BaseURL)
+	}
+}
+
+// Test that Azure DNS provider configuration is generated from user config
+func TestNewAzureConfig(t *testing.T) {
+	userConfig := configuration.UserConfig{
+		Azure: configuration.AzureConfig{
+			SubscriptionID: "subscription",
+			ResourceGroup:  "group",
+			TenantID:       "tenant",
+			ClientID:       "client",
+			ClientSecret:   "secret",
+		},
+	}
+	providerConfig := newAzureConfig(userConfig)
+	if providerConfig.SubscriptionID != "subscription" {
+		t.Errorf("Bad subscription ID. Want: subscription. Got: %s", providerConfig.SubscriptionID)
+	}
+	if providerConfig.ResourceGroup != "group" {
+		t.Errorf("Bad resource group. Want: group. Got: %s", providerConfig.ResourceGroup)
+	}
+	if providerConfig.TenantID != "tenant" || providerConfig.ClientID != "client" || providerConfig.ClientSecret != "secret" {
+		t.Errorf("Bad client credentials: %s %s %s", providerConfig.TenantID, providerConfig.ClientID, providerConfig.ClientSecret)
+	}
+}
+
+// Test that OVH provider configuration is generated from user config
+func TestNewOVHConfig(t *testing.T) {
+	userConfig := configuration.UserConfig{
+		PropagationTimeout: time.Minute,
+		OVH: configuration.OVHConfig{
+			Endpoint:          "ovh-eu",
+			ApplicationKey:    "key",
+			ApplicationSecret: "secret",
+			ConsumerKey:       "consumer",
+		},
+	}
+	providerConfig := newOVHConfig(userConfig)
+	if providerConfig.APIEndpoint != "ovh-eu" || providerConfig.ApplicationKey != "key" || providerConfig.ApplicationSecret != "secret" || providerConfig.ConsumerKey != "consumer" {
+		t.Errorf("Bad OVH credentials: %+v", providerConfig)
+	}
+	if providerConfig.PropagationTimeout != time.Minute {
+		t.Errorf("Bad propagation timeout. Want: 1m0s. Got: %s", providerConfig.PropagationTimeout)
+	}
+}
+
+// Test that an unknown DNS provider is rejected
+func TestNewDNSProviderUnsupported(t *testing.T) {
+	_, err := newDNSProvider(configuration.UserConfig{DNSProvider: "unknown"})
+	if err == nil {
+		t.Errorf("Expected error for unsupported provider")
+	}
+}
+
+// Test that A-label form of internationalized domain names reaches obtain request
+func TestNewObtainRequestWithIDN(t *testing.T) {
+	t.Setenv("DOMAINS", "bücher.example")
+	t.Setenv("ACCOUNT_EMAIL", "support@example.com")
+	t.Setenv("ACCOUNT_KEY_FILE", filepath.Join(t.TempDir(), "account.key"))
+	t.Setenv("DNS_AUTH_TOKEN", "XXXXX")
+	storage := stores.TestStores("")
+	userConfig, err := configuration.NewUserConfig(&storage)
+	if err != nil {
+		t.Fatalf(err.Error())
+	}
+	request := newObtainRequest(*userConfig)
+	if len(request.Domains) != 1 || request.Domains[0] != "xn--bcher-kva.example" {
+		t.Errorf("Bad request domains. Want: [xn--bcher-kva.example]. Got: %s", request.Domains)
+	}
+}
+
+// Test that Terms of Service URL is logged, or included in error when not agreed
+func TestCheckTermsOfService(t *testing.T) {
+	tosURL := "https://letsencrypt.org/documents/LE-SA-v1.3-September-21-2022.pdf"
+	buffer := &bytes.Buffer{}
+	log.SetOutput(buffer)
+	defer log.SetOutput(os.Stderr)
+	err := checkTermsOfService(tosURL, true)
+	if err != nil {
+		t.Fatalf(err.Error())
+	}
+	if !strings.Contains(buffer.String(), tosURL) {
+		t.Errorf("Terms of Service URL was not logged: %s", buffer.String())
+	}
+	err = checkTermsOfService(tosURL, false)
+	if err == nil {
+		t.Fatalf("Expected error when Terms of Service are not agreed")
+	}
+	if !strings.Contains(err.Error(), tosURL) || !strings.Contains(err.Error(), "LE_TOS_AGREED") {
+		t.Errorf("Bad error: %s", err.Error())
+	}
+}
+
+// Test that registration is skipped when an existing account is configured
+func TestRegisterSkip(t *testing.T) {
+	want := "https://acme.example.com/acct/1"
+	reg, err := register(nil, nil, configuration.UserConfig{SkipRegistration: true, AccountURI: want})
+	if err != nil {
+		t.Errorf(err.Error())
+	}
+	if reg.URI != want {
+		t.Errorf("Bad account URI. Want: %s. Got: %s", want, reg.URI)
+	}
+	_, err = register(nil, nil, configuration.UserConfig{SkipRegistration: true})
+	if err == nil {
+		t.Errorf("Expected error when skipping registration without account URI")
+	}
+}
+
+// Test that requests to CA server are sent through configured proxy
+func TestNewLegoConfigProxy(t *testing.T) {
+	proxyURL, _ := url.Parse("http://proxy.internal:3128")
+	userConfig := configuration.UserConfig{ACMEHTTPProxy: proxyURL}
+	legoConfig := newLegoConfig(&User{}, userConfig)
+	request, _ := http.NewRequest(http.MethodGet, "https://acme-v02.api.letsencrypt.org/directory", nil)
+	for name, transport := range map[string]*http.Transport{
+		"lego":         legoConfig.HTTPClient.Transport.(*http.Transport),
+		"digitalocean": newDigitalOceanConfig(userConfig).HTTPClient.Transport.(*http.Transport),
+	} {
+		got, err := transport.Proxy(request)
+		if err != nil {
+			t.Fatalf(err.Error())
+		}
+		if got == nil || got.String() != proxyURL.String() {
+			t.Errorf("Bad %s proxy. Want: %s. Got: %v", name, proxyURL, got)
+		}
+	}
+}
+
+// Test that configured minimum TLS version is applied to CA server and DNS provider transports
+func TestNewLegoConfigOutboundMinTLS(t *testing.T) {
+	userConfig := configuration.UserConfig{OutboundMinTLS: tls.VersionTLS13}
+	legoConfig := newLegoConfig(&User{}, userConfig)
+	for name, transport := range map[string]*http.Transport{
+		"lego":         legoConfig.HTTPClient.Transport.(*http.Transport),
+		"digitalocean": newDigitalOceanConfig(userConfig).HTTPClient.Transport.(*http.Transport),
+		"cloudflare":   newCloudflareConfig(userConfig).HTTPClient.Transport.(*http.Transport),
+		"ovh":          newOVHConfig(userConfig).HTTPClient.Transport.(*http.Transport),
+		"azure":        newAzureConfig(userConfig).HTTPClient.Transport.(*http.Transport),
+	} {
+		if transport.TLSClientConfig == nil || transport.TLSClientConfig.MinVersion != tls.VersionTLS13 {
+			t.Errorf("Bad %s minimum TLS version. Want: %d. Got: %+v", name, tls.VersionTLS13, transport.TLSClientConfig)
+		}
+	}
+}
+
+// Test that TLS verification is disabled for insecure CA directories only
+func TestNewLegoConfigInsecure(t *testing.T) {
+	legoConfig := newLegoConfig(&User{}, configuration.UserConfig{Insecure: true})
+	transport := legoConfig.HTTPClient.Transport.(*http.Transport)
+	if !transport.TLSClientConfig.InsecureSkipVerify {
+		t.Errorf("Expected TLS verification to be disabled")
+	}
+	legoConfig = newLegoConfig(&User{}, configuration.UserConfig{})
+	transport = legoConfig.HTTPClient.Transport.(*http.Transport)
+	if transport.TLSClientConfig.InsecureSkipVerify {
+		t.Errorf("Expected TLS verification to be enabled")
+	}
+}
+
+// Test that custom root certificates are used to verify CA server
+func TestNewLegoConfigCARoots(t *testing.T) {
+	server := httptest.NewTLSServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {}))
+	defer server.Close()
+	pool := x509.NewCertPool()
+	pool.AddCert(server.Certificate())
+
+	legoConfig := newLegoConfig(&User{}, configuration.UserConfig{CARoots: pool})
+	transport := legoConfig.HTTPClient.Transport.(*http.Transport)
+	if !transport.TLSClientConfig.RootCAs.Equal(pool) {
+		t.Errorf("Expected custom root certificates to be used")
+	}
+	response, err := legoConfig.HTTPClient.Head(server.URL)
+	if err != nil {
+		t.Fatalf(err.Error())
+	}
+	response.Body.Close()
+
+	legoConfig = newLegoConfig(&User{}, configuration.UserConfig{})
+	_, err = legoConfig.HTTPClient.Head(server.URL)
+	if err == nil {
+		t.Errorf("Expected TLS verification to fail without custom root certificates")
+	}
+}
+
+// Test that certificate domains are compared with configured domains
+func TestMatchDomains(t *testing.T) {
+	cert := newTestCertificate(t, "example.com", "*.example.com")
+	if !matchDomains(cert, []string{"*.example.com", "Example.com"}) {
+		t.Errorf("Expected domains to match")
+	}
+	if matchDomains(cert, []string{"example.com"}) {
+		t.Errorf("Expected domains not to match when a domain is removed")
+	}
+	if matchDomains(cert, []string{"example.com", "*.example.com", "test.example.com"}) {
+		t.Errorf("Expected domains not to match when a domain is added")
+	}
+	if matchDomains([]byte("invalid"), []string{"example.com"}) {
+		t.Errorf("Expected domains not to match for invalid certificate")
+	}
+}
@@ -0,0 +1,63 @@
+package client
+
+import (
+	"errors"
+	"fmt"
+	"log"
+	"net/http"
+	"time"
+
+	"github.com/charbonnierg/letsgo/configuration"
+)
+
+// Maximum clock skew tolerated between local clock and CA server clock
+const maxClockSkew = 5 * time.Minute
+
+// Measure difference between clock of server and local clock using Date header
+func clockSkew(httpClient *http.Client, userAgent string, url string) (time.Duration, error) {
+	request, err := http.NewRequest(http.MethodHead, url, nil)
+	if err != nil {
+		return 0, err
+	}
+	request.Header.Set("User-Agent", userAgent)
+	response, err := httpClient.Do(request)
+	if err != nil {
+		return 0, err
+	}
+	response.Body.Close()
+	serverTime, err := http.ParseTime(response.Header.Get("Date"))
+	if err != nil {
+		return 0, errors.New(fmt.Sprintf("Invalid Date header received from %s", url))
+	}
+	return time.Since(serverTime).Round(time.Second), nil
+}
+
+// Warn about clock skew, or return an error when strict
+func checkClockSkew(skew time.Duration, strict bool) error {
+	if skew < 0 {
+		skew = -skew
+	}
+	if skew <= maxClockSkew {
+		return nil
+	}
+	message := fmt.Sprintf("Local clock differs from CA server clock by %s, ACME requests may be rejected", skew)
+	if strict {
+		return errors.New(message)
+	}
+	log.Printf("WARNING: %s", message)
+	return nil
+}
+
+// Compare local clock with CA server clock before any ACME operation.
+//
+// An error is only returned when clock is skewed and strict clock
+// checking is enabled. CA server being unreachable is not an error.
+func CheckClockSkew(userConfig configuration.UserConfig) error {
+	legoConfig := newLegoConfig(&User{}, userConfig)
+	skew, err := clockSkew(legoConfig.HTTPClient, legoConfig.UserAgent, userConfig.CADirURL)
+	if err != nil {
+		log.Printf("Failed to check clock skew: %s", err.Error())
+		return nil
+	}
+	return checkClockSkew(skew, userConfig.StrictClock)
+}
@@ -0,0 +1,35 @@
+package client
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+)
+
+// Test that clock skew is measured using Date header of server
+func TestClockSkew(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Method != http.MethodHead {
+			t.Errorf("Bad method. Want: HEAD. Got: %s", r.Method)
+		}
+		w.Header().Set("Date", time.Now().Add(-10*time.Minute).UTC().Format(http.TimeFormat))
+	}))
+	defer server.Close()
+	skew, err := clockSkew(server.Client(), "test", server.URL)
+	if err != nil {
+		t.Fatalf(err.Error())
+	}
+	if skew < 9*time.Minute || skew > 11*time.Minute {
+		t.Errorf("Bad clock skew. Want: 10m0s. Got: %s", skew)
+	}
+	if checkClockSkew(skew, false) != nil {
+		t.Errorf("Expected only a warning when clock checking is not strict")
+	}
+	if checkClockSkew(skew, true) == nil {
+		t.Errorf("Expected error when clock checking is strict")
+	}
+	if checkClockSkew(-time.Minute, true) != nil {
+		t.Errorf("Expected small clock skew to be tolerated")
+	}
+}
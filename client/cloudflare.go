@@ -0,0 +1,113 @@
+package client
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/url"
+	"strings"
+
+	"github.com/charbonnierg/letsgo/configuration"
+	"golang.org/x/exp/slices"
+)
+
+// Base URL of Cloudflare API
+const cloudflareAPIURL = "https://api.cloudflare.com/client/v4"
+
+// Permission required on zones to create challenge records
+const cloudflareDNSEditPermission = "#dns_records:edit"
+
+// Envelope of Cloudflare API responses
+type cloudflareResponse struct {
+	Success bool `json:"success"`
+	Errors  []struct {
+		Message string `json:"message"`
+	} `json:"errors"`
+	Result json.RawMessage `json:"result"`
+}
+
+// Send an authenticated GET request to Cloudflare API and decode result
+func cloudflareGet(httpClient *http.Client, baseURL string, token string, path string, result interface{}) error {
+	request, err := http.NewRequest(http.MethodGet, baseURL+path, nil)
+	if err != nil {
+		return err
+	}
+	request.Header.Set("Authorization", "Bearer "+token)
+	response, err := httpClient.Do(request)
+	if err != nil {
+		return err
+	}
+	defer response.Body.Close()
+	body := cloudflareResponse{}
+	err = json.NewDecoder(response.Body).Decode(&body)
+	if err != nil {
+		return errors.New(fmt.Sprintf("Unexpected response from Cloudflare API for %s: status code %d", path, response.StatusCode))
+	}
+	if !body.Success {
+		messages := []string{}
+		for _, e := range body.Errors {
+			messages = append(messages, e.Message)
+		}
+		return errors.New(fmt.Sprintf("Cloudflare API request %s failed: %s", path, strings.Join(messages, ", ")))
+	}
+	return json.Unmarshal(body.Result, result)
+}
+
+// Find zone hosting domain among zones visible to token, and return its permissions
+func cloudflareZonePermissions(httpClient *http.Client, baseURL string, token string, domain string) (string, []string, error) {
+	labels := strings.Split(strings.TrimPrefix(domain, "*."), ".")
+	for i := 0; i < len(labels)-1; i++ {
+		name := strings.Join(labels[i:], ".")
+		zones := []struct {
+			Name        string   `json:"name"`
+			Permissions []string `json:"permissions"`
+		}{}
+		err := cloudflareGet(httpClient, baseURL, token, "/zones?name="+url.QueryEscape(name), &zones)
+		if err != nil {
+			return "", nil, err
+		}
+		if len(zones) > 0 {
+			return zones[0].Name, zones[0].Permissions, nil
+		}
+	}
+	return "", nil, errors.New(fmt.Sprintf("Cloudflare API token cannot access the zone of %s", domain))
+}
+
+// Verify that Cloudflare API token is active and can edit DNS records
+// in the zones of all domains
+func validateCloudflareToken(httpClient *http.Client, baseURL string, token string, domains []string) error {
+	status := struct {
+		Status string `json:"status"`
+	}{}
+	err := cloudflareGet(httpClient, baseURL, token, "/user/tokens/verify", &status)
+	if err != nil {
+		return err
+	}
+	if status.Status != "active" {
+		return errors.New(fmt.Sprintf("Cloudflare API token is not active: %s", status.Status))
+	}
+	for _, domain := range domains {
+		zone, permissions, err := cloudflareZonePermissions(httpClient, baseURL, token, domain)
+		if err != nil {
+			return err
+		}
+		if !slices.Contains(permissions, cloudflareDNSEditPermission) {
+			return errors.New(fmt.Sprintf("Cloudflare API token is missing DNS:Edit permission on zone %s", zone))
+		}
+	}
+	return nil
+}
+
+// Get domains of all certificates whose challenges are solved by provider
+func domainsForProvider(userConfig configuration.UserConfig, name string) []string {
+	domains := []string{}
+	for _, group := range userConfig.Certificates {
+		for _, domain := range group.Domains {
+			if userConfig.ProviderFor(domain) == name && !slices.Contains(domains, domain) {
+				domains = append(domains, domain)
+			}
+		}
+	}
+	return domains
+}
@@ -0,0 +1,68 @@
+package client
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+)
+
+// Fake Cloudflare API granting token permissions on zones
+func newCloudflareServer(status string, zones map[string][]string) *httptest.Server {
+	mux := http.NewServeMux()
+	mux.HandleFunc("/user/tokens/verify", func(w http.ResponseWriter, r *http.Request) {
+		if r.Header.Get("Authorization") != "Bearer token" {
+			w.WriteHeader(http.StatusUnauthorized)
+			json.NewEncoder(w).Encode(map[string]interface{}{"success": false, "errors": []map[string]string{{"message": "Invalid API Token"}}})
+			return
+		}
+		json.NewEncoder(w).Encode(map[string]interface{}{"success": true, "result": map[string]string{"id": "1", "status": status}})
+	})
+	mux.HandleFunc("/zones", func(w http.ResponseWriter, r *http.Request) {
+		result := []map[string]interface{}{}
+		name := r.URL.Query().Get("name")
+		if permissions, ok := zones[name]; ok {
+			result = append(result, map[string]interface{}{"name": name, "permissions": permissions})
+		}
+		json.NewEncoder(w).Encode(map[string]interface{}{"success": true, "result": result})
+	})
+	return httptest.NewServer(mux)
+}
+
+// Test that Cloudflare API token must be active and able to edit DNS records of all zones
+func TestValidateCloudflareToken(t *testing.T) {
+	server := newCloudflareServer("active", map[string][]string{
+		"example.com": {"#zone:read", "#dns_records:edit"},
+		"example.net": {"#zone:read", "#dns_records:read"},
+	})
+	defer server.Close()
+	err := validateCloudflareToken(server.Client(), server.URL, "token", []string{"example.com", "*.www.example.com"})
+	if err != nil {
+		t.Errorf(err.Error())
+	}
+	for domain, err_want := range map[string]string{
+		"www.example.net": "Cloudflare API token is missing DNS:Edit permission on zone example.net",
+		"example.org":     "Cloudflare API token cannot access the zone of example.org",
+	} {
+		err = validateCloudflareToken(server.Client(), server.URL, "token", []string{domain})
+		if err == nil || err.Error() != err_want {
+			t.Errorf("Bad error. Want: %s. Got: %v", err_want, err)
+		}
+	}
+	err = validateCloudflareToken(server.Client(), server.URL, "other", []string{"example.com"})
+	err_want := "Cloudflare API request /user/tokens/verify failed: Invalid API Token"
+	if err == nil || err.Error() != err_want {
+		t.Errorf("Bad error. Want: %s. Got: %v", err_want, err)
+	}
+}
+
+// Test that disabled Cloudflare API tokens are rejected
+func TestValidateCloudflareTokenDisabled(t *testing.T) {
+	server := newCloudflareServer("disabled", nil)
+	defer server.Close()
+	err := validateCloudflareToken(server.Client(), server.URL, "token", []string{"example.com"})
+	err_want := "Cloudflare API token is not active: disabled"
+	if err == nil || err.Error() != err_want {
+		t.Errorf("Bad error. Want: %s. Got: %v", err_want, err)
+	}
+}
@@ -0,0 +1,80 @@
+package client
+
+import (
+	"sync"
+	"time"
+
+	"github.com/go-acme/lego/v4/challenge"
+	"github.com/go-acme/lego/v4/challenge/dns01"
+)
+
+// Slots limiting concurrent DNS provider API calls, shared by the
+// DNS providers of all certificate groups
+var (
+	dnsAPISlotsLock sync.Mutex
+	dnsAPISlots     chan struct{}
+)
+
+// Get slots shared by all DNS providers, sized to limit
+func sharedDNSAPISlots(limit int) chan struct{} {
+	dnsAPISlotsLock.Lock()
+	defer dnsAPISlotsLock.Unlock()
+	if dnsAPISlots == nil || cap(dnsAPISlots) != limit {
+		dnsAPISlots = make(chan struct{}, limit)
+	}
+	return dnsAPISlots
+}
+
+// DNS provider holding a slot while creating or removing challenge records.
+//
+// Implements challenge.Provider and challenge.ProviderTimeout.
+type limitedProvider struct {
+	challenge.Provider
+	slots chan struct{}
+}
+
+func (p *limitedProvider) Present(domain, token, keyAuth string) error {
+	p.slots <- struct{}{}
+	defer func() { <-p.slots }()
+	return p.Provider.Present(domain, token, keyAuth)
+}
+
+func (p *limitedProvider) CleanUp(domain, token, keyAuth string) error {
+	p.slots <- struct{}{}
+	defer func() { <-p.slots }()
+	return p.Provider.CleanUp(domain, token, keyAuth)
+}
+
+// Use timeout and interval of wrapped provider
+func (p *limitedProvider) Timeout() (time.Duration, time.Duration) {
+	if provider, ok := p.Provider.(challenge.ProviderTimeout); ok {
+		return provider.Timeout()
+	}
+	return dns01.DefaultPropagationTimeout, dns01.DefaultPollingInterval
+}
+
+// DNS provider whose challenges are solved one after another by lego,
+// instead of creating all challenge records first
+type sequentialProvider struct {
+	*limitedProvider
+}
+
+// Solve next challenge as soon as previous one is cleaned up
+func (p *sequentialProvider) Sequential() time.Duration {
+	return 0
+}
+
+// Limit concurrent calls to DNS provider API.
+//
+// Provider is returned unchanged when limit is zero. Challenges are
+// solved sequentially when limit is one.
+func limitConcurrency(provider challenge.Provider, limit int) challenge.Provider {
+	if limit <= 0 {
+		return provider
+	}
+	limited := &limitedProvider{Provider: provider, slots: sharedDNSAPISlots(limit)}
+	if limit == 1 {
+		return &sequentialProvider{limitedProvider: limited}
+	}
+	return limited
+}
@@ -0,0 +1,69 @@
+package client
+
+import (
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/go-acme/lego/v4/challenge/dns01"
+)
+
+// Fake DNS provider recording maximum number of concurrent calls
+type concurrentProvider struct {
+	lock    sync.Mutex
+	current int
+	max     int
+}
+
+func (p *concurrentProvider) Present(domain, token, keyAuth string) error {
+	p.lock.Lock()
+	p.current++
+	if p.current > p.max {
+		p.max = p.current
+	}
+	p.lock.Unlock()
+	time.Sleep(10 * time.Millisecond)
+	p.lock.Lock()
+	p.current--
+	p.lock.Unlock()
+	return nil
+}
+
+func (p *concurrentProvider) CleanUp(domain, token, keyAuth string) error {
+	return nil
+}
+
+// Test that sequential solving is configured when concurrency is one
+func TestLimitConcurrencySequential(t *testing.T) {
+	provider := &concurrentProvider{}
+	sequential, _ := dns01.NewChallenge(nil, nil, limitConcurrency(provider, 1)).Sequential()
+	if !sequential {
+		t.Errorf("Expected challenges to be solved sequentially")
+	}
+	sequential, _ = dns01.NewChallenge(nil, nil, limitConcurrency(provider, 2)).Sequential()
+	if sequential {
+		t.Errorf("Expected challenges to be solved in parallel")
+	}
+	if limitConcurrency(provider, 0) != provider {
+		t.Errorf("Expected provider to be unchanged without limit")
+	}
+}
+
+// Test that concurrent calls are limited across providers
+func TestLimitConcurrency(t *testing.T) {
+	provider := &concurrentProvider{}
+	wg := sync.WaitGroup{}
+	for i := 0; i < 6; i++ {
+		// Each certificate group creates its own provider
+		limited := limitConcurrency(provider, 2)
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			limited.Present("example.com", "token", "keyAuth")
+		}()
+	}
+	wg.Wait()
+	if provider.max != 2 {
+		t.Errorf("Bad maximum number of concurrent calls. Want: 2. Got: %d", provider.max)
+	}
+}
@@ -0,0 +1,69 @@
+package client
+
+import (
+	"bytes"
+	"encoding/json"
+	"io"
+	"log"
+	"net/http"
+	"sort"
+	"strings"
+)
+
+// Headers which are never logged in clear
+var sensitiveHeaders = []string{"Authorization", "Cookie", "Set-Cookie", "Replay-Nonce"}
+
+// HTTP transport logging ACME requests and responses.
+//
+// Request bodies (JWS signed by account key) are never logged.
+// Only type, status and detail of problem documents are logged.
+type debugTransport struct {
+	next http.RoundTripper
+}
+
+// Format headers, redacting sensitive values
+func redactHeaders(headers http.Header) string {
+	names := []string{}
+	for name := range headers {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	lines := []string{}
+	for _, name := range names {
+		value := strings.Join(headers.Values(name), ", ")
+		for _, sensitive := range sensitiveHeaders {
+			if http.CanonicalHeaderKey(name) == sensitive {
+				value = "[REDACTED]"
+			}
+		}
+		lines = append(lines, name+": "+value)
+	}
+	return strings.Join(lines, "; ")
+}
+
+func (t *debugTransport) RoundTrip(request *http.Request) (*http.Response, error) {
+	response, err := t.next.RoundTrip(request)
+	if err != nil {
+		log.Printf("ACME %s %s failed: %s", request.Method, request.URL, err.Error())
+		return response, err
+	}
+	log.Printf("ACME %s %s: %s (%s)", request.Method, request.URL, response.Status, redactHeaders(response.Header))
+	if strings.HasPrefix(response.Header.Get("Content-Type"), "application/problem+json") {
+		body, err := io.ReadAll(response.Body)
+		response.Body.Close()
+		if err != nil {
+			return nil, err
+		}
+		// Body is read again by lego
+		response.Body = io.NopCloser(bytes.NewReader(body))
+		problem := struct {
+			Type   string `json:"type"`
+			Status int    `json:"status"`
+			Detail string `json:"detail"`
+		}{}
+		if json.Unmarshal(body, &problem) == nil {
+			log.Printf("ACME problem: type=%s status=%d detail=%s", problem.Type, problem.Status, problem.Detail)
+		}
+	}
+	return response, nil
+}
@@ -0,0 +1,65 @@
+package client
+
+import (
+	"bytes"
+	"io"
+	"log"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"strings"
+	"testing"
+
+	"github.com/charbonnierg/letsgo/configuration"
+)
+
+// Test that debug transport is installed only when enabled
+func TestNewLegoConfigACMEDebug(t *testing.T) {
+	legoConfig := newLegoConfig(&User{}, configuration.UserConfig{ACMEDebug: true})
+	if _, ok := legoConfig.HTTPClient.Transport.(*debugTransport); !ok {
+		t.Errorf("Expected debug transport to be installed but got %T", legoConfig.HTTPClient.Transport)
+	}
+	legoConfig = newLegoConfig(&User{}, configuration.UserConfig{})
+	if _, ok := legoConfig.HTTPClient.Transport.(*debugTransport); ok {
+		t.Errorf("Expected debug transport not to be installed")
+	}
+}
+
+// Test that requests are logged with sensitive material redacted
+func TestDebugTransport(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Replay-Nonce", "secret-nonce")
+		w.Header().Set("Content-Type", "application/problem+json")
+		w.WriteHeader(http.StatusBadRequest)
+		w.Write([]byte(`{"type": "urn:ietf:params:acme:error:badNonce", "status": 400, "detail": "JWS has an invalid anti-replay nonce"}`))
+	}))
+	defer server.Close()
+	buffer := &bytes.Buffer{}
+	log.SetOutput(buffer)
+	defer log.SetOutput(os.Stderr)
+
+	client := &http.Client{Transport: &debugTransport{next: http.DefaultTransport}}
+	request, _ := http.NewRequest(http.MethodPost, server.URL+"/new-order", strings.NewReader("signed-jws-payload"))
+	request.Header.Set("Authorization", "Bearer secret-token")
+	response, err := client.Do(request)
+	if err != nil {
+		t.Fatalf(err.Error())
+	}
+	body, _ := io.ReadAll(response.Body)
+	response.Body.Close()
+	if !strings.Contains(string(body), "badNonce") {
+		t.Errorf("Expected response body to be readable after logging")
+	}
+
+	logs := buffer.String()
+	for _, want := range []string{"POST " + server.URL + "/new-order", "400 Bad Request", "Replay-Nonce: [REDACTED]", "type=urn:ietf:params:acme:error:badNonce"} {
+		if !strings.Contains(logs, want) {
+			t.Errorf("Expected logs to contain %q but got: %s", want, logs)
+		}
+	}
+	for _, secret := range []string{"secret-nonce", "secret-token", "signed-jws-payload"} {
+		if strings.Contains(logs, secret) {
+			t.Errorf("Sensitive value %q was logged: %s", secret, logs)
+		}
+	}
+}
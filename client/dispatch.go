@@ -0,0 +1,62 @@
+package client
+
+import (
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/go-acme/lego/v4/challenge"
+	"github.com/go-acme/lego/v4/challenge/dns01"
+)
+
+// DNS provider routing challenges to the provider hosting the domain zone.
+//
+// Implements challenge.Provider and challenge.ProviderTimeout.
+type dispatchProvider struct {
+	// Get name of provider used for domain
+	route     func(domain string) string
+	providers map[string]challenge.Provider
+}
+
+// Get provider used for domain
+func (d *dispatchProvider) providerFor(domain string) (challenge.Provider, error) {
+	name := d.route(domain)
+	provider, ok := d.providers[name]
+	if !ok {
+		return nil, errors.New(fmt.Sprintf("No DNS provider configured for %s: %s", domain, name))
+	}
+	return provider, nil
+}
+
+func (d *dispatchProvider) Present(domain, token, keyAuth string) error {
+	provider, err := d.providerFor(domain)
+	if err != nil {
+		return err
+	}
+	return provider.Present(domain, token, keyAuth)
+}
+
+func (d *dispatchProvider) CleanUp(domain, token, keyAuth string) error {
+	provider, err := d.providerFor(domain)
+	if err != nil {
+		return err
+	}
+	return provider.CleanUp(domain, token, keyAuth)
+}
+
+// Use the longest timeout and interval of all providers
+func (d *dispatchProvider) Timeout() (time.Duration, time.Duration) {
+	timeout, interval := dns01.DefaultPropagationTimeout, dns01.DefaultPollingInterval
+	for _, provider := range d.providers {
+		if p, ok := provider.(challenge.ProviderTimeout); ok {
+			providerTimeout, providerInterval := p.Timeout()
+			if providerTimeout > timeout {
+				timeout = providerTimeout
+			}
+			if providerInterval > interval {
+				interval = providerInterval
+			}
+		}
+	}
+	return timeout, interval
+}
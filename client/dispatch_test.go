@@ -0,0 +1,106 @@
+package client
+
+import (
+	"testing"
+	"time"
+
+	"github.com/charbonnierg/letsgo/configuration"
+	"github.com/go-acme/lego/v4/challenge"
+	"github.com/go-acme/lego/v4/providers/dns/linode"
+	"github.com/go-acme/lego/v4/providers/dns/vultr"
+)
+
+// Fake DNS provider recording presented records for a set of zones
+type fakeZoneProvider struct {
+	records []string
+	timeout time.Duration
+}
+
+func (p *fakeZoneProvider) Present(domain, token, keyAuth string) error {
+	p.records = append(p.records, domain)
+	return nil
+}
+
+func (p *fakeZoneProvider) CleanUp(domain, token, keyAuth string) error {
+	return nil
+}
+
+func (p *fakeZoneProvider) Timeout() (time.Duration, time.Duration) {
+	return p.timeout, time.Second
+}
+
+// Test that challenges are routed to the provider hosting the domain zone
+func TestDispatchProvider(t *testing.T) {
+	userConfig := configuration.UserConfig{
+		DNSProvider: "digitalocean",
+		DNSProviderMap: map[string]string{
+			"b.net":          "azuredns",
+			"internal.a.com": "azuredns",
+		},
+	}
+	do := &fakeZoneProvider{timeout: time.Minute}
+	az := &fakeZoneProvider{timeout: 5 * time.Minute}
+	dispatcher := &dispatchProvider{
+		route:     userConfig.ProviderFor,
+		providers: map[string]challenge.Provider{"digitalocean": do, "azuredns": az},
+	}
+	for _, domain := range []string{"a.com", "www.a.com", "b.net", "x.internal.a.com", "www.b.net"} {
+		err := dispatcher.Present(domain, "token", "keyAuth")
+		if err != nil {
+			t.Errorf(err.Error())
+		}
+	}
+	if len(do.records) != 2 || do.records[0] != "a.com" || do.records[1] != "www.a.com" {
+		t.Errorf("Bad records presented by DigitalOcean provider: %s", do.records)
+	}
+	if len(az.records) != 3 {
+		t.Errorf("Bad records presented by Azure DNS provider: %s", az.records)
+	}
+	timeout, _ := dispatcher.Timeout()
+	if timeout != 5*time.Minute {
+		t.Errorf("Bad timeout. Want: 5m0s. Got: %s", timeout)
+	}
+}
+
+// Test that an error is returned when no provider is configured for domain
+func TestDispatchProviderMissing(t *testing.T) {
+	dispatcher := &dispatchProvider{
+		route:     func(domain string) string { return "digitalocean" },
+		providers: map[string]challenge.Provider{},
+	}
+	err := dispatcher.Present("a.com", "token", "keyAuth")
+	if err == nil {
+		t.Errorf("Expected error when no provider is configured for domain")
+	}
+}
+
+// Test that challenges are routed to named providers of providers file
+func TestDispatchNamedProviders(t *testing.T) {
+	userConfig := configuration.UserConfig{
+		DNSProvider:    "digitalocean",
+		DNSProviderMap: map[string]string{"a.com": "public", "internal.a.com": "internal"},
+		NamedProviders: map[string]configuration.NamedProvider{
+			"public":   {Type: "linode", Zones: []string{"a.com"}, AuthToken: "LINODE"},
+			"internal": {Type: "vultr", Zones: []string{"internal.a.com"}, AuthToken: "VULTR"},
+		},
+		Certificates: []configuration.CertificateGroup{{Domains: []string{"www.a.com", "db.internal.a.com"}}},
+	}
+	provider, err := newDNSProvider(userConfig)
+	if err != nil {
+		t.Fatalf(err.Error())
+	}
+	dispatcher, ok := provider.(*dispatchProvider)
+	if !ok {
+		t.Fatalf("Bad provider. Want: *dispatchProvider. Got: %T", provider)
+	}
+	if _, ok := dispatcher.providers[dispatcher.route("www.a.com")].(*linode.DNSProvider); !ok {
+		t.Errorf("Bad provider for www.a.com. Want: *linode.DNSProvider. Got: %T", dispatcher.providers[dispatcher.route("www.a.com")])
+	}
+	if _, ok := dispatcher.providers[dispatcher.route("db.internal.a.com")].(*vultr.DNSProvider); !ok {
+		t.Errorf("Bad provider for db.internal.a.com. Want: *vultr.DNSProvider. Got: %T", dispatcher.providers[dispatcher.route("db.internal.a.com")])
+	}
+	// Each named provider uses its own token
+	if token := newVultrConfig(userConfig.ForProvider("internal")).APIKey; token != "VULTR" {
+		t.Errorf("Bad API key. Want: VULTR. Got: %s", token)
+	}
+}
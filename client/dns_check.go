@@ -0,0 +1,136 @@
+package client
+
+import (
+	"errors"
+	"fmt"
+	"net"
+	"os"
+	"strconv"
+	"sync"
+
+	"github.com/charbonnierg/letsgo/configuration"
+	"github.com/charbonnierg/letsgo/constants"
+	"github.com/go-acme/lego/v4/challenge/dns01"
+	"github.com/miekg/dns"
+)
+
+// Propagation check performed before notifying CA server that challenge is ready
+type dnsCheck struct {
+	// Record must be found on all authoritative name servers
+	requireCompletePropagation bool
+	// No check is performed at all
+	skip bool
+}
+
+// Select propagation check according to DNS check mode
+func newDNSCheck(mode string) dnsCheck {
+	switch mode {
+	case constants.DNS_CHECK_MODE_STRICT:
+		return dnsCheck{requireCompletePropagation: true}
+	case constants.DNS_CHECK_MODE_NONE:
+		return dnsCheck{skip: true}
+	default:
+		return dnsCheck{}
+	}
+}
+
+// Environment variable used by lego to disable CNAME following
+const legoDisableCNAMESupport = "LEGO_DISABLE_CNAME_SUPPORT"
+
+// CNAME following setting applied to lego, shared by the whole process
+var cnameSupport struct {
+	sync.Mutex
+	configured bool
+	follow     bool
+}
+
+// Enable or disable following CNAME records of challenge records.
+//
+// lego only exposes this setting through its environment variable, which
+// it reads on every lookup. Setting is applied once, before any client is
+// built, and clients configured with a different value are rejected since
+// they would change the setting of concurrent obtains.
+func ConfigureCNAMESupport(follow bool) error {
+	cnameSupport.Lock()
+	defer cnameSupport.Unlock()
+	if cnameSupport.configured {
+		if follow != cnameSupport.follow {
+			return errors.New(fmt.Sprintf("Invalid %s: %t. Value cannot differ between certificates, %t is already applied", constants.DNS_FOLLOW_CNAME, follow, cnameSupport.follow))
+		}
+		return nil
+	}
+	err := os.Setenv(legoDisableCNAMESupport, strconv.FormatBool(!follow))
+	if err != nil {
+		return err
+	}
+	cnameSupport.configured = true
+	cnameSupport.follow = follow
+	return nil
+}
+
+// Pre-check reporting challenge record as propagated without any DNS query
+func skipPreCheck(domain, fqdn, value string, check dns01.PreCheckFunc) (bool, error) {
+	return true, nil
+}
+
+// Select pre-check wrapping lego propagation check.
+//
+// Nil is returned when lego propagation check is used as is.
+func newPreCheck(userConfig configuration.UserConfig, check dnsCheck, client *dns.Client) dns01.WrapPreCheckFunc {
+	if check.skip {
+		return skipPreCheck
+	}
+	resolversFor := preCheckResolvers(userConfig, client.Net)
+	var wrap dns01.WrapPreCheckFunc
+	if len(userConfig.ZoneResolvers) > 0 || client.Net != constants.DNS_PROTOCOL_UDP {
+		wrap = zonePreCheck(resolversFor, client)
+	}
+	if check.requireCompletePropagation && len(userConfig.DisableCPDomains) > 0 {
+		wrap = partialPropagationPreCheck(userConfig, resolversFor, client, wrap)
+	}
+	return wrap
+}
+
+// Check challenge records of domains listed in DISABLE_CP against recursive
+// resolvers only, instead of all authoritative name servers.
+//
+// Other domains are checked using next pre-check when not nil, else using
+// lego propagation check.
+func partialPropagationPreCheck(userConfig configuration.UserConfig, resolversFor func(domain string) []string, client *dns.Client, next dns01.WrapPreCheckFunc) dns01.WrapPreCheckFunc {
+	return func(domain, fqdn, value string, check dns01.PreCheckFunc) (bool, error) {
+		if !userConfig.DisableCPFor(domain) {
+			if next != nil {
+				return next(domain, fqdn, value, check)
+			}
+			return check(fqdn, value)
+		}
+		resolvers := resolversFor(domain)
+		if len(resolvers) == 0 {
+			resolvers = userConfig.DNSResolvers
+		}
+		if len(resolvers) == 0 {
+			system, err := systemResolvers()
+			if err != nil {
+				return false, err
+			}
+			resolvers = system
+		}
+		return checkTXTRecord(client, fqdn, value, parseResolvers(resolvers, client.Net))
+	}
+}
+
+// Path of resolver configuration holding system resolvers
+var resolvConfPath = "/etc/resolv.conf"
+
+// Get system resolvers
+func systemResolvers() ([]string, error) {
+	config, err := dns.ClientConfigFromFile(resolvConfPath)
+	if err != nil {
+		return nil, err
+	}
+	resolvers := []string{}
+	for _, server := range config.Servers {
+		resolvers = append(resolvers, net.JoinHostPort(server, config.Port))
+	}
+	return resolvers, nil
+}
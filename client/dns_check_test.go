@@ -0,0 +1,113 @@
+package client
+
+import (
+	"os"
+	"testing"
+	"time"
+
+	"github.com/charbonnierg/letsgo/configuration"
+	"github.com/go-acme/lego/v4/challenge/dns01"
+)
+
+// Test that propagation check is selected according to DNS check mode
+func TestNewDNSCheck(t *testing.T) {
+	for mode, want := range map[string]dnsCheck{
+		"strict":      {requireCompletePropagation: true},
+		"propagation": {},
+		"none":        {skip: true},
+	} {
+		got := newDNSCheck(mode)
+		if got != want {
+			t.Errorf("Bad DNS check for mode %s. Want: %+v. Got: %+v", mode, want, got)
+		}
+	}
+	done, err := skipPreCheck("example.com", "_acme-challenge.example.com.", "value", func(fqdn, value string) (bool, error) {
+		t.Errorf("Expected DNS propagation not to be checked")
+		return false, nil
+	})
+	if err != nil || !done {
+		t.Errorf("Expected skipped check to succeed")
+	}
+}
+
+// Forget CNAME following setting applied by previous tests
+func resetCNAMESupport(t *testing.T) {
+	t.Setenv(legoDisableCNAMESupport, "")
+	cnameSupport.Lock()
+	cnameSupport.configured = false
+	cnameSupport.Unlock()
+	t.Cleanup(func() {
+		cnameSupport.Lock()
+		cnameSupport.configured = false
+		cnameSupport.Unlock()
+	})
+}
+
+// Test that CNAME following setting is applied to lego once, and that differing values are rejected
+func TestConfigureCNAMESupport(t *testing.T) {
+	resetCNAMESupport(t)
+	err := ConfigureCNAMESupport(false)
+	if err != nil {
+		t.Fatalf(err.Error())
+	}
+	if got := os.Getenv(legoDisableCNAMESupport); got != "true" {
+		t.Errorf("Bad %s. Want: true. Got: %s", legoDisableCNAMESupport, got)
+	}
+	// Challenge record is not resolved through CNAME records
+	fqdn, _ := dns01.GetRecord("app.example.com", "key-auth")
+	if fqdn != "_acme-challenge.app.example.com." {
+		t.Errorf("Bad challenge record. Want: _acme-challenge.app.example.com.. Got: %s", fqdn)
+	}
+	// Same value is accepted, e.g. by clients of other certificate groups
+	err = ConfigureCNAMESupport(false)
+	if err != nil {
+		t.Errorf(err.Error())
+	}
+	err = ConfigureCNAMESupport(true)
+	err_want := "Invalid DNS_FOLLOW_CNAME: true. Value cannot differ between certificates, false is already applied"
+	if err == nil || err.Error() != err_want {
+		t.Errorf("Bad error. Want: %s. Got: %v", err_want, err)
+	}
+	if got := os.Getenv(legoDisableCNAMESupport); got != "true" {
+		t.Errorf("Bad %s. Want: true. Got: %s", legoDisableCNAMESupport, got)
+	}
+}
+
+// Test that complete propagation check is only disabled for listed domains
+func TestPartialPropagationPreCheck(t *testing.T) {
+	resolver := newTestDNSServer(t, map[string]string{"_acme-challenge.slow.example.com.": "value"})
+	userConfig := configuration.UserConfig{DNSResolvers: []string{resolver}, DisableCPDomains: []string{"slow.example.com"}}
+	client := newDNSClient("udp", time.Second)
+
+	// Global DISABLE_CP=true or DISABLE_CP=false keeps lego propagation check
+	if newPreCheck(configuration.UserConfig{}, newDNSCheck("strict"), client) != nil {
+		t.Errorf("Expected lego propagation check to be used in strict mode")
+	}
+	if newPreCheck(configuration.UserConfig{DisableCPDomains: []string{"slow.example.com"}}, newDNSCheck("propagation"), client) != nil {
+		t.Errorf("Expected lego propagation check to be used in propagation mode")
+	}
+
+	preCheck := newPreCheck(userConfig, newDNSCheck("strict"), client)
+	if preCheck == nil {
+		t.Fatalf("Expected a pre-check when complete propagation is disabled for some domains")
+	}
+	strictChecked := false
+	strictCheck := func(fqdn, value string) (bool, error) {
+		strictChecked = true
+		return false, nil
+	}
+	ok, err := preCheck("slow.example.com", "_acme-challenge.slow.example.com.", "value", strictCheck)
+	if err != nil {
+		t.Fatalf(err.Error())
+	}
+	if !ok || strictChecked {
+		t.Errorf("Expected record of listed domain to be checked against recursive resolvers")
+	}
+	ok, err = preCheck("example.com", "_acme-challenge.example.com.", "value", strictCheck)
+	if err != nil {
+		t.Fatalf(err.Error())
+	}
+	if ok || !strictChecked {
+		t.Errorf("Expected complete propagation check for other domains")
+	}
+}
@@ -0,0 +1,117 @@
+package client
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"strings"
+
+	"github.com/charbonnierg/letsgo/configuration"
+	"github.com/charbonnierg/letsgo/constants"
+)
+
+// Error returned when DNS provider offers no cheap way to verify credentials
+var ErrDNSVerificationUnsupported = errors.New("DNS provider does not support credentials verification")
+
+// Error returned when credentials of a DNS provider cannot be verified
+type ProviderError struct {
+	Provider string
+	Err      error
+}
+
+func (e *ProviderError) Error() string {
+	return fmt.Sprintf("[%s] %s", e.Provider, e.Err.Error())
+}
+
+func (e *ProviderError) Unwrap() error {
+	return e.Err
+}
+
+// Check that credentials of each DNS provider in use authenticate
+// and can access the zones of domains routed to the provider.
+//
+// Only read-only API calls are performed. ErrDNSVerificationUnsupported
+// is returned, wrapped in a ProviderError, when a provider in use cannot
+// be verified, once all other providers have been verified.
+func VerifyDNSCredentials(config configuration.UserConfig) error {
+	if config.ChallengeType == constants.CHALLENGE_TYPE_MANUAL || len(config.DNSPresentHook) > 0 || config.SelfTest {
+		return &ProviderError{Provider: config.ChallengeType, Err: ErrDNSVerificationUnsupported}
+	}
+	var unsupported error
+	for _, name := range config.UsedProviders() {
+		providerConfig := config.ForProvider(name)
+		domains := domainsForProvider(config, name)
+		var err error
+		switch config.ProviderType(name) {
+		case constants.DNS_PROVIDER_DIGITALOCEAN:
+			doConfig := newDigitalOceanConfig(providerConfig)
+			err = verifyDigitalOceanToken(doConfig.HTTPClient, doConfig.BaseURL, doConfig.AuthToken, domains)
+		case constants.DNS_PROVIDER_CLOUDFLARE:
+			cfConfig := newCloudflareConfig(providerConfig)
+			err = validateCloudflareToken(cfConfig.HTTPClient, cloudflareAPIURL, cfConfig.AuthToken, domains)
+		default:
+			if unsupported == nil {
+				unsupported = &ProviderError{Provider: name, Err: ErrDNSVerificationUnsupported}
+			}
+			continue
+		}
+		if err != nil {
+			return &ProviderError{Provider: name, Err: err}
+		}
+	}
+	return unsupported
+}
+
+// Verify that DigitalOcean API token authenticates and can see the zones of all domains
+func verifyDigitalOceanToken(httpClient *http.Client, baseURL string, token string, domains []string) error {
+	zones := []string{}
+	next := strings.TrimSuffix(baseURL, "/") + "/v2/domains?per_page=200"
+	for next != "" {
+		request, err := http.NewRequest(http.MethodGet, next, nil)
+		if err != nil {
+			return err
+		}
+		request.Header.Set("Authorization", "Bearer "+token)
+		response, err := httpClient.Do(request)
+		if err != nil {
+			return err
+		}
+		page := struct {
+			Domains []struct {
+				Name string `json:"name"`
+			} `json:"domains"`
+			Links struct {
+				Pages struct {
+					Next string `json:"next"`
+				} `json:"pages"`
+			} `json:"links"`
+		}{}
+		err = json.NewDecoder(response.Body).Decode(&page)
+		response.Body.Close()
+		if response.StatusCode == http.StatusUnauthorized || response.StatusCode == http.StatusForbidden {
+			return errors.New(fmt.Sprintf("DigitalOcean API token was rejected: status code %d", response.StatusCode))
+		}
+		if err != nil || response.StatusCode != http.StatusOK {
+			return errors.New(fmt.Sprintf("Unexpected response from DigitalOcean API for /v2/domains: status code %d", response.StatusCode))
+		}
+		for _, domain := range page.Domains {
+			zones = append(zones, strings.ToLower(domain.Name))
+		}
+		next = page.Links.Pages.Next
+	}
+	for _, domain := range domains {
+		name := strings.ToLower(strings.TrimPrefix(domain, "*."))
+		found := false
+		for _, zone := range zones {
+			if name == zone || strings.HasSuffix(name, "."+zone) {
+				found = true
+				break
+			}
+		}
+		if !found {
+			return errors.New(fmt.Sprintf("DigitalOcean API token cannot access the zone of %s", domain))
+		}
+	}
+	return nil
+}
@@ -0,0 +1,82 @@
+package client
+
+import (
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/charbonnierg/letsgo/configuration"
+)
+
+// Fake DigitalOcean API listing domains visible to token, one domain per page
+func newDigitalOceanServer(domains []string) *httptest.Server {
+	var server *httptest.Server
+	server = httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path != "/v2/domains" {
+			w.WriteHeader(http.StatusNotFound)
+			return
+		}
+		if r.Header.Get("Authorization") != "Bearer token" {
+			w.WriteHeader(http.StatusUnauthorized)
+			json.NewEncoder(w).Encode(map[string]string{"id": "Unauthorized", "message": "Unable to authenticate you."})
+			return
+		}
+		page := 0
+		if r.URL.Query().Get("page") == "2" {
+			page = 1
+		}
+		body := map[string]interface{}{"domains": []map[string]string{{"name": domains[page]}}}
+		if page == 0 && len(domains) > 1 {
+			body["links"] = map[string]interface{}{"pages": map[string]string{"next": server.URL + "/v2/domains?page=2"}}
+		}
+		json.NewEncoder(w).Encode(body)
+	}))
+	return server
+}
+
+// Test that DigitalOcean token must authenticate and see the zones of all domains
+func TestVerifyDNSCredentialsDigitalOcean(t *testing.T) {
+	server := newDigitalOceanServer([]string{"example.com", "example.net"})
+	defer server.Close()
+	config := configuration.UserConfig{
+		DNSProvider:        "digitalocean",
+		AuthToken:          "token",
+		DigitalOceanAPIURL: server.URL,
+		Certificates:       []configuration.CertificateGroup{{Domains: []string{"*.example.com", "www.example.net"}}},
+	}
+	err := VerifyDNSCredentials(config)
+	if err != nil {
+		t.Errorf(err.Error())
+	}
+	config.Certificates = []configuration.CertificateGroup{{Domains: []string{"example.org"}}}
+	err = VerifyDNSCredentials(config)
+	err_want := "[digitalocean] DigitalOcean API token cannot access the zone of example.org"
+	if err == nil || err.Error() != err_want {
+		t.Errorf("Bad error. Want: %s. Got: %v", err_want, err)
+	}
+	config.AuthToken = "invalid"
+	err = VerifyDNSCredentials(config)
+	err_want = "[digitalocean] DigitalOcean API token was rejected: status code 401"
+	if err == nil || err.Error() != err_want {
+		t.Errorf("Bad error. Want: %s. Got: %v", err_want, err)
+	}
+}
+
+// Test that providers without credentials verification are reported
+func TestVerifyDNSCredentialsUnsupported(t *testing.T) {
+	config := configuration.UserConfig{
+		DNSProvider:  "linode",
+		AuthToken:    "token",
+		Certificates: []configuration.CertificateGroup{{Domains: []string{"example.com"}}},
+	}
+	err := VerifyDNSCredentials(config)
+	if !errors.Is(err, ErrDNSVerificationUnsupported) {
+		t.Errorf("Bad error. Want: %s. Got: %v", ErrDNSVerificationUnsupported, err)
+	}
+	var providerErr *ProviderError
+	if !errors.As(err, &providerErr) || providerErr.Provider != "linode" {
+		t.Errorf("Bad provider error: %v", err)
+	}
+}
@@ -0,0 +1,162 @@
+package client
+
+import (
+	"errors"
+	"fmt"
+	"net/http"
+	"reflect"
+	"sort"
+
+	"github.com/go-acme/lego/v4/acme"
+	"golang.org/x/exp/slices"
+)
+
+// Error returned when a domain failed validation
+type DomainError struct {
+	Domain string
+	Err    error
+}
+
+func (e *DomainError) Error() string {
+	return fmt.Sprintf("[%s] %s", e.Domain, e.Err.Error())
+}
+
+func (e *DomainError) Unwrap() error {
+	return e.Err
+}
+
+// Type of errors per domain reported by lego
+var domainErrorsType = reflect.TypeOf(map[string]error(nil))
+
+// Extract errors per domain from an error returned by lego.
+//
+// lego reports challenge failures using an unexported type whose
+// underlying type is map[string]error (resolver.obtainError in lego v4.9).
+// Neither errors.As nor a type switch can match an unexported named type,
+// so the error is converted to map[string]error instead of being iterated
+// using reflection. TestDomainErrorsFromLego fails when lego changes this type.
+func domainErrors(err error) map[string]error {
+	for ; err != nil; err = errors.Unwrap(err) {
+		value := reflect.ValueOf(err)
+		if value.Kind() != reflect.Map || !value.Type().ConvertibleTo(domainErrorsType) {
+			continue
+		}
+		failures := map[string]error{}
+		for domain, domainErr := range value.Convert(domainErrorsType).Interface().(map[string]error) {
+			if domainErr != nil {
+				failures[domain] = domainErr
+			}
+		}
+		return failures
+	}
+	return nil
+}
+
+// Aggregate errors per domain into a single error.
+//
+// Each failing domain is reported as a DomainError.
+// Errors which do not hold errors per domain are returned as is.
+func joinDomainErrors(err error) error {
+	failures := domainErrors(err)
+	if len(failures) == 0 {
+		return err
+	}
+	domains := make([]string, 0, len(failures))
+	for domain := range failures {
+		domains = append(domains, domain)
+	}
+	sort.Strings(domains)
+	errs := make([]error, 0, len(domains))
+	for _, domain := range domains {
+		errs = append(errs, &DomainError{Domain: domain, Err: failures[domain]})
+	}
+	return errors.Join(errs...)
+}
+
+// Error returned when ACME account cannot be used, e.g. because it was
+// deactivated on the CA server or its key is no longer accepted
+type AccountError struct {
+	// URI of account, empty when unknown
+	AccountURI string
+	Err        error
+}
+
+func (e *AccountError) Error() string {
+	account := e.AccountURI
+	if account == "" {
+		account = "of account key"
+	}
+	return fmt.Sprintf("ACME account %s cannot be used: %s. Register a new account with a new account key, or rotate the account key if it was compromised", account, e.Err.Error())
+}
+
+func (e *AccountError) Unwrap() error {
+	return e.Err
+}
+
+// ACME problem types reported when account is not usable
+var accountProblems = []string{
+	"urn:ietf:params:acme:error:unauthorized",
+	"urn:ietf:params:acme:error:accountDoesNotExist",
+}
+
+// Wrap errors reporting an unusable account into an AccountError.
+// Other errors are returned as is.
+func accountError(err error, accountURI string) error {
+	problem, ok := acmeProblem(err)
+	if !ok || !slices.Contains(accountProblems, problem.Type) {
+		return err
+	}
+	return &AccountError{AccountURI: accountURI, Err: err}
+}
+
+// ACME problem types reported when account is not authorized
+var unauthorizedProblems = []string{
+	"urn:ietf:params:acme:error:unauthorized",
+	"urn:ietf:params:acme:error:accountDoesNotExist",
+	"urn:ietf:params:acme:error:externalAccountRequired",
+}
+
+// Extract ACME problem from error returned by lego.
+//
+// lego returns problems as pointers, but problem values are supported as well.
+func acmeProblem(err error) (acme.ProblemDetails, bool) {
+	var problem *acme.ProblemDetails
+	if errors.As(err, &problem) && problem != nil {
+		return *problem, true
+	}
+	var value acme.ProblemDetails
+	if errors.As(err, &value) {
+		return value, true
+	}
+	return acme.ProblemDetails{}, false
+}
+
+// Check whether CA server rejected a request because of rate limits
+func IsRateLimited(err error) bool {
+	problem, ok := acmeProblem(err)
+	if !ok {
+		return false
+	}
+	return problem.Type == "urn:ietf:params:acme:error:rateLimited" || problem.HTTPStatus == http.StatusTooManyRequests
+}
+
+// Check whether CA server rejected a request because account is not authorized.
+//
+// Challenge failures are reported per domain, and are never considered
+// as authorization failures.
+func IsUnauthorized(err error) bool {
+	var domainErr *DomainError
+	if errors.As(err, &domainErr) {
+		return false
+	}
+	problem, ok := acmeProblem(err)
+	if !ok {
+		return false
+	}
+	for _, problemType := range unauthorizedProblems {
+		if problem.Type == problemType {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,68 @@
+package client
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+	"testing"
+
+	"github.com/go-acme/lego/v4/acme"
+	"github.com/go-acme/lego/v4/challenge/resolver"
+)
+
+// Mimic the error type used by lego to report errors per domain
+type fakeObtainError map[string]error
+
+func (e fakeObtainError) Error() string {
+	return "error: one or more domains had a problem"
+}
+
+// Test that errors per domain are joined into a single error
+func TestJoinDomainErrors(t *testing.T) {
+	obtainErr := fakeObtainError{
+		"a.example.com": errors.New("NXDOMAIN looking up TXT"),
+		"b.example.com": errors.New("time limit exceeded"),
+	}
+	err := joinDomainErrors(fmt.Errorf("obtain failed: %w", obtainErr))
+	got := err.Error()
+	for _, want := range []string{
+		"[a.example.com] NXDOMAIN looking up TXT",
+		"[b.example.com] time limit exceeded",
+	} {
+		if !strings.Contains(got, want) {
+			t.Errorf("Missing domain error. Want: %s. Got: %s", want, got)
+		}
+	}
+	var domainErr *DomainError
+	if !errors.As(err, &domainErr) {
+		t.Errorf("Expected a DomainError but got %T", err)
+	}
+}
+
+// Test that errors per domain reported by lego are detected, so that
+// upgrading lego fails loudly when the type of these errors changes
+func TestDomainErrorsFromLego(t *testing.T) {
+	// Authorization without any solver fails with an error per domain
+	authz := acme.Authorization{Status: acme.StatusPending, Identifier: acme.Identifier{Type: "dns", Value: "example.com"}}
+	legoErr := resolver.NewProber(resolver.NewSolversManager(nil)).Solve([]acme.Authorization{authz})
+	if legoErr == nil {
+		t.Fatalf("Expected lego to fail without solver")
+	}
+	failures := domainErrors(legoErr)
+	if len(failures) != 1 || failures["example.com"] == nil {
+		t.Fatalf("Errors per domain of lego not detected in %T: %s", legoErr, legoErr)
+	}
+	var domainErr *DomainError
+	if !errors.As(joinDomainErrors(legoErr), &domainErr) || domainErr.Domain != "example.com" {
+		t.Errorf("Expected a DomainError for example.com but got: %v", domainErr)
+	}
+}
+
+// Test that errors without details per domain are returned as is
+func TestJoinDomainErrorsPassthrough(t *testing.T) {
+	want := errors.New("no domains to obtain a certificate for")
+	got := joinDomainErrors(want)
+	if got != want {
+		t.Errorf("Bad error. Want: %s. Got: %s", want, got)
+	}
+}
@@ -0,0 +1,12 @@
+package client
+
+import (
+	"testing"
+
+	"github.com/charbonnierg/letsgo/internal/testcert"
+)
+
+// Generate a self-signed PEM-encoded certificate used as fixture
+func newTestCertificate(t *testing.T, domains ...string) []byte {
+	return testcert.PEM(testcert.New(t, testcert.Options{Names: domains}))
+}
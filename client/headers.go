@@ -0,0 +1,23 @@
+package client
+
+import (
+	"net/http"
+)
+
+// HTTP transport adding extra headers to every ACME request.
+//
+// Used to authenticate against ACME relays brokering requests
+// to the CA server.
+type headersTransport struct {
+	headers http.Header
+	next    http.RoundTripper
+}
+
+func (t *headersTransport) RoundTrip(request *http.Request) (*http.Response, error) {
+	// Round trippers must not modify the original request
+	request = request.Clone(request.Context())
+	for name, values := range t.headers {
+		request.Header[name] = values
+	}
+	return t.next.RoundTrip(request)
+}
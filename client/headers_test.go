@@ -0,0 +1,38 @@
+package client
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/charbonnierg/letsgo/configuration"
+)
+
+// Test that extra headers are present on outgoing ACME requests
+func TestHeadersTransport(t *testing.T) {
+	received := http.Header{}
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		received = r.Header.Clone()
+		w.WriteHeader(http.StatusOK)
+	}))
+	defer server.Close()
+	headers := http.Header{}
+	headers.Set("X-Relay-Token", "XXXXX")
+	legoConfig := newLegoConfig(&User{}, configuration.UserConfig{ACMEExtraHeaders: headers, ACMEDebug: true})
+	request, err := http.NewRequest(http.MethodGet, server.URL, nil)
+	if err != nil {
+		t.Fatalf(err.Error())
+	}
+	request.Header.Set("User-Agent", "letsgo")
+	response, err := legoConfig.HTTPClient.Do(request)
+	if err != nil {
+		t.Fatalf(err.Error())
+	}
+	response.Body.Close()
+	if received.Get("X-Relay-Token") != "XXXXX" || received.Get("User-Agent") != "letsgo" {
+		t.Errorf("Bad received headers: %v", received)
+	}
+	if request.Header.Get("X-Relay-Token") != "" {
+		t.Errorf("Expected original request not to be modified")
+	}
+}
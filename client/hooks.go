@@ -0,0 +1,50 @@
+package client
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"os/exec"
+	"strings"
+
+	"github.com/go-acme/lego/v4/challenge/dns01"
+)
+
+// DNS provider running external commands to create and remove challenge records.
+//
+// FQDN and value of the TXT record are appended to command arguments,
+// and exposed as environment variables.
+type hookProvider struct {
+	present []string
+	cleanup []string
+}
+
+// Run hook command for challenge record
+func runHook(command []string, domain string, keyAuth string) error {
+	fqdn, value := dns01.GetRecord(domain, keyAuth)
+	args := append(append([]string{}, command[1:]...), fqdn, value)
+	cmd := exec.Command(command[0], args...)
+	cmd.Env = append(os.Environ(),
+		"LETSGO_DOMAIN="+domain,
+		"LETSGO_FQDN="+fqdn,
+		"LETSGO_VALUE="+value,
+	)
+	output, err := cmd.CombinedOutput()
+	if err != nil {
+		return errors.New(fmt.Sprintf("Hook %s failed for %s: %s: %s", command[0], fqdn, err.Error(), strings.TrimSpace(string(output))))
+	}
+	return nil
+}
+
+// Run present hook
+func (p *hookProvider) Present(domain, token, keyAuth string) error {
+	return runHook(p.present, domain, keyAuth)
+}
+
+// Run cleanup hook, if any
+func (p *hookProvider) CleanUp(domain, token, keyAuth string) error {
+	if len(p.cleanup) == 0 {
+		return nil
+	}
+	return runHook(p.cleanup, domain, keyAuth)
+}
@@ -0,0 +1,61 @@
+package client
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/go-acme/lego/v4/challenge/dns01"
+)
+
+// Write an executable shell script
+func writeHook(t *testing.T, dir string, name string, script string) string {
+	path := filepath.Join(dir, name)
+	err := os.WriteFile(path, []byte("#!/bin/sh\n"+script+"\n"), 0o700)
+	if err != nil {
+		t.Fatalf(err.Error())
+	}
+	return path
+}
+
+// Test that hooks receive challenge record as arguments and environment variables
+func TestHookProvider(t *testing.T) {
+	dir := t.TempDir()
+	records := filepath.Join(dir, "records")
+	present := writeHook(t, dir, "present", `echo "$1 $2 $LETSGO_DOMAIN $LETSGO_FQDN $LETSGO_VALUE" >> "`+records+`"`)
+	cleanup := writeHook(t, dir, "cleanup", `echo "cleanup $1" >> "`+records+`"`)
+	provider := &hookProvider{present: []string{present}, cleanup: []string{cleanup}}
+	err := provider.Present("example.com", "token", "keyAuth")
+	if err != nil {
+		t.Fatalf(err.Error())
+	}
+	err = provider.CleanUp("example.com", "token", "keyAuth")
+	if err != nil {
+		t.Fatalf(err.Error())
+	}
+	fqdn, value := dns01.GetRecord("example.com", "keyAuth")
+	content, err := os.ReadFile(records)
+	if err != nil {
+		t.Fatalf(err.Error())
+	}
+	want := fqdn + " " + value + " example.com " + fqdn + " " + value + "\ncleanup " + fqdn + "\n"
+	if string(content) != want {
+		t.Errorf("Bad hook invocations. Want: %q. Got: %q", want, string(content))
+	}
+}
+
+// Test that a failing hook fails the challenge with its output
+func TestHookProviderFailure(t *testing.T) {
+	present := writeHook(t, t.TempDir(), "present", `echo "zone not found"; exit 3`)
+	provider := &hookProvider{present: []string{present}}
+	err := provider.Present("example.com", "token", "keyAuth")
+	if err == nil || !strings.Contains(err.Error(), "zone not found") {
+		t.Errorf("Bad error: %v", err)
+	}
+	// Cleanup hook is optional
+	err = provider.CleanUp("example.com", "token", "keyAuth")
+	if err != nil {
+		t.Errorf(err.Error())
+	}
+}
@@ -0,0 +1,76 @@
+package client
+
+import (
+	"bufio"
+	"fmt"
+	"io"
+	"os"
+	"sync"
+	"time"
+
+	"github.com/go-acme/lego/v4/challenge/dns01"
+)
+
+// Console used to print instructions and read confirmations of operator.
+//
+// A single console is shared by manual providers of all certificate groups,
+// so that prompts of concurrent groups do not interleave, and that each
+// confirmation is read by the prompt it answers.
+type manualConsole struct {
+	mutex sync.Mutex
+	in    *bufio.Reader
+	out   io.Writer
+}
+
+func newManualConsole(in io.Reader, out io.Writer) *manualConsole {
+	return &manualConsole{in: bufio.NewReader(in), out: out}
+}
+
+// Console of operator, shared by all manual providers
+var stdConsole = newManualConsole(os.Stdin, os.Stdout)
+
+// DNS provider asking an operator to create challenge records.
+//
+// When wait is zero, operator must confirm that record was created
+// by pressing 'Enter', else provider waits for the given duration.
+type manualProvider struct {
+	wait    time.Duration
+	ttl     int
+	console *manualConsole
+}
+
+func newManualProvider(wait time.Duration, ttl int, console *manualConsole) *manualProvider {
+	return &manualProvider{wait: wait, ttl: ttl, console: console}
+}
+
+// Print instructions to create the TXT record
+func (p *manualProvider) Present(domain, token, keyAuth string) error {
+	fqdn, value := dns01.GetRecord(domain, keyAuth)
+	p.console.mutex.Lock()
+	fmt.Fprintf(p.console.out, "Please create the following TXT record:\n")
+	fmt.Fprintf(p.console.out, "%s %d IN TXT %q\n", fqdn, p.ttl, value)
+	if p.wait > 0 {
+		fmt.Fprintf(p.console.out, "Waiting %s before validating challenge\n", p.wait)
+		p.console.mutex.Unlock()
+		time.Sleep(p.wait)
+		return nil
+	}
+	defer p.console.mutex.Unlock()
+	fmt.Fprintf(p.console.out, "Press 'Enter' when you are done\n")
+	_, err := p.console.in.ReadBytes('\n')
+	return err
+}
+
+// Print instructions to remove the TXT record
+func (p *manualProvider) CleanUp(domain, token, keyAuth string) error {
+	fqdn, _ := dns01.GetRecord(domain, keyAuth)
+	p.console.mutex.Lock()
+	defer p.console.mutex.Unlock()
+	fmt.Fprintf(p.console.out, "You can now remove the TXT record %s\n", fqdn)
+	return nil
+}
+
+// Solve challenges one at a time
+func (p *manualProvider) Sequential() time.Duration {
+	return dns01.DefaultPropagationTimeout
+}
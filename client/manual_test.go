@@ -0,0 +1,79 @@
+package client
+
+import (
+	"bytes"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/go-acme/lego/v4/challenge/dns01"
+)
+
+// Test that manual provider prints challenge record and waits for confirmation
+func TestManualProviderConfirmation(t *testing.T) {
+	out := &bytes.Buffer{}
+	provider := newManualProvider(0, 300, newManualConsole(strings.NewReader("\n"), out))
+	err := provider.Present("example.com", "token", "keyAuth")
+	if err != nil {
+		t.Fatalf(err.Error())
+	}
+	_, value := dns01.GetRecord("example.com", "keyAuth")
+	if !strings.Contains(out.String(), "_acme-challenge.example.com.") {
+		t.Errorf("Record name not printed: %s", out.String())
+	}
+	if !strings.Contains(out.String(), value) {
+		t.Errorf("Record value not printed: %s", out.String())
+	}
+	if !strings.Contains(out.String(), " 300 IN TXT ") {
+		t.Errorf("Configured TTL not printed: %s", out.String())
+	}
+	if !strings.Contains(out.String(), "Press 'Enter'") {
+		t.Errorf("Confirmation prompt not printed: %s", out.String())
+	}
+}
+
+// Test that manual provider fails when confirmation cannot be read
+func TestManualProviderNoConfirmation(t *testing.T) {
+	provider := newManualProvider(0, 300, newManualConsole(strings.NewReader(""), &bytes.Buffer{}))
+	err := provider.Present("example.com", "token", "keyAuth")
+	if err == nil {
+		t.Errorf("Expected error when confirmation cannot be read")
+	}
+}
+
+// Test that manual provider waits for configured delay
+func TestManualProviderWait(t *testing.T) {
+	out := &bytes.Buffer{}
+	provider := newManualProvider(10*time.Millisecond, 300, newManualConsole(strings.NewReader(""), out))
+	start := time.Now()
+	err := provider.Present("example.com", "token", "keyAuth")
+	if err != nil {
+		t.Fatalf(err.Error())
+	}
+	if time.Since(start) < 10*time.Millisecond {
+		t.Errorf("Manual provider did not wait")
+	}
+	if strings.Contains(out.String(), "Press 'Enter'") {
+		t.Errorf("Confirmation prompt printed although wait is configured")
+	}
+}
+
+// Test that concurrent manual providers sharing a console each read one confirmation
+func TestManualProviderSharedConsole(t *testing.T) {
+	out := &bytes.Buffer{}
+	console := newManualConsole(strings.NewReader("\n\n"), out)
+	errs := make(chan error, 2)
+	for _, domain := range []string{"example.com", "example.org"} {
+		go func(domain string) {
+			errs <- newManualProvider(0, 300, console).Present(domain, "token", "keyAuth")
+		}(domain)
+	}
+	for i := 0; i < 2; i++ {
+		if err := <-errs; err != nil {
+			t.Errorf("Expected each provider to read a confirmation. Got: %s", err.Error())
+		}
+	}
+	if strings.Count(out.String(), "Press 'Enter'") != 2 {
+		t.Errorf("Expected two confirmation prompts: %s", out.String())
+	}
+}
@@ -0,0 +1,201 @@
+package client
+
+import (
+	"crypto/tls"
+	"errors"
+	"fmt"
+	"net/http"
+
+	"github.com/charbonnierg/letsgo/configuration"
+	"github.com/charbonnierg/letsgo/constants"
+	"github.com/go-acme/lego/v4/challenge"
+	"github.com/go-acme/lego/v4/providers/dns/azure"
+	"github.com/go-acme/lego/v4/providers/dns/cloudflare"
+	"github.com/go-acme/lego/v4/providers/dns/digitalocean"
+	"github.com/go-acme/lego/v4/providers/dns/linode"
+	"github.com/go-acme/lego/v4/providers/dns/ovh"
+	"github.com/go-acme/lego/v4/providers/dns/vultr"
+)
+
+// Create DNS provider according to user configuration
+func newDNSProvider(userConfig configuration.UserConfig) (challenge.Provider, error) {
+	// Operator creates challenge records manually
+	if userConfig.ChallengeType == constants.CHALLENGE_TYPE_MANUAL {
+		return newManualProvider(userConfig.ManualWait, userConfig.DNSTTL, stdConsole), nil
+	}
+	// Self test creates challenge records in mock DNS server of local test CA
+	if userConfig.SelfTest {
+		return newChalltestsrvProvider(constants.ACME_TEST_CHALLTESTSRV), nil
+	}
+	// External commands create challenge records
+	if len(userConfig.DNSPresentHook) > 0 {
+		return &hookProvider{present: userConfig.DNSPresentHook, cleanup: userConfig.DNSCleanupHook}, nil
+	}
+	// A single provider is used for all domains
+	if len(userConfig.DNSProviderMap) == 0 {
+		return newNamedDNSProvider(userConfig.DNSProvider, userConfig)
+	}
+	// Else domains are routed to the provider hosting their zone
+	providers := map[string]challenge.Provider{}
+	for _, name := range userConfig.UsedProviders() {
+		provider, err := newNamedDNSProvider(name, userConfig)
+		if err != nil {
+			return nil, err
+		}
+		providers[name] = provider
+	}
+	return &dispatchProvider{route: userConfig.ProviderFor, providers: providers}, nil
+}
+
+// Create DNS provider by name.
+//
+// Named providers of providers file are created with their own credentials.
+func newNamedDNSProvider(name string, userConfig configuration.UserConfig) (challenge.Provider, error) {
+	providerType := userConfig.ProviderType(name)
+	provider, err := newProviderByType(providerType, name, userConfig.ForProvider(name))
+	if err != nil {
+		return nil, err
+	}
+	return limitTXTLength(providerType, provider, maxTXTLengths[providerType]), nil
+}
+
+// Create lego DNS provider by name
+func newProviderByName(name string, userConfig configuration.UserConfig) (challenge.Provider, error) {
+	return newProviderByType(name, name, userConfig)
+}
+
+// Create lego DNS provider of given type, routed domains are those of provider name
+func newProviderByType(providerType string, name string, userConfig configuration.UserConfig) (challenge.Provider, error) {
+	switch providerType {
+	case constants.DNS_PROVIDER_DIGITALOCEAN:
+		return digitalocean.NewDNSProviderConfig(newDigitalOceanConfig(userConfig))
+	case constants.DNS_PROVIDER_AZURE:
+		return azure.NewDNSProviderConfig(newAzureConfig(userConfig))
+	case constants.DNS_PROVIDER_OVH:
+		return ovh.NewDNSProviderConfig(newOVHConfig(userConfig))
+	case constants.DNS_PROVIDER_CLOUDFLARE:
+		providerConfig := newCloudflareConfig(userConfig)
+		// Catch permission misconfigurations before solving any challenge
+		if userConfig.Cloudflare.ValidateToken {
+			err := validateCloudflareToken(providerConfig.HTTPClient, cloudflareAPIURL, providerConfig.AuthToken, domainsForProvider(userConfig, name))
+			if err != nil {
+				return nil, err
+			}
+		}
+		return cloudflare.NewDNSProviderConfig(providerConfig)
+	case constants.DNS_PROVIDER_LINODE:
+		return linode.NewDNSProviderConfig(newLinodeConfig(userConfig))
+	case constants.DNS_PROVIDER_VULTR:
+		return vultr.NewDNSProviderConfig(newVultrConfig(userConfig))
+	default:
+		return nil, errors.New(fmt.Sprintf("Unsupported DNS provider: %s", providerType))
+	}
+}
+
+// Create transport of requests sent to DNS provider API.
+//
+// Requests are sent through configured proxy, and outdated
+// TLS versions are not negotiated.
+func newProviderTransport(userConfig configuration.UserConfig) *http.Transport {
+	transport := http.DefaultTransport.(*http.Transport).Clone()
+	if userConfig.ACMEHTTPProxy != nil {
+		transport.Proxy = http.ProxyURL(userConfig.ACMEHTTPProxy)
+	}
+	transport.TLSClientConfig = &tls.Config{MinVersion: userConfig.OutboundMinTLS}
+	return transport
+}
+
+// Report rate-limited responses as RateLimitError when retries are enabled.
+//
+// Only used by providers whose API client does not handle 429 responses,
+// Cloudflare and Azure SDKs already retry rate-limited requests.
+func withRateLimitRetries(transport http.RoundTripper, userConfig configuration.UserConfig) http.RoundTripper {
+	if userConfig.DNSAPIMaxRetries > 0 {
+		return &rateLimitTransport{next: transport}
+	}
+	return transport
+}
+
+// Generate DigitalOcean provider configuration
+func newDigitalOceanConfig(userConfig configuration.UserConfig) *digitalocean.Config {
+	providerConfig := digitalocean.NewDefaultConfig()
+	// Set auth token from user config
+	providerConfig.AuthToken = userConfig.AuthToken
+	providerConfig.PropagationTimeout = userConfig.PropagationTimeout
+	// Keep provider default TTL unless configured
+	if userConfig.DNSTTL > 0 {
+		providerConfig.TTL = userConfig.DNSTTL
+	}
+	// Keep provider default API endpoint unless configured
+	if userConfig.DigitalOceanAPIURL != "" {
+		providerConfig.BaseURL = userConfig.DigitalOceanAPIURL
+	}
+	providerConfig.HTTPClient.Transport = withRateLimitRetries(newProviderTransport(userConfig), userConfig)
+	return providerConfig
+}
+
+// Generate Cloudflare provider configuration
+func newCloudflareConfig(userConfig configuration.UserConfig) *cloudflare.Config {
+	providerConfig := cloudflare.NewDefaultConfig()
+	// Scoped API token is read from DNS auth token
+	providerConfig.AuthToken = userConfig.AuthToken
+	providerConfig.PropagationTimeout = userConfig.PropagationTimeout
+	// Keep provider default TTL unless configured
+	if userConfig.DNSTTL > 0 {
+		providerConfig.TTL = userConfig.DNSTTL
+	}
+	providerConfig.HTTPClient.Transport = newProviderTransport(userConfig)
+	return providerConfig
+}
+
+// Generate Linode provider configuration
+func newLinodeConfig(userConfig configuration.UserConfig) *linode.Config {
+	providerConfig := linode.NewDefaultConfig()
+	// Token is read from LINODE_TOKEN, or from DNS auth token
+	providerConfig.Token = userConfig.TokenFor(constants.DNS_PROVIDER_LINODE)
+	providerConfig.PropagationTimeout = userConfig.PropagationTimeout
+	// Keep provider default TTL unless configured
+	if userConfig.DNSTTL > 0 {
+		providerConfig.TTL = userConfig.DNSTTL
+	}
+	return providerConfig
+}
+
+// Generate Vultr provider configuration
+func newVultrConfig(userConfig configuration.UserConfig) *vultr.Config {
+	providerConfig := vultr.NewDefaultConfig()
+	// API key is read from VULTR_API_KEY, or from DNS auth token
+	providerConfig.APIKey = userConfig.TokenFor(constants.DNS_PROVIDER_VULTR)
+	providerConfig.PropagationTimeout = userConfig.PropagationTimeout
+	// Keep provider default TTL unless configured
+	if userConfig.DNSTTL > 0 {
+		providerConfig.TTL = userConfig.DNSTTL
+	}
+	return providerConfig
+}
+
+// Generate Azure DNS provider configuration
+func newAzureConfig(userConfig configuration.UserConfig) *azure.Config {
+	providerConfig := azure.NewDefaultConfig()
+	providerConfig.SubscriptionID = userConfig.Azure.SubscriptionID
+	providerConfig.ResourceGroup = userConfig.Azure.ResourceGroup
+	// Managed identity is used when client credentials are not provided
+	providerConfig.TenantID = userConfig.Azure.TenantID
+	providerConfig.ClientID = userConfig.Azure.ClientID
+	providerConfig.ClientSecret = userConfig.Azure.ClientSecret
+	providerConfig.PropagationTimeout = userConfig.PropagationTimeout
+	providerConfig.HTTPClient = &http.Client{Transport: newProviderTransport(userConfig)}
+	return providerConfig
+}
+
+// Generate OVH provider configuration
+func newOVHConfig(userConfig configuration.UserConfig) *ovh.Config {
+	providerConfig := ovh.NewDefaultConfig()
+	providerConfig.APIEndpoint = userConfig.OVH.Endpoint
+	providerConfig.ApplicationKey = userConfig.OVH.ApplicationKey
+	providerConfig.ApplicationSecret = userConfig.OVH.ApplicationSecret
+	providerConfig.ConsumerKey = userConfig.OVH.ConsumerKey
+	providerConfig.PropagationTimeout = userConfig.PropagationTimeout
+	providerConfig.HTTPClient.Transport = withRateLimitRetries(newProviderTransport(userConfig), userConfig)
+	return providerConfig
+}
@@ -0,0 +1,132 @@
+package client
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/charbonnierg/letsgo/configuration"
+)
+
+// Rate limit information exposed by CA server.
+//
+// CA servers such as Let's Encrypt do not publish remaining budget of
+// rate limits. Only Retry-After header, sent when a limit is exceeded,
+// and rate limit headers sent by some CA servers or relays are reported.
+type RateLimitInfo struct {
+	// URL of CA directory
+	Directory string
+	// Duration to wait before sending new requests, zero when not limited
+	RetryAfter time.Duration
+	// Rate limit headers found in responses, e.g. RateLimit-Remaining
+	Headers map[string]string
+}
+
+// Generate a human readable report of rate limit information
+func (i RateLimitInfo) String() string {
+	lines := []string{fmt.Sprintf("CA directory: %s", i.Directory)}
+	if i.RetryAfter > 0 {
+		lines = append(lines, fmt.Sprintf("Retry after: %s", i.RetryAfter))
+	} else {
+		lines = append(lines, "Retry after: <not limited>")
+	}
+	names := []string{}
+	for name := range i.Headers {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	for _, name := range names {
+		lines = append(lines, fmt.Sprintf("%s: %s", name, i.Headers[name]))
+	}
+	if len(names) == 0 {
+		lines = append(lines, "Rate limit headers: <not provided by CA server>")
+	}
+	return strings.Join(lines, "\n")
+}
+
+// Parse Retry-After header, holding either a number of seconds or a date
+func parseRetryAfter(value string, now time.Time) time.Duration {
+	if value == "" {
+		return 0
+	}
+	if seconds, err := strconv.Atoi(value); err == nil && seconds > 0 {
+		return time.Duration(seconds) * time.Second
+	}
+	if date, err := http.ParseTime(value); err == nil && date.After(now) {
+		return date.Sub(now).Round(time.Second)
+	}
+	return 0
+}
+
+// Record rate limit information found in response headers
+func (i *RateLimitInfo) record(headers http.Header) {
+	if retryAfter := parseRetryAfter(headers.Get("Retry-After"), time.Now()); retryAfter > i.RetryAfter {
+		i.RetryAfter = retryAfter
+	}
+	for name := range headers {
+		lower := strings.ToLower(name)
+		if strings.HasPrefix(lower, "ratelimit") || strings.HasPrefix(lower, "x-ratelimit") {
+			i.Headers[name] = strings.Join(headers.Values(name), ", ")
+		}
+	}
+}
+
+// Send a request and record rate limit information of response
+func (i *RateLimitInfo) send(httpClient *http.Client, userAgent string, method string, url string) (*http.Response, error) {
+	request, err := http.NewRequest(method, url, nil)
+	if err != nil {
+		return nil, err
+	}
+	request.Header.Set("User-Agent", userAgent)
+	response, err := httpClient.Do(request)
+	if err != nil {
+		return nil, err
+	}
+	i.record(response.Header)
+	return response, nil
+}
+
+// Fetch rate limit information using directory and nonce requests only
+func fetchRateLimitInfo(httpClient *http.Client, userAgent string, caDir string) (*RateLimitInfo, error) {
+	info := &RateLimitInfo{Directory: caDir, Headers: map[string]string{}}
+	response, err := info.send(httpClient, userAgent, http.MethodGet, caDir)
+	if err != nil {
+		return nil, err
+	}
+	defer response.Body.Close()
+	if response.StatusCode == http.StatusTooManyRequests {
+		return info, nil
+	}
+	if response.StatusCode != http.StatusOK {
+		return nil, errors.New(fmt.Sprintf("Unexpected status code %d for %s", response.StatusCode, caDir))
+	}
+	directory := struct {
+		NewNonce string `json:"newNonce"`
+	}{}
+	err = json.NewDecoder(response.Body).Decode(&directory)
+	if err != nil {
+		return nil, err
+	}
+	if directory.NewNonce == "" {
+		return info, nil
+	}
+	nonce, err := info.send(httpClient, userAgent, http.MethodHead, directory.NewNonce)
+	if err != nil {
+		return nil, err
+	}
+	nonce.Body.Close()
+	return info, nil
+}
+
+// Report rate limit information of CA server without creating any order.
+//
+// Only the directory and a new nonce are requested.
+func RateLimitStatus(userConfig configuration.UserConfig) (*RateLimitInfo, error) {
+	legoConfig := newLegoConfig(&User{}, userConfig)
+	return fetchRateLimitInfo(legoConfig.HTTPClient, legoConfig.UserAgent, userConfig.CADirURL)
+}
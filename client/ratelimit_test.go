@@ -0,0 +1,62 @@
+package client
+
+import (
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+)
+
+// Test that rate limit headers are reported from directory and nonce responses
+func TestFetchRateLimitInfo(t *testing.T) {
+	var server *httptest.Server
+	server = httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch r.URL.Path {
+		case "/directory":
+			w.Header().Set("RateLimit-Limit", "300")
+			fmt.Fprintf(w, `{"newNonce": "%s/nonce"}`, server.URL)
+		case "/nonce":
+			if r.Method != http.MethodHead {
+				t.Errorf("Bad method. Want: HEAD. Got: %s", r.Method)
+			}
+			w.Header().Set("RateLimit-Remaining", "12")
+			w.Header().Set("Retry-After", "120")
+		}
+	}))
+	defer server.Close()
+	info, err := fetchRateLimitInfo(server.Client(), "test", server.URL+"/directory")
+	if err != nil {
+		t.Fatalf(err.Error())
+	}
+	if info.RetryAfter != 2*time.Minute {
+		t.Errorf("Bad retry after. Want: 2m0s. Got: %s", info.RetryAfter)
+	}
+	if info.Headers["Ratelimit-Limit"] != "300" || info.Headers["Ratelimit-Remaining"] != "12" {
+		t.Errorf("Bad rate limit headers: %v", info.Headers)
+	}
+	report := info.String()
+	if !strings.Contains(report, "Retry after: 2m0s") || !strings.Contains(report, "Ratelimit-Remaining: 12") {
+		t.Errorf("Bad report: %s", report)
+	}
+}
+
+// Test that a rate limited directory request is reported instead of failing
+func TestFetchRateLimitInfoLimited(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Retry-After", time.Now().Add(time.Hour).UTC().Format(http.TimeFormat))
+		w.WriteHeader(http.StatusTooManyRequests)
+	}))
+	defer server.Close()
+	info, err := fetchRateLimitInfo(server.Client(), "test", server.URL)
+	if err != nil {
+		t.Fatalf(err.Error())
+	}
+	if info.RetryAfter < 59*time.Minute || info.RetryAfter > time.Hour {
+		t.Errorf("Bad retry after. Want: 1h0m0s. Got: %s", info.RetryAfter)
+	}
+	if !strings.Contains(info.String(), "<not provided by CA server>") {
+		t.Errorf("Bad report: %s", info.String())
+	}
+}
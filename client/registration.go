@@ -0,0 +1,68 @@
+package client
+
+import (
+	"errors"
+	"fmt"
+	"log"
+	"net/http"
+	"strings"
+
+	"github.com/go-acme/lego/v4/acme"
+	"github.com/go-acme/lego/v4/registration"
+)
+
+// Registration operations of lego client used to register account
+type registrar interface {
+	Register(options registration.RegisterOptions) (*registration.Resource, error)
+	QueryRegistration() (*registration.Resource, error)
+	ResolveAccountByKey() (*registration.Resource, error)
+}
+
+// Check whether CA server rejected registration because an account
+// already exists for account key
+func isAccountAlreadyExists(err error) bool {
+	problem, ok := acmeProblem(err)
+	if !ok {
+		return false
+	}
+	return problem.HTTPStatus == http.StatusConflict || strings.Contains(strings.ToLower(problem.Detail), "already exists")
+}
+
+// Check that account returned by CA server can be used
+func checkAccountStatus(reg *registration.Resource) error {
+	switch reg.Body.Status {
+	case acme.StatusDeactivated, acme.StatusRevoked:
+		return &AccountError{AccountURI: reg.URI, Err: errors.New(fmt.Sprintf("account is %s", reg.Body.Status))}
+	}
+	return nil
+}
+
+// Register account of user.
+//
+// When an account already exists for account key, its registration
+// is recovered from CA server instead of failing. An AccountError is
+// returned when account was deactivated or does not exist anymore.
+func registerAccount(r registrar, user *User) (*registration.Resource, error) {
+	reg, err := r.Register(registration.RegisterOptions{TermsOfServiceAgreed: true})
+	if err != nil && !isAccountAlreadyExists(err) {
+		return nil, accountError(err, "")
+	}
+	// Account was created or returned by CA server
+	if err == nil && reg != nil && reg.Body.Status != "" {
+		return reg, checkAccountStatus(reg)
+	}
+	log.Printf("Account already exists for account key, recovering registration")
+	// Look up account URL when CA server did not provide it
+	if reg == nil || reg.URI == "" {
+		reg, err = r.ResolveAccountByKey()
+		if err != nil {
+			return nil, accountError(err, "")
+		}
+	}
+	user.Registration = reg
+	reg, err = r.QueryRegistration()
+	if err != nil {
+		return nil, accountError(err, user.Registration.URI)
+	}
+	return reg, checkAccountStatus(reg)
+}
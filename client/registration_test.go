@@ -0,0 +1,112 @@
+package client
+
+import (
+	"errors"
+	"net/http"
+	"strings"
+	"testing"
+
+	"github.com/go-acme/lego/v4/acme"
+	"github.com/go-acme/lego/v4/registration"
+)
+
+// Fake registrar answering registration as a CA server would
+type fakeRegistrar struct {
+	user        *User
+	registerReg *registration.Resource
+	registerErr error
+	accountURI  string
+	status      string
+	queryErr    error
+	queried     bool
+}
+
+func (r *fakeRegistrar) Register(options registration.RegisterOptions) (*registration.Resource, error) {
+	return r.registerReg, r.registerErr
+}
+
+func (r *fakeRegistrar) QueryRegistration() (*registration.Resource, error) {
+	r.queried = true
+	if r.user.Registration == nil || r.user.Registration.URI != r.accountURI {
+		return nil, errors.New("acme: cannot query the registration of a nil client or user")
+	}
+	if r.queryErr != nil {
+		return nil, r.queryErr
+	}
+	reg := &registration.Resource{URI: r.accountURI}
+	reg.Body.Status = "valid"
+	if r.status != "" {
+		reg.Body.Status = r.status
+	}
+	return reg, nil
+}
+
+func (r *fakeRegistrar) ResolveAccountByKey() (*registration.Resource, error) {
+	return &registration.Resource{URI: r.accountURI}, nil
+}
+
+// Test that registration is recovered when account already exists
+func TestRegisterAccountAlreadyExists(t *testing.T) {
+	accountURI := "https://ca.example.com/acct/1"
+	for name, registrar := range map[string]*fakeRegistrar{
+		"conflict":        {registerReg: &registration.Resource{URI: accountURI}},
+		"already exists":  {registerErr: acme.ProblemDetails{HTTPStatus: http.StatusBadRequest, Detail: "Account already exists"}},
+		"problem pointer": {registerErr: &acme.ProblemDetails{HTTPStatus: http.StatusConflict, Detail: "Account exists"}},
+	} {
+		registrar.user = &User{}
+		registrar.accountURI = accountURI
+		reg, err := registerAccount(registrar, registrar.user)
+		if err != nil {
+			t.Fatalf("Unexpected error for %s: %s", name, err.Error())
+		}
+		if !registrar.queried {
+			t.Errorf("Expected registration to be queried for %s", name)
+		}
+		if reg.URI != accountURI || reg.Body.Status != "valid" {
+			t.Errorf("Bad registration for %s: %+v", name, reg)
+		}
+	}
+}
+
+// Test that other registration errors are returned
+func TestRegisterAccountError(t *testing.T) {
+	registrar := &fakeRegistrar{user: &User{}, registerErr: acme.ProblemDetails{HTTPStatus: http.StatusBadRequest, Detail: "Invalid contact"}}
+	_, err := registerAccount(registrar, registrar.user)
+	if err == nil {
+		t.Fatalf("Expected registration error")
+	}
+	if registrar.queried {
+		t.Errorf("Expected registration not to be queried")
+	}
+	// New accounts are returned as is
+	created := &registration.Resource{URI: "https://ca.example.com/acct/2"}
+	created.Body.Status = "valid"
+	registrar = &fakeRegistrar{user: &User{}, registerReg: created}
+	reg, err := registerAccount(registrar, registrar.user)
+	if err != nil || reg != created || registrar.queried {
+		t.Errorf("Expected created account to be returned without query")
+	}
+}
+
+// Test that deactivated and unknown accounts are reported as account errors
+func TestRegisterAccountDeactivated(t *testing.T) {
+	accountURI := "https://ca.example.com/acct/1"
+	exists := &registration.Resource{URI: accountURI}
+	for name, registrar := range map[string]*fakeRegistrar{
+		"deactivated": {registerReg: exists, status: "deactivated"},
+		"query":       {registerReg: exists, queryErr: &acme.ProblemDetails{Type: "urn:ietf:params:acme:error:unauthorized", HTTPStatus: http.StatusForbidden, Detail: "Account is not valid, has status \"deactivated\""}},
+		"register":    {registerErr: &acme.ProblemDetails{Type: "urn:ietf:params:acme:error:accountDoesNotExist", HTTPStatus: http.StatusBadRequest, Detail: "No account exists with the provided key"}},
+	} {
+		registrar.user = &User{}
+		registrar.accountURI = accountURI
+		_, err := registerAccount(registrar, registrar.user)
+		var accountErr *AccountError
+		if !errors.As(err, &accountErr) {
+			t.Errorf("Bad error for %s. Want: *AccountError. Got: %v", name, err)
+			continue
+		}
+		if !strings.Contains(err.Error(), "Register a new account") {
+			t.Errorf("Expected error for %s to suggest re-registration. Got: %s", name, err)
+		}
+	}
+}
@@ -0,0 +1,141 @@
+package client
+
+import (
+	"crypto/x509"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/charbonnierg/letsgo/configuration"
+	"github.com/go-acme/lego/v4/certcrypto"
+	"github.com/go-acme/lego/v4/certificate"
+)
+
+// Error returned when CA server does not advertise ACME Renewal Information (ARI)
+var ErrRenewalInfoUnsupported = errors.New("CA server does not advertise renewal information")
+
+// Renewal window suggested by CA server
+type RenewalWindow struct {
+	Start time.Time `json:"start"`
+	End   time.Time `json:"end"`
+}
+
+type renewalInfo struct {
+	SuggestedWindow RenewalWindow `json:"suggestedWindow"`
+}
+
+// Generate ARI certificate identifier.
+//
+// Identifier is made of base64url-encoded authority key identifier
+// and serial number, separated by a dot.
+func renewalInfoID(cert *x509.Certificate) (string, error) {
+	if len(cert.AuthorityKeyId) == 0 {
+		return "", errors.New("Certificate does not have an authority key identifier")
+	}
+	serial := cert.SerialNumber.Bytes()
+	// Serial number is encoded as a positive DER integer
+	if len(serial) == 0 || serial[0]&0x80 != 0 {
+		serial = append([]byte{0}, serial...)
+	}
+	encoding := base64.RawURLEncoding
+	return encoding.EncodeToString(cert.AuthorityKeyId) + "." + encoding.EncodeToString(serial), nil
+}
+
+// Send a GET request and decode JSON response
+func getJSON(httpClient *http.Client, userAgent string, url string, value interface{}) error {
+	request, err := http.NewRequest(http.MethodGet, url, nil)
+	if err != nil {
+		return err
+	}
+	request.Header.Set("User-Agent", userAgent)
+	response, err := httpClient.Do(request)
+	if err != nil {
+		return err
+	}
+	defer response.Body.Close()
+	if response.StatusCode != http.StatusOK {
+		return errors.New(fmt.Sprintf("Unexpected status code %d for %s", response.StatusCode, url))
+	}
+	return json.NewDecoder(response.Body).Decode(value)
+}
+
+// Fetch renewal window suggested by CA server for certificate
+func fetchRenewalWindow(httpClient *http.Client, userAgent string, caDir string, cert *x509.Certificate) (*RenewalWindow, error) {
+	directory := struct {
+		RenewalInfo string `json:"renewalInfo"`
+	}{}
+	err := getJSON(httpClient, userAgent, caDir, &directory)
+	if err != nil {
+		return nil, err
+	}
+	if directory.RenewalInfo == "" {
+		return nil, ErrRenewalInfoUnsupported
+	}
+	id, err := renewalInfoID(cert)
+	if err != nil {
+		return nil, err
+	}
+	info := renewalInfo{}
+	err = getJSON(httpClient, userAgent, strings.TrimSuffix(directory.RenewalInfo, "/")+"/"+id, &info)
+	if err != nil {
+		return nil, err
+	}
+	return &info.SuggestedWindow, nil
+}
+
+// Check whether certificate is due for renewal.
+//
+// Renewal window suggested by CA server is preferred when available,
+// else certificate is renewed once it expires within renewBefore.
+func shouldRenew(cert *x509.Certificate, now time.Time, renewBefore time.Duration, window *RenewalWindow) bool {
+	if window != nil && !window.Start.IsZero() {
+		return !now.Before(window.Start)
+	}
+	return !now.Before(cert.NotAfter.Add(-renewBefore))
+}
+
+// Compute renewal threshold of certificate.
+//
+// When a percentage is configured, threshold is this fraction of
+// certificate lifetime, else configured duration is used. When neither
+// is configured, certificate is renewed during last third of its lifetime.
+func renewThreshold(cert *x509.Certificate, before time.Duration, percent float64) time.Duration {
+	lifetime := cert.NotAfter.Sub(cert.NotBefore)
+	if percent > 0 {
+		return time.Duration(float64(lifetime) * percent / 100)
+	}
+	if before > 0 {
+		return before
+	}
+	return lifetime / 3
+}
+
+// Check whether previous certificate must be renewed according to user configuration.
+//
+// Renewal window suggested by CA server is queried first, and renewal
+// threshold is only used when CA server cannot provide it. Certificates
+// are always renewed when configured domains changed.
+func NeedsRenewal(config configuration.UserConfig, previous certificate.Resource) bool {
+	if !matchDomains(previous.Certificate, config.Domains) {
+		return true
+	}
+	cert, err := certcrypto.ParsePEMCertificate(previous.Certificate)
+	if err != nil {
+		return true
+	}
+	legoConfig := newLegoConfig(&User{}, config)
+	window, err := fetchRenewalWindow(legoConfig.HTTPClient, legoConfig.UserAgent, config.CADirURL, cert)
+	if err != nil {
+		// Fall back to static threshold
+		if !errors.Is(err, ErrRenewalInfoUnsupported) {
+			log.Printf("Failed to fetch renewal information: %s", err.Error())
+		}
+		window = nil
+	}
+	return shouldRenew(cert, time.Now(), renewThreshold(cert, config.RenewBefore, config.RenewBeforePercent), window)
+}
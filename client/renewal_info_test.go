@@ -0,0 +1,165 @@
+package client
+
+import (
+	"crypto/x509"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/charbonnierg/letsgo/configuration"
+	"github.com/charbonnierg/letsgo/internal/testcert"
+	"github.com/go-acme/lego/v4/certificate"
+)
+
+// Generate a parsed certificate with an authority key identifier
+func newTestX509Certificate(t *testing.T, serial int64, notAfter time.Time) *x509.Certificate {
+	return testcert.New(t, testcert.Options{
+		Names:          []string{"example.com"},
+		Serial:         serial,
+		NotBefore:      notAfter.Add(-90 * 24 * time.Hour),
+		NotAfter:       notAfter,
+		AuthorityKeyID: []byte{0x69, 0x88, 0x5b, 0x6b, 0x87, 0x46, 0x40, 0x41},
+	})
+}
+
+// Test that ARI certificate identifier is built from authority key identifier and serial number
+func TestRenewalInfoID(t *testing.T) {
+	cert := newTestX509Certificate(t, 0x87654321, time.Now())
+	got, err := renewalInfoID(cert)
+	if err != nil {
+		t.Fatalf(err.Error())
+	}
+	want := "aYhba4dGQEE.AIdlQyE"
+	if got != want {
+		t.Errorf("Bad renewal info ID. Want: %s. Got: %s", want, got)
+	}
+}
+
+// Test that renewal window is fetched from a stubbed ARI endpoint
+func TestFetchRenewalWindow(t *testing.T) {
+	start := time.Date(2022, 12, 1, 0, 0, 0, 0, time.UTC)
+	end := start.Add(48 * time.Hour)
+	var server *httptest.Server
+	server = httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch {
+		case r.URL.Path == "/directory":
+			json.NewEncoder(w).Encode(map[string]string{"renewalInfo": server.URL + "/renewal-info"})
+		case strings.HasPrefix(r.URL.Path, "/renewal-info/"):
+			json.NewEncoder(w).Encode(renewalInfo{SuggestedWindow: RenewalWindow{Start: start, End: end}})
+		default:
+			http.NotFound(w, r)
+		}
+	}))
+	defer server.Close()
+	cert := newTestX509Certificate(t, 1, time.Now().Add(60*24*time.Hour))
+	window, err := fetchRenewalWindow(server.Client(), "test", server.URL+"/directory", cert)
+	if err != nil {
+		t.Fatalf(err.Error())
+	}
+	if !window.Start.Equal(start) || !window.End.Equal(end) {
+		t.Errorf("Bad renewal window. Want: %s - %s. Got: %s - %s", start, end, window.Start, window.End)
+	}
+}
+
+// Test that an error is returned when CA server does not advertise ARI
+func TestFetchRenewalWindowUnsupported(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		json.NewEncoder(w).Encode(map[string]string{"newOrder": "https://example.com/new-order"})
+	}))
+	defer server.Close()
+	cert := newTestX509Certificate(t, 1, time.Now())
+	_, err := fetchRenewalWindow(server.Client(), "test", server.URL, cert)
+	if err != ErrRenewalInfoUnsupported {
+		t.Errorf("Bad error. Want: %s. Got: %v", ErrRenewalInfoUnsupported, err)
+	}
+}
+
+// Test that suggested renewal window is preferred over static threshold
+func TestShouldRenew(t *testing.T) {
+	now := time.Date(2022, 11, 20, 0, 0, 0, 0, time.UTC)
+	cert := newTestX509Certificate(t, 1, now.Add(20*24*time.Hour))
+	if !shouldRenew(cert, now, 30*24*time.Hour, nil) {
+		t.Errorf("Expected certificate expiring within threshold to be renewed")
+	}
+	if shouldRenew(cert, now, 10*24*time.Hour, nil) {
+		t.Errorf("Expected certificate not expiring within threshold to be kept")
+	}
+	window := &RenewalWindow{Start: now.Add(24 * time.Hour), End: now.Add(48 * time.Hour)}
+	if shouldRenew(cert, now, 30*24*time.Hour, window) {
+		t.Errorf("Expected certificate to be kept before suggested window")
+	}
+	window = &RenewalWindow{Start: now.Add(-time.Hour), End: now.Add(time.Hour)}
+	if !shouldRenew(cert, now, 10*24*time.Hour, window) {
+		t.Errorf("Expected certificate to be renewed within suggested window")
+	}
+}
+
+// Test that renewal threshold is computed from lifetime of certificate when a percentage is configured
+func TestRenewThreshold(t *testing.T) {
+	notBefore := time.Date(2022, 11, 1, 0, 0, 0, 0, time.UTC)
+	day := 24 * time.Hour
+	for _, test := range []struct {
+		lifetime time.Duration
+		before   time.Duration
+		percent  float64
+		want     time.Duration
+	}{
+		{lifetime: 90 * day, percent: 33, want: 29*day + 16*time.Hour + 48*time.Minute},
+		{lifetime: 7 * day, percent: 33, want: 2*day + 7*time.Hour + 26*time.Minute + 24*time.Second},
+		{lifetime: 90 * day, before: 30 * day, want: 30 * day},
+		{lifetime: 7 * day, before: 30 * day, want: 30 * day},
+		{lifetime: 90 * day, want: 30 * day},
+		{lifetime: 6 * day, want: 2 * day},
+	} {
+		cert := &x509.Certificate{NotBefore: notBefore, NotAfter: notBefore.Add(test.lifetime)}
+		got := renewThreshold(cert, test.before, test.percent)
+		if got != test.want {
+			t.Errorf("Bad threshold for %s lifetime. Want: %s. Got: %s", test.lifetime, test.want, got)
+		}
+	}
+}
+
+// Test that renewal window suggested by CA server is used by default, and that
+// certificates are renewed during last third of their lifetime when it is unavailable
+func TestNeedsRenewalDefaults(t *testing.T) {
+	var window *RenewalWindow
+	var server *httptest.Server
+	server = httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch {
+		case r.URL.Path == "/directory" && window != nil:
+			json.NewEncoder(w).Encode(map[string]string{"renewalInfo": server.URL + "/renewal-info"})
+		case r.URL.Path == "/directory":
+			json.NewEncoder(w).Encode(map[string]string{"newOrder": server.URL + "/new-order"})
+		case strings.HasPrefix(r.URL.Path, "/renewal-info/"):
+			json.NewEncoder(w).Encode(renewalInfo{SuggestedWindow: *window})
+		default:
+			http.NotFound(w, r)
+		}
+	}))
+	defer server.Close()
+	config := configuration.UserConfig{Domains: []string{"example.com"}, CADirURL: server.URL + "/directory"}
+	// Certificate expires in 60 days out of 90
+	cert := newTestX509Certificate(t, 1, time.Now().Add(60*24*time.Hour))
+	previous := certificate.Resource{Certificate: testcert.PEM(cert)}
+	if NeedsRenewal(config, previous) {
+		t.Errorf("Expected certificate not in last third of lifetime to be kept")
+	}
+	window = &RenewalWindow{Start: time.Now().Add(-time.Hour), End: time.Now().Add(time.Hour)}
+	if !NeedsRenewal(config, previous) {
+		t.Errorf("Expected certificate to be renewed within suggested window")
+	}
+	// Certificate expires in 20 days out of 90
+	cert = newTestX509Certificate(t, 1, time.Now().Add(20*24*time.Hour))
+	previous = certificate.Resource{Certificate: testcert.PEM(cert)}
+	window = &RenewalWindow{Start: time.Now().Add(24 * time.Hour), End: time.Now().Add(48 * time.Hour)}
+	if NeedsRenewal(config, previous) {
+		t.Errorf("Expected certificate to be kept before suggested window")
+	}
+	window = nil
+	if !NeedsRenewal(config, previous) {
+		t.Errorf("Expected certificate in last third of lifetime to be renewed")
+	}
+}
@@ -0,0 +1,92 @@
+package client
+
+import (
+	"errors"
+	"fmt"
+	"net"
+	"strings"
+	"time"
+
+	"github.com/charbonnierg/letsgo/configuration"
+	"github.com/charbonnierg/letsgo/constants"
+	"github.com/go-acme/lego/v4/challenge/dns01"
+	"github.com/miekg/dns"
+)
+
+// Check challenge records against resolvers of their zone when configured.
+//
+// Default propagation check is used for other zones.
+func zonePreCheck(resolversFor func(domain string) []string, client *dns.Client) dns01.WrapPreCheckFunc {
+	return func(domain, fqdn, value string, check dns01.PreCheckFunc) (bool, error) {
+		resolvers := resolversFor(domain)
+		if len(resolvers) == 0 {
+			return check(fqdn, value)
+		}
+		return checkTXTRecord(client, fqdn, value, parseResolvers(resolvers, client.Net))
+	}
+}
+
+// Select resolvers checked by letsgo for a domain.
+//
+// Resolvers of the zone of domain are used when configured. When resolvers
+// are not queried over UDP, lego cannot check propagation so default
+// resolvers are checked by letsgo as well.
+func preCheckResolvers(userConfig configuration.UserConfig, protocol string) func(domain string) []string {
+	return func(domain string) []string {
+		resolvers := userConfig.ResolversFor(domain)
+		if len(resolvers) == 0 && protocol != constants.DNS_PROTOCOL_UDP {
+			return userConfig.DNSResolvers
+		}
+		return resolvers
+	}
+}
+
+// Create client querying DNS resolvers using protocol
+func newDNSClient(protocol string, timeout time.Duration) *dns.Client {
+	if protocol == "" {
+		protocol = constants.DNS_PROTOCOL_UDP
+	}
+	return &dns.Client{Net: protocol, Timeout: timeout}
+}
+
+// Add default port of protocol to resolvers without port
+func parseResolvers(resolvers []string, protocol string) []string {
+	port := "53"
+	if protocol == constants.DNS_PROTOCOL_TCP_TLS {
+		port = "853"
+	}
+	parsed := []string{}
+	for _, resolver := range resolvers {
+		if _, _, err := net.SplitHostPort(resolver); err != nil {
+			resolver = net.JoinHostPort(resolver, port)
+		}
+		parsed = append(parsed, resolver)
+	}
+	return parsed
+}
+
+// Check that TXT record holds value on all resolvers
+func checkTXTRecord(client *dns.Client, fqdn string, value string, resolvers []string) (bool, error) {
+	msg := new(dns.Msg)
+	msg.SetQuestion(dns.Fqdn(fqdn), dns.TypeTXT)
+	msg.RecursionDesired = true
+	for _, resolver := range resolvers {
+		in, _, err := client.Exchange(msg, resolver)
+		if err != nil {
+			return false, err
+		}
+		if in.Rcode != dns.RcodeSuccess && in.Rcode != dns.RcodeNameError {
+			return false, errors.New(fmt.Sprintf("Unexpected response code %s from %s for %s", dns.RcodeToString[in.Rcode], resolver, fqdn))
+		}
+		found := false
+		for _, answer := range in.Answer {
+			if txt, ok := answer.(*dns.TXT); ok && strings.Join(txt.Txt, "") == value {
+				found = true
+			}
+		}
+		if !found {
+			return false, nil
+		}
+	}
+	return true, nil
+}
@@ -0,0 +1,130 @@
+package client
+
+import (
+	"net"
+	"testing"
+	"time"
+
+	"github.com/charbonnierg/letsgo/configuration"
+	"github.com/miekg/dns"
+	"golang.org/x/exp/slices"
+)
+
+// Start a DNS server answering TXT queries from records
+func newTestDNSServer(t *testing.T, records map[string]string) string {
+	conn, err := net.ListenPacket("udp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatalf(err.Error())
+	}
+	server := &dns.Server{PacketConn: conn, Handler: newTestDNSHandler(records)}
+	go server.ActivateAndServe()
+	t.Cleanup(func() { server.Shutdown() })
+	return conn.LocalAddr().String()
+}
+
+// Start a DNS server answering TXT queries from records over TCP
+func newTestTCPDNSServer(t *testing.T, records map[string]string) string {
+	listener, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatalf(err.Error())
+	}
+	server := &dns.Server{Listener: listener, Handler: newTestDNSHandler(records)}
+	go server.ActivateAndServe()
+	t.Cleanup(func() { server.Shutdown() })
+	return listener.Addr().String()
+}
+
+// Handler answering TXT queries from records
+func newTestDNSHandler(records map[string]string) dns.Handler {
+	return dns.HandlerFunc(func(w dns.ResponseWriter, r *dns.Msg) {
+		msg := new(dns.Msg)
+		msg.SetReply(r)
+		question := r.Question[0]
+		if value, ok := records[question.Name]; ok {
+			msg.Answer = append(msg.Answer, &dns.TXT{
+				Hdr: dns.RR_Header{Name: question.Name, Rrtype: dns.TypeTXT, Class: dns.ClassINET, Ttl: 60},
+				Txt: []string{value},
+			})
+		} else {
+			msg.Rcode = dns.RcodeNameError
+		}
+		w.WriteMsg(msg)
+	})
+}
+
+// Test that challenge records are checked against resolvers of their zone
+func TestZonePreCheck(t *testing.T) {
+	resolver := newTestDNSServer(t, map[string]string{"_acme-challenge.internal.net.": "value"})
+	resolversFor := func(domain string) []string {
+		if domain == "internal.net" {
+			return []string{resolver}
+		}
+		return nil
+	}
+	defaultChecked := false
+	defaultCheck := func(fqdn, value string) (bool, error) {
+		defaultChecked = true
+		return true, nil
+	}
+	preCheck := zonePreCheck(resolversFor, newDNSClient("udp", 0))
+	ok, err := preCheck("internal.net", "_acme-challenge.internal.net.", "value", defaultCheck)
+	if err != nil {
+		t.Fatalf(err.Error())
+	}
+	if !ok || defaultChecked {
+		t.Errorf("Expected record to be checked using zone resolver")
+	}
+	ok, err = preCheck("internal.net", "_acme-challenge.internal.net.", "other", defaultCheck)
+	if err != nil {
+		t.Fatalf(err.Error())
+	}
+	if ok {
+		t.Errorf("Expected record with a different value not to be found")
+	}
+	ok, err = preCheck("example.com", "_acme-challenge.example.com.", "value", defaultCheck)
+	if err != nil {
+		t.Fatalf(err.Error())
+	}
+	if !ok || !defaultChecked {
+		t.Errorf("Expected default check to be used for zones without resolvers")
+	}
+}
+
+// Test that default resolvers are checked over TCP when configured
+func TestPreCheckResolversProtocol(t *testing.T) {
+	resolver := newTestTCPDNSServer(t, map[string]string{"_acme-challenge.example.com.": "value"})
+	userConfig := configuration.UserConfig{DNSResolvers: []string{resolver}, ResolverProtocol: "tcp"}
+	client := newDNSClient(userConfig.ResolverProtocol, time.Second)
+	resolversFor := preCheckResolvers(userConfig, client.Net)
+	if got := resolversFor("example.com"); len(got) != 1 || got[0] != resolver {
+		t.Fatalf("Bad resolvers. Want: [%s]. Got: %s", resolver, got)
+	}
+	preCheck := zonePreCheck(resolversFor, client)
+	ok, err := preCheck("example.com", "_acme-challenge.example.com.", "value", func(fqdn, value string) (bool, error) {
+		t.Errorf("Expected default check not to be used")
+		return false, nil
+	})
+	if err != nil {
+		t.Fatalf(err.Error())
+	}
+	if !ok {
+		t.Errorf("Expected record to be found over TCP")
+	}
+	// Default resolvers are left to lego over UDP
+	if got := preCheckResolvers(userConfig, "udp")("example.com"); len(got) != 0 {
+		t.Errorf("Expected no resolver to be checked by letsgo but got: %s", got)
+	}
+}
+
+// Test that default port of protocol is added to resolvers
+func TestParseResolvers(t *testing.T) {
+	got := parseResolvers([]string{"1.1.1.1", "9.9.9.9:5353", "2606:4700::1111"}, "tcp-tls")
+	want := []string{"1.1.1.1:853", "9.9.9.9:5353", "[2606:4700::1111]:853"}
+	if !slices.Equal(got, want) {
+		t.Errorf("Bad resolvers. Want: %s. Got: %s", want, got)
+	}
+	got = parseResolvers([]string{"1.1.1.1"}, "udp")
+	if !slices.Equal(got, []string{"1.1.1.1:53"}) {
+		t.Errorf("Bad resolvers. Want: [1.1.1.1:53]. Got: %s", got)
+	}
+}
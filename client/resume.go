@@ -0,0 +1,216 @@
+package client
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log"
+	"net/http"
+	"os"
+	"path/filepath"
+	"sort"
+	"time"
+
+	"github.com/charbonnierg/letsgo/configuration"
+	"github.com/go-acme/lego/v4/acme"
+	"github.com/go-acme/lego/v4/acme/api"
+	"github.com/go-acme/lego/v4/certcrypto"
+	"github.com/go-acme/lego/v4/certificate"
+	"github.com/go-acme/lego/v4/lego"
+	"github.com/go-acme/lego/v4/platform/wait"
+	"golang.org/x/exp/slices"
+)
+
+// Order created by a previous run, persisted until a certificate is obtained
+type savedOrder struct {
+	URL     string   `json:"url"`
+	Domains []string `json:"domains"`
+}
+
+// Path of file holding in-flight order of certificate
+func orderFile(config configuration.UserConfig) string {
+	return filepath.Join(config.OutputDirectory, config.Filename+".order.json")
+}
+
+// Load saved order, nil is returned when no order is saved
+func loadOrder(path string) (*savedOrder, error) {
+	content, err := os.ReadFile(path)
+	if errors.Is(err, os.ErrNotExist) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	order := &savedOrder{}
+	err = json.Unmarshal(content, order)
+	if err != nil {
+		return nil, err
+	}
+	return order, nil
+}
+
+// Save order so that it can be resumed by next run
+func saveOrder(path string, order savedOrder) error {
+	content, err := json.Marshal(order)
+	if err != nil {
+		return err
+	}
+	return os.WriteFile(path, content, 0o600)
+}
+
+// Transport saving URL of orders created by lego
+type orderRecorder struct {
+	newOrderURL string
+	path        string
+	domains     []string
+	next        http.RoundTripper
+}
+
+func (t *orderRecorder) RoundTrip(request *http.Request) (*http.Response, error) {
+	response, err := t.next.RoundTrip(request)
+	if err != nil {
+		return response, err
+	}
+	if request.Method == http.MethodPost && request.URL.String() == t.newOrderURL && response.StatusCode == http.StatusCreated {
+		location := response.Header.Get("Location")
+		if location != "" {
+			if err := saveOrder(t.path, savedOrder{URL: location, Domains: t.domains}); err != nil {
+				log.Printf("Could not save order %s: %s", location, err)
+			}
+		}
+	}
+	return response, nil
+}
+
+// Record orders created through lego configuration
+func recordOrders(legoConfig *lego.Config, config configuration.UserConfig) error {
+	directory := struct {
+		NewOrder string `json:"newOrder"`
+	}{}
+	err := getJSON(legoConfig.HTTPClient, legoConfig.UserAgent, config.CADirURL, &directory)
+	if err != nil {
+		return err
+	}
+	legoConfig.HTTPClient.Transport = &orderRecorder{
+		newOrderURL: directory.NewOrder,
+		path:        orderFile(config),
+		domains:     config.Domains,
+		next:        legoConfig.HTTPClient.Transport,
+	}
+	return nil
+}
+
+// Subset of lego order service used to resume an order
+type orderService interface {
+	Get(orderURL string) (acme.ExtendedOrder, error)
+	UpdateForCSR(orderURL string, csr []byte) (acme.ExtendedOrder, error)
+}
+
+// Subset of lego certificate service used to download certificate of resumed order
+type certificateService interface {
+	Get(certURL string, bundle bool) ([]byte, []byte, error)
+}
+
+// Check whether two lists hold the same domains, regardless of order
+func sameDomains(a []string, b []string) bool {
+	a = slices.Clone(a)
+	b = slices.Clone(b)
+	sort.Strings(a)
+	sort.Strings(b)
+	return slices.Equal(a, b)
+}
+
+// Finalize a saved order whose authorizations are all valid.
+//
+// Orders which are not ready cannot be resumed, because a new
+// private key must be generated and signed by the CA server.
+func resumeOrder(orders orderService, certificates certificateService, saved savedOrder, domains []string, keyType certcrypto.KeyType, timeout time.Duration) (*certificate.Resource, error) {
+	if !sameDomains(saved.Domains, domains) {
+		return nil, errors.New(fmt.Sprintf("Order %s was created for other domains: %s", saved.URL, saved.Domains))
+	}
+	order, err := orders.Get(saved.URL)
+	if err != nil {
+		return nil, err
+	}
+	if order.Status != acme.StatusReady {
+		return nil, errors.New(fmt.Sprintf("Order %s is %s", saved.URL, order.Status))
+	}
+	privateKey, err := certcrypto.GeneratePrivateKey(keyType)
+	if err != nil {
+		return nil, err
+	}
+	csr, err := certcrypto.GenerateCSR(privateKey, domains[0], domains, false)
+	if err != nil {
+		return nil, err
+	}
+	order, err = orders.UpdateForCSR(order.Finalize, csr)
+	if err != nil {
+		return nil, err
+	}
+	// Wait for CA server to issue certificate
+	if order.Status != acme.StatusValid {
+		var orderErr error
+		err = wait.For("certificate", timeout, timeout/60, func() (bool, error) {
+			order, orderErr = orders.Get(saved.URL)
+			if orderErr != nil {
+				return false, orderErr
+			}
+			if order.Status == acme.StatusInvalid {
+				orderErr = errors.New(fmt.Sprintf("Order %s is invalid", saved.URL))
+				return true, nil
+			}
+			return order.Status == acme.StatusValid, nil
+		})
+		if err == nil {
+			err = orderErr
+		}
+		if err != nil {
+			return nil, err
+		}
+	}
+	cert, issuer, err := certificates.Get(order.Certificate, true)
+	if err != nil {
+		return nil, err
+	}
+	return &certificate.Resource{
+		Domain:            domains[0],
+		CertURL:           order.Certificate,
+		CertStableURL:     order.Certificate,
+		PrivateKey:        certcrypto.PEMEncode(privateKey),
+		Certificate:       cert,
+		IssuerCertificate: issuer,
+	}, nil
+}
+
+// Resume order saved by an interrupted run, else obtain a new certificate.
+//
+// Saved order is removed once it is resumed or cannot be resumed,
+// and once a certificate is obtained.
+func obtainOrResume(config configuration.UserConfig, user *User, obtain func() (*certificate.Resource, error)) (*certificate.Resource, error) {
+	path := orderFile(config)
+	saved, err := loadOrder(path)
+	if err != nil {
+		log.Printf("Ignoring saved order %s: %s", path, err)
+	}
+	if saved != nil {
+		legoConfig := newLegoConfig(user, config)
+		core, err := api.New(legoConfig.HTTPClient, legoConfig.UserAgent, config.CADirURL, user.Registration.URI, user.Key)
+		if err != nil {
+			return &certificate.Resource{}, err
+		}
+		timeout := legoConfig.Certificate.Timeout
+		resource, err := resumeOrder(core.Orders, core.Certificates, *saved, config.Domains, config.CADirKeyType, timeout)
+		os.Remove(path)
+		if err == nil {
+			log.Printf("Resumed order %s", saved.URL)
+			return resource, nil
+		}
+		log.Printf("Could not resume order %s, creating a new order: %s", saved.URL, err)
+	}
+	resource, err := obtain()
+	if err != nil {
+		return resource, err
+	}
+	os.Remove(path)
+	return resource, nil
+}
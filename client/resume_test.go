@@ -0,0 +1,114 @@
+package client
+
+import (
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/go-acme/lego/v4/acme"
+	"github.com/go-acme/lego/v4/certcrypto"
+)
+
+// Fake order service of a CA server
+type fakeOrders struct {
+	status    string
+	finalized bool
+}
+
+func (o *fakeOrders) Get(orderURL string) (acme.ExtendedOrder, error) {
+	if o.finalized {
+		o.status = acme.StatusValid
+	}
+	return acme.ExtendedOrder{Order: acme.Order{
+		Status:      o.status,
+		Finalize:    orderURL + "/finalize",
+		Certificate: orderURL + "/cert",
+	}, Location: orderURL}, nil
+}
+
+func (o *fakeOrders) UpdateForCSR(orderURL string, csr []byte) (acme.ExtendedOrder, error) {
+	if o.status != acme.StatusReady {
+		return acme.ExtendedOrder{}, errors.New("order is not ready")
+	}
+	o.finalized = true
+	return acme.ExtendedOrder{Order: acme.Order{Status: acme.StatusProcessing}}, nil
+}
+
+// Fake certificate service of a CA server
+type fakeCertificates struct{}
+
+func (c *fakeCertificates) Get(certURL string, bundle bool) ([]byte, []byte, error) {
+	return []byte("certificate"), []byte("issuer"), nil
+}
+
+// Test that an order whose authorizations are valid is finalized without new challenges
+func TestResumeOrder(t *testing.T) {
+	saved := savedOrder{URL: "https://ca.example.com/order/1", Domains: []string{"*.example.com", "example.com"}}
+	orders := &fakeOrders{status: acme.StatusReady}
+	resource, err := resumeOrder(orders, &fakeCertificates{}, saved, []string{"example.com", "*.example.com"}, certcrypto.EC256, time.Second)
+	if err != nil {
+		t.Fatalf(err.Error())
+	}
+	if string(resource.Certificate) != "certificate" || resource.CertURL != "https://ca.example.com/order/1/cert" {
+		t.Errorf("Bad resource: %+v", resource)
+	}
+	if _, err := certcrypto.ParsePEMPrivateKey(resource.PrivateKey); err != nil {
+		t.Errorf("Bad private key: %s", err)
+	}
+}
+
+// Test that orders which are not ready or were created for other domains are not resumed
+func TestResumeOrderRejected(t *testing.T) {
+	saved := savedOrder{URL: "https://ca.example.com/order/1", Domains: []string{"example.com"}}
+	_, err := resumeOrder(&fakeOrders{status: acme.StatusPending}, &fakeCertificates{}, saved, []string{"example.com"}, certcrypto.EC256, time.Second)
+	err_want := "Order https://ca.example.com/order/1 is pending"
+	if err == nil || err.Error() != err_want {
+		t.Errorf("Bad error. Want: %s. Got: %v", err_want, err)
+	}
+	_, err = resumeOrder(&fakeOrders{status: acme.StatusReady}, &fakeCertificates{}, saved, []string{"example.net"}, certcrypto.EC256, time.Second)
+	err_want = "Order https://ca.example.com/order/1 was created for other domains: [example.com]"
+	if err == nil || err.Error() != err_want {
+		t.Errorf("Bad error. Want: %s. Got: %v", err_want, err)
+	}
+}
+
+// Test that URL of new orders is saved
+func TestOrderRecorder(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Location", "https://ca.example.com/order/1")
+		w.WriteHeader(http.StatusCreated)
+	}))
+	defer server.Close()
+	path := filepath.Join(t.TempDir(), "example.com.order.json")
+	client := &http.Client{Transport: &orderRecorder{
+		newOrderURL: server.URL + "/new-order",
+		path:        path,
+		domains:     []string{"example.com"},
+		next:        http.DefaultTransport,
+	}}
+	// Only new orders are saved
+	response, err := client.Post(server.URL+"/new-account", "application/jose+json", nil)
+	if err != nil {
+		t.Fatalf(err.Error())
+	}
+	response.Body.Close()
+	saved, err := loadOrder(path)
+	if err != nil || saved != nil {
+		t.Errorf("Expected no saved order but got: %+v (%v)", saved, err)
+	}
+	response, err = client.Post(server.URL+"/new-order", "application/jose+json", nil)
+	if err != nil {
+		t.Fatalf(err.Error())
+	}
+	response.Body.Close()
+	saved, err = loadOrder(path)
+	if err != nil {
+		t.Fatalf(err.Error())
+	}
+	if saved == nil || saved.URL != "https://ca.example.com/order/1" || saved.Domains[0] != "example.com" {
+		t.Errorf("Bad saved order: %+v", saved)
+	}
+}
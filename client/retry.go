@@ -0,0 +1,107 @@
+package client
+
+import (
+	"errors"
+	"fmt"
+	"log"
+	"net/http"
+	"time"
+
+	"github.com/go-acme/lego/v4/challenge"
+	"github.com/go-acme/lego/v4/challenge/dns01"
+)
+
+// Delay used when a rate-limited response holds no valid Retry-After header
+const defaultRetryAfter = time.Second
+
+// Longest delay honored before retrying a rate-limited call
+const maxRetryAfter = 5 * time.Minute
+
+// Error returned when DNS provider API is rate-limited
+type RateLimitError struct {
+	// Delay requested by DNS provider API before next call
+	RetryAfter time.Duration
+}
+
+func (e *RateLimitError) Error() string {
+	return fmt.Sprintf("DNS provider API is rate-limited, retry after %s", e.RetryAfter)
+}
+
+// Get delay requested by Retry-After header of a rate-limited response
+func retryDelay(value string, now time.Time) time.Duration {
+	delay := parseRetryAfter(value, now)
+	if delay == 0 {
+		return defaultRetryAfter
+	}
+	if delay > maxRetryAfter {
+		return maxRetryAfter
+	}
+	return delay
+}
+
+// Transport reporting 429 responses of DNS provider API as RateLimitError,
+// so that Retry-After header is not lost by lego providers
+type rateLimitTransport struct {
+	next http.RoundTripper
+}
+
+func (t *rateLimitTransport) RoundTrip(request *http.Request) (*http.Response, error) {
+	response, err := t.next.RoundTrip(request)
+	if err != nil || response.StatusCode != http.StatusTooManyRequests {
+		return response, err
+	}
+	response.Body.Close()
+	return nil, &RateLimitError{RetryAfter: retryDelay(response.Header.Get("Retry-After"), time.Now())}
+}
+
+// DNS provider retrying calls rejected with RateLimitError.
+//
+// Implements challenge.Provider and challenge.ProviderTimeout.
+type retryingProvider struct {
+	challenge.Provider
+	maxRetries int
+	sleep      func(time.Duration)
+}
+
+// Call fn until it succeeds, fails with another error, or retries are exhausted
+func (p *retryingProvider) retry(action string, domain string, fn func() error) error {
+	for attempt := 0; ; attempt++ {
+		err := fn()
+		var rateLimitErr *RateLimitError
+		if err == nil || attempt >= p.maxRetries || !errors.As(err, &rateLimitErr) {
+			return err
+		}
+		log.Printf("[%s] DNS provider API is rate-limited while %s challenge record, retrying in %s (%d/%d)", domain, action, rateLimitErr.RetryAfter, attempt+1, p.maxRetries)
+		p.sleep(rateLimitErr.RetryAfter)
+	}
+}
+
+func (p *retryingProvider) Present(domain, token, keyAuth string) error {
+	return p.retry("creating", domain, func() error {
+		return p.Provider.Present(domain, token, keyAuth)
+	})
+}
+
+func (p *retryingProvider) CleanUp(domain, token, keyAuth string) error {
+	return p.retry("removing", domain, func() error {
+		return p.Provider.CleanUp(domain, token, keyAuth)
+	})
+}
+
+// Use timeout and interval of wrapped provider
+func (p *retryingProvider) Timeout() (time.Duration, time.Duration) {
+	if provider, ok := p.Provider.(challenge.ProviderTimeout); ok {
+		return provider.Timeout()
+	}
+	return dns01.DefaultPropagationTimeout, dns01.DefaultPollingInterval
+}
+
+// Retry calls of DNS provider rejected because its API is rate-limited.
+//
+// Provider is returned unchanged when maxRetries is zero.
+func retryRateLimited(provider challenge.Provider, maxRetries int) challenge.Provider {
+	if maxRetries <= 0 {
+		return provider
+	}
+	return &retryingProvider{Provider: provider, maxRetries: maxRetries, sleep: time.Sleep}
+}
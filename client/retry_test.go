@@ -0,0 +1,114 @@
+package client
+
+import (
+	"errors"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/charbonnierg/letsgo/configuration"
+)
+
+// Fake DNS provider rejecting first calls because of rate limits
+type rateLimitedProvider struct {
+	limited int
+	calls   int
+}
+
+func (p *rateLimitedProvider) Present(domain, token, keyAuth string) error {
+	p.calls++
+	if p.calls <= p.limited {
+		// Lego providers wrap errors of HTTP client
+		return fmt.Errorf("digitalocean: %w", &RateLimitError{RetryAfter: 2 * time.Second})
+	}
+	return nil
+}
+
+func (p *rateLimitedProvider) CleanUp(domain, token, keyAuth string) error {
+	return errors.New("record not found")
+}
+
+// Test that a call rejected once because of rate limits is retried after requested delay
+func TestRetryRateLimited(t *testing.T) {
+	fake := &rateLimitedProvider{limited: 1}
+	delays := []time.Duration{}
+	provider := retryRateLimited(fake, 3).(*retryingProvider)
+	provider.sleep = func(delay time.Duration) { delays = append(delays, delay) }
+	err := provider.Present("example.com", "", "")
+	if err != nil {
+		t.Fatalf(err.Error())
+	}
+	if fake.calls != 2 || len(delays) != 1 || delays[0] != 2*time.Second {
+		t.Errorf("Bad retries. Want 2 calls and a 2s delay. Got %d calls and delays %s", fake.calls, delays)
+	}
+	// Other errors are not retried
+	err = provider.CleanUp("example.com", "", "")
+	if err == nil || err.Error() != "record not found" || len(delays) != 1 {
+		t.Errorf("Expected cleanup error without retry. Got: %v (%d delays)", err, len(delays))
+	}
+}
+
+// Test that rate limit error is returned once retries are exhausted
+func TestRetryRateLimitedExhausted(t *testing.T) {
+	fake := &rateLimitedProvider{limited: 10}
+	provider := retryRateLimited(fake, 2).(*retryingProvider)
+	provider.sleep = func(delay time.Duration) {}
+	err := provider.Present("example.com", "", "")
+	var rateLimitErr *RateLimitError
+	if !errors.As(err, &rateLimitErr) {
+		t.Errorf("Expected a rate limit error. Got: %v", err)
+	}
+	if fake.calls != 3 {
+		t.Errorf("Bad number of calls. Want: 3. Got: %d", fake.calls)
+	}
+	if retryRateLimited(fake, 0) != fake {
+		t.Errorf("Expected provider to be returned unchanged when retries are disabled")
+	}
+}
+
+// Test that 429 responses of DNS provider API are reported with their Retry-After delay
+func TestRateLimitTransport(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path == "/limited" {
+			w.Header().Set("Retry-After", "7")
+			w.WriteHeader(http.StatusTooManyRequests)
+			return
+		}
+		if r.URL.Path == "/limited-without-header" {
+			w.WriteHeader(http.StatusTooManyRequests)
+			return
+		}
+		w.Write([]byte("{}"))
+	}))
+	defer server.Close()
+	httpClient := &http.Client{Transport: &rateLimitTransport{next: http.DefaultTransport}}
+	for path, want := range map[string]time.Duration{"/limited": 7 * time.Second, "/limited-without-header": defaultRetryAfter} {
+		_, err := httpClient.Get(server.URL + path)
+		var rateLimitErr *RateLimitError
+		if !errors.As(err, &rateLimitErr) || rateLimitErr.RetryAfter != want {
+			t.Errorf("Bad error for %s. Want a rate limit error with delay %s. Got: %v", path, want, err)
+		}
+	}
+	response, err := httpClient.Get(server.URL + "/records")
+	if err != nil {
+		t.Fatalf(err.Error())
+	}
+	response.Body.Close()
+	if response.StatusCode != http.StatusOK {
+		t.Errorf("Bad status. Want: 200. Got: %d", response.StatusCode)
+	}
+}
+
+// Test that rate-limited responses of DigitalOcean API are reported when retries are enabled
+func TestNewDigitalOceanConfigRetries(t *testing.T) {
+	providerConfig := newDigitalOceanConfig(configuration.UserConfig{DNSAPIMaxRetries: 3})
+	if _, ok := providerConfig.HTTPClient.Transport.(*rateLimitTransport); !ok {
+		t.Errorf("Expected rate limit transport but got %T", providerConfig.HTTPClient.Transport)
+	}
+	providerConfig = newDigitalOceanConfig(configuration.UserConfig{})
+	if _, ok := providerConfig.HTTPClient.Transport.(*http.Transport); !ok {
+		t.Errorf("Expected HTTP transport but got %T", providerConfig.HTTPClient.Transport)
+	}
+}
@@ -0,0 +1,66 @@
+package client
+
+import (
+	"crypto/x509"
+	"errors"
+	"fmt"
+	"log"
+	"strings"
+
+	"github.com/charbonnierg/letsgo/configuration"
+	"github.com/charbonnierg/letsgo/output"
+	"github.com/go-acme/lego/v4/certcrypto"
+	"github.com/go-acme/lego/v4/certificate"
+)
+
+// Subset of lego certifier used to obtain a certificate for a CSR
+type csrObtainer interface {
+	ObtainForCSR(request certificate.ObtainForCSRRequest) (*certificate.Resource, error)
+}
+
+// Renew certificate using private key of previous certificate
+func renewWithKey(config configuration.UserConfig, previous certificate.Resource) (*certificate.Resource, error) {
+	if len(previous.PrivateKey) == 0 {
+		return &certificate.Resource{}, errors.New(fmt.Sprintf("Cannot reuse private key of certificate %s: private key file not found", config.Filename))
+	}
+	// Generate lego client
+	client, err := NewClient(config)
+	if err != nil {
+		return &certificate.Resource{}, err
+	}
+	// Send request
+	log.Printf("Renewing certificate for %s using existing private key", strings.Join(config.DisplayDomains, ","))
+	resource, err := obtainWithKey(client.Certificate, config.Domains, previous.PrivateKey, config.KeyPassphrase)
+	if err != nil {
+		return resource, joinDomainErrors(err)
+	}
+	return resource, nil
+}
+
+// Obtain certificate for domains using a CSR signed by PEM-encoded private key.
+//
+// Key is decrypted using passphrase when it is encrypted.
+func obtainWithKey(obtainer csrObtainer, domains []string, key []byte, passphrase string) (*certificate.Resource, error) {
+	privateKey, err := output.ParsePrivateKey(key, passphrase)
+	if err != nil {
+		return &certificate.Resource{}, err
+	}
+	der, err := certcrypto.GenerateCSR(privateKey, domains[0], domains, false)
+	if err != nil {
+		return &certificate.Resource{}, err
+	}
+	csr, err := x509.ParseCertificateRequest(der)
+	if err != nil {
+		return &certificate.Resource{}, err
+	}
+	resource, err := obtainer.ObtainForCSR(certificate.ObtainForCSRRequest{
+		CSR:    csr,
+		Bundle: true,
+	})
+	if err != nil {
+		return resource, err
+	}
+	// Certificates obtained for a CSR do not hold the private key
+	resource.PrivateKey = certcrypto.PEMEncode(privateKey)
+	return resource, nil
+}
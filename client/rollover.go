@@ -0,0 +1,143 @@
+package client
+
+import (
+	"bytes"
+	"crypto"
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rsa"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"log"
+	"net/http"
+
+	"github.com/charbonnierg/letsgo/configuration"
+	jose "gopkg.in/square/go-jose.v2"
+)
+
+// Payload of inner JWS of a key change request (RFC 8555 section 7.3.5)
+type keyChange struct {
+	Account string          `json:"account"`
+	OldKey  jose.JSONWebKey `json:"oldKey"`
+}
+
+// Select JWS algorithm matching private key
+func signatureAlgorithm(key crypto.PrivateKey) (jose.SignatureAlgorithm, error) {
+	switch k := key.(type) {
+	case *rsa.PrivateKey:
+		return jose.RS256, nil
+	case *ecdsa.PrivateKey:
+		switch k.Curve {
+		case elliptic.P256():
+			return jose.ES256, nil
+		case elliptic.P384():
+			return jose.ES384, nil
+		}
+	}
+	return "", errors.New(fmt.Sprintf("Unsupported account key type %T", key))
+}
+
+// Sign payload with private key.
+//
+// Public key is embedded into protected header when kid is empty.
+func signJWS(key crypto.PrivateKey, kid string, headers map[jose.HeaderKey]interface{}, payload []byte) (*jose.JSONWebSignature, error) {
+	alg, err := signatureAlgorithm(key)
+	if err != nil {
+		return nil, err
+	}
+	signer, err := jose.NewSigner(
+		jose.SigningKey{Algorithm: alg, Key: jose.JSONWebKey{Key: key, KeyID: kid}},
+		&jose.SignerOptions{EmbedJWK: kid == "", ExtraHeaders: headers},
+	)
+	if err != nil {
+		return nil, err
+	}
+	return signer.Sign(payload)
+}
+
+// Fetch a fresh anti-replay nonce from CA server
+func fetchNonce(httpClient *http.Client, userAgent string, url string) (string, error) {
+	request, err := http.NewRequest(http.MethodHead, url, nil)
+	if err != nil {
+		return "", err
+	}
+	request.Header.Set("User-Agent", userAgent)
+	response, err := httpClient.Do(request)
+	if err != nil {
+		return "", err
+	}
+	response.Body.Close()
+	nonce := response.Header.Get("Replay-Nonce")
+	if nonce == "" {
+		return "", errors.New(fmt.Sprintf("No nonce received from %s", url))
+	}
+	return nonce, nil
+}
+
+// Change key of account to newKey using key change endpoint of CA server
+func rolloverAccountKey(httpClient *http.Client, userAgent string, caDir string, accountURL string, oldKey crypto.PrivateKey, newKey crypto.PrivateKey) error {
+	directory := struct {
+		NewNonce  string `json:"newNonce"`
+		KeyChange string `json:"keyChange"`
+	}{}
+	err := getJSON(httpClient, userAgent, caDir, &directory)
+	if err != nil {
+		return err
+	}
+	if directory.KeyChange == "" {
+		return errors.New("CA server does not support account key change")
+	}
+	signer, ok := oldKey.(crypto.Signer)
+	if !ok {
+		return errors.New(fmt.Sprintf("Unsupported account key type %T", oldKey))
+	}
+	// Inner JWS is signed by new key and holds current key
+	payload, err := json.Marshal(keyChange{Account: accountURL, OldKey: jose.JSONWebKey{Key: signer.Public()}})
+	if err != nil {
+		return err
+	}
+	inner, err := signJWS(newKey, "", map[jose.HeaderKey]interface{}{"url": directory.KeyChange}, payload)
+	if err != nil {
+		return err
+	}
+	// Outer JWS is signed by current key
+	nonce, err := fetchNonce(httpClient, userAgent, directory.NewNonce)
+	if err != nil {
+		return err
+	}
+	outer, err := signJWS(oldKey, accountURL, map[jose.HeaderKey]interface{}{"url": directory.KeyChange, "nonce": nonce}, []byte(inner.FullSerialize()))
+	if err != nil {
+		return err
+	}
+	request, err := http.NewRequest(http.MethodPost, directory.KeyChange, bytes.NewReader([]byte(outer.FullSerialize())))
+	if err != nil {
+		return err
+	}
+	request.Header.Set("User-Agent", userAgent)
+	request.Header.Set("Content-Type", "application/jose+json")
+	response, err := httpClient.Do(request)
+	if err != nil {
+		return err
+	}
+	defer response.Body.Close()
+	if response.StatusCode != http.StatusOK {
+		body, _ := io.ReadAll(io.LimitReader(response.Body, 4096))
+		return errors.New(fmt.Sprintf("Unexpected status code %d for %s: %s", response.StatusCode, directory.KeyChange, body))
+	}
+	return nil
+}
+
+// Change account key on CA server to newKey.
+//
+// Account is registered (or resolved) using current account key first.
+func RolloverAccountKey(userConfig configuration.UserConfig, newKey crypto.PrivateKey) error {
+	_, user, err := newClient(userConfig)
+	if err != nil {
+		return err
+	}
+	log.Printf("Rolling over key of account %s", user.Registration.URI)
+	legoConfig := newLegoConfig(user, userConfig)
+	return rolloverAccountKey(legoConfig.HTTPClient, legoConfig.UserAgent, userConfig.CADirURL, user.Registration.URI, userConfig.Key, newKey)
+}
@@ -0,0 +1,40 @@
+//go:build staging
+
+package client
+
+import (
+	"testing"
+
+	"github.com/charbonnierg/letsgo/configuration"
+	"github.com/charbonnierg/letsgo/constants"
+	"github.com/go-acme/lego/v4/certcrypto"
+)
+
+// Test that account key is rolled over on Let's Encrypt staging environment.
+//
+// Run with: go test -tags staging ./client
+func TestRolloverAccountKeyStaging(t *testing.T) {
+	oldKey, _ := certcrypto.GeneratePrivateKey(certcrypto.EC256)
+	newKey, _ := certcrypto.GeneratePrivateKey(certcrypto.EC256)
+	userConfig := configuration.UserConfig{
+		Email:                "letsgo-test@example.org",
+		Key:                  oldKey,
+		CADirURL:             constants.ACME_STAGING_CA_DIR,
+		TermsOfServiceAgreed: true,
+		ChallengeType:        constants.CHALLENGE_TYPE_MANUAL,
+		UserAgent:            "letsgo-test",
+	}
+	err := RolloverAccountKey(userConfig, newKey)
+	if err != nil {
+		t.Fatalf(err.Error())
+	}
+	// Account must now be resolved using new key
+	userConfig.Key = newKey
+	_, user, err := newClient(userConfig)
+	if err != nil {
+		t.Fatalf(err.Error())
+	}
+	if user.Registration == nil || user.Registration.URI == "" {
+		t.Errorf("Expected account to be resolved with new key")
+	}
+}
@@ -0,0 +1,94 @@
+package client
+
+import (
+	"crypto"
+	"encoding/json"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/go-acme/lego/v4/certcrypto"
+	jose "gopkg.in/square/go-jose.v2"
+)
+
+// Fake CA server verifying key change requests
+func newKeyChangeServer(t *testing.T, accountURL string, oldKey crypto.Signer, newKey crypto.Signer) *httptest.Server {
+	mux := http.NewServeMux()
+	server := httptest.NewServer(mux)
+	mux.HandleFunc("/directory", func(w http.ResponseWriter, r *http.Request) {
+		json.NewEncoder(w).Encode(map[string]string{
+			"newNonce":  server.URL + "/nonce",
+			"keyChange": server.URL + "/key-change",
+		})
+	})
+	mux.HandleFunc("/nonce", func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Replay-Nonce", "nonce-1")
+	})
+	mux.HandleFunc("/key-change", func(w http.ResponseWriter, r *http.Request) {
+		body, _ := io.ReadAll(r.Body)
+		outer, err := jose.ParseSigned(string(body))
+		if err != nil {
+			t.Fatalf(err.Error())
+		}
+		header := outer.Signatures[0].Protected
+		if header.KeyID != accountURL || header.Nonce != "nonce-1" || header.ExtraHeaders["url"] != server.URL+"/key-change" {
+			t.Errorf("Bad outer protected header: %+v", header)
+		}
+		innerJWS, err := outer.Verify(oldKey.Public())
+		if err != nil {
+			t.Fatalf("Outer JWS is not signed by current key: %s", err.Error())
+		}
+		inner, err := jose.ParseSigned(string(innerJWS))
+		if err != nil {
+			t.Fatalf(err.Error())
+		}
+		jwk := inner.Signatures[0].Protected.JSONWebKey
+		if jwk == nil || inner.Signatures[0].Protected.ExtraHeaders["url"] != server.URL+"/key-change" {
+			t.Fatalf("Bad inner protected header: %+v", inner.Signatures[0].Protected)
+		}
+		payload, err := inner.Verify(newKey.Public())
+		if err != nil {
+			t.Fatalf("Inner JWS is not signed by new key: %s", err.Error())
+		}
+		change := keyChange{}
+		json.Unmarshal(payload, &change)
+		if change.Account != accountURL {
+			t.Errorf("Bad account. Want: %s. Got: %s", accountURL, change.Account)
+		}
+		oldJWK := jose.JSONWebKey{Key: oldKey.Public()}
+		want, _ := oldJWK.Thumbprint(crypto.SHA256)
+		got, _ := change.OldKey.Thumbprint(crypto.SHA256)
+		if string(want) != string(got) {
+			t.Errorf("Bad old key in key change payload")
+		}
+	})
+	return server
+}
+
+// Test that key change request is signed by both current and new keys
+func TestRolloverAccountKey(t *testing.T) {
+	oldKey, _ := certcrypto.GeneratePrivateKey(certcrypto.EC256)
+	newKey, _ := certcrypto.GeneratePrivateKey(certcrypto.RSA2048)
+	accountURL := "https://ca.example.com/acct/1"
+	server := newKeyChangeServer(t, accountURL, oldKey.(crypto.Signer), newKey.(crypto.Signer))
+	defer server.Close()
+	err := rolloverAccountKey(server.Client(), "test", server.URL+"/directory", accountURL, oldKey, newKey)
+	if err != nil {
+		t.Fatalf(err.Error())
+	}
+}
+
+// Test that rollover fails when CA server does not support key change
+func TestRolloverAccountKeyUnsupported(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Write([]byte(`{"newNonce": "https://ca.example.com/nonce"}`))
+	}))
+	defer server.Close()
+	oldKey, _ := certcrypto.GeneratePrivateKey(certcrypto.EC256)
+	newKey, _ := certcrypto.GeneratePrivateKey(certcrypto.EC256)
+	err := rolloverAccountKey(server.Client(), "test", server.URL, "https://ca.example.com/acct/1", oldKey, newKey)
+	if err == nil || err.Error() != "CA server does not support account key change" {
+		t.Errorf("Bad error. Want: CA server does not support account key change. Got: %v", err)
+	}
+}
@@ -0,0 +1,54 @@
+package client
+
+import (
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/charbonnierg/letsgo/constants"
+	"github.com/go-acme/lego/v4/challenge"
+	"github.com/go-acme/lego/v4/challenge/dns01"
+)
+
+// Maximum length of TXT record values accepted by each DNS provider
+var maxTXTLengths = map[string]int{
+	constants.DNS_PROVIDER_DIGITALOCEAN: 255,
+	constants.DNS_PROVIDER_AZURE:        1024,
+	constants.DNS_PROVIDER_OVH:          255,
+	constants.DNS_PROVIDER_CLOUDFLARE:   2048,
+}
+
+// DNS provider rejecting challenge records longer than the provider accepts
+// before reaching the provider API.
+//
+// Implements challenge.Provider and challenge.ProviderTimeout.
+type txtLimitProvider struct {
+	challenge.Provider
+	name      string
+	maxLength int
+}
+
+// Wrap provider to validate TXT record values against the provider declared
+// maximum length. Provider is returned unchanged when no maximum is declared.
+func limitTXTLength(name string, provider challenge.Provider, maxLength int) challenge.Provider {
+	if maxLength <= 0 {
+		return provider
+	}
+	return &txtLimitProvider{Provider: provider, name: name, maxLength: maxLength}
+}
+
+func (p *txtLimitProvider) Present(domain, token, keyAuth string) error {
+	fqdn, value := dns01.GetRecord(domain, keyAuth)
+	if len(value) > p.maxLength {
+		return errors.New(fmt.Sprintf("TXT record value for %s is %d characters long but DNS provider %s accepts at most %d characters", fqdn, len(value), p.name, p.maxLength))
+	}
+	return p.Provider.Present(domain, token, keyAuth)
+}
+
+// Use timeout and interval of wrapped provider
+func (p *txtLimitProvider) Timeout() (time.Duration, time.Duration) {
+	if provider, ok := p.Provider.(challenge.ProviderTimeout); ok {
+		return provider.Timeout()
+	}
+	return dns01.DefaultPropagationTimeout, dns01.DefaultPollingInterval
+}
@@ -0,0 +1,33 @@
+package client
+
+import (
+	"testing"
+	"time"
+)
+
+// Test that challenge records longer than the provider declared maximum are rejected early
+func TestLimitTXTLength(t *testing.T) {
+	fake := &fakeZoneProvider{timeout: time.Minute}
+	provider := limitTXTLength("tiny", fake, 10)
+	err := provider.Present("example.com", "token", "keyAuth")
+	err_want := "TXT record value for _acme-challenge.example.com. is 43 characters long but DNS provider tiny accepts at most 10 characters"
+	if err == nil || err.Error() != err_want {
+		t.Errorf("Bad error. Want: %s. Got: %v", err_want, err)
+	}
+	if len(fake.records) != 0 {
+		t.Errorf("Expected provider not to be called but got records: %s", fake.records)
+	}
+	// Timeout of wrapped provider is kept
+	if timeout, _ := provider.(*txtLimitProvider).Timeout(); timeout != time.Minute {
+		t.Errorf("Bad timeout. Want: 1m0s. Got: %s", timeout)
+	}
+	// Records within limit reach provider
+	provider = limitTXTLength("digitalocean", fake, maxTXTLengths["digitalocean"])
+	err = provider.Present("example.com", "token", "keyAuth")
+	if err != nil {
+		t.Errorf(err.Error())
+	}
+	if len(fake.records) != 1 {
+		t.Errorf("Expected record to be presented")
+	}
+}
@@ -0,0 +1,43 @@
+package client
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+
+	"github.com/charbonnierg/letsgo/configuration"
+	"github.com/go-acme/lego/v4/certcrypto"
+	"golang.org/x/exp/slices"
+)
+
+// Check that issued certificate holds all requested domains.
+//
+// Verification is skipped when disabled in configuration.
+func VerifyCertificate(config configuration.UserConfig, cert []byte) error {
+	if config.SkipVerifySANs {
+		return nil
+	}
+	return verifySANs(cert, config.Domains)
+}
+
+// Check that every domain is found in DNS SANs of PEM-encoded certificate
+func verifySANs(cert []byte, domains []string) error {
+	x509Cert, err := certcrypto.ParsePEMCertificate(cert)
+	if err != nil {
+		return errors.New(fmt.Sprintf("Cannot verify issued certificate: %s", err.Error()))
+	}
+	sans := []string{}
+	for _, name := range x509Cert.DNSNames {
+		sans = append(sans, strings.ToLower(name))
+	}
+	missing := []string{}
+	for _, domain := range domains {
+		if !slices.Contains(sans, strings.ToLower(domain)) {
+			missing = append(missing, domain)
+		}
+	}
+	if len(missing) > 0 {
+		return errors.New(fmt.Sprintf("Issued certificate does not cover requested domains: %s. Found SANs: %s", strings.Join(missing, ","), strings.Join(x509Cert.DNSNames, ",")))
+	}
+	return nil
+}
@@ -0,0 +1,26 @@
+package client
+
+import (
+	"testing"
+
+	"github.com/charbonnierg/letsgo/configuration"
+)
+
+// Test that issued certificate must hold every requested domain
+func TestVerifySANs(t *testing.T) {
+	cert := newTestCertificate(t, "example.com", "www.example.com")
+	err := verifySANs(cert, []string{"example.com", "WWW.example.com"})
+	if err != nil {
+		t.Errorf(err.Error())
+	}
+	err = verifySANs(cert, []string{"example.com", "www.example.com", "api.example.com"})
+	err_want := "Issued certificate does not cover requested domains: api.example.com. Found SANs: example.com,www.example.com"
+	if err == nil || err.Error() != err_want {
+		t.Errorf("Bad error. Want: %s. Got: %v", err_want, err)
+	}
+	// Verification can be skipped
+	err = VerifyCertificate(configuration.UserConfig{SkipVerifySANs: true, Domains: []string{"api.example.com"}}, cert)
+	if err != nil {
+		t.Errorf("Expected verification to be skipped but got: %s", err.Error())
+	}
+}
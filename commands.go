@@ -0,0 +1,281 @@
+package main
+
+import (
+	"crypto"
+	"errors"
+	"flag"
+	"fmt"
+	"io"
+	"log"
+	"os"
+	"os/signal"
+	"sort"
+	"strings"
+	"syscall"
+	"time"
+
+	"github.com/charbonnierg/letsgo/client"
+	"github.com/charbonnierg/letsgo/configuration"
+	"github.com/charbonnierg/letsgo/constants"
+	"github.com/charbonnierg/letsgo/daemon"
+	"github.com/charbonnierg/letsgo/lock"
+	"github.com/charbonnierg/letsgo/output"
+	"github.com/charbonnierg/letsgo/stores"
+	"github.com/charbonnierg/letsgo/version"
+)
+
+// Subcommand of letsgo.
+//
+// Configuration is always read from environment variables.
+type command struct {
+	// Short description printed in usage
+	description string
+	run         func(out io.Writer) error
+}
+
+// Supported subcommands
+var commands = map[string]command{
+	constants.MODE_OBTAIN: {
+		description: "Request or renew certificates.",
+		run:         runObtain,
+	},
+	constants.MODE_VALIDATE: {
+		description: "Print effective configuration without contacting the CA server. DNS credentials are verified when VERIFY_DNS_CREDENTIALS is true.",
+		run:         runValidate,
+	},
+	constants.MODE_ROTATE_ACCOUNT_KEY: {
+		description: "Change the key of the ACME account and replace the stored account key.",
+		run:         runRotateAccountKey,
+	},
+	constants.MODE_RATELIMIT: {
+		description: "Print rate limit information reported by the CA server without creating any order.",
+		run:         runRateLimit,
+	},
+	constants.MODE_SELFTEST: {
+		description: "Obtain a certificate for a test domain from the local Boulder test CA, using its mock DNS server, then discard it.",
+		run:         runSelfTest,
+	},
+	constants.MODE_VERSION: {
+		description: "Print letsgo version.",
+		run:         runVersion,
+	},
+}
+
+// Generate a human readable list of subcommands
+func allowedCommands(commands map[string]command) string {
+	names := []string{}
+	for name := range commands {
+		names = append(names, fmt.Sprintf("'%s'", name))
+	}
+	sort.Strings(names)
+	return strings.Join(names[:len(names)-1], ", ") + " and " + names[len(names)-1]
+}
+
+// Run subcommand selected by first argument.
+//
+// Mode is used when no subcommand is given, and defaults to obtain
+// for backward compatibility.
+func dispatch(args []string, mode string, commands map[string]command, out io.Writer) error {
+	name := strings.ToLower(mode)
+	if len(args) > 0 && !strings.HasPrefix(args[0], "-") {
+		name, args = args[0], args[1:]
+	}
+	if name == "" {
+		name = constants.MODE_OBTAIN
+	}
+	cmd, ok := commands[name]
+	if !ok {
+		return &configuration.ConfigError{Err: errors.New(fmt.Sprintf("Invalid mode: %s. Allowed values are %s.", name, allowedCommands(commands)))}
+	}
+	flags := flag.NewFlagSet(name, flag.ContinueOnError)
+	flags.SetOutput(out)
+	flags.Usage = func() {
+		fmt.Fprintf(flags.Output(), "Usage: letsgo %s\n\n%s\nConfiguration is read from environment variables.\n", name, cmd.description)
+	}
+	err := flags.Parse(args)
+	if err != nil {
+		return &configuration.ConfigError{Err: err}
+	}
+	if flags.NArg() > 0 {
+		return &configuration.ConfigError{Err: errors.New(fmt.Sprintf("Unexpected arguments for %s: %s", name, strings.Join(flags.Args(), " ")))}
+	}
+	return cmd.run(out)
+}
+
+// Create stores used to read tokens and keys
+func newStores() (stores.Stores, error) {
+	vaultTimeout, err := configuration.GetVaultTimeout()
+	if err != nil {
+		return stores.Stores{}, &configuration.ConfigError{Err: err}
+	}
+	return stores.DefaultStores(vaultTimeout), nil
+}
+
+// Wait for interruption, then let in-flight task of shutdown complete
+// within grace period before cancelling it. Task is cancelled immediately
+// when shutdown is nil.
+func handleSignals(signals <-chan os.Signal, shutdown *daemon.Shutdown, grace time.Duration, cancel func()) {
+	received := <-signals
+	if shutdown != nil {
+		log.Printf("Received %s, waiting up to %s for in-flight renewal to complete", received, grace)
+		if shutdown.Drain(grace) {
+			return
+		}
+		log.Printf("Shutdown grace period expired, cancelling in-flight renewal")
+	}
+	cancel()
+}
+
+// Parse configuration and run task while holding lock of output directory.
+//
+// When interrupted, tasks run through shutdown are allowed to complete.
+func withLock(shutdown *daemon.Shutdown, task func(config *configuration.UserConfig, storage *stores.Stores) error) error {
+	storage, err := newStores()
+	if err != nil {
+		return err
+	}
+	// Prevent concurrent runs from writing the same files, or generating the same account key
+	lockFile, lockTimeout, err := configuration.GetLockOptions()
+	if err != nil {
+		return err
+	}
+	instanceLock, err := lock.Acquire(lockFile, lockTimeout)
+	if err != nil {
+		return err
+	}
+	defer instanceLock.Release()
+	// Generate config for user
+	config, err := configuration.NewUserConfig(&storage)
+	if err != nil {
+		return err
+	}
+	// Apply process-wide settings before any client is built
+	err = client.ConfigureCNAMESupport(config.FollowCNAME)
+	if err != nil {
+		return err
+	}
+	// Release lock when process is interrupted
+	signals := make(chan os.Signal, 1)
+	signal.Notify(signals, os.Interrupt, syscall.SIGTERM)
+	go handleSignals(signals, shutdown, config.ShutdownGrace, func() {
+		instanceLock.Release()
+		os.Exit(1)
+	})
+	// Detect clock skew before any ACME operation
+	err = client.CheckClockSkew(*config)
+	if err != nil {
+		return err
+	}
+	return task(config, &storage)
+}
+
+// Request certificates once, or periodically when renewal interval is configured
+func runObtain(out io.Writer) error {
+	shutdown := daemon.NewShutdown()
+	return withLock(shutdown, func(config *configuration.UserConfig, storage *stores.Stores) error {
+		if config.RenewInterval > 0 {
+			health := &daemon.Health{Expiry: func() (time.Time, error) {
+				return earliestExpiry(config)
+			}}
+			if config.HealthAddr != "" {
+				err := daemon.ServeHealth(config.HealthAddr, health)
+				if err != nil {
+					return err
+				}
+			}
+			daemon.Run(config.RenewInterval, config.RenewJitter, health, shutdown, func() error {
+				return run(config)
+			})
+			return nil
+		}
+		return shutdown.Do(func() error {
+			return run(config)
+		})
+	})
+}
+
+// Only validate configuration
+func runValidate(out io.Writer) error {
+	storage, err := newStores()
+	if err != nil {
+		return err
+	}
+	return validate(&storage, out)
+}
+
+// Only rotate account key
+func runRotateAccountKey(out io.Writer) error {
+	return withLock(nil, func(config *configuration.UserConfig, storage *stores.Stores) error {
+		_, err := configuration.RotateAccountKey(storage, func(newKey crypto.PrivateKey) error {
+			return client.RolloverAccountKey(*config, newKey)
+		})
+		if err != nil {
+			return err
+		}
+		log.Printf("Account key rotated")
+		return nil
+	})
+}
+
+// Only print rate limit information of CA server
+func runRateLimit(out io.Writer) error {
+	storage, err := newStores()
+	if err != nil {
+		return err
+	}
+	config, err := configuration.NewUserConfig(&storage)
+	if err != nil {
+		return err
+	}
+	err = client.ConfigureCNAMESupport(config.FollowCNAME)
+	if err != nil {
+		return err
+	}
+	info, err := client.RateLimitStatus(*config)
+	if err != nil {
+		return err
+	}
+	fmt.Fprintln(out, info.String())
+	return nil
+}
+
+// Obtain a certificate from local test CA without touching real infrastructure
+func runSelfTest(out io.Writer) error {
+	storage, err := newStores()
+	if err != nil {
+		return err
+	}
+	dir, err := os.MkdirTemp("", "letsgo-selftest-")
+	if err != nil {
+		return err
+	}
+	defer os.RemoveAll(dir)
+	config, err := configuration.NewSelfTestConfig(&storage, dir)
+	if err != nil {
+		return err
+	}
+	err = client.ConfigureCNAMESupport(config.FollowCNAME)
+	if err != nil {
+		return err
+	}
+	// Certificate SANs are verified before files are written
+	err = obtain(config)
+	if err != nil {
+		return err
+	}
+	resource, err := output.LoadCertificates(dir, config.Filename)
+	if err != nil {
+		return err
+	}
+	if resource == nil {
+		return errors.New(fmt.Sprintf("Certificate %s was not written", config.Filename))
+	}
+	fmt.Fprintf(out, "Self test succeeded: obtained certificate for %s from %s\n", strings.Join(config.Domains, ","), config.CADirURL)
+	return nil
+}
+
+// Print version information
+func runVersion(out io.Writer) error {
+	fmt.Fprintln(out, version.Info())
+	return nil
+}
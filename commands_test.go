@@ -0,0 +1,132 @@
+package main
+
+import (
+	"bytes"
+	"errors"
+	"flag"
+	"io"
+	"os"
+	"strings"
+	"syscall"
+	"testing"
+	"time"
+
+	"github.com/charbonnierg/letsgo/daemon"
+	"github.com/charbonnierg/letsgo/version"
+)
+
+// Generate commands recording which one was run
+func newTestCommands(ran *string) map[string]command {
+	commands := map[string]command{}
+	for _, name := range []string{"obtain", "validate", "version"} {
+		name := name
+		commands[name] = command{description: name, run: func(out io.Writer) error {
+			*ran = name
+			return nil
+		}}
+	}
+	return commands
+}
+
+// Test that subcommand is selected from arguments, then from mode
+func TestDispatch(t *testing.T) {
+	for _, test := range []struct {
+		args []string
+		mode string
+		want string
+	}{
+		{args: []string{}, mode: "", want: "obtain"},
+		{args: []string{}, mode: "VALIDATE", want: "validate"},
+		{args: []string{"version"}, mode: "", want: "version"},
+		{args: []string{"obtain"}, mode: "validate", want: "obtain"},
+	} {
+		ran := ""
+		err := dispatch(test.args, test.mode, newTestCommands(&ran), &bytes.Buffer{})
+		if err != nil {
+			t.Errorf(err.Error())
+		}
+		if ran != test.want {
+			t.Errorf("Bad command for args %s and mode %q. Want: %s. Got: %s", test.args, test.mode, test.want, ran)
+		}
+	}
+}
+
+// Test that unknown subcommands and arguments are rejected
+func TestDispatchInvalid(t *testing.T) {
+	ran := ""
+	err := dispatch([]string{"revoke"}, "", newTestCommands(&ran), &bytes.Buffer{})
+	err_want := "Invalid mode: revoke. Allowed values are 'obtain', 'validate' and 'version'."
+	if err == nil || err.Error() != err_want {
+		t.Errorf("Bad error. Want: %s. Got: %v", err_want, err)
+	}
+	err = dispatch([]string{"obtain", "example.com"}, "", newTestCommands(&ran), &bytes.Buffer{})
+	err_want = "Unexpected arguments for obtain: example.com"
+	if err == nil || err.Error() != err_want {
+		t.Errorf("Bad error. Want: %s. Got: %v", err_want, err)
+	}
+	if ran != "" {
+		t.Errorf("Expected no command to run but %s did", ran)
+	}
+}
+
+// Test that usage of subcommand is printed on help
+func TestDispatchHelp(t *testing.T) {
+	ran := ""
+	out := &bytes.Buffer{}
+	err := dispatch([]string{"validate", "-h"}, "", newTestCommands(&ran), out)
+	if !errors.Is(err, flag.ErrHelp) {
+		t.Errorf("Bad error. Want: %s. Got: %v", flag.ErrHelp, err)
+	}
+	if !strings.Contains(out.String(), "Usage: letsgo validate") || ran != "" {
+		t.Errorf("Bad usage: %s", out.String())
+	}
+}
+
+// Test that version subcommand prints version information
+func TestDispatchVersion(t *testing.T) {
+	out := &bytes.Buffer{}
+	err := dispatch([]string{"version"}, "", commands, out)
+	if err != nil {
+		t.Fatalf(err.Error())
+	}
+	if strings.TrimSpace(out.String()) != version.Info() {
+		t.Errorf("Bad version. Want: %s. Got: %s", version.Info(), out.String())
+	}
+}
+
+// Test that a signal received during a slow obtain lets it complete before exiting
+func TestHandleSignalsDuringObtain(t *testing.T) {
+	for _, test := range []struct {
+		grace      time.Duration
+		wantCancel bool
+	}{
+		{grace: time.Second, wantCancel: false},
+		{grace: 10 * time.Millisecond, wantCancel: true},
+	} {
+		shutdown := daemon.NewShutdown()
+		started := make(chan struct{})
+		obtained := make(chan struct{})
+		go shutdown.Do(func() error {
+			close(started)
+			time.Sleep(100 * time.Millisecond)
+			close(obtained)
+			return nil
+		})
+		<-started
+		signals := make(chan os.Signal, 1)
+		signals <- syscall.SIGTERM
+		cancelled := false
+		handleSignals(signals, shutdown, test.grace, func() { cancelled = true })
+		if cancelled != test.wantCancel {
+			t.Errorf("Bad cancellation with grace %s. Want: %t. Got: %t", test.grace, test.wantCancel, cancelled)
+		}
+		if !test.wantCancel {
+			select {
+			case <-obtained:
+			default:
+				t.Errorf("Expected obtain to complete before shutdown")
+			}
+		}
+		<-obtained
+	}
+}
@@ -0,0 +1,257 @@
+package configuration
+
+import (
+	"context"
+	"crypto"
+	"crypto/ecdsa"
+	"crypto/rsa"
+	"crypto/x509"
+	"encoding/pem"
+	"errors"
+	"fmt"
+	"log"
+	"os"
+	"strings"
+
+	"github.com/charbonnierg/letsgo/constants"
+	"github.com/charbonnierg/letsgo/stores"
+	"github.com/go-acme/lego/v4/certcrypto"
+)
+
+// An account key provider loads and saves PEM-encoded account keys
+type accountKeyProvider interface {
+	// Return PEM-encoded key, or nil when key does not exist yet
+	load(ctx context.Context) ([]byte, error)
+	// Save PEM-encoded key
+	save(ctx context.Context, pemKey []byte) error
+	// Replace existing key with PEM-encoded key, keeping a backup of existing key
+	replace(ctx context.Context, pemKey []byte) error
+	// Return location of key, used in logs
+	location() string
+}
+
+// Description of account key used by letsgo
+type AccountKeyInfo struct {
+	// Algorithm and size of key (e.g. EC256 or RSA2048)
+	Type string
+	// Path of key file, or URL of keyvault secret
+	Path string
+	// Whether key was generated because it did not exist yet
+	Created bool
+	// Whether key does not exist yet, when it is loaded without being generated
+	Missing bool
+}
+
+// Account key provider storing key in a file
+type fileKeyProvider struct {
+	path string
+}
+
+func (p *fileKeyProvider) load(ctx context.Context) ([]byte, error) {
+	if !fileExists(p.path) {
+		return nil, nil
+	}
+	return os.ReadFile(p.path)
+}
+
+func (p *fileKeyProvider) save(ctx context.Context, pemKey []byte) error {
+	return os.WriteFile(p.path, pemKey, 0o600)
+}
+
+func (p *fileKeyProvider) location() string {
+	return p.path
+}
+
+// Existing key is copied to `<path>.bak`, then new key is written
+// to `<path>.new` and renamed over existing key atomically.
+func (p *fileKeyProvider) replace(ctx context.Context, pemKey []byte) error {
+	previous, err := os.ReadFile(p.path)
+	if err != nil {
+		return err
+	}
+	err = os.WriteFile(p.path+".bak", previous, 0o600)
+	if err != nil {
+		return err
+	}
+	err = os.WriteFile(p.path+".new", pemKey, 0o600)
+	if err != nil {
+		return err
+	}
+	return os.Rename(p.path+".new", p.path)
+}
+
+// Account key provider reading key from the value of an environment variable.
+//
+// Key is never written to disk, so it cannot be generated nor replaced.
+type envKeyProvider struct {
+	value string
+}
+
+func (p *envKeyProvider) load(ctx context.Context) ([]byte, error) {
+	value := p.value
+	// Line breaks may be escaped when variable is set on a single line
+	if !strings.Contains(value, "\n") {
+		value = strings.ReplaceAll(value, `\n`, "\n")
+	}
+	return []byte(value), nil
+}
+
+func (p *envKeyProvider) save(ctx context.Context, pemKey []byte) error {
+	return errors.New(fmt.Sprintf("Account key cannot be saved into %s environment variable", constants.ACCOUNT_KEY_PEM))
+}
+
+func (p *envKeyProvider) location() string {
+	return constants.ACCOUNT_KEY_PEM
+}
+
+func (p *envKeyProvider) replace(ctx context.Context, pemKey []byte) error {
+	return errors.New(fmt.Sprintf("Account key cannot be replaced in %s environment variable", constants.ACCOUNT_KEY_PEM))
+}
+
+// Account key provider storing key as a keyvault secret
+type vaultKeyProvider struct {
+	store  stores.KeyvaultStoreProtocol
+	uri    string
+	secret string
+}
+
+func (p *vaultKeyProvider) load(ctx context.Context) ([]byte, error) {
+	value, err := p.store.GetToken(ctx, p.uri, p.secret)
+	if errors.Is(err, stores.ErrSecretNotFound) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	return []byte(value), nil
+}
+
+// Get keyvault store used to save key, failing when store is read-only
+func (p *vaultKeyProvider) writer() (stores.KeyvaultWriter, error) {
+	writer, ok := p.store.(stores.KeyvaultWriter)
+	if !ok {
+		return nil, fmt.Errorf("Account key cannot be saved into %s: %w", p.location(), stores.ErrReadOnlyKeyvault)
+	}
+	return writer, nil
+}
+
+func (p *vaultKeyProvider) save(ctx context.Context, pemKey []byte) error {
+	writer, err := p.writer()
+	if err != nil {
+		return err
+	}
+	return writer.SetToken(ctx, p.uri, p.secret, string(pemKey))
+}
+
+func (p *vaultKeyProvider) location() string {
+	return p.uri + "secrets/" + p.secret
+}
+
+// Keyvault keeps previous versions of secret, which serve as backup
+func (p *vaultKeyProvider) replace(ctx context.Context, pemKey []byte) error {
+	writer, err := p.writer()
+	if err != nil {
+		return err
+	}
+	return writer.SetToken(ctx, p.uri, p.secret, string(pemKey))
+}
+
+// Parse a PEM-encoded account key
+func parseAccountKey(pemKey []byte) (crypto.PrivateKey, error) {
+	keyBlock, _ := pem.Decode(pemKey)
+	if keyBlock == nil {
+		return nil, errors.New("no PEM block found in account key")
+	}
+
+	switch keyBlock.Type {
+	case "RSA PRIVATE KEY":
+		return x509.ParsePKCS1PrivateKey(keyBlock.Bytes)
+	case "EC PRIVATE KEY":
+		return x509.ParseECPrivateKey(keyBlock.Bytes)
+	}
+
+	return nil, &UnknownKeyTypeError{BlockType: keyBlock.Type}
+}
+
+// Describe algorithm and size of private key (e.g. EC256 or RSA2048)
+func describeAccountKey(key crypto.PrivateKey) string {
+	switch k := key.(type) {
+	case *ecdsa.PrivateKey:
+		return fmt.Sprintf("EC%d", k.Curve.Params().BitSize)
+	case *rsa.PrivateKey:
+		return fmt.Sprintf("RSA%d", k.N.BitLen())
+	default:
+		return fmt.Sprintf("%T", key)
+	}
+}
+
+// Load account key from provider, or generate and save a new key of given type
+func loadOrCreateAccountKey(ctx context.Context, provider accountKeyProvider, keyType certcrypto.KeyType) (crypto.PrivateKey, error) {
+	privateKey, _, err := loadOrCreateAccountKeyInfo(ctx, provider, keyType)
+	return privateKey, err
+}
+
+// Load account key from provider and describe it, without ever generating it.
+//
+// A nil key is returned when key does not exist yet.
+func loadAccountKeyInfo(ctx context.Context, provider accountKeyProvider) (crypto.PrivateKey, AccountKeyInfo, error) {
+	info := AccountKeyInfo{Path: provider.location()}
+	pemKey, err := provider.load(ctx)
+	if err != nil {
+		return nil, info, err
+	}
+	if pemKey == nil {
+		info.Missing = true
+		return nil, info, nil
+	}
+	privateKey, err := parseAccountKey(pemKey)
+	if err != nil {
+		return nil, info, err
+	}
+	info.Type = describeAccountKey(privateKey)
+	return privateKey, info, nil
+}
+
+// Load account key from provider, or generate and save a new key of given type,
+// and describe loaded key
+func loadOrCreateAccountKeyInfo(ctx context.Context, provider accountKeyProvider, keyType certcrypto.KeyType) (crypto.PrivateKey, AccountKeyInfo, error) {
+	privateKey, info, err := loadAccountKeyInfo(ctx, provider)
+	if err != nil || !info.Missing {
+		return privateKey, info, err
+	}
+	info.Missing = false
+	// Create a private key. New accounts need an email and private key to start.
+	privateKey, err = certcrypto.GeneratePrivateKey(keyType)
+	if err != nil {
+		return nil, info, err
+	}
+	err = provider.save(ctx, pem.EncodeToMemory(certcrypto.PEMBlock(privateKey)))
+	if err != nil {
+		return nil, info, err
+	}
+	info.Type = describeAccountKey(privateKey)
+	info.Created = true
+	log.Printf("Generated %s account key into %s", info.Type, info.Path)
+	return privateKey, info, nil
+}
+
+// Rotate account key.
+//
+// A new key is generated and handed to rollover function, which must
+// change the account key on CA server. Stored account key is replaced
+// only once rollover succeeds, so current key is kept when it fails.
+func rotateAccountKey(ctx context.Context, provider accountKeyProvider, keyType certcrypto.KeyType, rollover func(newKey crypto.PrivateKey) error) (crypto.PrivateKey, error) {
+	newKey, err := certcrypto.GeneratePrivateKey(keyType)
+	if err != nil {
+		return nil, err
+	}
+	err = rollover(newKey)
+	if err != nil {
+		return nil, errors.New(fmt.Sprintf("Account key rollover failed, current account key is kept: %s", err.Error()))
+	}
+	err = provider.replace(ctx, pem.EncodeToMemory(certcrypto.PEMBlock(newKey)))
+	if err != nil {
+		return nil, errors.New(fmt.Sprintf("Account key rollover succeeded but new account key could not be saved: %s", err.Error()))
+	}
+	return newKey, nil
+}
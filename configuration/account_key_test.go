@@ -0,0 +1,260 @@
+package configuration
+
+import (
+	"bytes"
+	"context"
+	"crypto"
+	"encoding/pem"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/charbonnierg/letsgo/stores"
+	"github.com/go-acme/lego/v4/certcrypto"
+)
+
+// Test that account key is generated and stored into keyvault when missing
+func TestGetAccountKeyFromVault(t *testing.T) {
+	vault := &stores.KeyVaultSecretsMock{}
+	storage := stores.NewStores(stores.WithKeyvault(vault))
+	rawConfig := &RawUserConfig{
+		AccountKeyVault:  "test-vault",
+		AccountKeySecret: "account-key",
+	}
+	key, err := rawConfig.getAccountKey(context.Background(), &storage)
+	if err != nil {
+		t.Fatalf(err.Error())
+	}
+	stored, ok := vault.Secrets["account-key"]
+	if !ok {
+		t.Fatalf("Account key was not stored into keyvault")
+	}
+	storedKey, err := parseAccountKey([]byte(stored))
+	if err != nil {
+		t.Fatalf(err.Error())
+	}
+	if !bytes.Equal(certcrypto.PEMBlock(key).Bytes, certcrypto.PEMBlock(storedKey).Bytes) {
+		t.Errorf("Stored account key does not match generated key")
+	}
+	// Second call must load existing key
+	secondKey, err := rawConfig.getAccountKey(context.Background(), &storage)
+	if err != nil {
+		t.Fatalf(err.Error())
+	}
+	if !bytes.Equal(certcrypto.PEMBlock(key).Bytes, certcrypto.PEMBlock(secondKey).Bytes) {
+		t.Errorf("getAccountKey did not load existing key from keyvault but created a new key instead")
+	}
+}
+
+// Keyvault store which cannot store secrets
+type readOnlyKeyvault struct{}
+
+func (k *readOnlyKeyvault) GetToken(ctx context.Context, uri string, secret string) (string, error) {
+	return "", stores.ErrSecretNotFound
+}
+
+// Test that a missing account key cannot be generated into a read-only keyvault store
+func TestGetAccountKeyFromReadOnlyVault(t *testing.T) {
+	storage := stores.NewStores(stores.WithKeyvault(&readOnlyKeyvault{}))
+	rawConfig := &RawUserConfig{
+		AccountKeyVault:  "test-vault",
+		AccountKeySecret: "account-key",
+	}
+	_, err := rawConfig.getAccountKey(context.Background(), &storage)
+	if !errors.Is(err, stores.ErrReadOnlyKeyvault) {
+		t.Fatalf("Bad error. Want: %s. Got: %v", stores.ErrReadOnlyKeyvault, err)
+	}
+	err_want := "Account key cannot be saved into https://test-vault.vault.azure.net/secrets/account-key: keyvault store is read-only"
+	if !strings.Contains(err.Error(), err_want) {
+		t.Errorf("Bad error. Want: %s. Got: %s", err_want, err.Error())
+	}
+}
+
+// Test that keyvault is not used when no vault is configured
+func TestGetAccountKeyProvider(t *testing.T) {
+	storage := stores.TestStores("")
+	rawConfig := &RawUserConfig{AccountKeyFile: "account.key"}
+	if _, ok := rawConfig.getAccountKeyProvider(&storage).(*fileKeyProvider); !ok {
+		t.Errorf("Expected file key provider")
+	}
+	rawConfig.AccountKeyVault = "test-vault"
+	provider, ok := rawConfig.getAccountKeyProvider(&storage).(*vaultKeyProvider)
+	if !ok {
+		t.Fatalf("Expected vault key provider")
+	}
+	if provider.uri != "https://test-vault.vault.azure.net/" {
+		t.Errorf("Bad vault URI: %s", provider.uri)
+	}
+}
+
+// Test that EC and RSA account keys are parsed from environment, taking precedence over file
+func TestGetAccountKeyFromEnv(t *testing.T) {
+	storage := stores.TestStores("")
+	path := filepath.Join(t.TempDir(), "account.key")
+	for keyType, description := range map[certcrypto.KeyType]string{certcrypto.EC256: "EC256", certcrypto.RSA2048: "RSA2048"} {
+		key, err := certcrypto.GeneratePrivateKey(keyType)
+		if err != nil {
+			t.Fatalf(err.Error())
+		}
+		pemKey := string(pem.EncodeToMemory(certcrypto.PEMBlock(key)))
+		// Line breaks may be escaped
+		for _, value := range []string{pemKey, strings.ReplaceAll(pemKey, "\n", `\n`)} {
+			rawConfig := &RawUserConfig{AccountKeyPEM: value, AccountKeyFile: path}
+			got, info, err := rawConfig.getAccountKeyWithInfo(context.Background(), &storage)
+			if err != nil {
+				t.Fatalf(err.Error())
+			}
+			if !bytes.Equal(certcrypto.PEMBlock(got).Bytes, certcrypto.PEMBlock(key).Bytes) {
+				t.Errorf("Account key does not match %s key found in environment", keyType)
+			}
+			want := AccountKeyInfo{Type: description, Path: "ACCOUNT_KEY_PEM"}
+			if info != want {
+				t.Errorf("Bad account key info. Want: %+v. Got: %+v", want, info)
+			}
+		}
+	}
+	if fileExists(path) {
+		t.Errorf("Account key file must not be written when key is found in environment")
+	}
+	rawConfig := &RawUserConfig{AccountKeyPEM: "not a key"}
+	_, err := rawConfig.getAccountKey(context.Background(), &storage)
+	if err == nil {
+		t.Errorf("Expected error for invalid account key")
+	}
+}
+
+// Test that invalid PEM content is rejected
+func TestParseAccountKeyInvalid(t *testing.T) {
+	_, err := parseAccountKey([]byte("not a key"))
+	if err == nil {
+		t.Errorf("Expected error for invalid account key")
+	}
+}
+
+// Test that unsupported PEM blocks are reported with their type
+func TestParseAccountKeyUnknownType(t *testing.T) {
+	_, err := parseAccountKey(pem.EncodeToMemory(&pem.Block{Type: "DSA PRIVATE KEY", Bytes: []byte{0x30, 0x00}}))
+	var typeErr *UnknownKeyTypeError
+	if !errors.As(err, &typeErr) {
+		t.Fatalf("Bad error type. Want: *UnknownKeyTypeError. Got: %T", err)
+	}
+	if typeErr.BlockType != "DSA PRIVATE KEY" {
+		t.Errorf("Bad block type. Want: DSA PRIVATE KEY. Got: %s", typeErr.BlockType)
+	}
+	err_want := "unknown private key type: DSA PRIVATE KEY"
+	if err.Error() != err_want {
+		t.Errorf("Bad error. Want: %s. Got: %s", err_want, err.Error())
+	}
+}
+
+// Test that generated account key type matches setting
+func TestGetAccountKeyType(t *testing.T) {
+	for value, want := range map[string]string{
+		"":        "*ecdsa.PrivateKey",
+		"EC256":   "*ecdsa.PrivateKey",
+		"RSA2048": "*rsa.PrivateKey",
+	} {
+		vault := &stores.KeyVaultSecretsMock{}
+		storage := stores.NewStores(stores.WithKeyvault(vault))
+		rawConfig := &RawUserConfig{
+			AccountKeyVault:  "test-vault",
+			AccountKeySecret: "account-key",
+			AccountKeyType:   value,
+		}
+		key, err := rawConfig.getAccountKey(context.Background(), &storage)
+		if err != nil {
+			t.Fatalf(err.Error())
+		}
+		if got := fmt.Sprintf("%T", key); got != want {
+			t.Errorf("Bad account key type for %s. Want: %s. Got: %s", value, want, got)
+		}
+	}
+	rawConfig := &RawUserConfig{AccountKeyType: "EC384"}
+	_, err := rawConfig.getAccountKeyType()
+	if err == nil {
+		t.Errorf("Expected error for unsupported account key type")
+	}
+	rawConfig = &RawUserConfig{AccountKeyType: "EC521"}
+	_, err = rawConfig.getAccountKeyType()
+	err_want := "Invalid ACCOUNT_KEY_TYPE: EC521 keys are not supported by lego. Allowed values are 'EC256', 'RSA2048' and 'RSA4096'."
+	if err == nil || err.Error() != err_want {
+		t.Errorf("Bad error. Want: %s. Got: %v", err_want, err)
+	}
+}
+
+// Test that created account key is described
+func TestLoadOrCreateAccountKeyInfo(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "account.key")
+	provider := &fileKeyProvider{path: path}
+	_, info, err := loadOrCreateAccountKeyInfo(context.Background(), provider, certcrypto.EC256)
+	if err != nil {
+		t.Fatalf(err.Error())
+	}
+	want := AccountKeyInfo{Type: "EC256", Path: path, Created: true}
+	if info != want {
+		t.Errorf("Bad account key info. Want: %+v. Got: %+v", want, info)
+	}
+	// Existing key is loaded
+	_, info, err = loadOrCreateAccountKeyInfo(context.Background(), provider, certcrypto.RSA2048)
+	if err != nil {
+		t.Fatalf(err.Error())
+	}
+	want.Created = false
+	if info != want {
+		t.Errorf("Bad account key info. Want: %+v. Got: %+v", want, info)
+	}
+}
+
+// Test that account key file is replaced only once rollover succeeds
+func TestRotateAccountKey(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "account.key")
+	provider := &fileKeyProvider{path: path}
+	oldKey, err := loadOrCreateAccountKey(context.Background(), provider, certcrypto.EC256)
+	if err != nil {
+		t.Fatalf(err.Error())
+	}
+	oldPEM, _ := os.ReadFile(path)
+
+	// Failed rollover keeps current key
+	_, err = rotateAccountKey(context.Background(), provider, certcrypto.EC256, func(newKey crypto.PrivateKey) error {
+		return errors.New("unauthorized")
+	})
+	err_want := "Account key rollover failed, current account key is kept: unauthorized"
+	if err == nil || err.Error() != err_want {
+		t.Errorf("Bad error. Want: %s. Got: %v", err_want, err)
+	}
+	current, _ := os.ReadFile(path)
+	if !bytes.Equal(current, oldPEM) {
+		t.Errorf("Expected current account key to be kept")
+	}
+
+	// Successful rollover replaces current key and keeps a backup
+	var rolled crypto.PrivateKey
+	newKey, err := rotateAccountKey(context.Background(), provider, certcrypto.EC256, func(newKey crypto.PrivateKey) error {
+		rolled = newKey
+		return nil
+	})
+	if err != nil {
+		t.Fatalf(err.Error())
+	}
+	if rolled != newKey {
+		t.Errorf("Expected rolled over key to be returned")
+	}
+	loaded, err := loadOrCreateAccountKey(context.Background(), provider, certcrypto.EC256)
+	if err != nil {
+		t.Fatalf(err.Error())
+	}
+	if !bytes.Equal(certcrypto.PEMBlock(loaded).Bytes, certcrypto.PEMBlock(newKey).Bytes) {
+		t.Errorf("Expected account key to be replaced with new key")
+	}
+	backup, _ := os.ReadFile(path + ".bak")
+	if !bytes.Equal(backup, oldPEM) {
+		t.Errorf("Expected previous account key to be backed up")
+	}
+	if bytes.Equal(certcrypto.PEMBlock(oldKey).Bytes, certcrypto.PEMBlock(newKey).Bytes) {
+		t.Errorf("Expected a new account key to be generated")
+	}
+}
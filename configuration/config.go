@@ -1,14 +1,17 @@
 package configuration
 
 import (
+	"context"
 	"crypto"
-	"crypto/ecdsa"
-	"crypto/elliptic"
-	"crypto/rand"
+	"crypto/tls"
 	"crypto/x509"
-	"encoding/pem"
 	"errors"
 	"fmt"
+	"log"
+	"net"
+	"net/http"
+	"net/mail"
+	"net/url"
 	"os"
 	"path/filepath"
 	"strconv"
@@ -16,357 +19,2436 @@ import (
 	"time"
 
 	"github.com/charbonnierg/letsgo/constants"
+	"github.com/charbonnierg/letsgo/output"
 	"github.com/charbonnierg/letsgo/stores"
+	"github.com/charbonnierg/letsgo/version"
 	"github.com/go-acme/lego/v4/certcrypto"
+	"golang.org/x/exp/slices"
 )
 
 type RawUserConfig struct {
-	AccountEmail       string
-	AccountKeyFile     string
-	TOSAgreed          string
-	CADir              string
-	KeyType            string
-	Domains            string
-	Filename           string
-	OutputDirectory    string
-	DisableCP          string
-	DNSTimeout         string
-	DNSResolver        string
-	DNSAuthToken       string
-	DNSAuthTokenFile   string
-	DNSAuthTokenVault  string
-	DNSAuthTokenSecret string
+	AccountEmail       string `json:"ACCOUNT_EMAIL,omitempty"`
+	AccountEmailFile   string `json:"ACCOUNT_EMAIL_FILE,omitempty"`
+	AccountKeyFile     string `json:"ACCOUNT_KEY_FILE,omitempty"`
+	AccountKeyPEM      string `json:"ACCOUNT_KEY_PEM,omitempty"`
+	AccountKeyVault    string `json:"ACCOUNT_KEY_VAULT,omitempty"`
+	AccountKeySecret   string `json:"ACCOUNT_KEY_SECRET,omitempty"`
+	AccountKeyType     string `json:"ACCOUNT_KEY_TYPE,omitempty"`
+	AccountURI         string `json:"ACCOUNT_URI,omitempty"`
+	SkipRegistration   string `json:"SKIP_REGISTRATION,omitempty"`
+	TOSAgreed          string `json:"LE_TOS_AGREED,omitempty"`
+	CADir              string `json:"CA_DIR,omitempty"`
+	CARootCertFile     string `json:"CA_ROOT_CERT_FILE,omitempty"`
+	KeyType            string `json:"LE_CRT_KEY_TYPE,omitempty"`
+	Domains            string `json:"DOMAINS,omitempty"`
+	CommonName         string `json:"COMMON_NAME,omitempty"`
+	AllowedDomains     string `json:"ALLOWED_DOMAINS,omitempty"`
+	Certificates       string `json:"CERTIFICATES,omitempty"`
+	CertNames          string `json:"CERT_NAMES,omitempty"`
+	SeparateCerts      string `json:"SEPARATE_CERTS,omitempty"`
+	MaxSANs            string `json:"MAX_SANS,omitempty"`
+	AutoChunk          string `json:"AUTO_CHUNK,omitempty"`
+	Concurrency        string `json:"CONCURRENCY,omitempty"`
+	Filename           string `json:"FILENAME,omitempty"`
+	FilenameTemplate   string `json:"FILENAME_TEMPLATE,omitempty"`
+	SkipVerifySANs     string `json:"SKIP_VERIFY_SANS,omitempty"`
+	OutputDirectory    string `json:"OUTPUT_DIRECTORY,omitempty"`
+	OutputMetadata     string `json:"OUTPUT_METADATA,omitempty"`
+	OutputIssuer       string `json:"OUTPUT_ISSUER,omitempty"`
+	OutputAllChains    string `json:"OUTPUT_ALL_CHAINS,omitempty"`
+	OutputDER          string `json:"OUTPUT_DER,omitempty"`
+	OutputP7B          string `json:"OUTPUT_P7B,omitempty"`
+	OutputOnlyChanged  string `json:"OUTPUT_ONLY_CHANGED,omitempty"`
+	OutputLayout       string `json:"OUTPUT_LAYOUT,omitempty"`
+	OutputUID          string `json:"OUTPUT_UID,omitempty"`
+	OutputGID          string `json:"OUTPUT_GID,omitempty"`
+	OutputJSON         string `json:"OUTPUT_JSON,omitempty"`
+	LockTimeout        string `json:"LOCK_TIMEOUT,omitempty"`
+	StoreTimeout       string `json:"STORE_TIMEOUT,omitempty"`
+	StrictClock        string `json:"STRICT_CLOCK,omitempty"`
+	ACMEDebug          string `json:"ACME_DEBUG,omitempty"`
+	ACMEHTTPProxy      string `json:"ACME_HTTP_PROXY,omitempty"`
+	OutboundMinTLS     string `json:"OUTBOUND_MIN_TLS,omitempty"`
+	ACMEExtraHeaders   string `json:"ACME_EXTRA_HEADERS,omitempty"`
+	Prune              string `json:"PRUNE,omitempty"`
+	NotifyWebhookURL   string `json:"NOTIFY_WEBHOOK_URL,omitempty"`
+	NotifyOn           string `json:"NOTIFY_ON,omitempty"`
+	KeyPassphrase      string `json:"KEY_PASSPHRASE,omitempty"`
+	DisableCP          string `json:"DISABLE_CP,omitempty"`
+	DNSCheckMode       string `json:"DNS_CHECK_MODE,omitempty"`
+	FollowCNAME        string `json:"DNS_FOLLOW_CNAME,omitempty"`
+	DNSTimeout         string `json:"DNS_TIMEOUT,omitempty"`
+	DNSTTL             string `json:"DNS_TTL,omitempty"`
+	DigitalOceanAPIURL string `json:"DO_API_URL,omitempty"`
+	DNSResolver        string `json:"DNS_RESOLVERS,omitempty"`
+	ResolverProtocol   string `json:"DNS_RESOLVER_PROTOCOL,omitempty"`
+	ChallengeType      string `json:"CHALLENGE_TYPE,omitempty"`
+	ManualWait         string `json:"MANUAL_WAIT,omitempty"`
+	DNSPresentHook     string `json:"DNS_PRESENT_HOOK,omitempty"`
+	DNSCleanupHook     string `json:"DNS_CLEANUP_HOOK,omitempty"`
+	DNSProvider        string `json:"DNS_PROVIDER,omitempty"`
+	DNSProviderMap     string `json:"DNS_PROVIDER_MAP,omitempty"`
+	ProvidersFile      string `json:"PROVIDERS_FILE,omitempty"`
+	VerifyDNSCreds     string `json:"VERIFY_DNS_CREDENTIALS,omitempty"`
+	DNSAPIConcurrency  string `json:"DNS_API_CONCURRENCY,omitempty"`
+	DNSAPIMaxRetries   string `json:"DNS_API_MAX_RETRIES,omitempty"`
+	DNSAuthToken       string `json:"DNS_AUTH_TOKEN,omitempty"`
+	DNSAuthTokenFile   string `json:"DNS_AUTH_TOKEN_FILE,omitempty"`
+	DNSAuthTokenDir    string `json:"DNS_AUTH_TOKEN_DIR,omitempty"`
+	DNSAuthTokenCred   string `json:"DNS_AUTH_TOKEN_CREDENTIAL,omitempty"`
+	DNSAuthTokenURL    string `json:"DNS_AUTH_TOKEN_URL,omitempty"`
+	DNSAuthTokenHeader string `json:"DNS_AUTH_TOKEN_URL_HEADER,omitempty"`
+	DNSAuthTokenVault  string `json:"DNS_AUTH_TOKEN_VAULT,omitempty"`
+	DNSAuthTokenSecret string `json:"DNS_AUTH_TOKEN_SECRET,omitempty"`
+	DNSAuthFallback    string `json:"DNS_AUTH_TOKEN_FALLBACK,omitempty"`
+	UserAgent          string `json:"USER_AGENT,omitempty"`
+	RenewInterval      string `json:"RENEW_INTERVAL,omitempty"`
+	RenewJitter        string `json:"RENEW_JITTER,omitempty"`
+	RenewBefore        string `json:"RENEW_BEFORE,omitempty"`
+	ReuseKey           string `json:"REUSE_KEY,omitempty"`
+	HealthAddr         string `json:"HEALTH_ADDR,omitempty"`
+	ShutdownGrace      string `json:"SHUTDOWN_GRACE,omitempty"`
+	PropagationTimeout string `json:"PROPAGATION_TIMEOUT,omitempty"`
+	ChallengeTimeout   string `json:"CHALLENGE_TIMEOUT,omitempty"`
+	ACMEHTTPTimeout    string `json:"ACME_HTTP_TIMEOUT,omitempty"`
+	AzureSubscription  string `json:"AZURE_SUBSCRIPTION_ID,omitempty"`
+	AzureResourceGroup string `json:"AZURE_RESOURCE_GROUP,omitempty"`
+	AzureTenantID      string `json:"AZURE_TENANT_ID,omitempty"`
+	AzureClientID      string `json:"AZURE_CLIENT_ID,omitempty"`
+	AzureClientSecret  string `json:"AZURE_CLIENT_SECRET,omitempty"`
+	OVHEndpoint        string `json:"OVH_ENDPOINT,omitempty"`
+	OVHAppKey          string `json:"OVH_APPLICATION_KEY,omitempty"`
+	OVHAppSecret       string `json:"OVH_APPLICATION_SECRET,omitempty"`
+	OVHConsumerKey     string `json:"OVH_CONSUMER_KEY,omitempty"`
+	CFValidateToken    string `json:"CF_VALIDATE_TOKEN,omitempty"`
 }
 
 type UserConfig struct {
 	Email                string
 	Key                  crypto.PrivateKey
+	AccountKeyInfo       AccountKeyInfo
+	AccountURI           string
+	SkipRegistration     bool
 	CADirURL             string
+	Insecure             bool
+	CARoots              *x509.CertPool
 	CADirKeyType         certcrypto.KeyType
 	TermsOfServiceAgreed bool
 	Domains              []string
+	DisplayDomains       []string
+	AllowedDomains       []string
 	Filename             string
+	FilenameTemplate     string
+	SkipVerifySANs       bool
+	Certificates         []CertificateGroup
+	Concurrency          int
+	MaxSANs              int
+	AutoChunk            bool
 	OutputDirectory      string
+	OutputMetadata       bool
+	OutputIssuer         bool
+	OutputAllChains      bool
+	OutputDER            bool
+	OutputP7B            bool
+	OutputOnlyChanged    bool
+	OutputLayout         string
+	OutputUID            int
+	OutputGID            int
+	OutputJSON           bool
+	LockTimeout          time.Duration
+	StrictClock          bool
+	ACMEDebug            bool
+	ACMEHTTPProxy        *url.URL
+	OutboundMinTLS       uint16
+	ACMEExtraHeaders     http.Header
+	Prune                bool
+	NotifyWebhookURL     string
+	NotifyAlways         bool
+	KeyPassphrase        string
+	ChallengeType        string
+	ManualWait           time.Duration
+	DNSPresentHook       []string
+	DNSCleanupHook       []string
+	DNSProvider          string
+	DNSProviderMap       map[string]string
+	NamedProviders       map[string]NamedProvider
+	VerifyDNSCredentials bool
+	DNSAPIConcurrency    int
+	DNSAPIMaxRetries     int
 	AuthToken            string
+	ProviderTokens       map[string]string
 	DisableCP            bool
+	DisableCPDomains     []string
+	DNSCheckMode         string
+	FollowCNAME          bool
 	DNSResolvers         []string
+	ZoneResolvers        map[string][]string
+	ResolverProtocol     string
 	DNSTimeout           time.Duration
+	DNSTTL               int
+	DigitalOceanAPIURL   string
+	UserAgent            string
+	RenewInterval        time.Duration
+	RenewJitter          time.Duration
+	RenewBefore          time.Duration
+	RenewBeforePercent   float64
+	ReuseKey             bool
+	HealthAddr           string
+	ShutdownGrace        time.Duration
+	SelfTest             bool
+	PropagationTimeout   time.Duration
+	ChallengeTimeout     time.Duration
+	ACMEHTTPTimeout      time.Duration
+	Azure                AzureConfig
+	OVH                  OVHConfig
+	Cloudflare           CloudflareConfig
+}
+
+// Group of domains issued as a single certificate
+type CertificateGroup struct {
+	// Domains in A-label (punycode) form, sent to the CA server
+	Domains []string
+	// Domains as configured by user, used in logs
+	DisplayDomains []string
+	Filename       string
+	// Path of account key file used for this group, shared account key is used when empty
+	AccountKeyFile string
+	// Account key loaded from AccountKeyFile
+	Key            crypto.PrivateKey
+	AccountKeyInfo AccountKeyInfo
+}
+
+// Create a certificate group from configured entries
+func newCertificateGroup(entries []string, filename string) (CertificateGroup, error) {
+	err := checkIPAddresses(entries)
+	if err != nil {
+		return CertificateGroup{}, err
+	}
+	domains, err := toASCIIDomains(entries)
+	if err != nil {
+		return CertificateGroup{}, err
+	}
+	return CertificateGroup{Domains: domains, DisplayDomains: entries, Filename: filename}, nil
+}
+
+// Get user configuration for a single certificate group
+func (c UserConfig) ForGroup(group CertificateGroup) UserConfig {
+	c.Domains = group.Domains
+	c.DisplayDomains = group.DisplayDomains
+	c.Filename = group.Filename
+	// Account URI and registration options only apply to shared account
+	if group.Key != nil {
+		c.Key = group.Key
+		c.AccountKeyInfo = group.AccountKeyInfo
+		c.AccountURI = ""
+		c.SkipRegistration = false
+	}
+	return c
+}
+
+// Azure DNS provider configuration
+//
+// Client credentials are optional, managed identity
+// is used when they are not provided.
+type AzureConfig struct {
+	SubscriptionID string
+	ResourceGroup  string
+	TenantID       string
+	ClientID       string
+	ClientSecret   string
+}
+
+// OVH DNS provider configuration
+type OVHConfig struct {
+	Endpoint          string
+	ApplicationKey    string
+	ApplicationSecret string
+	ConsumerKey       string
+}
+
+type CloudflareConfig struct {
+	// Verify API token permissions before solving challenges
+	ValidateToken bool
 }
 
 // Parse domains from string
 func (c *RawUserConfig) getDomains() ([]string, error) {
-	domains := strings.Split(c.Domains, ",")
+	domains := splitList(c.Domains, ",")
 	fallback := []string{}
 	if len(domains) == 0 {
 		return fallback, errors.New(fmt.Sprintf("A comma-separated list of domain names must be provided through %s environment variable", constants.DOMAINS))
 	}
-	if len(domains) == 1 && (domains[0] == "") {
-		return fallback, errors.New(fmt.Sprintf("A comma-separated list of domain names must be provided through %s environment variable", constants.DOMAINS))
+	if len(domains) == 1 && (domains[0] == "") {
+		return fallback, errors.New(fmt.Sprintf("A comma-separated list of domain names must be provided through %s environment variable", constants.DOMAINS))
+	}
+	return domains, nil
+}
+
+// Move common name to the front of domains, so that CA server uses it as
+// common name of certificate. Domains are returned as is when common name
+// is not configured.
+func (c *RawUserConfig) promoteCommonName(domains []string) ([]string, error) {
+	if c.CommonName == "" {
+		return domains, nil
+	}
+	idx := slices.IndexFunc(domains, func(domain string) bool {
+		return strings.EqualFold(domain, c.CommonName)
+	})
+	if idx < 0 {
+		return nil, errors.New(fmt.Sprintf("Invalid %s: %s is not found in %s", constants.COMMON_NAME, c.CommonName, constants.DOMAINS))
+	}
+	promoted := []string{domains[idx]}
+	promoted = append(promoted, domains[:idx]...)
+	return append(promoted, domains[idx+1:]...), nil
+}
+
+// Get domain suffixes that certificates may be requested for.
+//
+// Returned suffixes are in A-label (punycode) form. Nil value means
+// that any domain may be requested.
+func (c *RawUserConfig) getAllowedDomains() ([]string, error) {
+	if c.AllowedDomains == "" {
+		return nil, nil
+	}
+	suffixes := []string{}
+	for _, entry := range splitList(c.AllowedDomains, ",") {
+		suffix := normalizeZone(entry)
+		if suffix == "" {
+			return nil, errors.New(fmt.Sprintf("Invalid %s: empty domain found in %s", constants.ALLOWED_DOMAINS, c.AllowedDomains))
+		}
+		suffixes = append(suffixes, suffix)
+	}
+	suffixes, err := toASCIIDomains(suffixes)
+	if err != nil {
+		return nil, errors.New(fmt.Sprintf("Invalid %s: %s", constants.ALLOWED_DOMAINS, err.Error()))
+	}
+	return suffixes, nil
+}
+
+// Check that every domain of certificate groups is equal to, or a subdomain
+// of, an allowed suffix. Wildcard domains are matched without their `*.` label.
+func checkAllowedDomains(groups []CertificateGroup, allowed []string) error {
+	if allowed == nil {
+		return nil
+	}
+	for _, group := range groups {
+		for _, domain := range group.Domains {
+			name := strings.TrimPrefix(strings.ToLower(domain), "*.")
+			if slices.IndexFunc(allowed, func(suffix string) bool {
+				return name == suffix || strings.HasSuffix(name, "."+suffix)
+			}) < 0 {
+				return errors.New(fmt.Sprintf("Domain %s is not allowed by %s", domain, constants.ALLOWED_DOMAINS))
+			}
+		}
+	}
+	return nil
+}
+
+// Check that no entry of a certificate is an IP address.
+//
+// IP identifiers cannot be validated using DNS-01 challenges (RFC 8738),
+// so they are rejected before any ACME call.
+func checkIPAddresses(entries []string) error {
+	ips := []string{}
+	for _, entry := range entries {
+		if net.ParseIP(entry) != nil {
+			ips = append(ips, entry)
+		}
+	}
+	if len(ips) > 0 {
+		return errors.New(fmt.Sprintf("IP address SANs cannot be validated using DNS-01 challenges: %s", strings.Join(ips, ",")))
+	}
+	return nil
+}
+
+// Get account email.
+//
+// Email is read from ACCOUNT_EMAIL, or from the file found at
+// ACCOUNT_EMAIL_FILE when ACCOUNT_EMAIL is not set.
+func (c *RawUserConfig) getAccountEmail(ctx context.Context, storage *stores.Stores) (string, error) {
+	email := c.AccountEmail
+	variable := constants.ACCOUNT_EMAIL
+	if email == "" && c.AccountEmailFile != "" {
+		filestore := storage.GetFileStore()
+		value, err := filestore.GetToken(ctx, c.AccountEmailFile)
+		if err != nil {
+			return "", storeError(err)
+		}
+		email = value
+		variable = constants.ACCOUNT_EMAIL_FILE
+	}
+	if email == "" {
+		return "", errors.New(fmt.Sprintf("An email must be provided through %s or %s environment variable", constants.ACCOUNT_EMAIL, constants.ACCOUNT_EMAIL_FILE))
+	}
+	// Validate email format
+	address, err := mail.ParseAddress(email)
+	if err != nil {
+		return "", errors.New(fmt.Sprintf("invalid %s: %s", variable, err.Error()))
+	}
+	return address.Address, nil
+}
+
+// Get Terms of Service agreement.
+//
+// When agreement is not configured, default agreement is returned and
+// operator is prompted instead while parsing configuration when running
+// interactively.
+func (c *RawUserConfig) getTOSAgreement() (bool, bool, error) {
+	value := c.TOSAgreed
+	prompt := value == ""
+	if prompt {
+		value = constants.DEFAULT_LE_TOS_AGREED
+	}
+	tosAgreed, err := strconv.ParseBool(value)
+	if err != nil {
+		return false, false, err
+	}
+	// Agreement is checked against Terms of Service URL of CA server when registering
+	return tosAgreed, prompt, nil
+}
+
+func (c *RawUserConfig) getCADir() (string, error) {
+	// Return URL associated with environment
+	switch strings.ToUpper(c.CADir) {
+	case constants.ACME_PRODUCTION_ENV:
+		return constants.ACME_PRODUCTION_CA_DIR, nil
+	case constants.ACME_STAGING_ENV:
+		return constants.ACME_STAGING_CA_DIR, nil
+	// This CA URL is configured for a local dev instance of Boulder running in Docker in a VM.
+	case constants.ACME_TEST_ENV:
+		return constants.ACME_TEST_CA_DIR, nil
+	default:
+		if !(strings.HasPrefix(c.CADir, "http://") || strings.HasPrefix(c.CADir, "https://")) {
+			return "", errors.New(fmt.Sprintf("Invalid CA directory: %s", c.CADir))
+		}
+		return c.CADir, nil
+	}
+}
+
+// Get root certificates trusted to verify CA server, or nil to use system roots
+func (c *RawUserConfig) getCARoots() (*x509.CertPool, error) {
+	if c.CARootCertFile == "" {
+		return nil, nil
+	}
+	bundle, err := os.ReadFile(c.CARootCertFile)
+	if err != nil {
+		return nil, errors.New(fmt.Sprintf("Invalid %s: %s", constants.CA_ROOT_CERT_FILE, err.Error()))
+	}
+	pool := x509.NewCertPool()
+	if !pool.AppendCertsFromPEM(bundle) {
+		return nil, errors.New(fmt.Sprintf("Invalid %s: no PEM-encoded certificate found in %s", constants.CA_ROOT_CERT_FILE, c.CARootCertFile))
+	}
+	return pool, nil
+}
+
+func (c *RawUserConfig) getAccountKeyProvider(storage *stores.Stores) accountKeyProvider {
+	// Account key found in environment takes precedence over stored key
+	if c.AccountKeyPEM != "" {
+		return &envKeyProvider{value: c.AccountKeyPEM}
+	}
+	// Account key is stored in keyvault when a vault is configured
+	if c.AccountKeyVault != "" {
+		return &vaultKeyProvider{
+			store:  storage.GetKeyvaultStore(),
+			uri:    vaultURI(c.AccountKeyVault),
+			secret: c.AccountKeySecret,
+		}
+	}
+	return &fileKeyProvider{path: c.AccountKeyFile}
+}
+
+// Get type of generated account key. ECDSA P-256 is used by default.
+func (c *RawUserConfig) getAccountKeyType() (certcrypto.KeyType, error) {
+	switch strings.ToUpper(c.AccountKeyType) {
+	case "", constants.KEY_TYPE_EC256:
+		return certcrypto.EC256, nil
+	case constants.KEY_TYPE_RSA2048:
+		return certcrypto.RSA2048, nil
+	case constants.KEY_TYPE_RSA4096:
+		return certcrypto.RSA4096, nil
+	case constants.KEY_TYPE_EC521:
+		return certcrypto.EC256, errors.New(fmt.Sprintf("Invalid %s: %s keys are not supported by lego. Allowed values are '%s', '%s' and '%s'.", constants.ACCOUNT_KEY_TYPE, constants.KEY_TYPE_EC521, constants.KEY_TYPE_EC256, constants.KEY_TYPE_RSA2048, constants.KEY_TYPE_RSA4096))
+	default:
+		return certcrypto.EC256, errors.New(fmt.Sprintf("Invalid %s: %s. Allowed values are '%s', '%s' and '%s'.", constants.ACCOUNT_KEY_TYPE, c.AccountKeyType, constants.KEY_TYPE_EC256, constants.KEY_TYPE_RSA2048, constants.KEY_TYPE_RSA4096))
+	}
+}
+
+func (c *RawUserConfig) getAccountKey(ctx context.Context, storage *stores.Stores) (crypto.PrivateKey, error) {
+	key, _, err := c.getAccountKeyWithInfo(ctx, storage)
+	return key, err
+}
+
+// Load account key, or generate it if missing, and describe it
+func (c *RawUserConfig) getAccountKeyWithInfo(ctx context.Context, storage *stores.Stores) (crypto.PrivateKey, AccountKeyInfo, error) {
+	keyType, err := c.getAccountKeyType()
+	if err != nil {
+		return nil, AccountKeyInfo{}, err
+	}
+	key, info, err := loadOrCreateAccountKeyInfo(ctx, c.getAccountKeyProvider(storage), keyType)
+	return key, info, storeError(err)
+}
+
+// Load account key and describe it, without generating it when missing
+func (c *RawUserConfig) loadAccountKeyWithInfo(ctx context.Context, storage *stores.Stores) (crypto.PrivateKey, AccountKeyInfo, error) {
+	_, err := c.getAccountKeyType()
+	if err != nil {
+		return nil, AccountKeyInfo{}, err
+	}
+	key, info, err := loadAccountKeyInfo(ctx, c.getAccountKeyProvider(storage))
+	return key, info, storeError(err)
+}
+
+func (c *RawUserConfig) getKeyType() (certcrypto.KeyType, error) {
+	switch c.KeyType {
+	case constants.KEY_TYPE_RSA2048:
+		return certcrypto.RSA2048, nil
+	case constants.KEY_TYPE_RSA4096:
+		return certcrypto.RSA4096, nil
+	case constants.KEY_TYPE_RSA8192:
+		return certcrypto.RSA8192, nil
+	case constants.KEY_TYPE_EC521:
+		return certcrypto.RSA2048, errors.New(fmt.Sprintf("Invalid key type. %s keys are not supported by lego. Allowed values are '%s', '%s' and '%s'.", constants.KEY_TYPE_EC521, constants.KEY_TYPE_RSA2048, constants.KEY_TYPE_RSA4096, constants.KEY_TYPE_RSA8192))
+	default:
+		return certcrypto.RSA2048, errors.New(fmt.Sprintf("Invalid key type. Allowed values are '%s', '%s' and '%s'.", constants.KEY_TYPE_RSA2048, constants.KEY_TYPE_RSA4096, constants.KEY_TYPE_RSA8192))
+	}
+}
+
+func (c *RawUserConfig) getFilename(domains []string) (string, error) {
+	if c.Filename == "" {
+		defaultName, err := sanitizeDomain(domains[0])
+		if err != nil {
+			return "", err
+		}
+		return defaultName, nil
+	}
+	return c.Filename, nil
+}
+
+// Get template of filename under which each issued certificate is archived.
+// Empty value means certificates are only written under their name.
+func (c *RawUserConfig) getFilenameTemplate() (string, error) {
+	if c.FilenameTemplate == "" {
+		return "", nil
+	}
+	err := output.CheckFilenameTemplate(c.FilenameTemplate)
+	if err != nil {
+		return "", errors.New(fmt.Sprintf("Invalid %s: %s", constants.FILENAME_TEMPLATE, err.Error()))
+	}
+	// Archived certificates would be removed as stale certificates
+	prune, err := c.getPruneOption()
+	if err == nil && prune {
+		return "", errors.New(fmt.Sprintf("Invalid %s: cannot be used with %s", constants.FILENAME_TEMPLATE, constants.PRUNE))
+	}
+	return c.FilenameTemplate, nil
+}
+
+// Get whether SANs of issued certificates are not verified, from SKIP_VERIFY_SANS
+func (c *RawUserConfig) getSkipVerifySANsOption() (bool, error) {
+	option, err := strconv.ParseBool(c.SkipVerifySANs)
+	if err != nil {
+		return false, errors.New(fmt.Sprintf("Invalid %s: %s", constants.SKIP_VERIFY_SANS, c.SkipVerifySANs))
+	}
+	return option, nil
+}
+
+// Get user agent sent to CA server, identifying letsgo version by default
+func (c *RawUserConfig) getUserAgent() (string, error) {
+	if c.UserAgent == "" {
+		return version.UserAgent(), nil
+	}
+	return c.UserAgent, nil
+}
+
+// Get explicit output names of certificate groups, if any
+func (c *RawUserConfig) getCertNames(count int) ([]string, error) {
+	if c.CertNames == "" {
+		return nil, nil
+	}
+	names := splitList(c.CertNames, ";")
+	if len(names) != count {
+		return nil, errors.New(fmt.Sprintf("Invalid %s: expected %d names, one per certificate group, but got %d", constants.CERT_NAMES, count, len(names)))
+	}
+	for _, name := range names {
+		safe, err := sanitizeDomain(name)
+		if err != nil || safe != name || name == "" || name == "." || name == ".." || strings.ContainsAny(name, `/\`) {
+			return nil, errors.New(fmt.Sprintf("Invalid %s: '%s' cannot be used as a filename", constants.CERT_NAMES, name))
+		}
+	}
+	return names, nil
+}
+
+// Get overall timeout of stores used to fetch account key and DNS auth token
+func (c *RawUserConfig) getStoreTimeout() (time.Duration, error) {
+	timeout, err := parseDuration(c.StoreTimeout)
+	if err == nil && timeout == 0 {
+		err = errors.New("duration must be positive")
+	}
+	if err != nil {
+		return 0, errors.New(fmt.Sprintf("Invalid %s: %s", constants.STORE_TIMEOUT, err.Error()))
+	}
+	return timeout, nil
+}
+
+func (c *RawUserConfig) getSeparateCertsOption() (bool, error) {
+	if c.SeparateCerts == "" {
+		return false, nil
+	}
+	option, err := strconv.ParseBool(c.SeparateCerts)
+	if err != nil {
+		return false, err
+	}
+	return option, nil
+}
+
+// Get maximum number of SANs of a single certificate
+func (c *RawUserConfig) getMaxSANs() (int, error) {
+	if c.MaxSANs == "" {
+		return strconv.Atoi(constants.DEFAULT_MAX_SANS)
+	}
+	maxSANs, err := strconv.Atoi(c.MaxSANs)
+	if err != nil || maxSANs < 1 {
+		return 0, errors.New(fmt.Sprintf("Invalid %s: %s. A positive integer is expected.", constants.MAX_SANS, c.MaxSANs))
+	}
+	return maxSANs, nil
+}
+
+func (c *RawUserConfig) getAutoChunkOption() (bool, error) {
+	if c.AutoChunk == "" {
+		return false, nil
+	}
+	option, err := strconv.ParseBool(c.AutoChunk)
+	if err != nil {
+		return false, errors.New(fmt.Sprintf("Invalid %s: %s", constants.AUTO_CHUNK, c.AutoChunk))
+	}
+	return option, nil
+}
+
+// Create certificate groups holding entries.
+//
+// Entries exceeding MAX_SANS are rejected, unless AUTO_CHUNK is enabled.
+// Entries are then split into groups of at most MAX_SANS entries, named
+// `<filename>-1`, `<filename>-2`, and so on.
+func (c *RawUserConfig) newCertificateGroups(entries []string, filename string) ([]CertificateGroup, error) {
+	maxSANs, err := c.getMaxSANs()
+	if err != nil {
+		return nil, err
+	}
+	if len(entries) <= maxSANs {
+		group, err := newCertificateGroup(entries, filename)
+		if err != nil {
+			return nil, err
+		}
+		return []CertificateGroup{group}, nil
+	}
+	autoChunk, err := c.getAutoChunkOption()
+	if err != nil {
+		return nil, err
+	}
+	if !autoChunk {
+		return nil, errors.New(fmt.Sprintf("Certificate %s holds %d domains, more than %s (%d). Split domains into several certificates, or set %s=true", filename, len(entries), constants.MAX_SANS, maxSANs, constants.AUTO_CHUNK))
+	}
+	groups := []CertificateGroup{}
+	for start := 0; start < len(entries); start += maxSANs {
+		end := start + maxSANs
+		if end > len(entries) {
+			end = len(entries)
+		}
+		group, err := newCertificateGroup(entries[start:end], fmt.Sprintf("%s-%d", filename, len(groups)+1))
+		if err != nil {
+			return nil, err
+		}
+		groups = append(groups, group)
+	}
+	return groups, nil
+}
+
+func (c *RawUserConfig) getCertificateGroups() ([]CertificateGroup, error) {
+	separate, err := c.getSeparateCertsOption()
+	if err != nil {
+		return nil, err
+	}
+	// Common name can only be selected among domains of a single certificate
+	if c.CommonName != "" && (c.Certificates != "" || separate) {
+		return nil, errors.New(fmt.Sprintf("Invalid %s: cannot be used with %s or %s", constants.COMMON_NAME, constants.CERTIFICATES, constants.SEPARATE_CERTS))
+	}
+	// Request one certificate per domain when enabled
+	if c.Certificates == "" && separate {
+		entries, err := c.getDomains()
+		if err != nil {
+			return nil, err
+		}
+		groups := []CertificateGroup{}
+		for _, entry := range entries {
+			name, err := sanitizeDomain(entry)
+			if err != nil {
+				return nil, err
+			}
+			group, err := newCertificateGroup([]string{entry}, name)
+			if err != nil {
+				return nil, err
+			}
+			groups = append(groups, group)
+		}
+		return groups, nil
+	}
+	// Request a single certificate when no group is configured
+	if c.Certificates == "" {
+		if c.CertNames != "" {
+			return nil, errors.New(fmt.Sprintf("Invalid %s: %s must be set as well", constants.CERT_NAMES, constants.CERTIFICATES))
+		}
+		entries, err := c.getDomains()
+		if err != nil {
+			return nil, err
+		}
+		name, err := c.getFilename(entries)
+		if err != nil {
+			return nil, err
+		}
+		// Filename keeps following the first configured domain
+		entries, err = c.promoteCommonName(entries)
+		if err != nil {
+			return nil, err
+		}
+		return c.newCertificateGroups(entries, name)
+	}
+	groups := []CertificateGroup{}
+	rawGroups := splitList(c.Certificates, ";")
+	names, err := c.getCertNames(len(rawGroups))
+	if err != nil {
+		return nil, err
+	}
+	for idx, group := range rawGroups {
+		// Group may be paired with its own account key file
+		group, accountKeyFile, paired := strings.Cut(group, "@")
+		accountKeyFile = strings.TrimSpace(accountKeyFile)
+		if paired && accountKeyFile == "" {
+			return nil, errors.New(fmt.Sprintf("Invalid %s: empty account key path for group %s", constants.CERTIFICATES, group))
+		}
+		entries := splitList(group, ",")
+		if len(entries) == 1 && entries[0] == "" {
+			return nil, errors.New(fmt.Sprintf("Invalid %s: empty certificate group", constants.CERTIFICATES))
+		}
+		// Use explicit name when configured, else first domain of group
+		var name string
+		if names != nil {
+			name = names[idx]
+		} else {
+			name, err = sanitizeDomain(entries[0])
+			if err != nil {
+				return nil, err
+			}
+		}
+		certificateGroups, err := c.newCertificateGroups(entries, name)
+		if err != nil {
+			return nil, err
+		}
+		for _, certificateGroup := range certificateGroups {
+			certificateGroup.AccountKeyFile = accountKeyFile
+			groups = append(groups, certificateGroup)
+		}
+	}
+	return groups, nil
+}
+
+// Load account keys of certificate groups paired with their own account key file.
+//
+// Groups using the same file share the same key. Missing keys are generated
+// unless loadOnly is true.
+func (c *RawUserConfig) loadGroupAccountKeys(ctx context.Context, groups []CertificateGroup, loadOnly bool) error {
+	keyType, err := c.getAccountKeyType()
+	if err != nil {
+		return err
+	}
+	keys := map[string]crypto.PrivateKey{}
+	infos := map[string]AccountKeyInfo{}
+	for idx := range groups {
+		path := groups[idx].AccountKeyFile
+		if path == "" {
+			continue
+		}
+		if _, ok := keys[path]; !ok {
+			provider := &fileKeyProvider{path: path}
+			var key crypto.PrivateKey
+			var info AccountKeyInfo
+			if loadOnly {
+				key, info, err = loadAccountKeyInfo(ctx, provider)
+			} else {
+				key, info, err = loadOrCreateAccountKeyInfo(ctx, provider, keyType)
+			}
+			if err != nil {
+				return err
+			}
+			keys[path] = key
+			infos[path] = info
+		}
+		groups[idx].Key = keys[path]
+		groups[idx].AccountKeyInfo = infos[path]
+	}
+	return nil
+}
+
+func (c *RawUserConfig) getConcurrency() (int, error) {
+	concurrency, err := strconv.Atoi(c.Concurrency)
+	if err != nil || concurrency < 1 {
+		return 0, errors.New(fmt.Sprintf("Invalid %s: %s. A positive integer is expected.", constants.CONCURRENCY, c.Concurrency))
+	}
+	return concurrency, nil
+}
+
+// Parse maximum number of concurrent DNS provider API calls.
+// Zero means no limit.
+func (c *RawUserConfig) getDNSAPIConcurrency() (int, error) {
+	concurrency, err := strconv.Atoi(c.DNSAPIConcurrency)
+	if err != nil || concurrency < 0 {
+		return 0, errors.New(fmt.Sprintf("Invalid %s: %s. A non-negative integer is expected.", constants.DNS_API_CONCURRENCY, c.DNSAPIConcurrency))
+	}
+	return concurrency, nil
+}
+
+// Parse maximum number of retries of DNS provider API calls rejected
+// because of rate limits. Zero means calls are not retried.
+func (c *RawUserConfig) getDNSAPIMaxRetries() (int, error) {
+	retries, err := strconv.Atoi(c.DNSAPIMaxRetries)
+	if err != nil || retries < 0 {
+		return 0, errors.New(fmt.Sprintf("Invalid %s: %s. A non-negative integer is expected.", constants.DNS_API_MAX_RETRIES, c.DNSAPIMaxRetries))
+	}
+	return retries, nil
+}
+
+// Parse DNS resolvers.
+//
+// Value is either a comma-separated list of resolvers used for all zones,
+// or a semicolon-separated list of zone=resolvers pairs. In the latter case,
+// entries without a zone are used for all other zones.
+//
+// Resolvers are normalized to host:port form, so that bare IPv6 addresses
+// are enclosed in brackets and default port of protocol is added when missing.
+func (c *RawUserConfig) getDNSResolvers() ([]string, map[string][]string, error) {
+	dnsResolvers := []string{}
+	zoneResolvers := map[string][]string{}
+	if c.DNSResolver == "" {
+		return dnsResolvers, zoneResolvers, nil
+	}
+	port := "53"
+	if strings.ToLower(c.ResolverProtocol) == constants.DNS_PROTOCOL_TCP_TLS {
+		port = "853"
+	}
+	if !strings.Contains(c.DNSResolver, "=") {
+		return splitResolvers(c.DNSResolver, port), zoneResolvers, nil
+	}
+	for _, entry := range splitList(c.DNSResolver, ";") {
+		zone, servers, found := strings.Cut(entry, "=")
+		if !found {
+			dnsResolvers = append(dnsResolvers, splitResolvers(entry, port)...)
+			continue
+		}
+		zone = normalizeZone(zone)
+		if zone == "" || servers == "" {
+			return nil, nil, errors.New(fmt.Sprintf("Invalid %s: %s. Expected format is zone=server.", constants.DNS_RESOLVERS, entry))
+		}
+		zoneResolvers[zone] = append(zoneResolvers[zone], splitResolvers(servers, port)...)
+	}
+	return dnsResolvers, zoneResolvers, nil
+}
+
+// Split comma-separated list of resolvers and normalize each of them
+func splitResolvers(value string, port string) []string {
+	resolvers := []string{}
+	for _, resolver := range splitList(value, ",") {
+		resolvers = append(resolvers, normalizeResolver(resolver, port))
+	}
+	return resolvers
+}
+
+// Normalize resolver to host:port form.
+//
+// Bare IPv6 addresses such as 2606:4700:4700::1111 cannot hold a port,
+// so they are always considered as host only.
+func normalizeResolver(resolver string, port string) string {
+	resolver = strings.TrimSpace(resolver)
+	if resolver == "" {
+		return resolver
+	}
+	if _, _, err := net.SplitHostPort(resolver); err == nil {
+		return resolver
+	}
+	host := strings.TrimSuffix(strings.TrimPrefix(resolver, "["), "]")
+	return net.JoinHostPort(host, port)
+}
+
+func (c *RawUserConfig) getDNSTimeout() (time.Duration, error) {
+	timeout, err := strconv.ParseFloat(c.DNSTimeout, 32)
+	fallback := time.Duration(0)
+	if err != nil {
+		return fallback, err
+	}
+	return time.Duration(timeout) * time.Second, nil
+}
+
+// Get TTL of challenge records in seconds. Zero means provider default is used.
+func (c *RawUserConfig) getDNSTTL() (int, error) {
+	if c.DNSTTL == "" {
+		return 0, nil
+	}
+	ttl, err := strconv.Atoi(c.DNSTTL)
+	if err != nil || ttl < 1 {
+		return 0, errors.New(fmt.Sprintf("Invalid %s: %s. A positive integer of seconds is expected.", constants.DNS_TTL, c.DNSTTL))
+	}
+	return ttl, nil
+}
+
+// Get DigitalOcean API base URL. Empty value means provider default is used.
+func (c *RawUserConfig) getDigitalOceanAPIURL() (string, error) {
+	if c.DigitalOceanAPIURL == "" {
+		return "", nil
+	}
+	apiURL, err := url.Parse(c.DigitalOceanAPIURL)
+	if err != nil || apiURL.Scheme == "" || apiURL.Host == "" {
+		return "", errors.New(fmt.Sprintf("Invalid %s: %s. An absolute URL is expected.", constants.DO_API_URL, c.DigitalOceanAPIURL))
+	}
+	return strings.TrimSuffix(c.DigitalOceanAPIURL, "/"), nil
+}
+
+func (c *RawUserConfig) getFollowCNAMEOption() (bool, error) {
+	option, err := strconv.ParseBool(c.FollowCNAME)
+	if err != nil {
+		return false, err
+	}
+	return option, nil
+}
+
+// Get complete propagation option.
+//
+// Value is either a boolean applying to all domains, or a comma-separated
+// list of domains for which complete propagation check is disabled, while
+// other domains keep the complete propagation check.
+func (c *RawUserConfig) getDisableCPOption() (bool, []string, error) {
+	value := strings.TrimSpace(c.DisableCP)
+	if value == "" {
+		value = constants.DEFAULT_DISABLE_CP
+	}
+	option, err := strconv.ParseBool(value)
+	if err == nil {
+		return option, nil, nil
+	}
+	domains := []string{}
+	for _, entry := range splitList(value, ",") {
+		domain := normalizeZone(entry)
+		if !strings.Contains(domain, ".") {
+			return false, nil, errors.New(fmt.Sprintf("Invalid %s: %s. Expected 'true', 'false' or a comma-separated list of domains.", constants.DISABLE_CP, c.DisableCP))
+		}
+		domains = append(domains, domain)
+	}
+	return false, domains, nil
+}
+
+// Get DNS propagation check mode.
+//
+// When not set, mode is derived from DISABLE_CP option.
+func (c *RawUserConfig) getDNSCheckMode(disableCP bool) (string, error) {
+	mode := strings.ToLower(c.DNSCheckMode)
+	switch mode {
+	case "":
+		if disableCP {
+			return constants.DNS_CHECK_MODE_PROPAGATION, nil
+		}
+		return constants.DNS_CHECK_MODE_STRICT, nil
+	case constants.DNS_CHECK_MODE_STRICT, constants.DNS_CHECK_MODE_PROPAGATION, constants.DNS_CHECK_MODE_NONE:
+		return mode, nil
+	default:
+		return "", errors.New(fmt.Sprintf("Invalid %s: %s. Allowed values are '%s', '%s' and '%s'.", constants.DNS_CHECK_MODE, c.DNSCheckMode, constants.DNS_CHECK_MODE_STRICT, constants.DNS_CHECK_MODE_PROPAGATION, constants.DNS_CHECK_MODE_NONE))
+	}
+}
+
+// Get protocol used to query DNS resolvers during propagation checks.
+//
+// Challenge records are checked by letsgo instead of lego when protocol
+// is not UDP, which requires DNS resolvers to be configured. Strict
+// checks query authoritative name servers over UDP and are not supported.
+func (c *RawUserConfig) getResolverProtocol(resolvers []string, zoneResolvers map[string][]string, checkMode string) (string, error) {
+	protocol := strings.ToLower(c.ResolverProtocol)
+	switch protocol {
+	case constants.DNS_PROTOCOL_UDP:
+		return protocol, nil
+	case constants.DNS_PROTOCOL_TCP, constants.DNS_PROTOCOL_TCP_TLS:
+	default:
+		return "", errors.New(fmt.Sprintf("Invalid %s: %s. Allowed values are '%s', '%s' and '%s'.", constants.DNS_RESOLVER_PROTOCOL, c.ResolverProtocol, constants.DNS_PROTOCOL_UDP, constants.DNS_PROTOCOL_TCP, constants.DNS_PROTOCOL_TCP_TLS))
+	}
+	if len(resolvers) == 0 && len(zoneResolvers) == 0 {
+		return "", errors.New(fmt.Sprintf("Invalid %s: %s requires %s to be set", constants.DNS_RESOLVER_PROTOCOL, protocol, constants.DNS_RESOLVERS))
+	}
+	if checkMode == constants.DNS_CHECK_MODE_STRICT {
+		return "", errors.New(fmt.Sprintf("Invalid %s: %s is not supported with %s '%s', authoritative name servers are queried over UDP", constants.DNS_RESOLVER_PROTOCOL, protocol, constants.DNS_CHECK_MODE, checkMode))
+	}
+	return protocol, nil
+}
+
+// Get registration options. An account URI is required when registration is skipped.
+func (c *RawUserConfig) getRegistration() (string, bool, error) {
+	skip, err := strconv.ParseBool(c.SkipRegistration)
+	if err != nil {
+		return "", false, errors.New(fmt.Sprintf("Invalid %s: %s", constants.SKIP_REGISTRATION, c.SkipRegistration))
+	}
+	if skip && c.AccountURI == "" {
+		return "", false, errors.New(fmt.Sprintf("%s requires %s to be set to the URL of an existing account", constants.SKIP_REGISTRATION, constants.ACCOUNT_URI))
+	}
+	return c.AccountURI, skip, nil
+}
+
+func (c *RawUserConfig) getChallengeType() (string, error) {
+	challengeType := strings.ToLower(c.ChallengeType)
+	switch challengeType {
+	case constants.CHALLENGE_TYPE_DNS01, constants.CHALLENGE_TYPE_MANUAL:
+		return challengeType, nil
+	default:
+		return "", errors.New(fmt.Sprintf("Invalid challenge type: %s. Allowed values are '%s' and '%s'.", c.ChallengeType, constants.CHALLENGE_TYPE_DNS01, constants.CHALLENGE_TYPE_MANUAL))
+	}
+}
+
+func (c *RawUserConfig) getManualWait() (time.Duration, error) {
+	wait, err := parseDuration(c.ManualWait)
+	if err != nil {
+		return 0, errors.New(fmt.Sprintf("Invalid %s: %s", constants.MANUAL_WAIT, err.Error()))
+	}
+	return wait, nil
+}
+
+// Parse commands creating and removing challenge records.
+//
+// Commands are split on whitespace, no shell is involved.
+// Cleanup hook is optional, but requires a present hook.
+func (c *RawUserConfig) getDNSHooks() ([]string, []string, error) {
+	present := strings.Fields(c.DNSPresentHook)
+	cleanup := strings.Fields(c.DNSCleanupHook)
+	if len(present) == 0 && len(cleanup) > 0 {
+		return nil, nil, errors.New(fmt.Sprintf("Invalid %s: %s must be set as well", constants.DNS_CLEANUP_HOOK, constants.DNS_PRESENT_HOOK))
+	}
+	return present, cleanup, nil
+}
+
+func (c *RawUserConfig) getDNSProvider() (string, error) {
+	provider := strings.ToLower(c.DNSProvider)
+	if _, ok := dnsProviders[provider]; !ok {
+		return "", errors.New(fmt.Sprintf("Invalid DNS provider: %s. Allowed values are %s.", c.DNSProvider, allowedDNSProviders()))
+	}
+	return provider, nil
+}
+
+// Parse DNS providers associated with zones.
+//
+// Expected format is a semicolon-separated list of zone=provider pairs.
+// Providers are either supported DNS providers or named providers.
+func (c *RawUserConfig) getDNSProviderMap(named map[string]NamedProvider) (map[string]string, error) {
+	providerMap := map[string]string{}
+	if c.DNSProviderMap == "" {
+		return providerMap, nil
+	}
+	for _, entry := range splitList(c.DNSProviderMap, ";") {
+		zone, provider, found := strings.Cut(entry, "=")
+		zone = normalizeZone(zone)
+		provider = strings.ToLower(strings.TrimSpace(provider))
+		if !found || zone == "" {
+			return nil, errors.New(fmt.Sprintf("Invalid %s: %s. Expected format is zone=provider.", constants.DNS_PROVIDER_MAP, entry))
+		}
+		_, isNamed := named[provider]
+		if _, ok := dnsProviders[provider]; !ok && !isNamed {
+			return nil, errors.New(fmt.Sprintf("Invalid DNS provider for zone %s: %s. Allowed values are %s.", zone, provider, allowedDNSProviders()))
+		}
+		providerMap[zone] = provider
+	}
+	return providerMap, nil
+}
+
+func (c *RawUserConfig) getAzureConfig() (AzureConfig, error) {
+	if c.AzureSubscription == "" {
+		return AzureConfig{}, errors.New(fmt.Sprintf("An Azure subscription ID must be provided through %s environment variable", constants.AZURE_SUBSCRIPTION_ID))
+	}
+	if c.AzureResourceGroup == "" {
+		return AzureConfig{}, errors.New(fmt.Sprintf("An Azure resource group must be provided through %s environment variable", constants.AZURE_RESOURCE_GROUP))
+	}
+	return AzureConfig{
+		SubscriptionID: c.AzureSubscription,
+		ResourceGroup:  c.AzureResourceGroup,
+		TenantID:       c.AzureTenantID,
+		ClientID:       c.AzureClientID,
+		ClientSecret:   c.AzureClientSecret,
+	}, nil
+}
+
+func (c *RawUserConfig) getCloudflareConfig() (CloudflareConfig, error) {
+	validateToken, err := strconv.ParseBool(c.CFValidateToken)
+	if err != nil {
+		return CloudflareConfig{}, errors.New(fmt.Sprintf("Invalid %s: %s", constants.CF_VALIDATE_TOKEN, c.CFValidateToken))
+	}
+	return CloudflareConfig{ValidateToken: validateToken}, nil
+}
+
+// Read provider credentials which are not set from the file whose path is
+// found in `<NAME>_FILE` environment variable, e.g. OVH_CONSUMER_KEY is read
+// from OVH_CONSUMER_KEY_FILE, so that each secret can be mounted as a file.
+func (c *RawUserConfig) loadCredentialFiles(ctx context.Context, storage *stores.Stores) error {
+	credentials := []struct {
+		name  string
+		value *string
+	}{
+		{constants.AZURE_CLIENT_SECRET, &c.AzureClientSecret},
+		{constants.OVH_APPLICATION_KEY, &c.OVHAppKey},
+		{constants.OVH_APPLICATION_SECRET, &c.OVHAppSecret},
+		{constants.OVH_CONSUMER_KEY, &c.OVHConsumerKey},
+	}
+	for _, credential := range credentials {
+		if *credential.value != "" || os.Getenv(credential.name+stores.FileSuffix) == "" {
+			continue
+		}
+		value, err := storage.GetFileStore().GetValue(ctx, credential.name)
+		if err != nil {
+			return storeError(err)
+		}
+		*credential.value = value
+	}
+	return nil
+}
+
+func (c *RawUserConfig) getOVHConfig() (OVHConfig, error) {
+	required := []struct {
+		name  string
+		value string
+	}{
+		{constants.OVH_ENDPOINT, c.OVHEndpoint},
+		{constants.OVH_APPLICATION_KEY, c.OVHAppKey},
+		{constants.OVH_APPLICATION_SECRET, c.OVHAppSecret},
+		{constants.OVH_CONSUMER_KEY, c.OVHConsumerKey},
+	}
+	for _, variable := range required {
+		if variable.value == "" {
+			return OVHConfig{}, errors.New(fmt.Sprintf("An OVH credential must be provided through %s environment variable", variable.name))
+		}
+	}
+	return OVHConfig{
+		Endpoint:          c.OVHEndpoint,
+		ApplicationKey:    c.OVHAppKey,
+		ApplicationSecret: c.OVHAppSecret,
+		ConsumerKey:       c.OVHConsumerKey,
+	}, nil
+}
+
+// Source of DNS auth token
+type tokenSource struct {
+	// Name of environment variable configuring source
+	name string
+	get  func() (string, error)
+}
+
+// Get configured sources of DNS auth token, in order of precedence
+func (c *RawUserConfig) getDNSAuthTokenSources(ctx context.Context, storage *stores.Stores) []tokenSource {
+	sources := []tokenSource{}
+	// Check that token is not empty
+	if c.DNSAuthToken != "" {
+		sources = append(sources, tokenSource{constants.DNS_AUTH_TOKEN, func() (string, error) {
+			return c.DNSAuthToken, nil
+		}})
+	}
+	// Check if token should be fetched from file
+	if c.DNSAuthTokenFile != "" {
+		sources = append(sources, tokenSource{constants.DNS_AUTH_TOKEN_FILE, func() (string, error) {
+			filestore := storage.GetFileStore()
+			token, err := filestore.GetToken(ctx, c.DNSAuthTokenFile)
+			return token, storeError(err)
+		}})
+	}
+	// Check if token should be fetched from secret directory
+	if c.DNSAuthTokenDir != "" {
+		sources = append(sources, tokenSource{constants.DNS_AUTH_TOKEN_DIR, func() (string, error) {
+			directory := storage.GetDirectoryStore()
+			token, err := directory.GetToken(ctx, c.DNSAuthTokenDir)
+			return token, storeError(err)
+		}})
+	}
+	// Check if token should be fetched from systemd credentials
+	if c.DNSAuthTokenCred != "" {
+		sources = append(sources, tokenSource{constants.DNS_AUTH_TOKEN_CREDENTIAL, func() (string, error) {
+			credentials := storage.GetCredentialStore()
+			token, err := credentials.GetToken(ctx, c.DNSAuthTokenCred)
+			return token, storeError(err)
+		}})
+	}
+	// Check if token should be fetched from HTTP endpoint
+	if c.DNSAuthTokenURL != "" {
+		sources = append(sources, tokenSource{constants.DNS_AUTH_TOKEN_URL, func() (string, error) {
+			endpoint := storage.GetHTTPStore()
+			token, err := endpoint.GetToken(ctx, c.DNSAuthTokenURL, c.DNSAuthTokenHeader)
+			return token, storeError(err)
+		}})
+	}
+	// Check if token should be fetched from vault
+	if c.DNSAuthTokenVault != "" {
+		sources = append(sources, tokenSource{constants.DNS_AUTH_TOKEN_VAULT, func() (string, error) {
+			uri, err := c.getDNSAuthTokenVaultURI()
+			if err != nil {
+				return "", err
+			}
+			secret, err := c.getDNSAuthTokenSecretName()
+			if err != nil {
+				return "", err
+			}
+			keyvault := storage.GetKeyvaultStore()
+			token, err := keyvault.GetToken(ctx, uri, secret)
+			return token, storeError(err)
+		}})
+	}
+	return sources
+}
+
+// Get DNS auth token from the first configured source.
+//
+// When fallback is enabled, a failing source is skipped and the next
+// configured source is used instead.
+func (c *RawUserConfig) getDNSAuthToken(ctx context.Context, storage *stores.Stores) (string, error) {
+	sources := c.getDNSAuthTokenSources(ctx, storage)
+	if len(sources) == 0 {
+		return "", errors.New(fmt.Sprintf("Invalid DNS auth token. Use one of '%s', '%s', '%s', '%s', '%s' or '%s' env variable", constants.DNS_AUTH_TOKEN_VAULT, constants.DNS_AUTH_TOKEN_URL, constants.DNS_AUTH_TOKEN_CREDENTIAL, constants.DNS_AUTH_TOKEN_DIR, constants.DNS_AUTH_TOKEN_FILE, constants.DNS_AUTH_TOKEN))
+	}
+	fallback, err := c.getDNSAuthTokenFallbackOption()
+	if err != nil {
+		return "", err
+	}
+	failures := []string{}
+	for _, source := range sources {
+		token, err := source.get()
+		if err == nil {
+			return token, nil
+		}
+		if !fallback {
+			return "", err
+		}
+		log.Printf("Could not read DNS auth token from %s, trying next source: %s", source.name, err.Error())
+		failures = append(failures, fmt.Sprintf("%s: %s", source.name, err.Error()))
+	}
+	return "", storeError(errors.New(fmt.Sprintf("Could not read DNS auth token from any source: %s", strings.Join(failures, "; "))))
+}
+
+// Get token of DNS provider from its own environment variable.
+//
+// Returns false when provider does not have its own variable,
+// or when variable is not set.
+func (c *RawUserConfig) getProviderToken(ctx context.Context, storage *stores.Stores, name string) (string, bool, error) {
+	variable := dnsProviders[name].tokenVariable
+	if variable == "" || getEnv(variable, "") == "" {
+		return "", false, nil
+	}
+	token, err := storage.GetEnvStore().GetToken(ctx, variable)
+	if err != nil {
+		return "", false, storeError(err)
+	}
+	return token, true, nil
+}
+
+func (c *RawUserConfig) getDNSAuthTokenFallbackOption() (bool, error) {
+	option, err := strconv.ParseBool(c.DNSAuthFallback)
+	if err != nil {
+		return false, errors.New(fmt.Sprintf("Invalid %s: %s", constants.DNS_AUTH_TOKEN_FALLBACK, c.DNSAuthFallback))
+	}
+	return option, nil
+}
+
+func (c *RawUserConfig) getDNSAuthTokenVaultURI() (string, error) {
+	if c.DNSAuthTokenVault == "" {
+		return "", errors.New(fmt.Sprintf("Invalid Keyvault URI: %s", c.DNSAuthTokenVault))
+	}
+	return vaultURI(c.DNSAuthTokenVault), nil
+}
+
+func (c *RawUserConfig) getDNSAuthTokenSecretName() (string, error) {
+	if c.DNSAuthTokenSecret == "" {
+		return "", errors.New(fmt.Sprintf("Invalid DNS auth token: %s", c.DNSAuthTokenSecret))
+	}
+	return c.DNSAuthTokenSecret, nil
+}
+
+// Get absolute path of output directory, created when missing unless loadOnly is true
+func (c *RawUserConfig) getOutputDirectory(loadOnly bool) (string, error) {
+	dir, err := filepath.Abs(c.OutputDirectory)
+	if err != nil {
+		return "", err
+	}
+	if loadOnly {
+		return dir, nil
+	}
+	err = os.MkdirAll(dir, os.ModePerm)
+	if err != nil {
+		return "", err
+	}
+	return dir, nil
+}
+
+func (c *RawUserConfig) getOutputIssuerOption() (bool, error) {
+	option, err := strconv.ParseBool(c.OutputIssuer)
+	if err != nil {
+		return false, err
+	}
+	return option, nil
+}
+
+func (c *RawUserConfig) getOutputAllChainsOption() (bool, error) {
+	option, err := strconv.ParseBool(c.OutputAllChains)
+	if err != nil {
+		return false, err
+	}
+	return option, nil
+}
+
+func (c *RawUserConfig) getOutputDEROption() (bool, error) {
+	option, err := strconv.ParseBool(c.OutputDER)
+	if err != nil {
+		return false, err
+	}
+	return option, nil
+}
+
+func (c *RawUserConfig) getOutputP7BOption() (bool, error) {
+	option, err := strconv.ParseBool(c.OutputP7B)
+	if err != nil {
+		return false, errors.New(fmt.Sprintf("Invalid %s: %s", constants.OUTPUT_P7B, c.OutputP7B))
+	}
+	return option, nil
+}
+
+func (c *RawUserConfig) getOutputOnlyChangedOption() (bool, error) {
+	option, err := strconv.ParseBool(c.OutputOnlyChanged)
+	if err != nil {
+		return false, errors.New(fmt.Sprintf("Invalid %s: %s", constants.OUTPUT_ONLY_CHANGED, c.OutputOnlyChanged))
+	}
+	return option, nil
+}
+
+// Get layout of files expected by a server
+func (c *RawUserConfig) getOutputLayout() (string, error) {
+	layout := strings.ToLower(c.OutputLayout)
+	switch layout {
+	case constants.OUTPUT_LAYOUT_STANDARD, constants.OUTPUT_LAYOUT_NGINX, constants.OUTPUT_LAYOUT_APACHE, constants.OUTPUT_LAYOUT_HAPROXY, constants.OUTPUT_LAYOUT_TRAEFIK:
+		return layout, nil
+	default:
+		return "", errors.New(fmt.Sprintf("Invalid %s: %s. Allowed values are '%s', '%s', '%s', '%s' and '%s'.", constants.OUTPUT_LAYOUT, c.OutputLayout, constants.OUTPUT_LAYOUT_STANDARD, constants.OUTPUT_LAYOUT_NGINX, constants.OUTPUT_LAYOUT_APACHE, constants.OUTPUT_LAYOUT_HAPROXY, constants.OUTPUT_LAYOUT_TRAEFIK))
+	}
+}
+
+// Parse owner of written files, -1 is returned when unset
+func parseOwnerID(name string, value string) (int, error) {
+	if value == "" {
+		return -1, nil
+	}
+	id, err := strconv.Atoi(value)
+	if err != nil || id < 0 {
+		return -1, errors.New(fmt.Sprintf("Invalid %s: %s. A non-negative integer is expected.", name, value))
+	}
+	return id, nil
+}
+
+func (c *RawUserConfig) getOutputOwner() (int, int, error) {
+	uid, err := parseOwnerID(constants.OUTPUT_UID, c.OutputUID)
+	if err != nil {
+		return -1, -1, err
+	}
+	gid, err := parseOwnerID(constants.OUTPUT_GID, c.OutputGID)
+	if err != nil {
+		return -1, -1, err
+	}
+	return uid, gid, nil
+}
+
+func (c *RawUserConfig) getOutputMetadataOption() (bool, error) {
+	option, err := strconv.ParseBool(c.OutputMetadata)
+	if err != nil {
+		return false, err
+	}
+	return option, nil
+}
+
+func (c *RawUserConfig) getOutputJSONOption() (bool, error) {
+	option, err := strconv.ParseBool(c.OutputJSON)
+	if err != nil {
+		return false, err
+	}
+	return option, nil
+}
+
+func (c *RawUserConfig) getLockTimeout() (time.Duration, error) {
+	timeout, err := parseDuration(c.LockTimeout)
+	if err != nil {
+		return 0, errors.New(fmt.Sprintf("Invalid %s: %s", constants.LOCK_TIMEOUT, err.Error()))
+	}
+	return timeout, nil
+}
+
+func (c *RawUserConfig) getStrictClockOption() (bool, error) {
+	option, err := strconv.ParseBool(c.StrictClock)
+	if err != nil {
+		return false, err
+	}
+	return option, nil
+}
+
+func (c *RawUserConfig) getVerifyDNSCredentialsOption() (bool, error) {
+	option, err := strconv.ParseBool(c.VerifyDNSCreds)
+	if err != nil {
+		return false, errors.New(fmt.Sprintf("Invalid %s: %s", constants.VERIFY_DNS_CREDENTIALS, c.VerifyDNSCreds))
+	}
+	return option, nil
+}
+
+func (c *RawUserConfig) getACMEDebugOption() (bool, error) {
+	option, err := strconv.ParseBool(c.ACMEDebug)
+	if err != nil {
+		return false, err
+	}
+	return option, nil
+}
+
+// Get proxy used for requests sent to CA server and DNS provider API.
+// Nil value means proxy is selected from HTTP_PROXY, HTTPS_PROXY and NO_PROXY.
+func (c *RawUserConfig) getACMEHTTPProxy() (*url.URL, error) {
+	if c.ACMEHTTPProxy == "" {
+		return nil, nil
+	}
+	proxyURL, err := url.Parse(c.ACMEHTTPProxy)
+	if err != nil || proxyURL.Scheme == "" || proxyURL.Host == "" {
+		return nil, errors.New(fmt.Sprintf("Invalid %s. An absolute URL is expected.", constants.ACME_HTTP_PROXY))
+	}
+	return proxyURL, nil
+}
+
+// Get minimum TLS version of requests sent to CA server and DNS provider API
+func (c *RawUserConfig) getOutboundMinTLS() (uint16, error) {
+	switch c.OutboundMinTLS {
+	case constants.TLS_VERSION_1_2:
+		return tls.VersionTLS12, nil
+	case constants.TLS_VERSION_1_3:
+		return tls.VersionTLS13, nil
+	default:
+		return 0, errors.New(fmt.Sprintf("Invalid %s: %s. Allowed values are '%s' and '%s'.", constants.OUTBOUND_MIN_TLS, c.OutboundMinTLS, constants.TLS_VERSION_1_2, constants.TLS_VERSION_1_3))
+	}
+}
+
+// Get headers added to every request sent to CA server.
+//
+// Value is a comma-separated list of Key=Value pairs.
+func (c *RawUserConfig) getACMEExtraHeaders() (http.Header, error) {
+	headers := http.Header{}
+	if strings.TrimSpace(c.ACMEExtraHeaders) == "" {
+		return headers, nil
+	}
+	for _, entry := range splitList(c.ACMEExtraHeaders, ",") {
+		name, value, found := strings.Cut(entry, "=")
+		name = strings.TrimSpace(name)
+		if !found || name == "" || strings.ContainsAny(name, " \t:") {
+			return nil, errors.New(fmt.Sprintf("Invalid %s: %s. Expected format is Key=Value.", constants.ACME_EXTRA_HEADERS, strings.TrimSpace(entry)))
+		}
+		headers.Add(name, strings.TrimSpace(value))
+	}
+	return headers, nil
+}
+
+func (c *RawUserConfig) getPruneOption() (bool, error) {
+	option, err := strconv.ParseBool(c.Prune)
+	if err != nil {
+		return false, err
+	}
+	return option, nil
+}
+
+// Get URL of incoming webhook notified of renewal failures
+func (c *RawUserConfig) getNotifyWebhookURL() (string, error) {
+	if c.NotifyWebhookURL == "" {
+		return "", nil
+	}
+	webhookURL, err := url.Parse(c.NotifyWebhookURL)
+	if err != nil || webhookURL.Scheme == "" || webhookURL.Host == "" {
+		return "", errors.New(fmt.Sprintf("Invalid %s. An absolute URL is expected.", constants.NOTIFY_WEBHOOK_URL))
+	}
+	return c.NotifyWebhookURL, nil
+}
+
+// Get whether successes are notified as well as failures
+func (c *RawUserConfig) getNotifyAlways() (bool, error) {
+	switch strings.ToLower(c.NotifyOn) {
+	case constants.NOTIFY_ON_FAILURE:
+		return false, nil
+	case constants.NOTIFY_ON_ALWAYS:
+		return true, nil
+	default:
+		return false, errors.New(fmt.Sprintf("Invalid %s: %s. Allowed values are '%s' and '%s'.", constants.NOTIFY_ON, c.NotifyOn, constants.NOTIFY_ON_FAILURE, constants.NOTIFY_ON_ALWAYS))
+	}
+}
+
+// Get passphrase used to encrypt certificate private key.
+// Empty value means private key is not encrypted.
+func (c *RawUserConfig) getKeyPassphrase() (string, error) {
+	if c.KeyPassphrase == "" {
+		return "", nil
+	}
+	if len(c.KeyPassphrase) < constants.MIN_KEY_PASSPHRASE_LENGTH {
+		return "", errors.New(fmt.Sprintf("Invalid %s: passphrase must be at least %d characters long", constants.KEY_PASSPHRASE, constants.MIN_KEY_PASSPHRASE_LENGTH))
+	}
+	if strings.TrimSpace(c.KeyPassphrase) == "" || strings.Count(c.KeyPassphrase, c.KeyPassphrase[:1]) == len(c.KeyPassphrase) {
+		return "", errors.New(fmt.Sprintf("Invalid %s: passphrase is too weak", constants.KEY_PASSPHRASE))
+	}
+	return c.KeyPassphrase, nil
+}
+
+func (c *RawUserConfig) getRenewInterval() (time.Duration, error) {
+	interval, err := parseDuration(c.RenewInterval)
+	if err != nil {
+		return 0, errors.New(fmt.Sprintf("Invalid %s: %s", constants.RENEW_INTERVAL, err.Error()))
+	}
+	return interval, nil
+}
+
+func (c *RawUserConfig) getRenewJitter() (time.Duration, error) {
+	jitter, err := parseDuration(c.RenewJitter)
+	if err != nil {
+		return 0, errors.New(fmt.Sprintf("Invalid %s: %s", constants.RENEW_JITTER, err.Error()))
+	}
+	return jitter, nil
+}
+
+// Get address of health endpoint served in daemon mode.
+// Empty value means health endpoint is disabled.
+func (c *RawUserConfig) getHealthAddr() (string, error) {
+	if c.HealthAddr == "" {
+		return "", nil
+	}
+	if _, _, err := net.SplitHostPort(c.HealthAddr); err != nil {
+		return "", errors.New(fmt.Sprintf("Invalid %s: %s. Expected format is 'host:port' (e.g. ':8080').", constants.HEALTH_ADDR, c.HealthAddr))
+	}
+	return c.HealthAddr, nil
+}
+
+// Parse maximum time to wait for an in-flight renewal when interrupted.
+// Renewal is cancelled immediately when zero.
+func (c *RawUserConfig) getShutdownGrace() (time.Duration, error) {
+	grace, err := parseDuration(c.ShutdownGrace)
+	if err != nil {
+		return 0, errors.New(fmt.Sprintf("Invalid %s: %s", constants.SHUTDOWN_GRACE, err.Error()))
+	}
+	return grace, nil
+}
+
+// Parse renewal threshold.
+//
+// Threshold is either a duration, or a percentage of certificate
+// lifetime (e.g. `33%`). Only one of returned values is non-zero.
+func (c *RawUserConfig) getRenewBefore() (time.Duration, float64, error) {
+	if value, found := strings.CutSuffix(c.RenewBefore, "%"); found {
+		percent, err := strconv.ParseFloat(strings.TrimSpace(value), 64)
+		if err != nil || percent < 0 || percent > 100 {
+			return 0, 0, errors.New(fmt.Sprintf("Invalid %s: %s. A percentage between 0%% and 100%% is expected.", constants.RENEW_BEFORE, c.RenewBefore))
+		}
+		return 0, percent, nil
+	}
+	before, err := parseDuration(c.RenewBefore)
+	if err != nil {
+		return 0, 0, errors.New(fmt.Sprintf("Invalid %s: %s", constants.RENEW_BEFORE, err.Error()))
+	}
+	return before, 0, nil
+}
+
+func (c *RawUserConfig) getReuseKeyOption() (bool, error) {
+	option, err := strconv.ParseBool(c.ReuseKey)
+	if err != nil {
+		return false, errors.New(fmt.Sprintf("Invalid %s: %s", constants.REUSE_KEY, c.ReuseKey))
+	}
+	return option, nil
+}
+
+func (c *RawUserConfig) getPropagationTimeout() (time.Duration, error) {
+	timeout, err := parseDuration(c.PropagationTimeout)
+	if err == nil && timeout == 0 {
+		err = errors.New("duration must be positive")
+	}
+	if err != nil {
+		return 0, errors.New(fmt.Sprintf("Invalid %s: %s", constants.PROPAGATION_TIMEOUT, err.Error()))
+	}
+	return timeout, nil
+}
+
+// Get challenge timeout. Zero means lego default is used.
+func (c *RawUserConfig) getChallengeTimeout() (time.Duration, error) {
+	timeout, err := parseDuration(c.ChallengeTimeout)
+	if err != nil {
+		return 0, errors.New(fmt.Sprintf("Invalid %s: %s", constants.CHALLENGE_TIMEOUT, err.Error()))
+	}
+	return timeout, nil
+}
+
+// Get timeout of HTTP requests sent to CA server. Zero means lego default is used.
+func (c *RawUserConfig) getACMEHTTPTimeout() (time.Duration, error) {
+	timeout, err := parseDuration(c.ACMEHTTPTimeout)
+	if err != nil {
+		return 0, errors.New(fmt.Sprintf("Invalid %s: %s", constants.ACME_HTTP_TIMEOUT, err.Error()))
+	}
+	return timeout, nil
+}
+
+// Parse user configuration.
+//
+// When loadOnly is true, nothing is generated, saved or created: missing
+// account keys are reported as missing and output directory is not created.
+func (c *RawUserConfig) parse(storage *stores.Stores, loadOnly bool) (*UserConfig, error) {
+	config := &UserConfig{}
+
+	// Check JSON configuration, whose values are already merged by getEnv
+	_, err := loadConfigJSON()
+	if err != nil {
+		return config, err
+	}
+
+	// Parse store timeout, bounding all reads and writes to stores
+	storeTimeout, err := c.getStoreTimeout()
+	if err != nil {
+		return config, err
+	}
+	ctx, cancel := context.WithTimeout(context.Background(), storeTimeout)
+	defer cancel()
+
+	// Read provider credentials mounted as files, also used by named providers
+	err = c.loadCredentialFiles(ctx, storage)
+	if err != nil {
+		return config, err
+	}
+
+	// Parse certificate groups
+	groups, err := c.getCertificateGroups()
+	if err != nil {
+		return config, err
+	} else {
+		config.Certificates = groups
+		// First group is used as default certificate
+		config.Domains = groups[0].Domains
+		config.DisplayDomains = groups[0].DisplayDomains
+		config.Filename = groups[0].Filename
+	}
+
+	// Abort before any ACME call when a domain is not allowed
+	allowedDomains, err := c.getAllowedDomains()
+	if err != nil {
+		return config, err
+	} else {
+		config.AllowedDomains = allowedDomains
+	}
+	err = checkAllowedDomains(config.Certificates, config.AllowedDomains)
+	if err != nil {
+		return config, err
+	}
+
+	// Parse filename template
+	filenameTemplate, err := c.getFilenameTemplate()
+	if err != nil {
+		return config, err
+	} else {
+		config.FilenameTemplate = filenameTemplate
+	}
+
+	// Parse SANs verification option
+	skipVerifySANs, err := c.getSkipVerifySANsOption()
+	if err != nil {
+		return config, err
+	} else {
+		config.SkipVerifySANs = skipVerifySANs
+	}
+
+	// Parse concurrency
+	concurrency, err := c.getConcurrency()
+	if err != nil {
+		return config, err
+	} else {
+		config.Concurrency = concurrency
+	}
+
+	// Parse maximum number of SANs per certificate, already applied to certificate groups
+	maxSANs, err := c.getMaxSANs()
+	if err != nil {
+		return config, err
+	} else {
+		config.MaxSANs = maxSANs
+	}
+	autoChunk, err := c.getAutoChunkOption()
+	if err != nil {
+		return config, err
+	} else {
+		config.AutoChunk = autoChunk
+	}
+
+	// Parse DNS provider API concurrency
+	dnsAPIConcurrency, err := c.getDNSAPIConcurrency()
+	if err != nil {
+		return config, err
+	} else {
+		config.DNSAPIConcurrency = dnsAPIConcurrency
+	}
+
+	// Parse DNS provider API retries
+	dnsAPIMaxRetries, err := c.getDNSAPIMaxRetries()
+	if err != nil {
+		return config, err
+	} else {
+		config.DNSAPIMaxRetries = dnsAPIMaxRetries
+	}
+
+	// Parse email
+	email, err := c.getAccountEmail(ctx, storage)
+	if err != nil {
+		return config, err
+	} else {
+		config.Email = email
+	}
+
+	// Parse TOS agreement
+	tosAgreed, tosPrompt, err := c.getTOSAgreement()
+	if err != nil {
+		return config, err
+	} else {
+		config.TermsOfServiceAgreed = tosAgreed
+	}
+
+	// Parse account key (and generate it if missing)
+	var accountKey crypto.PrivateKey
+	var accountKeyInfo AccountKeyInfo
+	if loadOnly {
+		accountKey, accountKeyInfo, err = c.loadAccountKeyWithInfo(ctx, storage)
+	} else {
+		accountKey, accountKeyInfo, err = c.getAccountKeyWithInfo(ctx, storage)
+	}
+	if err != nil {
+		return config, err
+	} else {
+		config.Key = accountKey
+		config.AccountKeyInfo = accountKeyInfo
+	}
+
+	// Parse account keys of certificate groups (and generate them if missing)
+	err = c.loadGroupAccountKeys(ctx, config.Certificates, loadOnly)
+	if err != nil {
+		return config, err
+	}
+
+	// Parse account registration options
+	accountURI, skipRegistration, err := c.getRegistration()
+	if err != nil {
+		return config, err
+	} else {
+		config.AccountURI = accountURI
+		config.SkipRegistration = skipRegistration
+	}
+
+	// Parsa CA directory
+	caDir, err := c.getCADir()
+	if err != nil {
+		return config, err
+	} else {
+		config.CADirURL = caDir
+		// Plaintext CA directories are only used for local testing
+		config.Insecure = strings.HasPrefix(caDir, "http://")
+	}
+
+	// Parse CA root certificates
+	caRoots, err := c.getCARoots()
+	if err != nil {
+		return config, err
+	} else {
+		config.CARoots = caRoots
+	}
+
+	// Parse key type
+	keyType, err := c.getKeyType()
+	if err != nil {
+		return config, err
+	} else {
+		config.CADirKeyType = keyType
+	}
+
+	// Parse user agent
+	userAgent, err := c.getUserAgent()
+	if err != nil {
+		return config, err
+	} else {
+		config.UserAgent = userAgent
+	}
+
+	// Parse DNS resolvers
+	resolvers, zoneResolvers, err := c.getDNSResolvers()
+	if err != nil {
+		return config, err
+	} else {
+		config.DNSResolvers = resolvers
+		config.ZoneResolvers = zoneResolvers
+	}
+
+	// Parse DNS timeout
+	timeout, err := c.getDNSTimeout()
+	if err != nil {
+		return config, err
+	} else {
+		config.DNSTimeout = timeout
+	}
+
+	// Parse DigitalOcean API base URL
+	doAPIURL, err := c.getDigitalOceanAPIURL()
+	if err != nil {
+		return config, err
+	} else {
+		config.DigitalOceanAPIURL = doAPIURL
+	}
+
+	// Parse propagation timeout
+	propagationTimeout, err := c.getPropagationTimeout()
+	if err != nil {
+		return config, err
+	} else {
+		config.PropagationTimeout = propagationTimeout
+	}
+
+	// Parse challenge timeout
+	challengeTimeout, err := c.getChallengeTimeout()
+	if err != nil {
+		return config, err
+	} else {
+		config.ChallengeTimeout = challengeTimeout
+	}
+
+	// Parse timeout of HTTP requests sent to CA server
+	acmeHTTPTimeout, err := c.getACMEHTTPTimeout()
+	if err != nil {
+		return config, err
+	} else {
+		config.ACMEHTTPTimeout = acmeHTTPTimeout
+	}
+
+	// Parse TTL of challenge records
+	ttl, err := c.getDNSTTL()
+	if err != nil {
+		return config, err
+	} else {
+		config.DNSTTL = ttl
+	}
+
+	// Parse disableCP option
+	disableCP, disableCPDomains, err := c.getDisableCPOption()
+	if err != nil {
+		return config, err
+	} else {
+		config.DisableCP = disableCP
+		config.DisableCPDomains = disableCPDomains
 	}
-	return domains, nil
-}
 
-func (c *RawUserConfig) getAccountEmail() (string, error) {
-	if c.AccountEmail == "" {
-		return "", errors.New(fmt.Sprintf("An email must be provided through %s environment variable", constants.ACCOUNT_EMAIL))
+	// Parse DNS check mode
+	dnsCheckMode, err := c.getDNSCheckMode(config.DisableCP)
+	if err != nil {
+		return config, err
+	} else {
+		config.DNSCheckMode = dnsCheckMode
+		config.DisableCP = dnsCheckMode != constants.DNS_CHECK_MODE_STRICT
+		// Complete propagation is only checked in strict mode
+		if config.DisableCP {
+			config.DisableCPDomains = nil
+		}
 	}
-	return c.AccountEmail, nil
-}
 
-func (c *RawUserConfig) getTOSAgreement() (bool, error) {
-	tosAgreed, err := strconv.ParseBool(c.TOSAgreed)
+	// Parse CNAME following option
+	followCNAME, err := c.getFollowCNAMEOption()
 	if err != nil {
-		return false, err
+		return config, err
+	} else {
+		config.FollowCNAME = followCNAME
 	}
-	if !tosAgreed {
-		return false, errors.New(fmt.Sprintf("It is mandatory to agree to Let's Encrypt Term of Usage through %s environment variable", constants.LE_TOS_AGREED))
+
+	// Parse DNS resolver protocol
+	resolverProtocol, err := c.getResolverProtocol(config.DNSResolvers, config.ZoneResolvers, config.DNSCheckMode)
+	if err != nil {
+		return config, err
+	} else {
+		config.ResolverProtocol = resolverProtocol
 	}
-	return true, nil
-}
 
-func (c *RawUserConfig) getCADir() (string, error) {
-	// Return URL associated with environment
-	switch strings.ToUpper(c.CADir) {
-	case constants.ACME_PRODUCTION_ENV:
-		return constants.ACME_PRODUCTION_CA_DIR, nil
-	case constants.ACME_STAGING_ENV:
-		return constants.ACME_STAGING_CA_DIR, nil
-	// This CA URL is configured for a local dev instance of Boulder running in Docker in a VM.
-	case constants.ACME_TEST_ENV:
-		return constants.ACME_TEST_CA_DIR, nil
-	default:
-		if !(strings.HasPrefix(c.CADir, "http://") || strings.HasPrefix(c.CADir, "https://")) {
-			return "", errors.New(fmt.Sprintf("Invalid CA directory: %s", c.CADir))
-		}
-		return c.CADir, nil
+	// Parse output directory
+	outputDirectory, err := c.getOutputDirectory(loadOnly)
+	if err != nil {
+		return config, err
+	} else {
+		config.OutputDirectory = outputDirectory
 	}
-}
 
-func (c *RawUserConfig) getAccountKey() (crypto.PrivateKey, error) {
-	if fileExists(c.AccountKeyFile) {
-		pemKey, err := os.ReadFile(c.AccountKeyFile)
-		if err != nil {
-			return nil, err
-		}
-		keyBlock, _ := pem.Decode(pemKey)
+	// Parse output metadata option
+	outputMetadata, err := c.getOutputMetadataOption()
+	if err != nil {
+		return config, err
+	} else {
+		config.OutputMetadata = outputMetadata
+	}
 
-		switch keyBlock.Type {
-		case "RSA PRIVATE KEY":
-			return x509.ParsePKCS1PrivateKey(keyBlock.Bytes)
-		case "EC PRIVATE KEY":
-			return x509.ParseECPrivateKey(keyBlock.Bytes)
-		}
+	// Parse output issuer option
+	outputIssuer, err := c.getOutputIssuerOption()
+	if err != nil {
+		return config, err
+	} else {
+		config.OutputIssuer = outputIssuer
+	}
 
-		return nil, errors.New("unknown private key type")
+	// Parse output all chains option
+	outputAllChains, err := c.getOutputAllChainsOption()
+	if err != nil {
+		return config, err
+	} else {
+		config.OutputAllChains = outputAllChains
 	}
-	// Create a private key. New accounts need an email and private key to start.
-	privateKey, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+
+	// Parse DER output option
+	outputDER, err := c.getOutputDEROption()
 	if err != nil {
-		return nil, err
+		return config, err
+	} else {
+		config.OutputDER = outputDER
 	}
-	keyFile, err := os.Create(c.AccountKeyFile)
+
+	// Parse PKCS#7 output option
+	outputP7B, err := c.getOutputP7BOption()
 	if err != nil {
-		return nil, err
+		return config, err
+	} else {
+		config.OutputP7B = outputP7B
 	}
-	defer keyFile.Close()
 
-	pemKey := certcrypto.PEMBlock(privateKey)
-	err = pem.Encode(keyFile, pemKey)
+	// Parse option to only rewrite changed files
+	outputOnlyChanged, err := c.getOutputOnlyChangedOption()
 	if err != nil {
-		return nil, err
+		return config, err
+	} else {
+		config.OutputOnlyChanged = outputOnlyChanged
 	}
-	return privateKey, nil
-}
 
-func (c *RawUserConfig) getKeyType() (certcrypto.KeyType, error) {
-	switch c.KeyType {
-	case constants.KEY_TYPE_RSA2048:
-		return certcrypto.RSA2048, nil
-	case constants.KEY_TYPE_RSA4096:
-		return certcrypto.RSA4096, nil
-	case constants.KEY_TYPE_RSA8192:
-		return certcrypto.RSA8192, nil
-	default:
-		return certcrypto.RSA2048, errors.New(fmt.Sprintf("Invalid key type. Allowed values are '%s', '%s' and '%s'.", constants.KEY_TYPE_RSA2048, constants.KEY_TYPE_RSA4096, constants.KEY_TYPE_RSA8192))
+	// Parse output layout
+	outputLayout, err := c.getOutputLayout()
+	if err != nil {
+		return config, err
+	} else {
+		config.OutputLayout = outputLayout
 	}
-}
 
-func (c *RawUserConfig) getFilename(domains []string) (string, error) {
-	if c.Filename == "" {
-		defaultName, err := sanitizeDomain(domains[0])
-		if err != nil {
-			return "", err
-		}
-		return defaultName, nil
+	// Parse owner of written files
+	outputUID, outputGID, err := c.getOutputOwner()
+	if err != nil {
+		return config, err
+	} else {
+		config.OutputUID = outputUID
+		config.OutputGID = outputGID
 	}
-	return c.Filename, nil
-}
 
-func (c *RawUserConfig) getDNSResolvers() ([]string, error) {
-	dnsResolvers := []string{}
-	if c.DNSResolver != "" {
-		dnsResolvers = strings.Split(c.DNSResolver, ",")
+	// Parse JSON output option
+	outputJSON, err := c.getOutputJSONOption()
+	if err != nil {
+		return config, err
+	} else {
+		config.OutputJSON = outputJSON
 	}
-	return dnsResolvers, nil
-}
 
-func (c *RawUserConfig) getDNSTimeout() (time.Duration, error) {
-	timeout, err := strconv.ParseFloat(c.DNSTimeout, 32)
-	fallback := time.Duration(0)
+	// Parse lock timeout
+	lockTimeout, err := c.getLockTimeout()
 	if err != nil {
-		return fallback, err
+		return config, err
+	} else {
+		config.LockTimeout = lockTimeout
 	}
-	return time.Duration(timeout) * time.Second, nil
-}
 
-func (c *RawUserConfig) getDisableCPOption() (bool, error) {
-	option, err := strconv.ParseBool(c.DisableCP)
+	// Parse strict clock option
+	strictClock, err := c.getStrictClockOption()
 	if err != nil {
-		return false, err
+		return config, err
+	} else {
+		config.StrictClock = strictClock
 	}
-	return option, nil
-}
 
-func (c *RawUserConfig) getDNSAuthToken(storage *stores.Stores) (string, error) {
-	// Check that token is not empty
-	if c.DNSAuthToken != "" {
-		return c.DNSAuthToken, nil
+	// Parse ACME debug option
+	acmeDebug, err := c.getACMEDebugOption()
+	if err != nil {
+		return config, err
+	} else {
+		config.ACMEDebug = acmeDebug
 	}
-	// Check if token should be fetched from file
-	if c.DNSAuthTokenFile != "" {
-		filestore := storage.GetFileStore()
-		return filestore.GetToken(c.DNSAuthTokenFile)
+
+	// Parse ACME HTTP proxy
+	acmeHTTPProxy, err := c.getACMEHTTPProxy()
+	if err != nil {
+		return config, err
+	} else {
+		config.ACMEHTTPProxy = acmeHTTPProxy
 	}
-	// Check if token should be fetched from vault
-	if c.DNSAuthTokenVault != "" {
-		uri, err := c.getDNSAuthTokenVaultURI()
-		if err != nil {
-			return "", err
-		}
-		secret, err := c.getDNSAuthTokenSecretName()
-		if err != nil {
-			return "", err
-		}
-		keyvault := storage.GetKeyvaultStore()
-		return keyvault.GetToken(uri, secret)
+
+	// Parse outbound minimum TLS version
+	outboundMinTLS, err := c.getOutboundMinTLS()
+	if err != nil {
+		return config, err
+	} else {
+		config.OutboundMinTLS = outboundMinTLS
 	}
-	// Return an error
-	return "", errors.New(fmt.Sprintf("Invalid DNS auth token. Use one of '%s', '%s' or '%s' env variable", constants.DNS_AUTH_TOKEN_VAULT, constants.DNS_AUTH_TOKEN_FILE, constants.DNS_AUTH_TOKEN))
-}
 
-func (c *RawUserConfig) getDNSAuthTokenVaultURI() (string, error) {
-	if c.DNSAuthTokenVault == "" {
-		return "", errors.New(fmt.Sprintf("Invalid Keyvault URI: %s", c.DNSAuthTokenVault))
+	// Parse ACME extra headers
+	acmeExtraHeaders, err := c.getACMEExtraHeaders()
+	if err != nil {
+		return config, err
+	} else {
+		config.ACMEExtraHeaders = acmeExtraHeaders
 	}
-	if strings.HasPrefix(c.DNSAuthTokenVault, "https://") {
-		return c.DNSAuthTokenVault, nil
+
+	// Parse prune option
+	prune, err := c.getPruneOption()
+	if err != nil {
+		return config, err
 	} else {
-		return fmt.Sprintf("https://%s.vault.azure.net/", c.DNSAuthTokenVault), nil
+		config.Prune = prune
 	}
-}
 
-func (c *RawUserConfig) getDNSAuthTokenSecretName() (string, error) {
-	if c.DNSAuthTokenSecret == "" {
-		return "", errors.New(fmt.Sprintf("Invalid DNS auth token: %s", c.DNSAuthTokenSecret))
+	// Parse notification webhook
+	notifyWebhookURL, err := c.getNotifyWebhookURL()
+	if err != nil {
+		return config, err
+	} else {
+		config.NotifyWebhookURL = notifyWebhookURL
 	}
-	return c.DNSAuthTokenSecret, nil
-}
 
-func (c *RawUserConfig) getOutputDirectory() (string, error) {
-	dir, err := filepath.Abs(c.OutputDirectory)
+	// Parse notification option
+	notifyAlways, err := c.getNotifyAlways()
 	if err != nil {
-		return "", err
+		return config, err
+	} else {
+		config.NotifyAlways = notifyAlways
 	}
-	err = os.MkdirAll(dir, os.ModePerm)
+
+	// Parse private key passphrase
+	keyPassphrase, err := c.getKeyPassphrase()
 	if err != nil {
-		return "", err
+		return config, err
+	} else {
+		config.KeyPassphrase = keyPassphrase
 	}
-	return dir, nil
-}
 
-func (c *RawUserConfig) parse(storage *stores.Stores) (*UserConfig, error) {
-	config := &UserConfig{}
+	// Parse renewal interval
+	renewInterval, err := c.getRenewInterval()
+	if err != nil {
+		return config, err
+	} else {
+		config.RenewInterval = renewInterval
+	}
 
-	// Parse domains
-	domains, err := c.getDomains()
+	// Parse renewal jitter
+	renewJitter, err := c.getRenewJitter()
 	if err != nil {
 		return config, err
 	} else {
-		config.Domains = domains
+		config.RenewJitter = renewJitter
 	}
 
-	// Parse filename
-	name, err := c.getFilename(domains)
+	// Parse renewal threshold
+	renewBefore, renewBeforePercent, err := c.getRenewBefore()
 	if err != nil {
 		return config, err
 	} else {
-		config.Filename = name
+		config.RenewBefore = renewBefore
+		config.RenewBeforePercent = renewBeforePercent
 	}
 
-	// Parse email
-	email, err := c.getAccountEmail()
+	// Parse key reuse option
+	reuseKey, err := c.getReuseKeyOption()
 	if err != nil {
 		return config, err
 	} else {
-		config.Email = email
+		config.ReuseKey = reuseKey
 	}
 
-	// Parse TOS agreement
-	tosAgreed, err := c.getTOSAgreement()
+	// Parse health endpoint address
+	healthAddr, err := c.getHealthAddr()
 	if err != nil {
 		return config, err
 	} else {
-		config.TermsOfServiceAgreed = tosAgreed
+		config.HealthAddr = healthAddr
 	}
 
-	// Parse account key (and generate it if missing)
-	accountKey, err := c.getAccountKey()
+	// Parse shutdown grace period
+	shutdownGrace, err := c.getShutdownGrace()
 	if err != nil {
 		return config, err
 	} else {
-		config.Key = accountKey
+		config.ShutdownGrace = shutdownGrace
 	}
 
-	// Parsa CA directory
-	caDir, err := c.getCADir()
+	// Parse challenge type
+	challengeType, err := c.getChallengeType()
 	if err != nil {
 		return config, err
 	} else {
-		config.CADirURL = caDir
+		config.ChallengeType = challengeType
 	}
 
-	// Parse key type
-	keyType, err := c.getKeyType()
+	// Parse manual wait
+	manualWait, err := c.getManualWait()
 	if err != nil {
 		return config, err
 	} else {
-		config.CADirKeyType = keyType
+		config.ManualWait = manualWait
 	}
 
-	// Parse DNS resolvers
-	resolvers, err := c.getDNSResolvers()
+	// Parse DNS hooks
+	presentHook, cleanupHook, err := c.getDNSHooks()
 	if err != nil {
 		return config, err
 	} else {
-		config.DNSResolvers = resolvers
+		config.DNSPresentHook = presentHook
+		config.DNSCleanupHook = cleanupHook
 	}
 
-	// Parse DNS timeout
-	timeout, err := c.getDNSTimeout()
+	// Manual challenges and DNS hooks do not use any DNS provider
+	useProvider := challengeType != constants.CHALLENGE_TYPE_MANUAL && len(presentHook) == 0
+
+	// Parse DNS provider
+	provider, err := c.getDNSProvider()
 	if err != nil {
 		return config, err
 	} else {
-		config.DNSTimeout = timeout
+		config.DNSProvider = provider
 	}
 
-	// Parse disableCP option
-	disableCP, err := c.getDisableCPOption()
+	// Parse named DNS providers
+	namedProviders, err := c.getNamedProviders()
 	if err != nil {
 		return config, err
 	} else {
-		config.DisableCP = disableCP
+		config.NamedProviders = namedProviders
 	}
 
-	// Parse output directory
-	outputDirectory, err := c.getOutputDirectory()
+	// Parse DNS credentials verification option
+	verifyDNSCredentials, err := c.getVerifyDNSCredentialsOption()
 	if err != nil {
 		return config, err
 	} else {
-		config.OutputDirectory = outputDirectory
+		config.VerifyDNSCredentials = verifyDNSCredentials
 	}
 
-	// Parse dns auth token
-	token, err := c.getDNSAuthToken(storage)
+	// Parse DNS providers associated with zones
+	providerMap, err := c.getDNSProviderMap(namedProviders)
 	if err != nil {
 		return config, err
 	} else {
-		config.AuthToken = token
+		// Zones of DNS provider map take precedence over zones of providers file
+		for name, provider := range namedProviders {
+			for _, zone := range provider.Zones {
+				if _, ok := providerMap[zone]; !ok {
+					providerMap[zone] = name
+				}
+			}
+		}
+		config.DNSProviderMap = providerMap
+	}
+
+	// Credentials are only required for providers in use
+	usedProviders := []string{}
+	if useProvider {
+		usedProviders = config.UsedProviders()
+	}
+	requiresToken := false
+	config.ProviderTokens = map[string]string{}
+	for _, name := range usedProviders {
+		// Credentials of named providers are read from providers file
+		if _, ok := namedProviders[name]; ok {
+			continue
+		}
+		// Provider specific token takes precedence over DNS auth token
+		token, found, err := c.getProviderToken(ctx, storage, name)
+		if err != nil {
+			return config, err
+		}
+		if found {
+			config.ProviderTokens[name] = token
+			continue
+		}
+		requiresToken = requiresToken || dnsProviders[name].requiresToken
+	}
+
+	// Parse dns auth token when required by provider
+	if requiresToken {
+		token, err := c.getDNSAuthToken(ctx, storage)
+		if err != nil {
+			return config, err
+		} else {
+			config.AuthToken = token
+		}
+	}
+
+	// Parse credentials of providers which do not use DNS auth token
+	for _, name := range usedProviders {
+		if _, ok := namedProviders[name]; ok {
+			continue
+		}
+		parseCredentials := dnsProviders[name].parseCredentials
+		if parseCredentials == nil {
+			continue
+		}
+		err := parseCredentials(c, config)
+		if err != nil {
+			return config, err
+		}
+	}
+
+	// Ask operator once, before any client is built, when agreement is not configured.
+	// Directory is fetched last, so that it is requested with all HTTP options.
+	if tosPrompt && !loadOnly && !config.SkipRegistration && isTerminal() {
+		tosAgreed, err := askTermsOfService(config, os.Stdin, os.Stderr)
+		if err != nil {
+			return config, err
+		}
+		config.TermsOfServiceAgreed = tosAgreed
 	}
 
 	return config, nil
 }
 
+// Get timeout of keyvault requests.
+//
+// Timeout is read before user configuration is parsed,
+// because stores are needed to parse user configuration.
+func GetVaultTimeout() (time.Duration, error) {
+	value := getEnv(constants.VAULT_TIMEOUT, constants.DEFAULT_VAULT_TIMEOUT)
+	timeout, err := parseDuration(value)
+	if err == nil && timeout == 0 {
+		err = errors.New("duration must be positive")
+	}
+	if err != nil {
+		return 0, errors.New(fmt.Sprintf("Invalid %s: %s", constants.VAULT_TIMEOUT, err.Error()))
+	}
+	return timeout, nil
+}
+
+// Get path of lock file of output directory and lock timeout.
+//
+// They are read before user configuration is parsed, because lock
+// must be held before a missing account key is generated. Output
+// directory is created when missing.
+func GetLockOptions() (string, time.Duration, error) {
+	c := NewRawUserConfig()
+	dir, err := c.getOutputDirectory(false)
+	if err != nil {
+		return "", 0, &ConfigError{Err: err}
+	}
+	timeout, err := c.getLockTimeout()
+	if err != nil {
+		return "", 0, &ConfigError{Err: err}
+	}
+	return filepath.Join(dir, constants.LOCK_FILENAME), timeout, nil
+}
+
+// Rotate account key configured through environment variables.
+//
+// See rotateAccountKey for details.
+func RotateAccountKey(storage *stores.Stores, rollover func(newKey crypto.PrivateKey) error) (crypto.PrivateKey, error) {
+	c := NewRawUserConfig()
+	// New key could not be saved, so rollover must not happen
+	if c.AccountKeyPEM != "" {
+		return nil, errors.New(fmt.Sprintf("Account key found in %s environment variable cannot be rotated", constants.ACCOUNT_KEY_PEM))
+	}
+	keyType, err := c.getAccountKeyType()
+	if err != nil {
+		return nil, err
+	}
+	storeTimeout, err := c.getStoreTimeout()
+	if err != nil {
+		return nil, err
+	}
+	ctx, cancel := context.WithTimeout(context.Background(), storeTimeout)
+	defer cancel()
+	return rotateAccountKey(ctx, c.getAccountKeyProvider(storage), keyType, rollover)
+}
+
+// Load account key configured in environment, or generate it if missing,
+// and describe it
+func LoadAccountKey(storage *stores.Stores) (crypto.PrivateKey, AccountKeyInfo, error) {
+	c := NewRawUserConfig()
+	storeTimeout, err := c.getStoreTimeout()
+	if err != nil {
+		return nil, AccountKeyInfo{}, err
+	}
+	ctx, cancel := context.WithTimeout(context.Background(), storeTimeout)
+	defer cancel()
+	return c.getAccountKeyWithInfo(ctx, storage)
+}
+
 func NewRawUserConfig() *RawUserConfig {
 	return &RawUserConfig{
 		AccountEmail:       getEnv(constants.ACCOUNT_EMAIL, ""),
+		AccountEmailFile:   getEnv(constants.ACCOUNT_EMAIL_FILE, ""),
 		AccountKeyFile:     getEnv(constants.ACCOUNT_KEY_FILE, constants.DEFAULT_ACCOUNT_KEY_FILE),
-		TOSAgreed:          getEnv(constants.LE_TOS_AGREED, constants.DEFAULT_LE_TOS_AGREED),
+		AccountKeyPEM:      getEnv(constants.ACCOUNT_KEY_PEM, ""),
+		AccountKeyVault:    getEnv(constants.ACCOUNT_KEY_VAULT, ""),
+		AccountKeySecret:   getEnv(constants.ACCOUNT_KEY_SECRET, constants.DEFAULT_ACCOUNT_KEY_SECRET),
+		AccountKeyType:     getEnv(constants.ACCOUNT_KEY_TYPE, constants.DEFAULT_ACCOUNT_KEY_TYPE),
+		AccountURI:         getEnv(constants.ACCOUNT_URI, ""),
+		SkipRegistration:   getEnv(constants.SKIP_REGISTRATION, constants.DEFAULT_SKIP_REGISTRATION),
+		TOSAgreed:          getEnv(constants.LE_TOS_AGREED, ""),
+		CARootCertFile:     getEnv(constants.CA_ROOT_CERT_FILE, ""),
 		CADir:              getEnv(constants.CA_DIR, constants.DEFAULT_CA_DIR),
 		KeyType:            getEnv(constants.LE_CRT_KEY_TYPE, constants.DEFAULT_LE_CRT_KEY_TYPE),
 		Domains:            getEnv(constants.DOMAINS, ""),
+		CommonName:         getEnv(constants.COMMON_NAME, ""),
+		AllowedDomains:     getEnv(constants.ALLOWED_DOMAINS, ""),
+		Certificates:       getEnv(constants.CERTIFICATES, ""),
+		CertNames:          getEnv(constants.CERT_NAMES, ""),
+		SeparateCerts:      getEnv(constants.SEPARATE_CERTS, constants.DEFAULT_SEPARATE_CERTS),
+		MaxSANs:            getEnv(constants.MAX_SANS, constants.DEFAULT_MAX_SANS),
+		AutoChunk:          getEnv(constants.AUTO_CHUNK, constants.DEFAULT_AUTO_CHUNK),
+		Concurrency:        getEnv(constants.CONCURRENCY, constants.DEFAULT_CONCURRENCY),
 		Filename:           getEnv(constants.FILENAME, ""),
+		FilenameTemplate:   getEnv(constants.FILENAME_TEMPLATE, ""),
+		SkipVerifySANs:     getEnv(constants.SKIP_VERIFY_SANS, constants.DEFAULT_SKIP_VERIFY_SANS),
 		DisableCP:          getEnv(constants.DISABLE_CP, constants.DEFAULT_DISABLE_CP),
+		DNSCheckMode:       getEnv(constants.DNS_CHECK_MODE, ""),
+		FollowCNAME:        getEnv(constants.DNS_FOLLOW_CNAME, constants.DEFAULT_DNS_FOLLOW_CNAME),
 		DNSTimeout:         getEnv(constants.DNS_TIMEOUT, "0"),
+		DNSTTL:             getEnv(constants.DNS_TTL, constants.DEFAULT_DNS_TTL),
+		PropagationTimeout: getEnv(constants.PROPAGATION_TIMEOUT, constants.DEFAULT_PROPAGATION_TIMEOUT),
+		ChallengeTimeout:   getEnv(constants.CHALLENGE_TIMEOUT, constants.DEFAULT_CHALLENGE_TIMEOUT),
+		ACMEHTTPTimeout:    getEnv(constants.ACME_HTTP_TIMEOUT, constants.DEFAULT_ACME_HTTP_TIMEOUT),
+		DigitalOceanAPIURL: getEnv(constants.DO_API_URL, ""),
+		ResolverProtocol:   getEnv(constants.DNS_RESOLVER_PROTOCOL, constants.DEFAULT_DNS_RESOLVER_PROTOCOL),
 		DNSResolver:        getEnv(constants.DNS_RESOLVERS, ""),
+		ChallengeType:      getEnv(constants.CHALLENGE_TYPE, constants.DEFAULT_CHALLENGE_TYPE),
+		ManualWait:         getEnv(constants.MANUAL_WAIT, constants.DEFAULT_MANUAL_WAIT),
+		DNSPresentHook:     getEnv(constants.DNS_PRESENT_HOOK, ""),
+		DNSCleanupHook:     getEnv(constants.DNS_CLEANUP_HOOK, ""),
+		DNSProvider:        getEnv(constants.DNS_PROVIDER, constants.DEFAULT_DNS_PROVIDER),
+		DNSProviderMap:     getEnv(constants.DNS_PROVIDER_MAP, ""),
+		ProvidersFile:      getEnv(constants.PROVIDERS_FILE, ""),
+		VerifyDNSCreds:     getEnv(constants.VERIFY_DNS_CREDENTIALS, constants.DEFAULT_VERIFY_DNS_CREDENTIALS),
+		DNSAPIConcurrency:  getEnv(constants.DNS_API_CONCURRENCY, constants.DEFAULT_DNS_API_CONCURRENCY),
+		DNSAPIMaxRetries:   getEnv(constants.DNS_API_MAX_RETRIES, constants.DEFAULT_DNS_API_MAX_RETRIES),
 		DNSAuthToken:       getEnv(constants.DNS_AUTH_TOKEN, ""),
 		DNSAuthTokenFile:   getEnv(constants.DNS_AUTH_TOKEN_FILE, ""),
+		DNSAuthTokenDir:    getEnv(constants.DNS_AUTH_TOKEN_DIR, ""),
+		DNSAuthTokenCred:   getEnv(constants.DNS_AUTH_TOKEN_CREDENTIAL, ""),
+		DNSAuthTokenURL:    getEnv(constants.DNS_AUTH_TOKEN_URL, ""),
+		DNSAuthTokenHeader: getEnv(constants.DNS_AUTH_TOKEN_URL_HEADER, ""),
 		DNSAuthTokenVault:  getEnv(constants.DNS_AUTH_TOKEN_VAULT, ""),
 		DNSAuthTokenSecret: getEnv(constants.DNS_AUTH_TOKEN_SECRET, constants.DEFAULT_DNS_AUTH_TOKEN_SECRET),
+		DNSAuthFallback:    getEnv(constants.DNS_AUTH_TOKEN_FALLBACK, constants.DEFAULT_DNS_AUTH_TOKEN_FALLBACK),
 		OutputDirectory:    getEnv(constants.OUTPUT_DIRECTORY, "./"),
+		OutputMetadata:     getEnv(constants.OUTPUT_METADATA, constants.DEFAULT_OUTPUT_METADATA),
+		OutputIssuer:       getEnv(constants.OUTPUT_ISSUER, constants.DEFAULT_OUTPUT_ISSUER),
+		OutputAllChains:    getEnv(constants.OUTPUT_ALL_CHAINS, constants.DEFAULT_OUTPUT_ALL_CHAINS),
+		OutputDER:          getEnv(constants.OUTPUT_DER, constants.DEFAULT_OUTPUT_DER),
+		OutputP7B:          getEnv(constants.OUTPUT_P7B, constants.DEFAULT_OUTPUT_P7B),
+		OutputOnlyChanged:  getEnv(constants.OUTPUT_ONLY_CHANGED, constants.DEFAULT_OUTPUT_ONLY_CHANGED),
+		OutputLayout:       getEnv(constants.OUTPUT_LAYOUT, constants.DEFAULT_OUTPUT_LAYOUT),
+		OutputUID:          getEnv(constants.OUTPUT_UID, ""),
+		OutputGID:          getEnv(constants.OUTPUT_GID, ""),
+		OutputJSON:         getEnv(constants.OUTPUT_JSON, constants.DEFAULT_OUTPUT_JSON),
+		LockTimeout:        getEnv(constants.LOCK_TIMEOUT, constants.DEFAULT_LOCK_TIMEOUT),
+		StoreTimeout:       getEnv(constants.STORE_TIMEOUT, constants.DEFAULT_STORE_TIMEOUT),
+		StrictClock:        getEnv(constants.STRICT_CLOCK, constants.DEFAULT_STRICT_CLOCK),
+		ACMEDebug:          getEnv(constants.ACME_DEBUG, constants.DEFAULT_ACME_DEBUG),
+		ACMEHTTPProxy:      getEnv(constants.ACME_HTTP_PROXY, ""),
+		OutboundMinTLS:     getEnv(constants.OUTBOUND_MIN_TLS, constants.DEFAULT_OUTBOUND_MIN_TLS),
+		ACMEExtraHeaders:   getEnv(constants.ACME_EXTRA_HEADERS, ""),
+		Prune:              getEnv(constants.PRUNE, constants.DEFAULT_PRUNE),
+		NotifyWebhookURL:   getEnv(constants.NOTIFY_WEBHOOK_URL, ""),
+		NotifyOn:           getEnv(constants.NOTIFY_ON, constants.DEFAULT_NOTIFY_ON),
+		KeyPassphrase:      getEnv(constants.KEY_PASSPHRASE, ""),
+		UserAgent:          getEnv(constants.USER_AGENT, ""),
+		RenewInterval:      getEnv(constants.RENEW_INTERVAL, constants.DEFAULT_RENEW_INTERVAL),
+		RenewJitter:        getEnv(constants.RENEW_JITTER, constants.DEFAULT_RENEW_JITTER),
+		RenewBefore:        getEnv(constants.RENEW_BEFORE, constants.DEFAULT_RENEW_BEFORE),
+		ReuseKey:           getEnv(constants.REUSE_KEY, constants.DEFAULT_REUSE_KEY),
+		HealthAddr:         getEnv(constants.HEALTH_ADDR, ""),
+		ShutdownGrace:      getEnv(constants.SHUTDOWN_GRACE, constants.DEFAULT_SHUTDOWN_GRACE),
+		AzureSubscription:  getEnv(constants.AZURE_SUBSCRIPTION_ID, ""),
+		AzureResourceGroup: getEnv(constants.AZURE_RESOURCE_GROUP, ""),
+		AzureTenantID:      getEnv(constants.AZURE_TENANT_ID, ""),
+		AzureClientID:      getEnv(constants.AZURE_CLIENT_ID, ""),
+		AzureClientSecret:  getEnv(constants.AZURE_CLIENT_SECRET, ""),
+		OVHEndpoint:        getEnv(constants.OVH_ENDPOINT, ""),
+		OVHAppKey:          getEnv(constants.OVH_APPLICATION_KEY, ""),
+		OVHAppSecret:       getEnv(constants.OVH_APPLICATION_SECRET, ""),
+		OVHConsumerKey:     getEnv(constants.OVH_CONSUMER_KEY, ""),
+		CFValidateToken:    getEnv(constants.CF_VALIDATE_TOKEN, constants.DEFAULT_CF_VALIDATE_TOKEN),
+	}
+}
+
+// Create user configuration used by self test.
+//
+// Configuration is read from environment like NewUserConfig, but a
+// certificate is requested from local Boulder test CA for a test domain,
+// using the mock DNS server of test CA, and an account key and output
+// directory found in dir, which are discarded afterwards.
+func NewSelfTestConfig(storage *stores.Stores, dir string) (*UserConfig, error) {
+	c := NewRawUserConfig()
+	c.CADir = constants.ACME_TEST_ENV
+	c.Domains = constants.SELFTEST_DOMAIN
+	c.CommonName = ""
+	c.AllowedDomains = ""
+	c.Certificates = ""
+	c.CertNames = ""
+	c.SeparateCerts = "false"
+	c.Filename = ""
+	c.AccountEmail = constants.SELFTEST_EMAIL
+	c.AccountEmailFile = ""
+	c.AccountKeyPEM = ""
+	c.AccountKeyVault = ""
+	c.AccountKeyFile = filepath.Join(dir, "account.key")
+	c.AccountURI = ""
+	c.SkipRegistration = "false"
+	c.TOSAgreed = "true"
+	// Parsed as manual challenges so that no DNS provider credentials are required
+	c.ChallengeType = constants.CHALLENGE_TYPE_MANUAL
+	c.DNSPresentHook = ""
+	c.DNSCleanupHook = ""
+	c.DNSProviderMap = ""
+	c.ProvidersFile = ""
+	// Mock DNS server is only reachable by test CA
+	c.DNSCheckMode = constants.DNS_CHECK_MODE_NONE
+	c.OutputDirectory = dir
+	userConfig, err := c.parse(storage, false)
+	if err != nil {
+		var storeErr *StoreError
+		if !errors.As(err, &storeErr) {
+			return userConfig, &ConfigError{Err: err}
+		}
+		return userConfig, err
 	}
+	// Challenge records are created in mock DNS server of test CA
+	userConfig.ChallengeType = constants.CHALLENGE_TYPE_DNS01
+	userConfig.SelfTest = true
+	return userConfig, nil
 }
 
+// Parse user configuration from environment variables.
+//
+// Errors are returned as StoreError when a token or a key cannot be
+// read from its store, else as ConfigError.
 func NewUserConfig(storage *stores.Stores) (*UserConfig, error) {
 	config := NewRawUserConfig()
-	return config.parse(storage)
+	userConfig, err := config.parse(storage, false)
+	var storeErr *StoreError
+	if err != nil && !errors.As(err, &storeErr) {
+		return userConfig, &ConfigError{Err: err}
+	}
+	return userConfig, err
+}
+
+// Parse user configuration from environment variables without side effects.
+//
+// Unlike NewUserConfig, missing account keys are never generated nor saved,
+// and output directory is never created. Account keys which do not exist
+// yet are left nil and reported as missing in AccountKeyInfo.
+func LoadUserConfig(storage *stores.Stores) (*UserConfig, error) {
+	config := NewRawUserConfig()
+	userConfig, err := config.parse(storage, true)
+	var storeErr *StoreError
+	if err != nil && !errors.As(err, &storeErr) {
+		return userConfig, &ConfigError{Err: err}
+	}
+	return userConfig, err
 }
@@ -0,0 +1,41 @@
+package configuration
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"strings"
+
+	"github.com/charbonnierg/letsgo/constants"
+)
+
+// Load values of JSON configuration found in LETSGO_CONFIG_JSON.
+//
+// JSON object keys are the names of environment variables, as found in
+// JSON tags of RawUserConfig, and values must be strings. Unknown keys are
+// rejected. Returned map is empty when JSON configuration is not set.
+func loadConfigJSON() (map[string]string, error) {
+	blob := os.Getenv(constants.LETSGO_CONFIG_JSON)
+	values := map[string]string{}
+	if blob == "" {
+		return values, nil
+	}
+	raw := RawUserConfig{}
+	decoder := json.NewDecoder(strings.NewReader(blob))
+	decoder.DisallowUnknownFields()
+	err := decoder.Decode(&raw)
+	if err != nil {
+		return nil, errors.New(fmt.Sprintf("Invalid %s: %s", constants.LETSGO_CONFIG_JSON, err.Error()))
+	}
+	// Convert back to a map keyed by environment variable names
+	encoded, err := json.Marshal(raw)
+	if err != nil {
+		return nil, err
+	}
+	err = json.Unmarshal(encoded, &values)
+	if err != nil {
+		return nil, err
+	}
+	return values, nil
+}
@@ -0,0 +1,60 @@
+package configuration
+
+import (
+	"encoding/json"
+	"fmt"
+	"reflect"
+	"testing"
+)
+
+// Test that JSON configuration populates all fields of raw config
+func TestLoadConfigJSON(t *testing.T) {
+	want := RawUserConfig{}
+	fields := reflect.ValueOf(&want).Elem()
+	for idx := 0; idx < fields.NumField(); idx++ {
+		fields.Field(idx).SetString(fmt.Sprintf("value-%d", idx))
+	}
+	blob, err := json.Marshal(want)
+	if err != nil {
+		t.Fatalf(err.Error())
+	}
+	t.Setenv("LETSGO_CONFIG_JSON", string(blob))
+	got := NewRawUserConfig()
+	if *got != want {
+		t.Errorf("Bad raw config. Want: %+v. Got: %+v", want, *got)
+	}
+}
+
+// Test that individual environment variables take precedence over JSON configuration
+func TestLoadConfigJSONOverride(t *testing.T) {
+	t.Setenv("LETSGO_CONFIG_JSON", `{"DOMAINS": "example.com", "RENEW_INTERVAL": "24h"}`)
+	t.Setenv("DOMAINS", "example.net")
+	c := NewRawUserConfig()
+	if c.Domains != "example.net" {
+		t.Errorf("Bad domains. Want: example.net. Got: %s", c.Domains)
+	}
+	if c.RenewInterval != "24h" {
+		t.Errorf("Bad renew interval. Want: 24h. Got: %s", c.RenewInterval)
+	}
+	// Defaults still apply to keys missing from JSON configuration
+	if c.OutputJSON != "false" {
+		t.Errorf("Bad output JSON option. Want: false. Got: %s", c.OutputJSON)
+	}
+}
+
+// Test that invalid JSON configuration is rejected
+func TestLoadConfigJSONInvalid(t *testing.T) {
+	t.Setenv("LETSGO_CONFIG_JSON", `{"DOMAIN": "example.com"}`)
+	_, err := loadConfigJSON()
+	err_want := `Invalid LETSGO_CONFIG_JSON: json: unknown field "DOMAIN"`
+	if err == nil {
+		t.Fatalf("Expected error. Want: %s. Got: nil", err_want)
+	}
+	if err.Error() != err_want {
+		t.Errorf("Bad error. Want: %s. Got: %s", err_want, err.Error())
+	}
+	_, err = NewUserConfig(nil)
+	if err == nil || err.Error() != err_want {
+		t.Errorf("Bad error. Want: %s. Got: %v", err_want, err)
+	}
+}
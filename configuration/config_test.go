@@ -2,14 +2,23 @@ package configuration
 
 import (
 	"bytes"
+	"context"
+	"crypto/tls"
+	"crypto/x509"
+	"encoding/pem"
+	"errors"
 	"fmt"
+	"net/http"
+	"net/http/httptest"
 	"os"
 	"path/filepath"
+	"strings"
 	"testing"
 	"time"
 
 	"github.com/charbonnierg/letsgo/constants"
 	"github.com/charbonnierg/letsgo/stores"
+	"github.com/charbonnierg/letsgo/version"
 	"github.com/go-acme/lego/v4/certcrypto"
 	"golang.org/x/exp/slices"
 )
@@ -20,16 +29,17 @@ func TestGetAccountKey(t *testing.T) {
 	file := filepath.Join(dir, "account.key")
 	rawConfig := NewRawUserConfig()
 	rawConfig.AccountKeyFile = file
-	key, err := rawConfig.getAccountKey()
+	storage := stores.TestStores("")
+	key, err := rawConfig.getAccountKey(context.Background(), &storage)
 	if err != nil {
 		t.Errorf(err.Error())
 	}
-	secondKey, err := rawConfig.getAccountKey()
+	secondKey, err := rawConfig.getAccountKey(context.Background(), &storage)
 	if bytes.Equal(certcrypto.PEMBlock(key).Bytes, certcrypto.PEMBlock(secondKey).Bytes) != true {
 		t.Errorf("getAccountKey did not load existing key but created a new key instead")
 	}
 	os.Remove(file)
-	thirdKey, err := rawConfig.getAccountKey()
+	thirdKey, err := rawConfig.getAccountKey(context.Background(), &storage)
 	if bytes.Equal(certcrypto.PEMBlock(key).Bytes, certcrypto.PEMBlock(thirdKey).Bytes) != false {
 		t.Errorf("getAccountKey did not create a new key")
 	}
@@ -70,6 +80,14 @@ func TestGetKeyType(t *testing.T) {
 	if got != want {
 		t.Errorf(fmt.Sprintf("Bad error message. Want: %s. Got: %s", want, got))
 	}
+
+	// P-521 keys are not available in lego certcrypto package
+	c = RawUserConfig{KeyType: "EC521"}
+	_, err = c.getKeyType()
+	want = "Invalid key type. EC521 keys are not supported by lego. Allowed values are 'RSA2048', 'RSA4096' and 'RSA8192'."
+	if err == nil || err.Error() != want {
+		t.Errorf("Bad error message. Want: %s. Got: %v", want, err)
+	}
 }
 
 // Test that getCADir function behaves as expected
@@ -128,15 +146,49 @@ func TestGetCADir(t *testing.T) {
 
 }
 
+// Test that provider specific tokens are read through environment store
+func TestGetProviderToken(t *testing.T) {
+	t.Setenv("DOMAINS", "example.com,example.net")
+	t.Setenv("ACCOUNT_EMAIL", "support@example.com")
+	t.Setenv("ACCOUNT_KEY_FILE", filepath.Join(t.TempDir(), "account.key"))
+	t.Setenv("DNS_PROVIDER", "linode")
+	t.Setenv("DNS_PROVIDER_MAP", "example.net=vultr")
+	t.Setenv("LINODE_TOKEN", "LINODE")
+	t.Setenv("VULTR_API_KEY", "VULTR")
+	storage := stores.NewStores()
+	config, err := NewUserConfig(&storage)
+	if err != nil {
+		t.Fatalf(err.Error())
+	}
+	if config.TokenFor("linode") != "LINODE" || config.TokenFor("vultr") != "VULTR" || config.AuthToken != "" {
+		t.Errorf("Bad provider tokens: %v", config.ProviderTokens)
+	}
+
+	// DNS auth token is required when provider variable is not set
+	t.Setenv("VULTR_API_KEY", "")
+	_, err = NewUserConfig(&storage)
+	if err == nil || !strings.HasPrefix(err.Error(), "Invalid DNS auth token") {
+		t.Errorf("Expected DNS auth token to be required. Got: %v", err)
+	}
+	t.Setenv("DNS_AUTH_TOKEN", "XXXXX")
+	config, err = NewUserConfig(&storage)
+	if err != nil {
+		t.Fatalf(err.Error())
+	}
+	if config.TokenFor("linode") != "LINODE" || config.TokenFor("vultr") != "XXXXX" {
+		t.Errorf("Bad provider tokens: %v", config.ProviderTokens)
+	}
+}
+
 // Test that getAuthToken behaves as expected
 func TestGetAuthTokenFail(t *testing.T) {
 	c := NewRawUserConfig()
 	storage := stores.TestStores("")
-	token, err := c.getDNSAuthToken(&storage)
+	token, err := c.getDNSAuthToken(context.Background(), &storage)
 	if token != "" || err == nil {
 		t.Errorf(fmt.Sprintf("Expected empty token and error, got token: %s", token))
 	}
-	err_want := "Invalid DNS auth token. Use one of 'DNS_AUTH_TOKEN_VAULT', 'DNS_AUTH_TOKEN_FILE' or 'DNS_AUTH_TOKEN' env variable"
+	err_want := "Invalid DNS auth token. Use one of 'DNS_AUTH_TOKEN_VAULT', 'DNS_AUTH_TOKEN_URL', 'DNS_AUTH_TOKEN_CREDENTIAL', 'DNS_AUTH_TOKEN_DIR', 'DNS_AUTH_TOKEN_FILE' or 'DNS_AUTH_TOKEN' env variable"
 	err_got := err.Error()
 	if err_want != err_got {
 		t.Errorf(fmt.Sprintf("Invalid error message. Want: %s. Got %s.", err_want, err_got))
@@ -148,7 +200,7 @@ func TestGetAuthTokenFromValue(t *testing.T) {
 	t.Setenv("DNS_AUTH_TOKEN", want)
 	c := NewRawUserConfig()
 	storage := stores.TestStores("")
-	token, err := c.getDNSAuthToken(&storage)
+	token, err := c.getDNSAuthToken(context.Background(), &storage)
 	if err != nil {
 		t.Errorf(err.Error())
 	}
@@ -166,7 +218,7 @@ func TestGetAuthTokenFromFile(t *testing.T) {
 	t.Setenv("DNS_AUTH_TOKEN_FILE", tokenFile)
 	c := NewRawUserConfig()
 	storage := stores.TestStores(want)
-	token, err := c.getDNSAuthToken(&storage)
+	token, err := c.getDNSAuthToken(context.Background(), &storage)
 	if err != nil {
 		t.Errorf(err.Error())
 	}
@@ -175,12 +227,101 @@ func TestGetAuthTokenFromFile(t *testing.T) {
 	}
 }
 
+// Test that DNS auth token fetch is cancelled when store timeout expires
+func TestGetAuthTokenStoreTimeout(t *testing.T) {
+	storage := stores.NewStores(stores.WithKeyvault(&stores.KeyVaultMock{Token: "XXXXX", Delay: time.Minute}))
+	t.Setenv("DOMAINS", "example.com")
+	t.Setenv("ACCOUNT_EMAIL", "support@example.com")
+	t.Setenv("ACCOUNT_KEY_FILE", filepath.Join(t.TempDir(), "account.key"))
+	t.Setenv("DNS_AUTH_TOKEN_VAULT", "test-vault")
+	t.Setenv("STORE_TIMEOUT", "10ms")
+	_, err := NewUserConfig(&storage)
+	if !errors.Is(err, context.DeadlineExceeded) {
+		t.Errorf("Bad error. Want: %s. Got: %v", context.DeadlineExceeded, err)
+	}
+
+	t.Setenv("STORE_TIMEOUT", "0")
+	_, err = NewUserConfig(&storage)
+	err_want := "Invalid STORE_TIMEOUT: duration must be positive"
+	if err == nil || err.Error() != err_want {
+		t.Errorf("Bad error. Want: %s. Got: %v", err_want, err)
+	}
+}
+
+// Test that token is read from a secret directory with a nested symlinked file
+func TestGetAuthTokenFromDirectory(t *testing.T) {
+	dir := t.TempDir()
+	os.Mkdir(filepath.Join(dir, "..2022_11_20"), 0o700)
+	os.WriteFile(filepath.Join(dir, "..2022_11_20", "token"), []byte("XXXXX\n"), 0o600)
+	os.Symlink("..2022_11_20", filepath.Join(dir, "..data"))
+	os.Symlink(filepath.Join("..data", "token"), filepath.Join(dir, "token"))
+	t.Setenv("DNS_AUTH_TOKEN_DIR", dir)
+	c := NewRawUserConfig()
+	storage := stores.NewStores()
+	token, err := c.getDNSAuthToken(context.Background(), &storage)
+	if err != nil {
+		t.Errorf(err.Error())
+	}
+	if token != "XXXXX" {
+		t.Errorf(fmt.Sprintf("Bad token. Want: XXXXX. Got: %s", token))
+	}
+}
+
+// Test that token is read from systemd credentials, before keyvault
+func TestGetAuthTokenFromCredential(t *testing.T) {
+	dir := t.TempDir()
+	os.WriteFile(filepath.Join(dir, "dns-token"), []byte("XXXXX\n"), 0o600)
+	t.Setenv("CREDENTIALS_DIRECTORY", dir)
+	t.Setenv("DNS_AUTH_TOKEN_CREDENTIAL", "dns-token")
+	t.Setenv("DNS_AUTH_TOKEN_VAULT", "test-vault")
+	c := NewRawUserConfig()
+	storage := stores.NewStores(stores.WithKeyvault(&stores.KeyVaultMock{Token: "YYYYY"}))
+	token, err := c.getDNSAuthToken(context.Background(), &storage)
+	if err != nil {
+		t.Errorf(err.Error())
+	}
+	if token != "XXXXX" {
+		t.Errorf(fmt.Sprintf("Bad token. Want: XXXXX. Got: %s", token))
+	}
+}
+
+// Test that token fetched from HTTP endpoint takes precedence over vault
+func TestGetAuthTokenFromURL(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Header.Get("Authorization") != "Bearer secret" {
+			w.WriteHeader(http.StatusForbidden)
+			return
+		}
+		w.Write([]byte("XXXXX\n"))
+	}))
+	defer server.Close()
+	t.Setenv("DNS_AUTH_TOKEN_URL", server.URL)
+	t.Setenv("DNS_AUTH_TOKEN_URL_HEADER", "Authorization: Bearer secret")
+	t.Setenv("DNS_AUTH_TOKEN_VAULT", "test-vault")
+	c := NewRawUserConfig()
+	storage := stores.NewStores(stores.WithKeyvault(&stores.KeyVaultMock{Token: "YYYYY"}))
+	token, err := c.getDNSAuthToken(context.Background(), &storage)
+	if err != nil {
+		t.Errorf(err.Error())
+	}
+	if token != "XXXXX" {
+		t.Errorf(fmt.Sprintf("Bad token. Want: XXXXX. Got: %s", token))
+	}
+	// Endpoint errors are store errors
+	c.DNSAuthTokenHeader = ""
+	_, err = c.getDNSAuthToken(context.Background(), &storage)
+	var storeErr *StoreError
+	if !errors.As(err, &storeErr) {
+		t.Errorf("Expected a store error. Got: %v", err)
+	}
+}
+
 func TestGetAuthTokenFromKeyVault(t *testing.T) {
 	want := "XXXXX"
 	t.Setenv("DNS_AUTH_TOKEN_VAULT", "test-vault")
 	c := NewRawUserConfig()
 	storage := stores.TestStores(want)
-	token, err := c.getDNSAuthToken(&storage)
+	token, err := c.getDNSAuthToken(context.Background(), &storage)
 	if err != nil {
 		t.Errorf(err.Error())
 	}
@@ -189,7 +330,47 @@ func TestGetAuthTokenFromKeyVault(t *testing.T) {
 	}
 }
 
+// Test that a failing token source is skipped only when fallback is enabled
+func TestGetAuthTokenFallback(t *testing.T) {
+	t.Setenv("DNS_AUTH_TOKEN_FILE", filepath.Join(t.TempDir(), "missing"))
+	t.Setenv("DNS_AUTH_TOKEN_VAULT", "test-vault")
+	storage := stores.NewStores(stores.WithKeyvault(&stores.KeyVaultSecretsMock{Secrets: map[string]string{"do-auth-token": "XXXXX"}}))
+
+	c := NewRawUserConfig()
+	_, err := c.getDNSAuthToken(context.Background(), &storage)
+	if err == nil {
+		t.Errorf("Expected error when token file is missing and fallback is disabled")
+	}
+
+	t.Setenv("DNS_AUTH_TOKEN_FALLBACK", "true")
+	c = NewRawUserConfig()
+	token, err := c.getDNSAuthToken(context.Background(), &storage)
+	if err != nil {
+		t.Fatalf(err.Error())
+	}
+	if token != "XXXXX" {
+		t.Errorf("Bad token. Want: XXXXX. Got: %s", token)
+	}
+
+	// All sources fail
+	storage = stores.NewStores(stores.WithKeyvault(&stores.KeyVaultSecretsMock{}))
+	_, err = c.getDNSAuthToken(context.Background(), &storage)
+	if err == nil || !strings.HasPrefix(err.Error(), "Could not read DNS auth token from any source: DNS_AUTH_TOKEN_FILE: ") || !strings.Contains(err.Error(), "; DNS_AUTH_TOKEN_VAULT: ") {
+		t.Errorf("Bad error. Got: %v", err)
+	}
+
+	t.Setenv("DNS_AUTH_TOKEN_FALLBACK", "maybe")
+	c = NewRawUserConfig()
+	_, err = c.getDNSAuthToken(context.Background(), &storage)
+	err_want := "Invalid DNS_AUTH_TOKEN_FALLBACK: maybe"
+	if err == nil || err.Error() != err_want {
+		t.Errorf("Bad error. Want: %s. Got: %v", err_want, err)
+	}
+}
+
 func TestNewUserConfigFromEnv(t *testing.T) {
+	// Account key is generated, keep it out of the source tree
+	t.Setenv("ACCOUNT_KEY_FILE", filepath.Join(t.TempDir(), "account.key"))
 	stores := stores.TestStores("")
 	_, err := NewUserConfig(&stores)
 	err_want := "A comma-separated list of domain names must be provided through DOMAINS environment variable"
@@ -203,7 +384,7 @@ func TestNewUserConfigFromEnv(t *testing.T) {
 
 	t.Setenv("DOMAINS", "example.com")
 	_, err = NewUserConfig(&stores)
-	err_want = "An email must be provided through ACCOUNT_EMAIL environment variable"
+	err_want = "An email must be provided through ACCOUNT_EMAIL or ACCOUNT_EMAIL_FILE environment variable"
 	if err == nil {
 		t.Fatalf("Expected error. Want: %s. Got: nil", err_want)
 	}
@@ -214,7 +395,7 @@ func TestNewUserConfigFromEnv(t *testing.T) {
 
 	t.Setenv("ACCOUNT_EMAIL", "support@example.com")
 	_, err = NewUserConfig(&stores)
-	err_want = "Invalid DNS auth token. Use one of 'DNS_AUTH_TOKEN_VAULT', 'DNS_AUTH_TOKEN_FILE' or 'DNS_AUTH_TOKEN' env variable"
+	err_want = "Invalid DNS auth token. Use one of 'DNS_AUTH_TOKEN_VAULT', 'DNS_AUTH_TOKEN_URL', 'DNS_AUTH_TOKEN_CREDENTIAL', 'DNS_AUTH_TOKEN_DIR', 'DNS_AUTH_TOKEN_FILE' or 'DNS_AUTH_TOKEN' env variable"
 	if err == nil {
 		t.Fatalf("Expected error. Want: %s. Got: nil", err_want)
 	}
@@ -262,14 +443,1565 @@ func TestNewUserConfigFromEnv(t *testing.T) {
 		t.Fatalf("Bad DisableCP option. Want: true. Got: false")
 	}
 
+	// Default agreement is used when not running interactively
+	if !config.TermsOfServiceAgreed {
+		t.Fatalf("Bad Terms of Service agreement. Want: true. Got: false")
+	}
+
+	// Agreement is checked when registering against Terms of Service URL of CA server
 	t.Setenv(constants.LE_TOS_AGREED, "false")
-	err_want = "It is mandatory to agree to Let's Encrypt Term of Usage through LE_TOS_AGREED environment variable"
+	config, err = NewUserConfig(&stores)
+	if err != nil {
+		t.Fatalf(err.Error())
+	}
+	if config.TermsOfServiceAgreed {
+		t.Fatalf("Bad Terms of Service agreement. Want: false. Got: true")
+	}
+}
+
+// Test that getAccountEmail validates email format
+func TestGetAccountEmail(t *testing.T) {
+	storage := stores.TestStores("")
+	c := &RawUserConfig{AccountEmail: "support@example.com"}
+	got, err := c.getAccountEmail(context.Background(), &storage)
+	if err != nil {
+		t.Errorf(err.Error())
+	}
+	if got != "support@example.com" {
+		t.Errorf("Bad email. Want: support@example.com. Got: %s", got)
+	}
+
+	c = &RawUserConfig{AccountEmail: "support"}
+	_, err = c.getAccountEmail(context.Background(), &storage)
+	if err == nil {
+		t.Fatalf("Expected error for email without @")
+	}
+	err_want := "invalid ACCOUNT_EMAIL: mail: missing '@' or angle-addr"
+	if err.Error() != err_want {
+		t.Errorf("Bad error. Want: %s. Got: %s", err_want, err.Error())
+	}
+
+	c = &RawUserConfig{AccountEmail: "support@"}
+	_, err = c.getAccountEmail(context.Background(), &storage)
+	if err == nil {
+		t.Errorf("Expected error for email without domain")
+	}
+
+	c = &RawUserConfig{AccountEmail: ""}
+	_, err = c.getAccountEmail(context.Background(), &storage)
+	err_want = "An email must be provided through ACCOUNT_EMAIL or ACCOUNT_EMAIL_FILE environment variable"
+	if err == nil {
+		t.Fatalf("Expected error. Want: %s. Got: nil", err_want)
+	}
+	if err.Error() != err_want {
+		t.Errorf("Bad error. Want: %s. Got: %s", err_want, err.Error())
+	}
+}
+
+// Test that email is read from file, unless ACCOUNT_EMAIL is set
+func TestGetAccountEmailFromFile(t *testing.T) {
+	storage := stores.NewStores(stores.WithFileStore(&stores.FileStore{}))
+	path := filepath.Join(t.TempDir(), "email")
+	err := os.WriteFile(path, []byte("file@example.com\n"), 0o600)
+	if err != nil {
+		t.Fatalf(err.Error())
+	}
+	c := &RawUserConfig{AccountEmailFile: path}
+	got, err := c.getAccountEmail(context.Background(), &storage)
+	if err != nil {
+		t.Errorf(err.Error())
+	}
+	if got != "file@example.com" {
+		t.Errorf("Bad email. Want: file@example.com. Got: %s", got)
+	}
+
+	// ACCOUNT_EMAIL takes precedence
+	c = &RawUserConfig{AccountEmail: "env@example.com", AccountEmailFile: path}
+	got, err = c.getAccountEmail(context.Background(), &storage)
+	if err != nil {
+		t.Errorf(err.Error())
+	}
+	if got != "env@example.com" {
+		t.Errorf("Bad email. Want: env@example.com. Got: %s", got)
+	}
+
+	// Email read from file is validated
+	err = os.WriteFile(path, []byte("support"), 0o600)
+	if err != nil {
+		t.Fatalf(err.Error())
+	}
+	c = &RawUserConfig{AccountEmailFile: path}
+	_, err = c.getAccountEmail(context.Background(), &storage)
+	err_want := "invalid ACCOUNT_EMAIL_FILE: mail: missing '@' or angle-addr"
+	if err == nil || err.Error() != err_want {
+		t.Errorf("Bad error. Want: %s. Got: %v", err_want, err)
+	}
+
+	c = &RawUserConfig{AccountEmailFile: filepath.Join(t.TempDir(), "missing")}
+	_, err = c.getAccountEmail(context.Background(), &storage)
+	var storeErr *StoreError
+	if !errors.As(err, &storeErr) {
+		t.Errorf("Expected a store error. Got: %v", err)
+	}
+}
+
+// Test that getUserAgent falls back to default user agent
+func TestGetUserAgent(t *testing.T) {
+	c := NewRawUserConfig()
+	got, err := c.getUserAgent()
+	if err != nil {
+		t.Errorf(err.Error())
+	}
+	if got != version.UserAgent() {
+		t.Errorf("Bad user agent. Want: %s. Got: %s", version.UserAgent(), got)
+	}
+
+	want := "custom-agent/1.0"
+	t.Setenv("USER_AGENT", want)
+	c = NewRawUserConfig()
+	got, err = c.getUserAgent()
+	if err != nil {
+		t.Errorf(err.Error())
+	}
+	if got != want {
+		t.Errorf("Bad user agent. Want: %s. Got: %s", want, got)
+	}
+}
+
+// Test that getDNSProvider validates provider name
+func TestGetDNSProvider(t *testing.T) {
+	c := NewRawUserConfig()
+	got, err := c.getDNSProvider()
+	if err != nil {
+		t.Errorf(err.Error())
+	}
+	if got != constants.DNS_PROVIDER_DIGITALOCEAN {
+		t.Errorf("Bad DNS provider. Want: %s. Got: %s", constants.DNS_PROVIDER_DIGITALOCEAN, got)
+	}
+
+	c = &RawUserConfig{DNSProvider: "AzureDNS"}
+	got, err = c.getDNSProvider()
+	if err != nil {
+		t.Errorf(err.Error())
+	}
+	if got != constants.DNS_PROVIDER_AZURE {
+		t.Errorf("Bad DNS provider. Want: %s. Got: %s", constants.DNS_PROVIDER_AZURE, got)
+	}
+
+	c = &RawUserConfig{DNSProvider: "unknown"}
+	_, err = c.getDNSProvider()
+	err_want := "Invalid DNS provider: unknown. Allowed values are 'azuredns', 'cloudflare', 'digitalocean', 'linode', 'ovh' and 'vultr'."
+	if err == nil {
+		t.Fatalf("Expected error. Want: %s. Got: nil", err_want)
+	}
+	if err.Error() != err_want {
+		t.Errorf("Bad error. Want: %s. Got: %s", err_want, err.Error())
+	}
+}
+
+// Test that Azure DNS provider does not require a DNS auth token
+func TestNewUserConfigWithAzureDNS(t *testing.T) {
+	stores := stores.TestStores("")
+	t.Setenv("DOMAINS", "example.com")
+	t.Setenv("ACCOUNT_EMAIL", "support@example.com")
+	t.Setenv("ACCOUNT_KEY_FILE", filepath.Join(t.TempDir(), "account.key"))
+	t.Setenv("DNS_PROVIDER", "azuredns")
+	_, err := NewUserConfig(&stores)
+	err_want := "An Azure subscription ID must be provided through AZURE_SUBSCRIPTION_ID environment variable"
+	if err == nil {
+		t.Fatalf("Expected error. Want: %s. Got: nil", err_want)
+	}
+	if err.Error() != err_want {
+		t.Fatalf("Bad error. Want: %s. Got: %s", err_want, err.Error())
+	}
+
+	t.Setenv("AZURE_SUBSCRIPTION_ID", "subscription")
 	_, err = NewUserConfig(&stores)
+	err_want = "An Azure resource group must be provided through AZURE_RESOURCE_GROUP environment variable"
 	if err == nil {
 		t.Fatalf("Expected error. Want: %s. Got: nil", err_want)
 	}
-	err_got = err.Error()
-	if err_got != err_want {
-		t.Fatalf("Bad error. Want: %s. Got: %s", err_want, err_got)
+	if err.Error() != err_want {
+		t.Fatalf("Bad error. Want: %s. Got: %s", err_want, err.Error())
+	}
+
+	t.Setenv("AZURE_RESOURCE_GROUP", "group")
+	config, err := NewUserConfig(&stores)
+	if err != nil {
+		t.Fatalf(err.Error())
+	}
+	if config.AuthToken != "" {
+		t.Errorf("Expected empty auth token but got: %s", config.AuthToken)
+	}
+	if config.Azure.SubscriptionID != "subscription" || config.Azure.ResourceGroup != "group" {
+		t.Errorf("Bad Azure config: %+v", config.Azure)
+	}
+}
+
+// Test that DNS provider map is parsed and validated
+func TestGetDNSProviderMap(t *testing.T) {
+	c := &RawUserConfig{DNSProviderMap: "A.com.=DigitalOcean;b.net=azuredns"}
+	got, err := c.getDNSProviderMap(nil)
+	if err != nil {
+		t.Fatalf(err.Error())
+	}
+	if len(got) != 2 || got["a.com"] != "digitalocean" || got["b.net"] != "azuredns" {
+		t.Errorf("Bad DNS provider map: %v", got)
+	}
+	for _, value := range []string{"a.com", "=digitalocean", "b.net=route53"} {
+		c = &RawUserConfig{DNSProviderMap: value}
+		_, err = c.getDNSProviderMap(nil)
+		if err == nil {
+			t.Errorf("Expected error for DNS provider map %s", value)
+		}
+	}
+}
+
+// Test that credentials are only required for DNS providers used by domains
+func TestNewUserConfigWithDNSProviderMap(t *testing.T) {
+	stores := stores.TestStores("")
+	t.Setenv("DOMAINS", "a.com,b.net")
+	t.Setenv("ACCOUNT_EMAIL", "support@example.com")
+	t.Setenv("ACCOUNT_KEY_FILE", filepath.Join(t.TempDir(), "account.key"))
+	t.Setenv("DNS_PROVIDER_MAP", "b.net=azuredns")
+	t.Setenv("AZURE_SUBSCRIPTION_ID", "subscription")
+	t.Setenv("AZURE_RESOURCE_GROUP", "group")
+	_, err := NewUserConfig(&stores)
+	if err == nil {
+		t.Fatalf("Expected error for missing DigitalOcean auth token")
+	}
+	t.Setenv("DNS_PROVIDER_MAP", "a.com=azuredns;b.net=azuredns")
+	config, err := NewUserConfig(&stores)
+	if err != nil {
+		t.Fatalf(err.Error())
+	}
+	if !slices.Equal(config.UsedProviders(), []string{"azuredns"}) {
+		t.Errorf("Bad used providers. Want: [azuredns]. Got: %s", config.UsedProviders())
+	}
+	if config.ProviderFor("*.www.a.com") != "azuredns" || config.ProviderFor("c.org") != "digitalocean" {
+		t.Errorf("Bad provider routing: %v", config.DNSProviderMap)
+	}
+}
+
+// Test that OVH credentials are required when OVH provider is used
+func TestNewUserConfigWithOVH(t *testing.T) {
+	stores := stores.TestStores("")
+	t.Setenv("DOMAINS", "example.com")
+	t.Setenv("ACCOUNT_EMAIL", "support@example.com")
+	t.Setenv("ACCOUNT_KEY_FILE", filepath.Join(t.TempDir(), "account.key"))
+	t.Setenv("DNS_PROVIDER", "ovh")
+	t.Setenv("OVH_ENDPOINT", "ovh-eu")
+	t.Setenv("OVH_APPLICATION_KEY", "key")
+	t.Setenv("OVH_APPLICATION_SECRET", "secret")
+	_, err := NewUserConfig(&stores)
+	err_want := "An OVH credential must be provided through OVH_CONSUMER_KEY environment variable"
+	if err == nil {
+		t.Fatalf("Expected error. Want: %s. Got: nil", err_want)
+	}
+	if err.Error() != err_want {
+		t.Fatalf("Bad error. Want: %s. Got: %s", err_want, err.Error())
+	}
+
+	t.Setenv("OVH_CONSUMER_KEY", "consumer")
+	config, err := NewUserConfig(&stores)
+	if err != nil {
+		t.Fatalf(err.Error())
+	}
+	if config.AuthToken != "" {
+		t.Errorf("Expected empty auth token but got: %s", config.AuthToken)
+	}
+	want := OVHConfig{Endpoint: "ovh-eu", ApplicationKey: "key", ApplicationSecret: "secret", ConsumerKey: "consumer"}
+	if config.OVH != want {
+		t.Errorf("Bad OVH config. Want: %+v. Got: %+v", want, config.OVH)
+	}
+}
+
+// Test that OVH secrets are read from files configured with <NAME>_FILE environment variables
+func TestNewUserConfigWithOVHCredentialFiles(t *testing.T) {
+	dir := t.TempDir()
+	secretFile := filepath.Join(dir, "application-secret")
+	consumerFile := filepath.Join(dir, "consumer-key")
+	os.WriteFile(secretFile, []byte("secret\n"), 0o600)
+	os.WriteFile(consumerFile, []byte("consumer\n"), 0o600)
+	storage := stores.NewStores(stores.WithFileStore(&stores.FileStore{}))
+	t.Setenv("DOMAINS", "example.com")
+	t.Setenv("ACCOUNT_EMAIL", "support@example.com")
+	t.Setenv("ACCOUNT_KEY_FILE", filepath.Join(dir, "account.key"))
+	t.Setenv("DNS_PROVIDER", "ovh")
+	t.Setenv("OVH_ENDPOINT", "ovh-eu")
+	t.Setenv("OVH_APPLICATION_KEY", "key")
+	t.Setenv("OVH_APPLICATION_SECRET_FILE", secretFile)
+	t.Setenv("OVH_CONSUMER_KEY_FILE", consumerFile)
+	config, err := NewUserConfig(&storage)
+	if err != nil {
+		t.Fatalf(err.Error())
+	}
+	want := OVHConfig{Endpoint: "ovh-eu", ApplicationKey: "key", ApplicationSecret: "secret", ConsumerKey: "consumer"}
+	if config.OVH != want {
+		t.Errorf("Bad OVH config. Want: %+v. Got: %+v", want, config.OVH)
+	}
+	// Value of environment variable takes precedence over file
+	t.Setenv("OVH_CONSUMER_KEY", "other")
+	config, err = NewUserConfig(&storage)
+	if err != nil {
+		t.Fatalf(err.Error())
+	}
+	if config.OVH.ConsumerKey != "other" {
+		t.Errorf("Bad OVH consumer key. Want: other. Got: %s", config.OVH.ConsumerKey)
+	}
+	// Missing file is reported
+	t.Setenv("OVH_CONSUMER_KEY", "")
+	t.Setenv("OVH_CONSUMER_KEY_FILE", filepath.Join(dir, "missing"))
+	_, err = NewUserConfig(&storage)
+	var storeErr *StoreError
+	if !errors.As(err, &storeErr) {
+		t.Errorf("Expected a StoreError. Got: %v", err)
+	}
+}
+
+// Test that output metadata option is disabled by default
+func TestGetOutputMetadataOption(t *testing.T) {
+	c := NewRawUserConfig()
+	got, err := c.getOutputMetadataOption()
+	if err != nil {
+		t.Errorf(err.Error())
+	}
+	if got {
+		t.Errorf("Bad OutputMetadata option. Want: false. Got: true")
+	}
+
+	t.Setenv("OUTPUT_METADATA", "true")
+	c = NewRawUserConfig()
+	got, err = c.getOutputMetadataOption()
+	if err != nil {
+		t.Errorf(err.Error())
+	}
+	if !got {
+		t.Errorf("Bad OutputMetadata option. Want: true. Got: false")
+	}
+}
+
+// Test that issuer output option is enabled by default
+func TestGetOutputIssuerOption(t *testing.T) {
+	c := NewRawUserConfig()
+	got, err := c.getOutputIssuerOption()
+	if err != nil {
+		t.Errorf(err.Error())
+	}
+	if !got {
+		t.Errorf("Bad OutputIssuer option. Want: true. Got: false")
+	}
+
+	t.Setenv("OUTPUT_ISSUER", "false")
+	c = NewRawUserConfig()
+	got, err = c.getOutputIssuerOption()
+	if err != nil {
+		t.Errorf(err.Error())
+	}
+	if got {
+		t.Errorf("Bad OutputIssuer option. Want: false. Got: true")
+	}
+}
+
+// Test that JSON output option is disabled by default
+func TestGetOutputJSONOption(t *testing.T) {
+	c := NewRawUserConfig()
+	got, err := c.getOutputJSONOption()
+	if err != nil {
+		t.Errorf(err.Error())
+	}
+	if got {
+		t.Errorf("Bad OutputJSON option. Want: false. Got: true")
+	}
+
+	t.Setenv("OUTPUT_JSON", "true")
+	c = NewRawUserConfig()
+	got, err = c.getOutputJSONOption()
+	if err != nil {
+		t.Errorf(err.Error())
+	}
+	if !got {
+		t.Errorf("Bad OutputJSON option. Want: true. Got: false")
+	}
+}
+
+// Test that CNAME records are followed by default
+func TestGetFollowCNAMEOption(t *testing.T) {
+	c := NewRawUserConfig()
+	got, err := c.getFollowCNAMEOption()
+	if err != nil {
+		t.Errorf(err.Error())
+	}
+	if !got {
+		t.Errorf("Bad FollowCNAME option. Want: true. Got: false")
+	}
+
+	t.Setenv("DNS_FOLLOW_CNAME", "false")
+	c = NewRawUserConfig()
+	got, err = c.getFollowCNAMEOption()
+	if err != nil {
+		t.Errorf(err.Error())
+	}
+	if got {
+		t.Errorf("Bad FollowCNAME option. Want: false. Got: true")
+	}
+}
+
+// Test that DNS check mode defaults according to DISABLE_CP and can be overridden
+func TestGetDNSCheckMode(t *testing.T) {
+	c := &RawUserConfig{}
+	if mode, _ := c.getDNSCheckMode(true); mode != "propagation" {
+		t.Errorf("Bad DNS check mode. Want: propagation. Got: %s", mode)
+	}
+	if mode, _ := c.getDNSCheckMode(false); mode != "strict" {
+		t.Errorf("Bad DNS check mode. Want: strict. Got: %s", mode)
+	}
+	c = &RawUserConfig{DNSCheckMode: "NONE"}
+	if mode, _ := c.getDNSCheckMode(false); mode != "none" {
+		t.Errorf("Bad DNS check mode. Want: none. Got: %s", mode)
+	}
+	c = &RawUserConfig{DNSCheckMode: "never"}
+	_, err := c.getDNSCheckMode(true)
+	err_want := "Invalid DNS_CHECK_MODE: never. Allowed values are 'strict', 'propagation' and 'none'."
+	if err == nil {
+		t.Fatalf("Expected error. Want: %s. Got: nil", err_want)
+	}
+	if err.Error() != err_want {
+		t.Errorf("Bad error. Want: %s. Got: %s", err_want, err.Error())
+	}
+}
+
+// Test that notification options are parsed
+func TestGetNotifyOptions(t *testing.T) {
+	c := NewRawUserConfig()
+	webhookURL, err := c.getNotifyWebhookURL()
+	if err != nil || webhookURL != "" {
+		t.Errorf("Expected no notification webhook by default")
+	}
+	always, err := c.getNotifyAlways()
+	if err != nil || always {
+		t.Errorf("Expected only failures to be notified by default")
+	}
+
+	c = &RawUserConfig{NotifyWebhookURL: "https://hooks.slack.com/services/XXX", NotifyOn: "always"}
+	webhookURL, err = c.getNotifyWebhookURL()
+	if err != nil || webhookURL != "https://hooks.slack.com/services/XXX" {
+		t.Errorf("Bad notification webhook. Want: https://hooks.slack.com/services/XXX. Got: %s", webhookURL)
+	}
+	always, err = c.getNotifyAlways()
+	if err != nil || !always {
+		t.Errorf("Expected all outcomes to be notified")
+	}
+
+	c = &RawUserConfig{NotifyWebhookURL: "hooks.slack.com", NotifyOn: "sometimes"}
+	if _, err = c.getNotifyWebhookURL(); err == nil {
+		t.Errorf("Expected error for relative webhook URL")
+	}
+	_, err = c.getNotifyAlways()
+	err_want := "Invalid NOTIFY_ON: sometimes. Allowed values are 'failure' and 'always'."
+	if err == nil {
+		t.Fatalf("Expected error. Want: %s. Got: nil", err_want)
+	}
+	if err.Error() != err_want {
+		t.Errorf("Bad error. Want: %s. Got: %s", err_want, err.Error())
+	}
+}
+
+// Test that CA root certificates are loaded from PEM bundle
+func TestGetCARoots(t *testing.T) {
+	c := NewRawUserConfig()
+	pool, err := c.getCARoots()
+	if err != nil || pool != nil {
+		t.Errorf("Expected system roots to be used by default")
+	}
+
+	server := httptest.NewTLSServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {}))
+	defer server.Close()
+	path := filepath.Join(t.TempDir(), "root.pem")
+	os.WriteFile(path, pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: server.Certificate().Raw}), 0o600)
+	c = &RawUserConfig{CARootCertFile: path}
+	pool, err = c.getCARoots()
+	if err != nil {
+		t.Fatalf(err.Error())
+	}
+	want := x509.NewCertPool()
+	want.AddCert(server.Certificate())
+	if !pool.Equal(want) {
+		t.Errorf("Expected root certificate to be loaded")
+	}
+
+	os.WriteFile(path, []byte("not a certificate"), 0o600)
+	_, err = c.getCARoots()
+	err_want := "Invalid CA_ROOT_CERT_FILE: no PEM-encoded certificate found in " + path
+	if err == nil {
+		t.Fatalf("Expected error. Want: %s. Got: nil", err_want)
+	}
+	if err.Error() != err_want {
+		t.Errorf("Bad error. Want: %s. Got: %s", err_want, err.Error())
+	}
+}
+
+// Test that DNS resolver protocol is validated
+func TestGetResolverProtocol(t *testing.T) {
+	resolvers := []string{"1.1.1.1"}
+	c := NewRawUserConfig()
+	protocol, err := c.getResolverProtocol(nil, nil, "strict")
+	if err != nil || protocol != "udp" {
+		t.Errorf("Bad protocol. Want: udp. Got: %s (%v)", protocol, err)
+	}
+	c = &RawUserConfig{ResolverProtocol: "TCP-TLS"}
+	protocol, err = c.getResolverProtocol(resolvers, nil, "propagation")
+	if err != nil || protocol != "tcp-tls" {
+		t.Errorf("Bad protocol. Want: tcp-tls. Got: %s (%v)", protocol, err)
+	}
+	for _, test := range []struct {
+		protocol  string
+		resolvers []string
+		mode      string
+		err_want  string
+	}{
+		{"https", resolvers, "propagation", "Invalid DNS_RESOLVER_PROTOCOL: https. Allowed values are 'udp', 'tcp' and 'tcp-tls'."},
+		{"tcp", nil, "propagation", "Invalid DNS_RESOLVER_PROTOCOL: tcp requires DNS_RESOLVERS to be set"},
+		{"tcp-tls", resolvers, "strict", "Invalid DNS_RESOLVER_PROTOCOL: tcp-tls is not supported with DNS_CHECK_MODE 'strict', authoritative name servers are queried over UDP"},
+	} {
+		c = &RawUserConfig{ResolverProtocol: test.protocol}
+		_, err = c.getResolverProtocol(test.resolvers, nil, test.mode)
+		if err == nil || err.Error() != test.err_want {
+			t.Errorf("Bad error. Want: %s. Got: %v", test.err_want, err)
+		}
+	}
+}
+
+// Test that strict clock option is disabled by default
+func TestGetStrictClockOption(t *testing.T) {
+	c := NewRawUserConfig()
+	got, err := c.getStrictClockOption()
+	if err != nil {
+		t.Errorf(err.Error())
+	}
+	if got {
+		t.Errorf("Bad StrictClock option. Want: false. Got: true")
+	}
+
+	t.Setenv("STRICT_CLOCK", "true")
+	c = NewRawUserConfig()
+	got, err = c.getStrictClockOption()
+	if err != nil {
+		t.Errorf(err.Error())
+	}
+	if !got {
+		t.Errorf("Bad StrictClock option. Want: true. Got: false")
+	}
+}
+
+// Test that ACME debug option is disabled by default
+func TestGetACMEDebugOption(t *testing.T) {
+	c := NewRawUserConfig()
+	got, err := c.getACMEDebugOption()
+	if err != nil {
+		t.Errorf(err.Error())
+	}
+	if got {
+		t.Errorf("Bad ACMEDebug option. Want: false. Got: true")
+	}
+
+	t.Setenv("ACME_DEBUG", "true")
+	c = NewRawUserConfig()
+	got, err = c.getACMEDebugOption()
+	if err != nil {
+		t.Errorf(err.Error())
+	}
+	if !got {
+		t.Errorf("Bad ACMEDebug option. Want: true. Got: false")
+	}
+}
+
+// Test that ACME HTTP proxy must be an absolute URL
+// Test that ACME extra headers are parsed from Key=Value pairs
+func TestGetACMEExtraHeaders(t *testing.T) {
+	c := NewRawUserConfig()
+	headers, err := c.getACMEExtraHeaders()
+	if err != nil {
+		t.Fatalf(err.Error())
+	}
+	if len(headers) != 0 {
+		t.Errorf("Bad headers. Want: none. Got: %v", headers)
+	}
+
+	t.Setenv("ACME_EXTRA_HEADERS", "x-relay-token=abc=def, X-Team = pki")
+	c = NewRawUserConfig()
+	headers, err = c.getACMEExtraHeaders()
+	if err != nil {
+		t.Fatalf(err.Error())
+	}
+	if headers.Get("X-Relay-Token") != "abc=def" || headers.Get("X-Team") != "pki" || len(headers) != 2 {
+		t.Errorf("Bad headers: %v", headers)
+	}
+
+	c = &RawUserConfig{ACMEExtraHeaders: "X-Relay-Token"}
+	_, err = c.getACMEExtraHeaders()
+	err_want := "Invalid ACME_EXTRA_HEADERS: X-Relay-Token. Expected format is Key=Value."
+	if err == nil || err.Error() != err_want {
+		t.Errorf("Bad error. Want: %s. Got: %v", err_want, err)
+	}
+}
+
+func TestGetACMEHTTPProxy(t *testing.T) {
+	c := NewRawUserConfig()
+	got, err := c.getACMEHTTPProxy()
+	if err != nil {
+		t.Errorf(err.Error())
+	}
+	if got != nil {
+		t.Errorf("Bad ACME HTTP proxy. Want: nil. Got: %s", got)
+	}
+
+	t.Setenv("ACME_HTTP_PROXY", "http://proxy.internal:3128")
+	c = NewRawUserConfig()
+	got, err = c.getACMEHTTPProxy()
+	if err != nil {
+		t.Errorf(err.Error())
+	}
+	if got == nil || got.Host != "proxy.internal:3128" {
+		t.Errorf("Bad ACME HTTP proxy. Want: http://proxy.internal:3128. Got: %s", got)
+	}
+
+	c = &RawUserConfig{ACMEHTTPProxy: "proxy.internal"}
+	_, err = c.getACMEHTTPProxy()
+	err_want := "Invalid ACME_HTTP_PROXY. An absolute URL is expected."
+	if err == nil || err.Error() != err_want {
+		t.Errorf("Bad error. Want: %s. Got: %v", err_want, err)
+	}
+}
+
+// Test that output layout defaults to standard and rejects unknown presets
+func TestGetOutputLayout(t *testing.T) {
+	c := NewRawUserConfig()
+	got, err := c.getOutputLayout()
+	if err != nil {
+		t.Errorf(err.Error())
+	}
+	if got != "standard" {
+		t.Errorf("Bad output layout. Want: standard. Got: %s", got)
+	}
+
+	t.Setenv("OUTPUT_LAYOUT", "HAProxy")
+	c = NewRawUserConfig()
+	got, err = c.getOutputLayout()
+	if err != nil {
+		t.Errorf(err.Error())
+	}
+	if got != "haproxy" {
+		t.Errorf("Bad output layout. Want: haproxy. Got: %s", got)
+	}
+
+	c = &RawUserConfig{OutputLayout: "iis"}
+	_, err = c.getOutputLayout()
+	err_want := "Invalid OUTPUT_LAYOUT: iis. Allowed values are 'standard', 'nginx', 'apache', 'haproxy' and 'traefik'."
+	if err == nil || err.Error() != err_want {
+		t.Errorf("Bad error. Want: %s. Got: %v", err_want, err)
+	}
+}
+
+// Test that outbound minimum TLS version defaults to 1.2 and rejects older versions
+func TestGetOutboundMinTLS(t *testing.T) {
+	c := NewRawUserConfig()
+	got, err := c.getOutboundMinTLS()
+	if err != nil {
+		t.Errorf(err.Error())
+	}
+	if got != tls.VersionTLS12 {
+		t.Errorf("Bad outbound minimum TLS version. Want: %d. Got: %d", tls.VersionTLS12, got)
+	}
+
+	t.Setenv("OUTBOUND_MIN_TLS", "1.3")
+	c = NewRawUserConfig()
+	got, err = c.getOutboundMinTLS()
+	if err != nil {
+		t.Errorf(err.Error())
+	}
+	if got != tls.VersionTLS13 {
+		t.Errorf("Bad outbound minimum TLS version. Want: %d. Got: %d", tls.VersionTLS13, got)
+	}
+
+	c = &RawUserConfig{OutboundMinTLS: "1.1"}
+	_, err = c.getOutboundMinTLS()
+	err_want := "Invalid OUTBOUND_MIN_TLS: 1.1. Allowed values are '1.2' and '1.3'."
+	if err == nil || err.Error() != err_want {
+		t.Errorf("Bad error. Want: %s. Got: %v", err_want, err)
+	}
+}
+
+// Test that prune option is disabled by default
+func TestGetPruneOption(t *testing.T) {
+	c := NewRawUserConfig()
+	got, err := c.getPruneOption()
+	if err != nil {
+		t.Errorf(err.Error())
+	}
+	if got {
+		t.Errorf("Bad Prune option. Want: false. Got: true")
+	}
+
+	t.Setenv("PRUNE", "true")
+	c = NewRawUserConfig()
+	got, err = c.getPruneOption()
+	if err != nil {
+		t.Errorf(err.Error())
+	}
+	if !got {
+		t.Errorf("Bad Prune option. Want: true. Got: false")
+	}
+}
+
+// Test that renewal interval and jitter are parsed as durations
+func TestGetRenewSchedule(t *testing.T) {
+	c := NewRawUserConfig()
+	interval, err := c.getRenewInterval()
+	if err != nil {
+		t.Errorf(err.Error())
+	}
+	jitter, err := c.getRenewJitter()
+	if err != nil {
+		t.Errorf(err.Error())
+	}
+	if interval != 0 || jitter != 0 {
+		t.Errorf("Expected zero interval and jitter by default. Got: %s and %s", interval, jitter)
+	}
+
+	c = &RawUserConfig{RenewInterval: "24h", RenewJitter: "30m"}
+	interval, err = c.getRenewInterval()
+	if err != nil {
+		t.Errorf(err.Error())
+	}
+	jitter, err = c.getRenewJitter()
+	if err != nil {
+		t.Errorf(err.Error())
+	}
+	if interval != 24*time.Hour || jitter != 30*time.Minute {
+		t.Errorf("Bad renewal schedule. Got: %s and %s", interval, jitter)
+	}
+
+	c = &RawUserConfig{RenewBefore: "720h"}
+	before, percent, err := c.getRenewBefore()
+	if err != nil {
+		t.Errorf(err.Error())
+	}
+	if before != 720*time.Hour || percent != 0 {
+		t.Errorf("Bad renewal threshold. Want: 720h. Got: %s and %v%%", before, percent)
+	}
+
+	c = &RawUserConfig{RenewBefore: "33%"}
+	before, percent, err = c.getRenewBefore()
+	if err != nil {
+		t.Errorf(err.Error())
+	}
+	if before != 0 || percent != 33 {
+		t.Errorf("Bad renewal threshold. Want: 33%%. Got: %s and %v%%", before, percent)
+	}
+
+	c = &RawUserConfig{RenewBefore: "150%"}
+	_, _, err = c.getRenewBefore()
+	if err == nil || err.Error() != "Invalid RENEW_BEFORE: 150%. A percentage between 0% and 100% is expected." {
+		t.Errorf("Bad error for percentage above 100%%: %v", err)
+	}
+
+	c = &RawUserConfig{RenewJitter: "-5m"}
+	_, err = c.getRenewJitter()
+	err_want := "Invalid RENEW_JITTER: duration must not be negative: -5m"
+	if err == nil {
+		t.Fatalf("Expected error. Want: %s. Got: nil", err_want)
+	}
+	if err.Error() != err_want {
+		t.Errorf("Bad error. Want: %s. Got: %s", err_want, err.Error())
+	}
+}
+
+// Test that filename template is validated
+func TestGetFilenameTemplate(t *testing.T) {
+	c := &RawUserConfig{FilenameTemplate: "{domain}.{date}", Prune: "false"}
+	got, err := c.getFilenameTemplate()
+	if err != nil {
+		t.Errorf(err.Error())
+	}
+	if got != "{domain}.{date}" {
+		t.Errorf("Bad filename template. Want: {domain}.{date}. Got: %s", got)
+	}
+
+	c = &RawUserConfig{FilenameTemplate: "{name}", Prune: "false"}
+	_, err = c.getFilenameTemplate()
+	err_want := "Invalid FILENAME_TEMPLATE: Unsupported placeholder {name}. Allowed placeholders are {domain}, {date}, {serial}."
+	if err == nil || err.Error() != err_want {
+		t.Errorf("Bad error. Want: %s. Got: %v", err_want, err)
+	}
+
+	c = &RawUserConfig{FilenameTemplate: "{serial}", Prune: "true"}
+	_, err = c.getFilenameTemplate()
+	err_want = "Invalid FILENAME_TEMPLATE: cannot be used with PRUNE"
+	if err == nil || err.Error() != err_want {
+		t.Errorf("Bad error. Want: %s. Got: %v", err_want, err)
+	}
+}
+
+// Test that health endpoint address must include a port
+func TestGetHealthAddr(t *testing.T) {
+	c := &RawUserConfig{HealthAddr: ":8080"}
+	addr, err := c.getHealthAddr()
+	if err != nil {
+		t.Errorf(err.Error())
+	}
+	if addr != ":8080" {
+		t.Errorf("Bad health address. Want: :8080. Got: %s", addr)
+	}
+
+	c = &RawUserConfig{HealthAddr: "localhost"}
+	_, err = c.getHealthAddr()
+	err_want := "Invalid HEALTH_ADDR: localhost. Expected format is 'host:port' (e.g. ':8080')."
+	if err == nil || err.Error() != err_want {
+		t.Errorf("Bad error. Want: %s. Got: %v", err_want, err)
+	}
+}
+
+// Test that insecure flag is derived from CA directory
+func TestInsecureCADir(t *testing.T) {
+	stores := stores.TestStores("")
+	t.Setenv("DOMAINS", "example.com")
+	t.Setenv("ACCOUNT_EMAIL", "support@example.com")
+	t.Setenv("ACCOUNT_KEY_FILE", filepath.Join(t.TempDir(), "account.key"))
+	t.Setenv("DNS_AUTH_TOKEN", "XXXXX")
+	t.Setenv("CA_DIR", "TEST")
+	config, err := NewUserConfig(&stores)
+	if err != nil {
+		t.Fatalf(err.Error())
+	}
+	if !config.Insecure {
+		t.Errorf("Expected insecure flag to be set for TEST CA directory")
+	}
+	t.Setenv("CA_DIR", "STAGING")
+	config, err = NewUserConfig(&stores)
+	if err != nil {
+		t.Fatalf(err.Error())
+	}
+	if config.Insecure {
+		t.Errorf("Expected insecure flag to be unset for STAGING CA directory")
+	}
+}
+
+// Test that IP addresses found in domains are rejected since DNS-01 challenges cannot validate them
+func TestNewUserConfigWithIPAddresses(t *testing.T) {
+	stores := stores.TestStores("")
+	t.Setenv("DOMAINS", "example.com,192.0.2.1,2001:db8::1")
+	t.Setenv("ACCOUNT_EMAIL", "support@example.com")
+	t.Setenv("ACCOUNT_KEY_FILE", filepath.Join(t.TempDir(), "account.key"))
+	t.Setenv("DNS_AUTH_TOKEN", "XXXXX")
+	_, err := NewUserConfig(&stores)
+	err_want := "IP address SANs cannot be validated using DNS-01 challenges: 192.0.2.1,2001:db8::1"
+	if err == nil || err.Error() != err_want {
+		t.Fatalf("Bad error. Want: %s. Got: %v", err_want, err)
+	}
+	// Certificate groups are checked as well
+	t.Setenv("CERTIFICATES", "example.com;example.net,192.0.2.1")
+	_, err = NewUserConfig(&stores)
+	err_want = "IP address SANs cannot be validated using DNS-01 challenges: 192.0.2.1"
+	if err == nil || err.Error() != err_want {
+		t.Fatalf("Bad error. Want: %s. Got: %v", err_want, err)
+	}
+}
+
+// Test that certificate groups are parsed
+func TestGetCertificateGroups(t *testing.T) {
+	c := &RawUserConfig{Domains: "example.com,*.example.com", Filename: "custom"}
+	groups, err := c.getCertificateGroups()
+	if err != nil {
+		t.Fatalf(err.Error())
+	}
+	if len(groups) != 1 || groups[0].Filename != "custom" || !slices.Equal(groups[0].Domains, []string{"example.com", "*.example.com"}) {
+		t.Errorf("Bad certificate groups: %+v", groups)
+	}
+
+	c = &RawUserConfig{Certificates: "example.com,*.example.com;*.example.net"}
+	groups, err = c.getCertificateGroups()
+	if err != nil {
+		t.Fatalf(err.Error())
+	}
+	if len(groups) != 2 {
+		t.Fatalf("Expected 2 certificate groups but got: %+v", groups)
+	}
+	if groups[0].Filename != "example.com" || !slices.Equal(groups[0].Domains, []string{"example.com", "*.example.com"}) {
+		t.Errorf("Bad first group: %+v", groups[0])
+	}
+	if groups[1].Filename != "_.example.net" || !slices.Equal(groups[1].Domains, []string{"*.example.net"}) {
+		t.Errorf("Bad second group: %+v", groups[1])
+	}
+
+	c = &RawUserConfig{Certificates: "example.com;;example.net"}
+	_, err = c.getCertificateGroups()
+	err_want := "Invalid CERTIFICATES: empty certificate group"
+	if err == nil {
+		t.Fatalf("Expected error. Want: %s. Got: nil", err_want)
+	}
+	if err.Error() != err_want {
+		t.Errorf("Bad error. Want: %s. Got: %s", err_want, err.Error())
+	}
+}
+
+// Test that one certificate group is created per domain when separate certificates are enabled
+func TestGetCertificateGroupsSeparate(t *testing.T) {
+	c := &RawUserConfig{Domains: "example.com,*.example.net", Filename: "ignored", SeparateCerts: "true"}
+	groups, err := c.getCertificateGroups()
+	if err != nil {
+		t.Fatalf(err.Error())
+	}
+	if len(groups) != 2 {
+		t.Fatalf("Expected 2 certificate groups but got: %+v", groups)
+	}
+	if groups[0].Filename != "example.com" || !slices.Equal(groups[0].Domains, []string{"example.com"}) {
+		t.Errorf("Bad first group: %+v", groups[0])
+	}
+	if groups[1].Filename != "_.example.net" || !slices.Equal(groups[1].Domains, []string{"*.example.net"}) {
+		t.Errorf("Bad second group: %+v", groups[1])
+	}
+}
+
+// Test that certificate groups can be given explicit names
+func TestGetCertificateGroupsWithNames(t *testing.T) {
+	c := &RawUserConfig{Certificates: "example.com,*.example.com;*.example.com", CertNames: "example;wildcard"}
+	groups, err := c.getCertificateGroups()
+	if err != nil {
+		t.Fatalf(err.Error())
+	}
+	if len(groups) != 2 || groups[0].Filename != "example" || groups[1].Filename != "wildcard" {
+		t.Errorf("Bad certificate groups: %+v", groups)
+	}
+
+	c = &RawUserConfig{Certificates: "example.com;example.net", CertNames: "example"}
+	_, err = c.getCertificateGroups()
+	err_want := "Invalid CERT_NAMES: expected 2 names, one per certificate group, but got 1"
+	if err == nil {
+		t.Fatalf("Expected error. Want: %s. Got: nil", err_want)
+	}
+	if err.Error() != err_want {
+		t.Errorf("Bad error. Want: %s. Got: %s", err_want, err.Error())
+	}
+
+	for _, name := range []string{"../example", "*.example.com", "", ".."} {
+		c = &RawUserConfig{Certificates: "example.com", CertNames: name}
+		if name == "" {
+			c.CertNames = ";"
+			c.Certificates = "example.com;example.net"
+		}
+		_, err = c.getCertificateGroups()
+		if err == nil {
+			t.Errorf("Expected error for certificate name '%s'", name)
+		}
+	}
+}
+
+// Test that internationalized domain names are stored in both display and A-label forms
+func TestGetCertificateGroupsWithIDN(t *testing.T) {
+	c := &RawUserConfig{Domains: "bücher.example,*.bücher.example"}
+	groups, err := c.getCertificateGroups()
+	if err != nil {
+		t.Fatalf(err.Error())
+	}
+	want := []string{"xn--bcher-kva.example", "*.xn--bcher-kva.example"}
+	if !slices.Equal(groups[0].Domains, want) {
+		t.Errorf("Bad domains. Want: %s. Got: %s", want, groups[0].Domains)
+	}
+	want_display := []string{"bücher.example", "*.bücher.example"}
+	if !slices.Equal(groups[0].DisplayDomains, want_display) {
+		t.Errorf("Bad display domains. Want: %s. Got: %s", want_display, groups[0].DisplayDomains)
+	}
+	if groups[0].Filename != "xn--bcher-kva.example" {
+		t.Errorf("Bad filename. Want: xn--bcher-kva.example. Got: %s", groups[0].Filename)
+	}
+}
+
+// Test that whitespace around domains is ignored
+func TestGetCertificateGroupsWithSpaces(t *testing.T) {
+	c := &RawUserConfig{Domains: "example.com, test.com"}
+	groups, err := c.getCertificateGroups()
+	if err != nil {
+		t.Fatalf(err.Error())
+	}
+	if !slices.Equal(groups[0].Domains, []string{"example.com", "test.com"}) {
+		t.Errorf("Bad domains: %q", groups[0].Domains)
+	}
+	c = &RawUserConfig{Certificates: "example.com, *.example.com ; example.net @ /keys/net.pem"}
+	groups, err = c.getCertificateGroups()
+	if err != nil {
+		t.Fatalf(err.Error())
+	}
+	if !slices.Equal(groups[0].Domains, []string{"example.com", "*.example.com"}) || groups[1].Domains[0] != "example.net" || groups[1].AccountKeyFile != "/keys/net.pem" {
+		t.Errorf("Bad certificate groups: %+v", groups)
+	}
+}
+
+// Test that common name is moved to the front of domains
+func TestGetCertificateGroupsWithCommonName(t *testing.T) {
+	c := &RawUserConfig{Domains: "example.com,www.example.com,api.example.com", CommonName: "WWW.example.com"}
+	groups, err := c.getCertificateGroups()
+	if err != nil {
+		t.Fatalf(err.Error())
+	}
+	if !slices.Equal(groups[0].Domains, []string{"www.example.com", "example.com", "api.example.com"}) {
+		t.Errorf("Bad domains: %q", groups[0].Domains)
+	}
+	if groups[0].Filename != "example.com" {
+		t.Errorf("Bad filename. Want: example.com. Got: %s", groups[0].Filename)
+	}
+	c = &RawUserConfig{Domains: "example.com,www.example.com", CommonName: "example.net"}
+	_, err = c.getCertificateGroups()
+	err_want := "Invalid COMMON_NAME: example.net is not found in DOMAINS"
+	if err == nil || err.Error() != err_want {
+		t.Errorf("Bad error. Want: %s. Got: %v", err_want, err)
+	}
+	c = &RawUserConfig{Certificates: "example.com;example.net", CommonName: "example.com"}
+	_, err = c.getCertificateGroups()
+	err_want = "Invalid COMMON_NAME: cannot be used with CERTIFICATES or SEPARATE_CERTS"
+	if err == nil || err.Error() != err_want {
+		t.Errorf("Bad error. Want: %s. Got: %v", err_want, err)
+	}
+}
+
+// Generate a comma-separated list of count domains
+func manyDomains(count int) string {
+	domains := []string{}
+	for idx := 0; idx < count; idx++ {
+		domains = append(domains, fmt.Sprintf("d%d.example.com", idx))
+	}
+	return strings.Join(domains, ",")
+}
+
+// Test that domains exceeding MAX_SANS are rejected unless AUTO_CHUNK is enabled
+func TestGetCertificateGroupsMaxSANs(t *testing.T) {
+	c := &RawUserConfig{Domains: manyDomains(150)}
+	_, err := c.getCertificateGroups()
+	err_want := "Certificate d0.example.com holds 150 domains, more than MAX_SANS (100). Split domains into several certificates, or set AUTO_CHUNK=true"
+	if err == nil || err.Error() != err_want {
+		t.Errorf("Bad error. Want: %s. Got: %v", err_want, err)
+	}
+	c = &RawUserConfig{Domains: manyDomains(150), MaxSANs: "0"}
+	_, err = c.getCertificateGroups()
+	err_want = "Invalid MAX_SANS: 0. A positive integer is expected."
+	if err == nil || err.Error() != err_want {
+		t.Errorf("Bad error. Want: %s. Got: %v", err_want, err)
+	}
+}
+
+// Test that domains are split into certificates named by index when AUTO_CHUNK is enabled
+func TestGetCertificateGroupsAutoChunk(t *testing.T) {
+	c := &RawUserConfig{Domains: manyDomains(150), AutoChunk: "true"}
+	groups, err := c.getCertificateGroups()
+	if err != nil {
+		t.Fatalf(err.Error())
+	}
+	if len(groups) != 2 {
+		t.Fatalf("Expected 2 certificate groups but got %d", len(groups))
+	}
+	if groups[0].Filename != "d0.example.com-1" || len(groups[0].Domains) != 100 || groups[0].Domains[0] != "d0.example.com" {
+		t.Errorf("Bad first group: %s (%d domains)", groups[0].Filename, len(groups[0].Domains))
+	}
+	if groups[1].Filename != "d0.example.com-2" || len(groups[1].Domains) != 50 || groups[1].Domains[0] != "d100.example.com" {
+		t.Errorf("Bad second group: %s (%d domains)", groups[1].Filename, len(groups[1].Domains))
+	}
+	// Groups of CERTIFICATES are chunked as well, keeping their account key file
+	c = &RawUserConfig{Certificates: "example.net;" + manyDomains(5) + "@/keys/customer.pem", MaxSANs: "2", AutoChunk: "true"}
+	groups, err = c.getCertificateGroups()
+	if err != nil {
+		t.Fatalf(err.Error())
+	}
+	names := []string{}
+	for _, group := range groups {
+		names = append(names, group.Filename)
+		if group.Filename != "example.net" && group.AccountKeyFile != "/keys/customer.pem" {
+			t.Errorf("Bad account key file for %s: %s", group.Filename, group.AccountKeyFile)
+		}
+	}
+	want := []string{"example.net", "d0.example.com-1", "d0.example.com-2", "d0.example.com-3"}
+	if !slices.Equal(names, want) {
+		t.Errorf("Bad certificate names. Want: %s. Got: %s", want, names)
+	}
+}
+
+// Test that domains must match a suffix of ALLOWED_DOMAINS, wildcards included
+func TestCheckAllowedDomains(t *testing.T) {
+	c := &RawUserConfig{AllowedDomains: "Example.com, *.example.net"}
+	allowed, err := c.getAllowedDomains()
+	if err != nil {
+		t.Fatalf(err.Error())
+	}
+	if !slices.Equal(allowed, []string{"example.com", "example.net"}) {
+		t.Errorf("Bad allowed domains: %q", allowed)
+	}
+	for _, domains := range [][]string{
+		{"example.com"},
+		{"www.example.com", "api.eu.example.net"},
+		{"*.example.com", "*.api.example.net"},
+	} {
+		err = checkAllowedDomains([]CertificateGroup{{Domains: domains}}, allowed)
+		if err != nil {
+			t.Errorf("Expected %s to be allowed. Got: %s", domains, err)
+		}
+	}
+	for domain, err_want := range map[string]string{
+		"example.org":      "Domain example.org is not allowed by ALLOWED_DOMAINS",
+		"badexample.com":   "Domain badexample.com is not allowed by ALLOWED_DOMAINS",
+		"*.example.org":    "Domain *.example.org is not allowed by ALLOWED_DOMAINS",
+		"*.badexample.com": "Domain *.badexample.com is not allowed by ALLOWED_DOMAINS",
+	} {
+		err = checkAllowedDomains([]CertificateGroup{{Domains: []string{"example.com", domain}}}, allowed)
+		if err == nil || err.Error() != err_want {
+			t.Errorf("Bad error. Want: %s. Got: %v", err_want, err)
+		}
+	}
+	// Any domain is allowed when ALLOWED_DOMAINS is not set
+	err = checkAllowedDomains([]CertificateGroup{{Domains: []string{"example.org"}}}, nil)
+	if err != nil {
+		t.Errorf(err.Error())
+	}
+}
+
+// Test that configuration is rejected when a domain is not allowed
+func TestNewUserConfigAllowedDomains(t *testing.T) {
+	storage := stores.TestStores("")
+	t.Setenv("DOMAINS", "example.com,www.example.org")
+	t.Setenv("ALLOWED_DOMAINS", "example.com")
+	_, err := NewUserConfig(&storage)
+	err_want := "Domain www.example.org is not allowed by ALLOWED_DOMAINS"
+	if err == nil || err.Error() != err_want {
+		t.Errorf("Bad error. Want: %s. Got: %v", err_want, err)
+	}
+}
+
+// Test that certificate groups may be paired with an account key file
+func TestGetCertificateGroupsWithAccountKey(t *testing.T) {
+	c := &RawUserConfig{Certificates: "example.com,*.example.com@/keys/customer1.pem;example.net"}
+	groups, err := c.getCertificateGroups()
+	if err != nil {
+		t.Fatalf(err.Error())
+	}
+	if len(groups) != 2 {
+		t.Fatalf("Expected 2 certificate groups but got: %+v", groups)
+	}
+	if groups[0].AccountKeyFile != "/keys/customer1.pem" || !slices.Equal(groups[0].Domains, []string{"example.com", "*.example.com"}) {
+		t.Errorf("Bad first group: %+v", groups[0])
+	}
+	if groups[1].AccountKeyFile != "" {
+		t.Errorf("Bad account key file for second group. Want: <empty>. Got: %s", groups[1].AccountKeyFile)
+	}
+
+	c = &RawUserConfig{Certificates: "example.com@"}
+	_, err = c.getCertificateGroups()
+	err_want := "Invalid CERTIFICATES: empty account key path for group example.com"
+	if err == nil || err.Error() != err_want {
+		t.Errorf("Bad error. Want: %s. Got: %v", err_want, err)
+	}
+}
+
+// Test that owner of written files is left unchanged unless configured
+func TestGetOutputOwner(t *testing.T) {
+	c := NewRawUserConfig()
+	uid, gid, err := c.getOutputOwner()
+	if err != nil || uid != -1 || gid != -1 {
+		t.Errorf("Bad owner. Want: -1:-1. Got: %d:%d (%v)", uid, gid, err)
+	}
+	c = &RawUserConfig{OutputUID: "1000", OutputGID: "1001"}
+	uid, gid, err = c.getOutputOwner()
+	if err != nil || uid != 1000 || gid != 1001 {
+		t.Errorf("Bad owner. Want: 1000:1001. Got: %d:%d (%v)", uid, gid, err)
+	}
+	c = &RawUserConfig{OutputUID: "app"}
+	_, _, err = c.getOutputOwner()
+	err_want := "Invalid OUTPUT_UID: app. A non-negative integer is expected."
+	if err == nil || err.Error() != err_want {
+		t.Errorf("Bad error. Want: %s. Got: %v", err_want, err)
+	}
+}
+
+// Test that concurrency must be a positive integer
+func TestGetConcurrency(t *testing.T) {
+	c := NewRawUserConfig()
+	got, err := c.getConcurrency()
+	if err != nil {
+		t.Errorf(err.Error())
+	}
+	if got != 1 {
+		t.Errorf("Bad concurrency. Want: 1. Got: %d", got)
+	}
+	c = &RawUserConfig{Concurrency: "0"}
+	_, err = c.getConcurrency()
+	if err == nil {
+		t.Errorf("Expected error for zero concurrency")
+	}
+}
+
+// Test that skipping registration requires an account URI
+func TestGetRegistration(t *testing.T) {
+	c := NewRawUserConfig()
+	_, skip, err := c.getRegistration()
+	if err != nil {
+		t.Errorf(err.Error())
+	}
+	if skip {
+		t.Errorf("Bad skip registration option. Want: false. Got: true")
+	}
+	c = &RawUserConfig{SkipRegistration: "true"}
+	_, _, err = c.getRegistration()
+	if err == nil {
+		t.Errorf("Expected error when skipping registration without account URI")
+	}
+	c = &RawUserConfig{SkipRegistration: "true", AccountURI: "https://acme.example.com/acct/1"}
+	uri, skip, err := c.getRegistration()
+	if err != nil {
+		t.Errorf(err.Error())
+	}
+	if !skip || uri != "https://acme.example.com/acct/1" {
+		t.Errorf("Bad registration options: %s %t", uri, skip)
+	}
+}
+
+// Test that DigitalOcean API base URL must be an absolute URL
+func TestGetDigitalOceanAPIURL(t *testing.T) {
+	c := &RawUserConfig{DigitalOceanAPIURL: "http://localhost:8080/"}
+	got, err := c.getDigitalOceanAPIURL()
+	if err != nil {
+		t.Errorf(err.Error())
+	}
+	if got != "http://localhost:8080" {
+		t.Errorf("Bad API URL. Want: http://localhost:8080. Got: %s", got)
+	}
+	c = &RawUserConfig{DigitalOceanAPIURL: "localhost"}
+	_, err = c.getDigitalOceanAPIURL()
+	if err == nil {
+		t.Errorf("Expected error for relative API URL")
+	}
+}
+
+// Test that weak private key passphrases are rejected
+func TestGetKeyPassphrase(t *testing.T) {
+	c := NewRawUserConfig()
+	got, err := c.getKeyPassphrase()
+	if err != nil {
+		t.Errorf(err.Error())
+	}
+	if got != "" {
+		t.Errorf("Bad passphrase. Want: empty. Got: %s", got)
+	}
+	for _, value := range []string{"short", "            ", "aaaaaaaaaaaaaaaa"} {
+		c = &RawUserConfig{KeyPassphrase: value}
+		_, err = c.getKeyPassphrase()
+		if err == nil {
+			t.Errorf("Expected error for passphrase '%s'", value)
+		}
+	}
+	c = &RawUserConfig{KeyPassphrase: "correct horse battery staple"}
+	got, err = c.getKeyPassphrase()
+	if err != nil {
+		t.Errorf(err.Error())
+	}
+	if got != "correct horse battery staple" {
+		t.Errorf("Bad passphrase. Want: correct horse battery staple. Got: %s", got)
+	}
+}
+
+// Test that propagation timeout and challenge timeout are parsed independently
+func TestGetTimeouts(t *testing.T) {
+	c := NewRawUserConfig()
+	propagation, err := c.getPropagationTimeout()
+	if err != nil {
+		t.Errorf(err.Error())
+	}
+	challenge, err := c.getChallengeTimeout()
+	if err != nil {
+		t.Errorf(err.Error())
+	}
+	if propagation != 90*time.Second || challenge != 0 {
+		t.Errorf("Bad default timeouts. Want: 1m30s and 0s. Got: %s and %s", propagation, challenge)
+	}
+	c = &RawUserConfig{PropagationTimeout: "5m", ChallengeTimeout: "10m"}
+	propagation, _ = c.getPropagationTimeout()
+	challenge, _ = c.getChallengeTimeout()
+	if propagation != 5*time.Minute || challenge != 10*time.Minute {
+		t.Errorf("Bad timeouts. Want: 5m0s and 10m0s. Got: %s and %s", propagation, challenge)
+	}
+	c = &RawUserConfig{PropagationTimeout: "0"}
+	_, err = c.getPropagationTimeout()
+	if err == nil {
+		t.Errorf("Expected error for zero propagation timeout")
+	}
+}
+
+// Test that rate-limited DNS provider API calls are retried 3 times by default
+func TestGetDNSAPIMaxRetries(t *testing.T) {
+	c := NewRawUserConfig()
+	got, err := c.getDNSAPIMaxRetries()
+	if err != nil || got != 3 {
+		t.Errorf("Bad default DNS API max retries. Want: 3. Got: %d (%v)", got, err)
+	}
+	c = &RawUserConfig{DNSAPIMaxRetries: "-1"}
+	_, err = c.getDNSAPIMaxRetries()
+	err_want := "Invalid DNS_API_MAX_RETRIES: -1. A non-negative integer is expected."
+	if err == nil || err.Error() != err_want {
+		t.Errorf("Bad error. Want: %s. Got: %v", err_want, err)
+	}
+}
+
+// Test that ACME HTTP timeout defaults to lego value and must be a positive duration
+func TestGetACMEHTTPTimeout(t *testing.T) {
+	c := NewRawUserConfig()
+	got, err := c.getACMEHTTPTimeout()
+	if err != nil || got != 0 {
+		t.Errorf("Bad default ACME HTTP timeout. Want: 0s. Got: %s (%v)", got, err)
+	}
+	t.Setenv("ACME_HTTP_TIMEOUT", "3m")
+	c = NewRawUserConfig()
+	got, err = c.getACMEHTTPTimeout()
+	if err != nil || got != 3*time.Minute {
+		t.Errorf("Bad ACME HTTP timeout. Want: 3m0s. Got: %s (%v)", got, err)
+	}
+	c = &RawUserConfig{ACMEHTTPTimeout: "-1m"}
+	_, err = c.getACMEHTTPTimeout()
+	err_want := "Invalid ACME_HTTP_TIMEOUT: duration must not be negative: -1m"
+	if err == nil || err.Error() != err_want {
+		t.Errorf("Bad error. Want: %s. Got: %v", err_want, err)
+	}
+}
+
+// Test that keyvault timeout must be a positive duration
+func TestGetVaultTimeout(t *testing.T) {
+	got, err := GetVaultTimeout()
+	if err != nil {
+		t.Errorf(err.Error())
+	}
+	if got != 15*time.Second {
+		t.Errorf("Bad vault timeout. Want: 15s. Got: %s", got)
+	}
+	t.Setenv("VAULT_TIMEOUT", "0")
+	_, err = GetVaultTimeout()
+	if err == nil {
+		t.Errorf("Expected error for zero vault timeout")
+	}
+}
+
+// Test that DNS resolvers are parsed in simple and per-zone formats
+func TestGetDNSResolvers(t *testing.T) {
+	c := &RawUserConfig{DNSResolver: "1.1.1.1:53,8.8.8.8:53"}
+	resolvers, zoneResolvers, err := c.getDNSResolvers()
+	if err != nil {
+		t.Fatalf(err.Error())
+	}
+	if !slices.Equal(resolvers, []string{"1.1.1.1:53", "8.8.8.8:53"}) || len(zoneResolvers) != 0 {
+		t.Errorf("Bad resolvers: %s %v", resolvers, zoneResolvers)
+	}
+
+	c = &RawUserConfig{DNSResolver: "example.com=1.1.1.1:53;internal.net=10.0.0.1:53,10.0.0.2:53;9.9.9.9:53"}
+	resolvers, zoneResolvers, err = c.getDNSResolvers()
+	if err != nil {
+		t.Fatalf(err.Error())
+	}
+	if !slices.Equal(resolvers, []string{"9.9.9.9:53"}) {
+		t.Errorf("Bad default resolvers: %s", resolvers)
+	}
+	if !slices.Equal(zoneResolvers["example.com"], []string{"1.1.1.1:53"}) || !slices.Equal(zoneResolvers["internal.net"], []string{"10.0.0.1:53", "10.0.0.2:53"}) {
+		t.Errorf("Bad zone resolvers: %v", zoneResolvers)
+	}
+	config := UserConfig{ZoneResolvers: zoneResolvers}
+	if !slices.Equal(config.ResolversFor("a.internal.net"), []string{"10.0.0.1:53", "10.0.0.2:53"}) || config.ResolversFor("example.org") != nil {
+		t.Errorf("Bad resolvers routing: %v", zoneResolvers)
+	}
+
+	c = &RawUserConfig{DNSResolver: "example.com=;=1.1.1.1:53"}
+	_, _, err = c.getDNSResolvers()
+	if err == nil {
+		t.Errorf("Expected error for zone without resolvers")
+	}
+}
+
+// Test that complete propagation can be disabled globally or for some domains
+func TestGetDisableCPOption(t *testing.T) {
+	for value, want := range map[string]bool{"": true, "true": true, "false": false} {
+		c := &RawUserConfig{DisableCP: value}
+		disableCP, domains, err := c.getDisableCPOption()
+		if err != nil {
+			t.Fatalf(err.Error())
+		}
+		if disableCP != want || domains != nil {
+			t.Errorf("Bad option for %q. Want: %t. Got: %t %s", value, want, disableCP, domains)
+		}
+	}
+
+	c := &RawUserConfig{DisableCP: "slow.example.com, *.Internal.NET."}
+	disableCP, domains, err := c.getDisableCPOption()
+	if err != nil {
+		t.Fatalf(err.Error())
+	}
+	if disableCP || !slices.Equal(domains, []string{"slow.example.com", "internal.net"}) {
+		t.Errorf("Bad option. Want: false [slow.example.com internal.net]. Got: %t %s", disableCP, domains)
+	}
+	config := UserConfig{DisableCPDomains: domains}
+	if !config.DisableCPFor("a.internal.net") || !config.DisableCPFor("slow.example.com") || config.DisableCPFor("example.com") {
+		t.Errorf("Bad domains routing: %s", domains)
+	}
+
+	c = &RawUserConfig{DisableCP: "yes"}
+	_, _, err = c.getDisableCPOption()
+	err_want := "Invalid DISABLE_CP: yes. Expected 'true', 'false' or a comma-separated list of domains."
+	if err == nil || err.Error() != err_want {
+		t.Errorf("Bad error. Want: %s. Got: %v", err_want, err)
+	}
+}
+
+// Test that DNS resolvers are normalized to host:port form
+func TestGetDNSResolversNormalize(t *testing.T) {
+	c := &RawUserConfig{DNSResolver: "[2606:4700:4700::1111]:5353,2606:4700:4700::1001,1.1.1.1,dns.google"}
+	resolvers, _, err := c.getDNSResolvers()
+	if err != nil {
+		t.Fatalf(err.Error())
+	}
+	want := []string{"[2606:4700:4700::1111]:5353", "[2606:4700:4700::1001]:53", "1.1.1.1:53", "dns.google:53"}
+	if !slices.Equal(resolvers, want) {
+		t.Errorf("Bad resolvers. Want: %s. Got: %s", want, resolvers)
+	}
+
+	c = &RawUserConfig{DNSResolver: "example.com=2606:4700:4700::1111;[2001:db8::1]", ResolverProtocol: "tcp-tls"}
+	resolvers, zoneResolvers, err := c.getDNSResolvers()
+	if err != nil {
+		t.Fatalf(err.Error())
+	}
+	if !slices.Equal(resolvers, []string{"[2001:db8::1]:853"}) {
+		t.Errorf("Bad default resolvers: %s", resolvers)
+	}
+	if !slices.Equal(zoneResolvers["example.com"], []string{"[2606:4700:4700::1111]:853"}) {
+		t.Errorf("Bad zone resolvers: %v", zoneResolvers)
+	}
+}
+
+// Test that lock timeout defaults to zero
+func TestGetLockTimeout(t *testing.T) {
+	c := NewRawUserConfig()
+	got, err := c.getLockTimeout()
+	if err != nil {
+		t.Errorf(err.Error())
+	}
+	if got != 0 {
+		t.Errorf("Bad lock timeout. Want: 0s. Got: %s", got)
+	}
+	c = &RawUserConfig{LockTimeout: "-1m"}
+	_, err = c.getLockTimeout()
+	if err == nil {
+		t.Errorf("Expected error for negative lock timeout")
+	}
+}
+
+// Test that TTL of challenge records is parsed
+func TestGetDNSTTL(t *testing.T) {
+	c := NewRawUserConfig()
+	got, err := c.getDNSTTL()
+	if err != nil {
+		t.Errorf(err.Error())
+	}
+	if got != 0 {
+		t.Errorf("Bad TTL. Want: 0. Got: %d", got)
+	}
+	c = &RawUserConfig{DNSTTL: "30"}
+	got, err = c.getDNSTTL()
+	if err != nil {
+		t.Errorf(err.Error())
+	}
+	if got != 30 {
+		t.Errorf("Bad TTL. Want: 30. Got: %d", got)
+	}
+	for _, value := range []string{"0", "-5", "1m"} {
+		c = &RawUserConfig{DNSTTL: value}
+		_, err = c.getDNSTTL()
+		if err == nil {
+			t.Errorf("Expected error for TTL %s", value)
+		}
+	}
+}
+
+// Test that manual challenges do not require a DNS auth token
+func TestNewUserConfigWithManualChallenge(t *testing.T) {
+	stores := stores.TestStores("")
+	t.Setenv("DOMAINS", "example.com")
+	t.Setenv("ACCOUNT_EMAIL", "support@example.com")
+	t.Setenv("ACCOUNT_KEY_FILE", filepath.Join(t.TempDir(), "account.key"))
+	t.Setenv("CHALLENGE_TYPE", "manual")
+	t.Setenv("MANUAL_WAIT", "2m")
+	config, err := NewUserConfig(&stores)
+	if err != nil {
+		t.Fatalf(err.Error())
+	}
+	if config.ChallengeType != constants.CHALLENGE_TYPE_MANUAL {
+		t.Errorf("Bad challenge type. Want: manual. Got: %s", config.ChallengeType)
+	}
+	if config.ManualWait != 2*time.Minute {
+		t.Errorf("Bad manual wait. Want: 2m. Got: %s", config.ManualWait)
+	}
+
+	t.Setenv("CHALLENGE_TYPE", "http01")
+	_, err = NewUserConfig(&stores)
+	err_want := "Invalid challenge type: http01. Allowed values are 'dns01' and 'manual'."
+	if err == nil {
+		t.Fatalf("Expected error. Want: %s. Got: nil", err_want)
+	}
+	if err.Error() != err_want {
+		t.Errorf("Bad error. Want: %s. Got: %s", err_want, err.Error())
+	}
+}
+
+// Test that self test configuration targets local test CA without DNS provider credentials
+func TestNewSelfTestConfig(t *testing.T) {
+	stores := stores.TestStores("")
+	t.Setenv("DOMAINS", "example.com")
+	t.Setenv("DNS_PROVIDER", "cloudflare")
+	t.Setenv("CA_DIR", "PRODUCTION")
+	dir := t.TempDir()
+	config, err := NewSelfTestConfig(&stores, dir)
+	if err != nil {
+		t.Fatalf(err.Error())
+	}
+	if config.CADirURL != constants.ACME_TEST_CA_DIR || !config.Insecure {
+		t.Errorf("Bad CA directory. Want: %s. Got: %s", constants.ACME_TEST_CA_DIR, config.CADirURL)
+	}
+	if !slices.Equal(config.Domains, []string{constants.SELFTEST_DOMAIN}) {
+		t.Errorf("Bad domains. Want: [%s]. Got: %s", constants.SELFTEST_DOMAIN, config.Domains)
+	}
+	if !config.SelfTest || config.ChallengeType != constants.CHALLENGE_TYPE_DNS01 || config.AuthToken != "" {
+		t.Errorf("Bad challenge configuration: %s (self test: %t)", config.ChallengeType, config.SelfTest)
+	}
+	if config.OutputDirectory != dir || config.AccountKeyInfo.Path != filepath.Join(dir, "account.key") {
+		t.Errorf("Expected output and account key to be written into %s but got: %s and %s", dir, config.OutputDirectory, config.AccountKeyInfo.Path)
+	}
+}
+
+// Test that DNS hooks do not require a DNS auth token
+func TestNewUserConfigWithDNSHooks(t *testing.T) {
+	stores := stores.TestStores("")
+	t.Setenv("DOMAINS", "example.com")
+	t.Setenv("ACCOUNT_EMAIL", "support@example.com")
+	t.Setenv("ACCOUNT_KEY_FILE", filepath.Join(t.TempDir(), "account.key"))
+	t.Setenv("DNS_PRESENT_HOOK", "/usr/local/bin/dns-hook present")
+	t.Setenv("DNS_CLEANUP_HOOK", "/usr/local/bin/dns-hook cleanup")
+	config, err := NewUserConfig(&stores)
+	if err != nil {
+		t.Fatalf(err.Error())
+	}
+	if !slices.Equal(config.DNSPresentHook, []string{"/usr/local/bin/dns-hook", "present"}) {
+		t.Errorf("Bad present hook: %q", config.DNSPresentHook)
+	}
+	if !slices.Equal(config.DNSCleanupHook, []string{"/usr/local/bin/dns-hook", "cleanup"}) {
+		t.Errorf("Bad cleanup hook: %q", config.DNSCleanupHook)
+	}
+	if config.AuthToken != "" {
+		t.Errorf("Expected no DNS auth token but got: %s", config.AuthToken)
+	}
+
+	t.Setenv("DNS_PRESENT_HOOK", "")
+	_, err = NewUserConfig(&stores)
+	err_want := "Invalid DNS_CLEANUP_HOOK: DNS_PRESENT_HOOK must be set as well"
+	if err == nil || err.Error() != err_want {
+		t.Errorf("Bad error. Want: %s. Got: %v", err_want, err)
 	}
 }
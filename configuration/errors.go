@@ -0,0 +1,47 @@
+package configuration
+
+import "fmt"
+
+// Error returned when configuration is invalid
+type ConfigError struct {
+	Err error
+}
+
+func (e *ConfigError) Error() string {
+	return e.Err.Error()
+}
+
+func (e *ConfigError) Unwrap() error {
+	return e.Err
+}
+
+// Error returned when a token or a key cannot be read from its store
+type StoreError struct {
+	Err error
+}
+
+func (e *StoreError) Error() string {
+	return e.Err.Error()
+}
+
+func (e *StoreError) Unwrap() error {
+	return e.Err
+}
+
+// Wrap error returned by a store, keeping nil errors as is
+func storeError(err error) error {
+	if err == nil {
+		return nil
+	}
+	return &StoreError{Err: err}
+}
+
+// Error returned when an account key is found in an unsupported PEM block
+type UnknownKeyTypeError struct {
+	// Type of PEM block, e.g. DSA PRIVATE KEY
+	BlockType string
+}
+
+func (e *UnknownKeyTypeError) Error() string {
+	return fmt.Sprintf("unknown private key type: %s", e.BlockType)
+}
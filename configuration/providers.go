@@ -0,0 +1,134 @@
+package configuration
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+
+	"github.com/charbonnierg/letsgo/constants"
+	"golang.org/x/exp/slices"
+)
+
+// DNS provider settings
+type dnsProvider struct {
+	// Provider is configured using DNS auth token
+	requiresToken bool
+	// Environment variable holding a token specific to provider, read
+	// through environment store. DNS auth token is used when not set.
+	tokenVariable string
+	// Parse provider specific credentials into user configuration.
+	// Nil when provider does not use its own credentials.
+	parseCredentials func(c *RawUserConfig, config *UserConfig) error
+}
+
+// Supported DNS providers
+var dnsProviders = map[string]dnsProvider{
+	constants.DNS_PROVIDER_DIGITALOCEAN: {requiresToken: true},
+	constants.DNS_PROVIDER_AZURE: {parseCredentials: func(c *RawUserConfig, config *UserConfig) error {
+		azureConfig, err := c.getAzureConfig()
+		config.Azure = azureConfig
+		return err
+	}},
+	constants.DNS_PROVIDER_OVH: {parseCredentials: func(c *RawUserConfig, config *UserConfig) error {
+		ovhConfig, err := c.getOVHConfig()
+		config.OVH = ovhConfig
+		return err
+	}},
+	constants.DNS_PROVIDER_CLOUDFLARE: {requiresToken: true, parseCredentials: func(c *RawUserConfig, config *UserConfig) error {
+		cloudflareConfig, err := c.getCloudflareConfig()
+		config.Cloudflare = cloudflareConfig
+		return err
+	}},
+	constants.DNS_PROVIDER_LINODE: {requiresToken: true, tokenVariable: constants.LINODE_TOKEN},
+	constants.DNS_PROVIDER_VULTR:  {requiresToken: true, tokenVariable: constants.VULTR_API_KEY},
+}
+
+// Generate a human readable list of supported DNS providers
+func allowedDNSProviders() string {
+	names := []string{}
+	for name := range dnsProviders {
+		names = append(names, fmt.Sprintf("'%s'", name))
+	}
+	sort.Strings(names)
+	return strings.Join(names[:len(names)-1], ", ") + " and " + names[len(names)-1]
+}
+
+// Normalize a DNS zone or domain name used to select DNS provider
+func normalizeZone(zone string) string {
+	zone = strings.ToLower(strings.TrimSpace(zone))
+	zone = strings.TrimPrefix(zone, "*.")
+	return strings.TrimSuffix(zone, ".")
+}
+
+// Get name of DNS provider used to solve challenges for domain.
+//
+// Provider associated with the longest matching zone in DNS provider map
+// is used. DNSProvider is used when no zone matches.
+func (c UserConfig) ProviderFor(domain string) string {
+	domain = normalizeZone(domain)
+	provider := c.DNSProvider
+	longest := -1
+	for zone, name := range c.DNSProviderMap {
+		if (domain == zone || strings.HasSuffix(domain, "."+zone)) && len(zone) > longest {
+			provider = name
+			longest = len(zone)
+		}
+	}
+	return provider
+}
+
+// Get names of DNS providers used to solve challenges for all certificates
+func (c UserConfig) UsedProviders() []string {
+	used := []string{}
+	for _, group := range c.Certificates {
+		for _, domain := range group.Domains {
+			provider := c.ProviderFor(domain)
+			if !slices.Contains(used, provider) {
+				used = append(used, provider)
+			}
+		}
+	}
+	sort.Strings(used)
+	return used
+}
+
+// Get resolvers used to check challenge records of domain.
+//
+// Resolvers associated with the longest matching zone are returned.
+// Nil is returned when no zone matches.
+func (c UserConfig) ResolversFor(domain string) []string {
+	domain = normalizeZone(domain)
+	var resolvers []string
+	longest := -1
+	for zone, servers := range c.ZoneResolvers {
+		if (domain == zone || strings.HasSuffix(domain, "."+zone)) && len(zone) > longest {
+			resolvers = servers
+			longest = len(zone)
+		}
+	}
+	return resolvers
+}
+
+// Check whether complete propagation check is disabled for domain.
+//
+// Domains listed in DISABLE_CP match themselves and their subdomains.
+func (c UserConfig) DisableCPFor(domain string) bool {
+	domain = normalizeZone(domain)
+	for _, zone := range c.DisableCPDomains {
+		if domain == zone || strings.HasSuffix(domain, "."+zone) {
+			return true
+		}
+	}
+	return false
+}
+
+// Get token used to authenticate against DNS provider.
+//
+// Token read from provider specific variable is used when found,
+// else DNS auth token is used.
+func (c UserConfig) TokenFor(name string) string {
+	if token, ok := c.ProviderTokens[name]; ok {
+		return token
+	}
+	return c.AuthToken
+}
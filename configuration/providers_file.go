@@ -0,0 +1,151 @@
+package configuration
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+
+	"github.com/charbonnierg/letsgo/constants"
+	"gopkg.in/yaml.v3"
+)
+
+// DNS provider declared in providers file.
+//
+// Several named providers of the same type may be declared,
+// each one with its own credentials.
+type NamedProvider struct {
+	// Type of DNS provider, e.g. cloudflare
+	Type string
+	// Zones whose challenges are solved by provider
+	Zones      []string
+	AuthToken  string
+	Azure      AzureConfig
+	OVH        OVHConfig
+	Cloudflare CloudflareConfig
+}
+
+// Content of providers file
+type providersFile struct {
+	Providers map[string]providerEntry `yaml:"providers"`
+}
+
+// Named provider as found in providers file
+type providerEntry struct {
+	Type  string   `yaml:"type"`
+	Zones []string `yaml:"zones"`
+	// Token used by providers authenticating with DNS auth token
+	Token string `yaml:"token"`
+	// Other credentials, keyed by environment variable names,
+	// e.g. AZURE_SUBSCRIPTION_ID
+	Credentials map[string]string `yaml:"credentials"`
+}
+
+// Parse named DNS providers declared in PROVIDERS_FILE.
+//
+// Providers file is a YAML document, unknown keys are rejected.
+// Returned map is empty when providers file is not set.
+func (c *RawUserConfig) getNamedProviders() (map[string]NamedProvider, error) {
+	named := map[string]NamedProvider{}
+	if c.ProvidersFile == "" {
+		return named, nil
+	}
+	content, err := os.ReadFile(c.ProvidersFile)
+	if err != nil {
+		return nil, errors.New(fmt.Sprintf("Invalid %s: %s", constants.PROVIDERS_FILE, err.Error()))
+	}
+	file := providersFile{}
+	decoder := yaml.NewDecoder(bytes.NewReader(content))
+	decoder.KnownFields(true)
+	err = decoder.Decode(&file)
+	if err != nil {
+		return nil, errors.New(fmt.Sprintf("Invalid %s: %s", constants.PROVIDERS_FILE, err.Error()))
+	}
+	zones := map[string]string{}
+	for name, entry := range file.Providers {
+		provider, err := c.parseProviderEntry(name, entry)
+		if err != nil {
+			return nil, errors.New(fmt.Sprintf("Invalid %s: %s", constants.PROVIDERS_FILE, err.Error()))
+		}
+		// A zone is solved by a single provider
+		for _, zone := range provider.Zones {
+			if other, ok := zones[zone]; ok {
+				return nil, errors.New(fmt.Sprintf("Invalid %s: zone %s is declared by providers %s and %s", constants.PROVIDERS_FILE, zone, other, name))
+			}
+			zones[zone] = name
+		}
+		named[name] = provider
+	}
+	return named, nil
+}
+
+// Parse a single named provider of providers file
+func (c *RawUserConfig) parseProviderEntry(name string, entry providerEntry) (NamedProvider, error) {
+	if _, ok := dnsProviders[name]; ok {
+		return NamedProvider{}, errors.New(fmt.Sprintf("provider name %s is reserved", name))
+	}
+	settings, ok := dnsProviders[entry.Type]
+	if !ok {
+		return NamedProvider{}, errors.New(fmt.Sprintf("invalid type of provider %s: %s. Allowed values are %s.", name, entry.Type, allowedDNSProviders()))
+	}
+	if settings.requiresToken && entry.Token == "" {
+		return NamedProvider{}, errors.New(fmt.Sprintf("provider %s requires a token", name))
+	}
+	provider := NamedProvider{Type: entry.Type, AuthToken: entry.Token}
+	for _, zone := range entry.Zones {
+		provider.Zones = append(provider.Zones, normalizeZone(zone))
+	}
+	if settings.parseCredentials == nil {
+		if len(entry.Credentials) > 0 {
+			return NamedProvider{}, errors.New(fmt.Sprintf("provider %s does not accept credentials", name))
+		}
+		return provider, nil
+	}
+	// Credentials override values of environment variables
+	raw := *c
+	encoded, err := json.Marshal(entry.Credentials)
+	if err != nil {
+		return NamedProvider{}, err
+	}
+	decoder := json.NewDecoder(bytes.NewReader(encoded))
+	decoder.DisallowUnknownFields()
+	err = decoder.Decode(&raw)
+	if err != nil {
+		return NamedProvider{}, errors.New(fmt.Sprintf("credentials of provider %s: %s", name, err.Error()))
+	}
+	config := &UserConfig{}
+	err = settings.parseCredentials(&raw, config)
+	if err != nil {
+		return NamedProvider{}, err
+	}
+	provider.Azure = config.Azure
+	provider.OVH = config.OVH
+	provider.Cloudflare = config.Cloudflare
+	return provider, nil
+}
+
+// Get configuration used to create named DNS provider.
+//
+// Credentials of named provider replace credentials of user configuration.
+// Configuration is returned unchanged when provider is not a named provider.
+func (c UserConfig) ForProvider(name string) UserConfig {
+	provider, ok := c.NamedProviders[name]
+	if !ok {
+		return c
+	}
+	c.AuthToken = provider.AuthToken
+	c.ProviderTokens = map[string]string{}
+	c.Azure = provider.Azure
+	c.OVH = provider.OVH
+	c.Cloudflare = provider.Cloudflare
+	return c
+}
+
+// Get type of DNS provider, named providers are resolved to their type
+func (c UserConfig) ProviderType(name string) string {
+	if provider, ok := c.NamedProviders[name]; ok {
+		return provider.Type
+	}
+	return name
+}
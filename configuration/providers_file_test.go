@@ -0,0 +1,80 @@
+package configuration
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/charbonnierg/letsgo/stores"
+)
+
+const testProvidersFile = `
+providers:
+  public:
+    type: linode
+    token: linode-token
+    zones: [example.com]
+  internal:
+    type: azuredns
+    zones: [internal.example.com, Example.NET.]
+    credentials:
+      AZURE_SUBSCRIPTION_ID: subscription
+      AZURE_RESOURCE_GROUP: group
+`
+
+// Write providers file into a temporary directory
+func writeProvidersFile(t *testing.T, content string) string {
+	path := filepath.Join(t.TempDir(), "providers.yaml")
+	err := os.WriteFile(path, []byte(content), 0o600)
+	if err != nil {
+		t.Fatalf(err.Error())
+	}
+	return path
+}
+
+// Test that named providers and their zones are read from providers file
+func TestNewUserConfigWithProvidersFile(t *testing.T) {
+	stores := stores.TestStores("")
+	t.Setenv("DOMAINS", "www.example.com,db.internal.example.com,example.net")
+	t.Setenv("ACCOUNT_EMAIL", "support@example.com")
+	t.Setenv("ACCOUNT_KEY_FILE", filepath.Join(t.TempDir(), "account.key"))
+	t.Setenv("PROVIDERS_FILE", writeProvidersFile(t, testProvidersFile))
+	config, err := NewUserConfig(&stores)
+	if err != nil {
+		t.Fatalf(err.Error())
+	}
+	public := config.NamedProviders["public"]
+	if public.Type != "linode" || public.AuthToken != "linode-token" {
+		t.Errorf("Bad named provider public: %+v", public)
+	}
+	internal := config.NamedProviders["internal"]
+	if internal.Type != "azuredns" || internal.Azure.SubscriptionID != "subscription" || internal.Azure.ResourceGroup != "group" {
+		t.Errorf("Bad named provider internal: %+v", internal)
+	}
+	for domain, want := range map[string]string{"www.example.com": "public", "db.internal.example.com": "internal", "example.net": "internal"} {
+		if got := config.ProviderFor(domain); got != want {
+			t.Errorf("Bad provider for %s. Want: %s. Got: %s", domain, want, got)
+		}
+	}
+	// Named provider credentials replace those of user configuration
+	providerConfig := config.ForProvider("public")
+	if providerConfig.TokenFor("linode") != "linode-token" {
+		t.Errorf("Bad token of named provider. Want: linode-token. Got: %s", providerConfig.TokenFor("linode"))
+	}
+}
+
+// Test that invalid providers files are rejected
+func TestGetNamedProvidersInvalid(t *testing.T) {
+	for content, err_want := range map[string]string{
+		"providers:\n  a:\n    type: route53\n":                                          "Invalid PROVIDERS_FILE: invalid type of provider a: route53. Allowed values are 'azuredns', 'cloudflare', 'digitalocean', 'linode', 'ovh' and 'vultr'.",
+		"providers:\n  a:\n    type: linode\n":                                           "Invalid PROVIDERS_FILE: provider a requires a token",
+		"providers:\n  linode:\n    type: linode\n":                                      "Invalid PROVIDERS_FILE: provider name linode is reserved",
+		"providers:\n  a:\n    type: ovh\n    credentials:\n      DOMAIN: example.com\n": "Invalid PROVIDERS_FILE: credentials of provider a: json: unknown field \"DOMAIN\"",
+	} {
+		c := &RawUserConfig{ProvidersFile: writeProvidersFile(t, content)}
+		_, err := c.getNamedProviders()
+		if err == nil || err.Error() != err_want {
+			t.Errorf("Bad error. Want: %s. Got: %v", err_want, err)
+		}
+	}
+}
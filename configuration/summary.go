@@ -0,0 +1,167 @@
+package configuration
+
+import (
+	"crypto/tls"
+	"fmt"
+	"sort"
+	"strings"
+
+	"github.com/charbonnierg/letsgo/constants"
+)
+
+// Mask a secret value so that it can be printed
+func mask(value string) string {
+	if value == "" {
+		return "<unset>"
+	}
+	return "<redacted>"
+}
+
+// Describe renewal threshold, either a duration or a percentage of lifetime
+func renewBefore(c UserConfig) string {
+	if c.RenewBeforePercent > 0 {
+		return fmt.Sprintf("%v%% of lifetime", c.RenewBeforePercent)
+	}
+	return c.RenewBefore.String()
+}
+
+// Describe account key in summary
+func summarizeAccountKey(info AccountKeyInfo) string {
+	if info.Missing {
+		return fmt.Sprintf("not found, generated on first run (%s)", info.Path)
+	}
+	return fmt.Sprintf("%s (%s, created: %t)", info.Type, info.Path, info.Created)
+}
+
+// Generate a human readable summary of effective configuration.
+// Secrets are never included in the summary.
+func (c UserConfig) Summary() string {
+	lines := []string{
+		fmt.Sprintf("Account email: %s", c.Email),
+		fmt.Sprintf("Account URI: %s", c.AccountURI),
+		fmt.Sprintf("Account key: %s", summarizeAccountKey(c.AccountKeyInfo)),
+		fmt.Sprintf("Skip registration: %t", c.SkipRegistration),
+		fmt.Sprintf("CA directory: %s", c.CADirURL),
+		fmt.Sprintf("Key type: %s", c.CADirKeyType),
+		fmt.Sprintf("User agent: %s", c.UserAgent),
+	}
+	if c.OutboundMinTLS == tls.VersionTLS13 {
+		lines = append(lines, fmt.Sprintf("Outbound minimum TLS version: %s", constants.TLS_VERSION_1_3))
+	} else {
+		lines = append(lines, fmt.Sprintf("Outbound minimum TLS version: %s", constants.TLS_VERSION_1_2))
+	}
+	// Proxy credentials are redacted
+	if c.ACMEHTTPProxy != nil {
+		lines = append(lines, fmt.Sprintf("ACME HTTP proxy: %s", c.ACMEHTTPProxy.Redacted()))
+	}
+	// Only names of extra headers are printed, values may hold credentials
+	if len(c.ACMEExtraHeaders) > 0 {
+		names := []string{}
+		for name := range c.ACMEExtraHeaders {
+			names = append(names, name)
+		}
+		sort.Strings(names)
+		lines = append(lines, fmt.Sprintf("ACME extra headers: %s", strings.Join(names, ",")))
+	}
+	if c.AllowedDomains != nil {
+		lines = append(lines, fmt.Sprintf("Allowed domains: %s", strings.Join(c.AllowedDomains, ",")))
+	}
+	for _, group := range c.Certificates {
+		lines = append(lines, fmt.Sprintf("Certificate %s: %s", group.Filename, strings.Join(group.Domains, ",")))
+		if group.AccountKeyFile != "" {
+			lines = append(lines, fmt.Sprintf("Account key for %s: %s", group.Filename, summarizeAccountKey(group.AccountKeyInfo)))
+		}
+	}
+	lines = append(lines,
+		fmt.Sprintf("Filename template: %s", c.FilenameTemplate),
+		fmt.Sprintf("Skip SANs verification: %t", c.SkipVerifySANs),
+		fmt.Sprintf("Concurrency: %d", c.Concurrency),
+		fmt.Sprintf("Maximum SANs per certificate: %d (auto chunk: %t)", c.MaxSANs, c.AutoChunk),
+		fmt.Sprintf("DNS API concurrency: %d", c.DNSAPIConcurrency),
+		fmt.Sprintf("DNS API max retries: %d", c.DNSAPIMaxRetries),
+		fmt.Sprintf("Challenge type: %s", c.ChallengeType),
+	)
+	if c.ChallengeType == constants.CHALLENGE_TYPE_MANUAL {
+		lines = append(lines, fmt.Sprintf("Manual wait: %s", c.ManualWait))
+	} else if len(c.DNSPresentHook) > 0 {
+		lines = append(lines,
+			fmt.Sprintf("DNS present hook: %s", strings.Join(c.DNSPresentHook, " ")),
+			fmt.Sprintf("DNS cleanup hook: %s", strings.Join(c.DNSCleanupHook, " ")),
+		)
+	} else {
+		lines = append(lines,
+			fmt.Sprintf("DNS provider: %s", c.DNSProvider),
+			fmt.Sprintf("DNS auth token: %s", mask(c.AuthToken)),
+		)
+		names := []string{}
+		for name := range c.ProviderTokens {
+			names = append(names, name)
+		}
+		sort.Strings(names)
+		for _, name := range names {
+			lines = append(lines, fmt.Sprintf("DNS auth token for %s: %s", name, mask(c.ProviderTokens[name])))
+		}
+		names = []string{}
+		for name := range c.NamedProviders {
+			names = append(names, name)
+		}
+		sort.Strings(names)
+		for _, name := range names {
+			provider := c.NamedProviders[name]
+			lines = append(lines, fmt.Sprintf("DNS provider %s: %s (zones: %s, token: %s)", name, provider.Type, strings.Join(provider.Zones, ","), mask(provider.AuthToken)))
+		}
+	}
+	if c.DNSProvider == constants.DNS_PROVIDER_AZURE && c.ChallengeType != constants.CHALLENGE_TYPE_MANUAL {
+		lines = append(lines,
+			fmt.Sprintf("Azure subscription: %s", c.Azure.SubscriptionID),
+			fmt.Sprintf("Azure resource group: %s", c.Azure.ResourceGroup),
+			fmt.Sprintf("Azure client secret: %s", mask(c.Azure.ClientSecret)),
+		)
+	}
+	if c.DNSProvider == constants.DNS_PROVIDER_OVH && c.ChallengeType != constants.CHALLENGE_TYPE_MANUAL {
+		lines = append(lines,
+			fmt.Sprintf("OVH endpoint: %s", c.OVH.Endpoint),
+			fmt.Sprintf("OVH application secret: %s", mask(c.OVH.ApplicationSecret)),
+		)
+	}
+	lines = append(lines,
+		fmt.Sprintf("DNS resolvers: %s", strings.Join(c.DNSResolvers, ",")),
+	)
+	for zone, resolvers := range c.ZoneResolvers {
+		lines = append(lines, fmt.Sprintf("DNS resolvers for %s: %s", zone, strings.Join(resolvers, ",")))
+	}
+	lines = append(lines,
+		fmt.Sprintf("DNS resolver protocol: %s", c.ResolverProtocol),
+		fmt.Sprintf("DNS timeout: %s", c.DNSTimeout),
+		fmt.Sprintf("DNS TTL: %d", c.DNSTTL),
+		fmt.Sprintf("Propagation timeout: %s", c.PropagationTimeout),
+		fmt.Sprintf("Challenge timeout: %s", c.ChallengeTimeout),
+		fmt.Sprintf("ACME HTTP timeout: %s", c.ACMEHTTPTimeout),
+		fmt.Sprintf("DNS check mode: %s", c.DNSCheckMode),
+	)
+	if len(c.DisableCPDomains) > 0 {
+		lines = append(lines, fmt.Sprintf("Complete propagation disabled for: %s", strings.Join(c.DisableCPDomains, ",")))
+	}
+	lines = append(lines,
+		fmt.Sprintf("Output directory: %s", c.OutputDirectory),
+		fmt.Sprintf("Output metadata: %t", c.OutputMetadata),
+		fmt.Sprintf("Output issuer: %t", c.OutputIssuer),
+		fmt.Sprintf("Output all chains: %t", c.OutputAllChains),
+		fmt.Sprintf("Output DER: %t", c.OutputDER),
+		fmt.Sprintf("Output PKCS#7: %t", c.OutputP7B),
+		fmt.Sprintf("Output only changed files: %t", c.OutputOnlyChanged),
+		fmt.Sprintf("Output layout: %s", c.OutputLayout),
+		fmt.Sprintf("Output owner: %d:%d", c.OutputUID, c.OutputGID),
+		fmt.Sprintf("Output JSON: %t", c.OutputJSON),
+		fmt.Sprintf("Key passphrase: %s", mask(c.KeyPassphrase)),
+		fmt.Sprintf("Notification webhook: %s", mask(c.NotifyWebhookURL)),
+		fmt.Sprintf("Notify always: %t", c.NotifyAlways),
+		fmt.Sprintf("Renew interval: %s", c.RenewInterval),
+		fmt.Sprintf("Renew jitter: %s", c.RenewJitter),
+		fmt.Sprintf("Renew before: %s", renewBefore(c)),
+		fmt.Sprintf("Reuse key: %t", c.ReuseKey),
+		fmt.Sprintf("Health address: %s", c.HealthAddr),
+		fmt.Sprintf("Shutdown grace: %s", c.ShutdownGrace),
+	)
+	return strings.Join(lines, "\n")
+}
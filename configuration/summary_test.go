@@ -0,0 +1,32 @@
+package configuration
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/charbonnierg/letsgo/constants"
+)
+
+// Test that summary describes certificates and never includes secrets
+func TestSummary(t *testing.T) {
+	config := UserConfig{
+		Email:         "support@example.com",
+		ChallengeType: constants.CHALLENGE_TYPE_DNS01,
+		DNSProvider:   constants.DNS_PROVIDER_DIGITALOCEAN,
+		AuthToken:     "XXXXX",
+		Certificates: []CertificateGroup{
+			{Domains: []string{"example.com", "*.example.com"}, Filename: "example.com"},
+		},
+	}
+	summary := config.Summary()
+	if strings.Contains(summary, "XXXXX") {
+		t.Errorf("Summary must not include auth token: %s", summary)
+	}
+	want := "Certificate example.com: example.com,*.example.com"
+	if !strings.Contains(summary, want) {
+		t.Errorf("Bad summary. Want: %s. Got: %s", want, summary)
+	}
+	if !strings.Contains(summary, "DNS auth token: <redacted>") {
+		t.Errorf("Bad summary. Want: DNS auth token: <redacted>. Got: %s", summary)
+	}
+}
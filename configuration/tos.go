@@ -0,0 +1,100 @@
+package configuration
+
+import (
+	"bufio"
+	"crypto/tls"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"os"
+	"strings"
+	"time"
+
+	"github.com/charbonnierg/letsgo/constants"
+	"golang.org/x/term"
+)
+
+// Timeout of directory request when ACME_HTTP_TIMEOUT is not set, same as lego
+const defaultDirectoryTimeout = 30 * time.Second
+
+// Check whether standard input is an interactive terminal
+var isTerminal = func() bool {
+	return term.IsTerminal(int(os.Stdin.Fd()))
+}
+
+// Fetch Terms of Service URL advertised in directory of CA server.
+//
+// Directory is requested with the same HTTP options as ACME requests.
+// An empty URL is returned when CA server does not advertise any.
+func fetchTermsOfServiceURL(config *UserConfig) (string, error) {
+	transport := http.DefaultTransport.(*http.Transport).Clone()
+	transport.TLSClientConfig = &tls.Config{
+		RootCAs:            config.CARoots,
+		MinVersion:         config.OutboundMinTLS,
+		InsecureSkipVerify: config.Insecure,
+	}
+	if config.ACMEHTTPProxy != nil {
+		transport.Proxy = http.ProxyURL(config.ACMEHTTPProxy)
+	}
+	timeout := config.ACMEHTTPTimeout
+	if timeout == 0 {
+		timeout = defaultDirectoryTimeout
+	}
+	httpClient := &http.Client{Transport: transport, Timeout: timeout}
+	request, err := http.NewRequest(http.MethodGet, config.CADirURL, nil)
+	if err != nil {
+		return "", err
+	}
+	for name, values := range config.ACMEExtraHeaders {
+		request.Header[name] = values
+	}
+	request.Header.Set("User-Agent", config.UserAgent)
+	response, err := httpClient.Do(request)
+	if err != nil {
+		return "", err
+	}
+	defer response.Body.Close()
+	if response.StatusCode != http.StatusOK {
+		return "", errors.New(fmt.Sprintf("Unexpected status code %d", response.StatusCode))
+	}
+	directory := struct {
+		Meta struct {
+			TermsOfService string `json:"termsOfService"`
+		} `json:"meta"`
+	}{}
+	err = json.NewDecoder(response.Body).Decode(&directory)
+	if err != nil {
+		return "", err
+	}
+	return directory.Meta.TermsOfService, nil
+}
+
+// Ask operator to agree to Terms of Service of CA server.
+//
+// Terms of Service URL advertised by CA server is fetched and printed,
+// then agreement is only given when operator answers 'y'.
+func askTermsOfService(config *UserConfig, in io.Reader, out io.Writer) (bool, error) {
+	tosURL, err := fetchTermsOfServiceURL(config)
+	if err != nil {
+		return false, errors.New(fmt.Sprintf("Invalid %s: cannot fetch Terms of Service from %s: %s", constants.CA_DIR, config.CADirURL, err.Error()))
+	}
+	return promptTermsOfService(tosURL, in, out)
+}
+
+// Print Terms of Service URL and ask operator to agree to them.
+//
+// Agreement is only given when operator answers 'y'.
+func promptTermsOfService(tosURL string, in io.Reader, out io.Writer) (bool, error) {
+	if tosURL == "" {
+		tosURL = "<not provided by CA server>"
+	}
+	fmt.Fprintf(out, "Terms of Service of CA server: %s\n", tosURL)
+	fmt.Fprintf(out, "Do you agree? [y/N] ")
+	answer, err := bufio.NewReader(in).ReadString('\n')
+	if err != nil && !errors.Is(err, io.EOF) {
+		return false, err
+	}
+	return strings.ToLower(strings.TrimSpace(answer)) == "y", nil
+}
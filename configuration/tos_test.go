@@ -0,0 +1,57 @@
+package configuration
+
+import (
+	"bytes"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+)
+
+// Test that Terms of Service URL is printed, and only agreed when operator answers 'y'
+func TestPromptTermsOfService(t *testing.T) {
+	tosURL := "https://letsencrypt.org/documents/LE-SA-v1.3-September-21-2022.pdf"
+	for answer, want := range map[string]bool{"y\n": true, " Y \r\n": true, "y": true, "n\n": false, "yes please\n": false, "\n": false, "": false} {
+		out := &bytes.Buffer{}
+		agreed, err := promptTermsOfService(tosURL, strings.NewReader(answer), out)
+		if err != nil {
+			t.Fatalf(err.Error())
+		}
+		if agreed != want {
+			t.Errorf("Bad agreement for %q. Want: %t. Got: %t", answer, want, agreed)
+		}
+		if !strings.Contains(out.String(), "Terms of Service of CA server: "+tosURL) || !strings.Contains(out.String(), "Do you agree? [y/N]") {
+			t.Errorf("Bad prompt: %s", out.String())
+		}
+	}
+}
+
+// Test that Terms of Service URL is fetched from directory of CA server before prompting
+func TestAskTermsOfService(t *testing.T) {
+	tosURL := "https://letsencrypt.org/documents/LE-SA-v1.3-September-21-2022.pdf"
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path != "/directory" {
+			http.NotFound(w, r)
+			return
+		}
+		w.Write([]byte(`{"newOrder": "https://acme.example.com/new-order", "meta": {"termsOfService": "` + tosURL + `"}}`))
+	}))
+	defer server.Close()
+	out := &bytes.Buffer{}
+	agreed, err := askTermsOfService(&UserConfig{CADirURL: server.URL + "/directory"}, strings.NewReader("y\n"), out)
+	if err != nil {
+		t.Fatalf(err.Error())
+	}
+	if !agreed {
+		t.Errorf("Expected Terms of Service to be agreed")
+	}
+	if !strings.Contains(out.String(), tosURL) {
+		t.Errorf("Terms of Service URL not printed: %s", out.String())
+	}
+	// Directory which cannot be fetched is a configuration error
+	_, err = askTermsOfService(&UserConfig{CADirURL: server.URL + "/missing"}, strings.NewReader("y\n"), &bytes.Buffer{})
+	err_want := "Invalid CA_DIR: cannot fetch Terms of Service from " + server.URL + "/missing: Unexpected status code 404"
+	if err == nil || err.Error() != err_want {
+		t.Errorf("Bad error. Want: %s. Got: %v", err_want, err)
+	}
+}
@@ -2,8 +2,10 @@ package configuration
 
 import (
 	"errors"
+	"fmt"
 	"os"
 	"strings"
+	"time"
 
 	"golang.org/x/net/idna"
 )
@@ -19,14 +21,67 @@ func sanitizeDomain(domain string) (string, error) {
 	return safe, nil
 }
 
+// Convert internationalized domain names to A-label (punycode) form
+// expected by CA servers. Domains already in ASCII are left untouched.
+func toASCIIDomains(domains []string) ([]string, error) {
+	labels := make([]string, len(domains))
+	for idx, domain := range domains {
+		label, err := idna.ToASCII(domain)
+		if err != nil {
+			return nil, errors.New(fmt.Sprintf("Invalid domain name %s: %s", domain, err.Error()))
+		}
+		labels[idx] = label
+	}
+	return labels, nil
+}
+
+// Parse a positive duration.
+//
+// Value must be a valid Go duration such as `12h` or `30m`.
+// A zero value (`0`) is also accepted.
+func parseDuration(value string) (time.Duration, error) {
+	duration, err := time.ParseDuration(value)
+	if err != nil {
+		return 0, err
+	}
+	if duration < 0 {
+		return 0, errors.New(fmt.Sprintf("duration must not be negative: %s", value))
+	}
+	return duration, nil
+}
+
+// Split a list and trim whitespace around each element
+func splitList(value string, sep string) []string {
+	elements := strings.Split(value, sep)
+	for idx, element := range elements {
+		elements[idx] = strings.TrimSpace(element)
+	}
+	return elements
+}
+
+// Get keyvault URI from keyvault name or URI
+func vaultURI(vault string) string {
+	if strings.HasPrefix(vault, "https://") {
+		return vault
+	}
+	return fmt.Sprintf("https://%s.vault.azure.net/", vault)
+}
+
 // Get an environment variable
 //
 // A fallback value must be provided as argument.
-// If environment variable is not defined, fallback value
-// is used instead.
+// If environment variable is not defined, value found in
+// JSON configuration is used, else fallback value is used.
+// Whitespace around values is trimmed.
 func getEnv(key, fallback string) string {
 	if value, ok := os.LookupEnv(key); ok {
-		return value
+		return strings.TrimSpace(value)
+	}
+	// Values of JSON configuration are used when variable is not set
+	if values, err := loadConfigJSON(); err == nil {
+		if value, ok := values[key]; ok {
+			return strings.TrimSpace(value)
+		}
 	}
 	return fallback
 }
@@ -4,6 +4,9 @@ import (
 	"os"
 	"path/filepath"
 	"testing"
+	"time"
+
+	"golang.org/x/exp/slices"
 )
 
 // Test that domain names are sanitized into valid filenames
@@ -49,6 +52,25 @@ func TestGetEnvReturnsValue(t *testing.T) {
 	}
 }
 
+// Test that getEnv function trims whitespace around value
+func TestGetEnvTrimsValue(t *testing.T) {
+	t.Setenv("test-var", " value \n")
+	got := getEnv("test-var", "default")
+	want := "value"
+	if got != want {
+		t.Errorf("got %q, wanted %q", got, want)
+	}
+}
+
+// Test that elements of lists are trimmed
+func TestSplitList(t *testing.T) {
+	got := splitList(" example.com, test.com ,*.example.net", ",")
+	want := []string{"example.com", "test.com", "*.example.net"}
+	if !slices.Equal(got, want) {
+		t.Errorf("got %q, wanted %q", got, want)
+	}
+}
+
 // Test that fileExists function behaves as expected
 func TestFileExists(t *testing.T) {
 	dir := t.TempDir()
@@ -61,3 +83,29 @@ func TestFileExists(t *testing.T) {
 		t.Errorf("File exists but fileExists returned false")
 	}
 }
+
+// Test that parseDuration accepts positive durations only
+func TestParseDuration(t *testing.T) {
+	got, err := parseDuration("12h")
+	if err != nil {
+		t.Errorf(err.Error())
+	}
+	if got != 12*time.Hour {
+		t.Errorf("got %s, wanted %s", got, 12*time.Hour)
+	}
+	got, err = parseDuration("0")
+	if err != nil {
+		t.Errorf(err.Error())
+	}
+	if got != 0 {
+		t.Errorf("got %s, wanted 0s", got)
+	}
+	_, err = parseDuration("-1h")
+	if err == nil {
+		t.Errorf("Expected error for negative duration")
+	}
+	_, err = parseDuration("tomorrow")
+	if err == nil {
+		t.Errorf("Expected error for invalid duration")
+	}
+}
@@ -0,0 +1,45 @@
+package configuration
+
+import (
+	"log"
+	"sort"
+	"strings"
+
+	"github.com/charbonnierg/letsgo/constants"
+	"golang.org/x/exp/slices"
+)
+
+// Prefixes of environment variables owned by letsgo.
+//
+// Variables set with one of these prefixes which are not recognized
+// are most likely misspelled.
+var checkedPrefixes = []string{"DNS_", "LE_", "ACCOUNT_"}
+
+// Get names of environment variables which look like letsgo variables
+// but are not recognized. Environment is given as "key=value" strings,
+// as returned by os.Environ().
+func UnknownVariables(environ []string) []string {
+	known := constants.All()
+	unknown := []string{}
+	for _, entry := range environ {
+		name, _, _ := strings.Cut(entry, "=")
+		if slices.Contains(known, name) {
+			continue
+		}
+		for _, prefix := range checkedPrefixes {
+			if strings.HasPrefix(name, prefix) {
+				unknown = append(unknown, name)
+				break
+			}
+		}
+	}
+	sort.Strings(unknown)
+	return unknown
+}
+
+// Log a warning for each unrecognized environment variable
+func WarnUnknownVariables(environ []string) {
+	for _, name := range UnknownVariables(environ) {
+		log.Printf("Warning: environment variable %s is not recognized and will be ignored. Check for typos.", name)
+	}
+}
@@ -0,0 +1,39 @@
+package configuration
+
+import (
+	"bytes"
+	"log"
+	"os"
+	"reflect"
+	"strings"
+	"testing"
+
+	"github.com/charbonnierg/letsgo/constants"
+	"golang.org/x/exp/slices"
+)
+
+// Test that a misspelled variable triggers a warning
+func TestWarnUnknownVariables(t *testing.T) {
+	var buf bytes.Buffer
+	log.SetOutput(&buf)
+	defer log.SetOutput(os.Stderr)
+	WarnUnknownVariables([]string{"DNS_AUTH_TOKN=XXXXX", "DNS_AUTH_TOKEN=XXXXX", "LE_TOS_AGREED=true", "ACCOUNT_MAIL=support@example.com", "PATH=/usr/bin"})
+	output := buf.String()
+	if !strings.Contains(output, "DNS_AUTH_TOKN is not recognized") || !strings.Contains(output, "ACCOUNT_MAIL is not recognized") {
+		t.Errorf("Expected warnings for misspelled variables. Got: %s", output)
+	}
+	if strings.Contains(output, "DNS_AUTH_TOKEN ") || strings.Contains(output, "PATH") || strings.Contains(output, "LE_TOS_AGREED") {
+		t.Errorf("Unexpected warning for known or unrelated variable. Got: %s", output)
+	}
+}
+
+// Test that every configuration variable is recognized
+func TestAllVariables(t *testing.T) {
+	fields := reflect.TypeOf(RawUserConfig{})
+	for idx := 0; idx < fields.NumField(); idx++ {
+		name, _, _ := strings.Cut(fields.Field(idx).Tag.Get("json"), ",")
+		if !slices.Contains(constants.All(), name) {
+			t.Errorf("Variable %s is not listed in constants.All()", name)
+		}
+	}
+}
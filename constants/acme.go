@@ -9,3 +9,14 @@ const ACME_TEST_ENV = "TEST"
 const ACME_PRODUCTION_CA_DIR = "https://acme-v02.api.letsencrypt.org/directory"
 const ACME_STAGING_CA_DIR = "https://acme-staging-v02.api.letsencrypt.org/directory"
 const ACME_TEST_CA_DIR = "http://localhost:4000/directory"
+
+// Supported minimum TLS versions of requests sent to CA server and DNS provider API
+const TLS_VERSION_1_2 = "1.2"
+const TLS_VERSION_1_3 = "1.3"
+
+// Management API of pebble-challtestsrv, the mock DNS server of local Boulder test CA
+const ACME_TEST_CHALLTESTSRV = "http://localhost:8055"
+
+// Domain and email used by self test against local Boulder test CA
+const SELFTEST_DOMAIN = "letsgo-selftest.com"
+const SELFTEST_EMAIL = "selftest@letsgo-selftest.com"
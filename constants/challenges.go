@@ -0,0 +1,18 @@
+package constants
+
+// This module contains supported challenge types
+
+const CHALLENGE_TYPE_DNS01 = "dns01"
+const CHALLENGE_TYPE_MANUAL = "manual"
+
+// Supported DNS propagation check modes
+
+const DNS_CHECK_MODE_STRICT = "strict"
+const DNS_CHECK_MODE_PROPAGATION = "propagation"
+const DNS_CHECK_MODE_NONE = "none"
+
+// Supported protocols used to query DNS resolvers
+
+const DNS_PROTOCOL_UDP = "udp"
+const DNS_PROTOCOL_TCP = "tcp"
+const DNS_PROTOCOL_TCP_TLS = "tcp-tls"
@@ -3,8 +3,56 @@ package constants
 // This module contains default values for user configuration
 
 const DEFAULT_ACCOUNT_KEY_FILE = "./account.key"
+const DEFAULT_ACCOUNT_KEY_SECRET = "letsgo-account-key"
+const DEFAULT_ACCOUNT_KEY_TYPE = "EC256"
+const DEFAULT_SKIP_REGISTRATION = "false"
+const DEFAULT_SKIP_VERIFY_SANS = "false"
 const DEFAULT_LE_TOS_AGREED = "true"
 const DEFAULT_DISABLE_CP = "true"
+const DEFAULT_DNS_FOLLOW_CNAME = "true"
 const DEFAULT_LE_CRT_KEY_TYPE = KEY_TYPE_RSA2048
+const DEFAULT_OUTPUT_METADATA = "false"
+const DEFAULT_OUTPUT_ISSUER = "true"
+const DEFAULT_OUTPUT_ALL_CHAINS = "false"
+const DEFAULT_OUTPUT_DER = "false"
+const DEFAULT_OUTPUT_P7B = "false"
+const DEFAULT_OUTPUT_ONLY_CHANGED = "false"
+const DEFAULT_OUTPUT_LAYOUT = OUTPUT_LAYOUT_STANDARD
+const DEFAULT_OUTPUT_JSON = "false"
+const DEFAULT_LOCK_TIMEOUT = "0"
+const DEFAULT_SHUTDOWN_GRACE = "5m"
+const DEFAULT_STRICT_CLOCK = "false"
+const DEFAULT_ACME_DEBUG = "false"
+const DEFAULT_OUTBOUND_MIN_TLS = TLS_VERSION_1_2
+const DEFAULT_DNS_AUTH_TOKEN_FALLBACK = "false"
+const DEFAULT_CF_VALIDATE_TOKEN = "false"
+const DEFAULT_VERIFY_DNS_CREDENTIALS = "false"
+const DEFAULT_PRUNE = "false"
+const DEFAULT_DNS_RESOLVER_PROTOCOL = DNS_PROTOCOL_UDP
+const DEFAULT_SEPARATE_CERTS = "false"
+
+// Let's Encrypt accepts at most 100 SANs per certificate
+const DEFAULT_MAX_SANS = "100"
+const DEFAULT_AUTO_CHUNK = "false"
+const DEFAULT_NOTIFY_ON = NOTIFY_ON_FAILURE
+
+// Name of lock file created in output directory
+const LOCK_FILENAME = ".letsgo.lock"
+const DEFAULT_CONCURRENCY = "1"
+const DEFAULT_DNS_API_CONCURRENCY = "0"
+const DEFAULT_DNS_API_MAX_RETRIES = "3"
+const DEFAULT_VAULT_TIMEOUT = "15s"
+const DEFAULT_STORE_TIMEOUT = "60s"
+const DEFAULT_DNS_TTL = ""
+const DEFAULT_PROPAGATION_TIMEOUT = "90s"
+const DEFAULT_CHALLENGE_TIMEOUT = "0"
+const DEFAULT_ACME_HTTP_TIMEOUT = "0"
 const DEFAULT_CA_DIR = ACME_STAGING_ENV
+const DEFAULT_CHALLENGE_TYPE = CHALLENGE_TYPE_DNS01
+const DEFAULT_MANUAL_WAIT = "0"
+const DEFAULT_DNS_PROVIDER = DNS_PROVIDER_DIGITALOCEAN
 const DEFAULT_DNS_AUTH_TOKEN_SECRET = "do-auth-token"
+const DEFAULT_RENEW_INTERVAL = "0"
+const DEFAULT_RENEW_JITTER = "0"
+const DEFAULT_RENEW_BEFORE = "0"
+const DEFAULT_REUSE_KEY = "false"
@@ -2,18 +2,207 @@ package constants
 
 // This module contains environment variable names
 
+const MODE = "MODE"
+const LETSGO_CONFIG_JSON = "LETSGO_CONFIG_JSON"
+const CHALLENGE_TYPE = "CHALLENGE_TYPE"
+const MANUAL_WAIT = "MANUAL_WAIT"
+const DNS_PRESENT_HOOK = "DNS_PRESENT_HOOK"
+const DNS_CLEANUP_HOOK = "DNS_CLEANUP_HOOK"
+const DNS_PROVIDER = "DNS_PROVIDER"
+const DNS_PROVIDER_MAP = "DNS_PROVIDER_MAP"
+const PROVIDERS_FILE = "PROVIDERS_FILE"
+const VERIFY_DNS_CREDENTIALS = "VERIFY_DNS_CREDENTIALS"
+const DNS_API_CONCURRENCY = "DNS_API_CONCURRENCY"
+const DNS_API_MAX_RETRIES = "DNS_API_MAX_RETRIES"
 const DNS_AUTH_TOKEN = "DNS_AUTH_TOKEN"
 const DNS_AUTH_TOKEN_FILE = "DNS_AUTH_TOKEN_FILE"
+const DNS_AUTH_TOKEN_DIR = "DNS_AUTH_TOKEN_DIR"
+const DNS_AUTH_TOKEN_CREDENTIAL = "DNS_AUTH_TOKEN_CREDENTIAL"
+const DNS_AUTH_TOKEN_URL = "DNS_AUTH_TOKEN_URL"
+const DNS_AUTH_TOKEN_URL_HEADER = "DNS_AUTH_TOKEN_URL_HEADER"
 const DNS_AUTH_TOKEN_VAULT = "DNS_AUTH_TOKEN_VAULT"
 const DNS_AUTH_TOKEN_SECRET = "DNS_AUTH_TOKEN_SECRET"
+const DNS_AUTH_TOKEN_FALLBACK = "DNS_AUTH_TOKEN_FALLBACK"
+const VAULT_TIMEOUT = "VAULT_TIMEOUT"
+const STORE_TIMEOUT = "STORE_TIMEOUT"
 const DNS_RESOLVERS = "DNS_RESOLVERS"
+const DNS_RESOLVER_PROTOCOL = "DNS_RESOLVER_PROTOCOL"
 const DNS_TIMEOUT = "DNS_TIMEOUT"
+const DNS_TTL = "DNS_TTL"
+const PROPAGATION_TIMEOUT = "PROPAGATION_TIMEOUT"
+const CHALLENGE_TIMEOUT = "CHALLENGE_TIMEOUT"
+const ACME_HTTP_TIMEOUT = "ACME_HTTP_TIMEOUT"
+const DO_API_URL = "DO_API_URL"
 const DISABLE_CP = "DISABLE_CP"
+const DNS_CHECK_MODE = "DNS_CHECK_MODE"
+const DNS_FOLLOW_CNAME = "DNS_FOLLOW_CNAME"
 const DOMAINS = "DOMAINS"
+const COMMON_NAME = "COMMON_NAME"
+const ALLOWED_DOMAINS = "ALLOWED_DOMAINS"
+const CERTIFICATES = "CERTIFICATES"
+const CERT_NAMES = "CERT_NAMES"
+const SEPARATE_CERTS = "SEPARATE_CERTS"
+const MAX_SANS = "MAX_SANS"
+const AUTO_CHUNK = "AUTO_CHUNK"
+const CONCURRENCY = "CONCURRENCY"
 const FILENAME = "FILENAME"
+const FILENAME_TEMPLATE = "FILENAME_TEMPLATE"
+const SKIP_VERIFY_SANS = "SKIP_VERIFY_SANS"
 const ACCOUNT_EMAIL = "ACCOUNT_EMAIL"
+const ACCOUNT_EMAIL_FILE = "ACCOUNT_EMAIL_FILE"
 const ACCOUNT_KEY_FILE = "ACCOUNT_KEY_FILE"
+const ACCOUNT_KEY_PEM = "ACCOUNT_KEY_PEM"
+const ACCOUNT_KEY_VAULT = "ACCOUNT_KEY_VAULT"
+const ACCOUNT_KEY_SECRET = "ACCOUNT_KEY_SECRET"
+const ACCOUNT_KEY_TYPE = "ACCOUNT_KEY_TYPE"
+const ACCOUNT_URI = "ACCOUNT_URI"
+const SKIP_REGISTRATION = "SKIP_REGISTRATION"
 const LE_TOS_AGREED = "LE_TOS_AGREED"
 const CA_DIR = "CA_DIR"
+const CA_ROOT_CERT_FILE = "CA_ROOT_CERT_FILE"
 const LE_CRT_KEY_TYPE = "LE_CRT_KEY_TYPE"
 const OUTPUT_DIRECTORY = "OUTPUT_DIRECTORY"
+const OUTPUT_METADATA = "OUTPUT_METADATA"
+const OUTPUT_ISSUER = "OUTPUT_ISSUER"
+const OUTPUT_ALL_CHAINS = "OUTPUT_ALL_CHAINS"
+const OUTPUT_DER = "OUTPUT_DER"
+const OUTPUT_P7B = "OUTPUT_P7B"
+const OUTPUT_ONLY_CHANGED = "OUTPUT_ONLY_CHANGED"
+const OUTPUT_LAYOUT = "OUTPUT_LAYOUT"
+const OUTPUT_UID = "OUTPUT_UID"
+const OUTPUT_GID = "OUTPUT_GID"
+const OUTPUT_JSON = "OUTPUT_JSON"
+const LOCK_TIMEOUT = "LOCK_TIMEOUT"
+const STRICT_CLOCK = "STRICT_CLOCK"
+const ACME_DEBUG = "ACME_DEBUG"
+const ACME_HTTP_PROXY = "ACME_HTTP_PROXY"
+const OUTBOUND_MIN_TLS = "OUTBOUND_MIN_TLS"
+const ACME_EXTRA_HEADERS = "ACME_EXTRA_HEADERS"
+const PRUNE = "PRUNE"
+const NOTIFY_WEBHOOK_URL = "NOTIFY_WEBHOOK_URL"
+const NOTIFY_ON = "NOTIFY_ON"
+const KEY_PASSPHRASE = "KEY_PASSPHRASE"
+const USER_AGENT = "USER_AGENT"
+const RENEW_INTERVAL = "RENEW_INTERVAL"
+const RENEW_JITTER = "RENEW_JITTER"
+const RENEW_BEFORE = "RENEW_BEFORE"
+const REUSE_KEY = "REUSE_KEY"
+const HEALTH_ADDR = "HEALTH_ADDR"
+const SHUTDOWN_GRACE = "SHUTDOWN_GRACE"
+const AZURE_SUBSCRIPTION_ID = "AZURE_SUBSCRIPTION_ID"
+const AZURE_RESOURCE_GROUP = "AZURE_RESOURCE_GROUP"
+const AZURE_TENANT_ID = "AZURE_TENANT_ID"
+const AZURE_CLIENT_ID = "AZURE_CLIENT_ID"
+const AZURE_CLIENT_SECRET = "AZURE_CLIENT_SECRET"
+const OVH_ENDPOINT = "OVH_ENDPOINT"
+const OVH_APPLICATION_KEY = "OVH_APPLICATION_KEY"
+const OVH_APPLICATION_SECRET = "OVH_APPLICATION_SECRET"
+const OVH_CONSUMER_KEY = "OVH_CONSUMER_KEY"
+const CF_VALIDATE_TOKEN = "CF_VALIDATE_TOKEN"
+const LINODE_TOKEN = "LINODE_TOKEN"
+const VULTR_API_KEY = "VULTR_API_KEY"
+
+// Get names of all environment variables recognized by letsgo
+func All() []string {
+	return []string{
+		MODE,
+		LETSGO_CONFIG_JSON,
+		CHALLENGE_TYPE,
+		MANUAL_WAIT,
+		DNS_PRESENT_HOOK,
+		DNS_CLEANUP_HOOK,
+		DNS_PROVIDER,
+		DNS_PROVIDER_MAP,
+		PROVIDERS_FILE,
+		VERIFY_DNS_CREDENTIALS,
+		DNS_API_CONCURRENCY,
+		DNS_API_MAX_RETRIES,
+		DNS_AUTH_TOKEN,
+		DNS_AUTH_TOKEN_FILE,
+		DNS_AUTH_TOKEN_DIR,
+		DNS_AUTH_TOKEN_CREDENTIAL,
+		DNS_AUTH_TOKEN_URL,
+		DNS_AUTH_TOKEN_URL_HEADER,
+		DNS_AUTH_TOKEN_VAULT,
+		DNS_AUTH_TOKEN_SECRET,
+		DNS_AUTH_TOKEN_FALLBACK,
+		VAULT_TIMEOUT,
+		STORE_TIMEOUT,
+		DNS_RESOLVERS,
+		DNS_RESOLVER_PROTOCOL,
+		DNS_TIMEOUT,
+		DNS_TTL,
+		PROPAGATION_TIMEOUT,
+		CHALLENGE_TIMEOUT,
+		ACME_HTTP_TIMEOUT,
+		DO_API_URL,
+		DISABLE_CP,
+		DNS_CHECK_MODE,
+		DNS_FOLLOW_CNAME,
+		DOMAINS,
+		COMMON_NAME,
+		ALLOWED_DOMAINS,
+		CERTIFICATES,
+		CERT_NAMES,
+		SEPARATE_CERTS,
+		MAX_SANS,
+		AUTO_CHUNK,
+		CONCURRENCY,
+		FILENAME,
+		FILENAME_TEMPLATE,
+		SKIP_VERIFY_SANS,
+		ACCOUNT_EMAIL,
+		ACCOUNT_EMAIL_FILE,
+		ACCOUNT_KEY_FILE,
+		ACCOUNT_KEY_PEM,
+		ACCOUNT_KEY_VAULT,
+		ACCOUNT_KEY_SECRET,
+		ACCOUNT_KEY_TYPE,
+		ACCOUNT_URI,
+		SKIP_REGISTRATION,
+		LE_TOS_AGREED,
+		CA_DIR,
+		CA_ROOT_CERT_FILE,
+		LE_CRT_KEY_TYPE,
+		OUTPUT_DIRECTORY,
+		OUTPUT_METADATA,
+		OUTPUT_ISSUER,
+		OUTPUT_ALL_CHAINS,
+		OUTPUT_DER,
+		OUTPUT_P7B,
+		OUTPUT_ONLY_CHANGED,
+		OUTPUT_LAYOUT,
+		OUTPUT_UID,
+		OUTPUT_GID,
+		OUTPUT_JSON,
+		LOCK_TIMEOUT,
+		STRICT_CLOCK,
+		ACME_DEBUG,
+		ACME_HTTP_PROXY,
+		OUTBOUND_MIN_TLS,
+		ACME_EXTRA_HEADERS,
+		PRUNE,
+		NOTIFY_WEBHOOK_URL,
+		NOTIFY_ON,
+		KEY_PASSPHRASE,
+		USER_AGENT,
+		RENEW_INTERVAL,
+		RENEW_JITTER,
+		RENEW_BEFORE,
+		REUSE_KEY,
+		HEALTH_ADDR,
+		SHUTDOWN_GRACE,
+		AZURE_SUBSCRIPTION_ID,
+		AZURE_RESOURCE_GROUP,
+		AZURE_TENANT_ID,
+		AZURE_CLIENT_ID,
+		AZURE_CLIENT_SECRET,
+		OVH_ENDPOINT,
+		OVH_APPLICATION_KEY,
+		OVH_APPLICATION_SECRET,
+		OVH_CONSUMER_KEY,
+		CF_VALIDATE_TOKEN,
+		LINODE_TOKEN,
+		VULTR_API_KEY,
+	}
+}
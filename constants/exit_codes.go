@@ -0,0 +1,11 @@
+package constants
+
+// This module contains exit codes of letsgo
+
+const EXIT_CODE_SUCCESS = 0
+const EXIT_CODE_FAILURE = 1
+const EXIT_CODE_CONFIG = 2
+const EXIT_CODE_AUTH = 3
+const EXIT_CODE_RATE_LIMITED = 4
+const EXIT_CODE_CHALLENGE = 5
+const EXIT_CODE_NETWORK = 6
@@ -2,6 +2,13 @@ package constants
 
 // This module contains valid key types
 
+const KEY_TYPE_EC256 = "EC256"
 const KEY_TYPE_RSA2048 = "RSA2048"
 const KEY_TYPE_RSA4096 = "RSA4096"
 const KEY_TYPE_RSA8192 = "RSA8192"
+
+// Key types which are known but not supported by lego
+const KEY_TYPE_EC521 = "EC521"
+
+// Minimum length of passphrase used to encrypt certificate private key
+const MIN_KEY_PASSPHRASE_LENGTH = 12
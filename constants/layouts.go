@@ -0,0 +1,9 @@
+package constants
+
+// This module contains the layouts of output files which can be selected using OUTPUT_LAYOUT environment variable
+
+const OUTPUT_LAYOUT_STANDARD = "standard"
+const OUTPUT_LAYOUT_NGINX = "nginx"
+const OUTPUT_LAYOUT_APACHE = "apache"
+const OUTPUT_LAYOUT_HAPROXY = "haproxy"
+const OUTPUT_LAYOUT_TRAEFIK = "traefik"
@@ -0,0 +1,10 @@
+package constants
+
+// This module contains the modes which can be selected using MODE environment variable
+
+const MODE_OBTAIN = "obtain"
+const MODE_VERSION = "version"
+const MODE_VALIDATE = "validate"
+const MODE_ROTATE_ACCOUNT_KEY = "rotate-account-key"
+const MODE_RATELIMIT = "ratelimit"
+const MODE_SELFTEST = "selftest"
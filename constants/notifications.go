@@ -0,0 +1,6 @@
+package constants
+
+// This module contains the values allowed for NOTIFY_ON environment variable
+
+const NOTIFY_ON_FAILURE = "failure"
+const NOTIFY_ON_ALWAYS = "always"
@@ -0,0 +1,10 @@
+package constants
+
+// This module contains supported DNS providers
+
+const DNS_PROVIDER_DIGITALOCEAN = "digitalocean"
+const DNS_PROVIDER_AZURE = "azuredns"
+const DNS_PROVIDER_OVH = "ovh"
+const DNS_PROVIDER_CLOUDFLARE = "cloudflare"
+const DNS_PROVIDER_LINODE = "linode"
+const DNS_PROVIDER_VULTR = "vultr"
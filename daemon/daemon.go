@@ -0,0 +1,51 @@
+package daemon
+
+import (
+	"errors"
+	"log"
+	"math/rand"
+	"time"
+)
+
+// Compute delay before next renewal cycle
+//
+// Delay is randomized by up to ±jitter around interval,
+// and is never negative.
+func NextDelay(interval time.Duration, jitter time.Duration, rng *rand.Rand) time.Duration {
+	if jitter <= 0 {
+		return interval
+	}
+	delay := interval + time.Duration(rng.Int63n(int64(2*jitter)+1)) - jitter
+	if delay < 0 {
+		return 0
+	}
+	return delay
+}
+
+// Run task until shutdown, waiting for interval (± jitter) between each run.
+//
+// Errors returned by task are logged but do not stop the loop.
+// Outcome of each run is recorded into health when not nil.
+// Loop returns once shutdown is draining, after in-flight run completed.
+func Run(interval time.Duration, jitter time.Duration, health *Health, shutdown *Shutdown, task func() error) {
+	rng := rand.New(rand.NewSource(time.Now().UnixNano()))
+	for {
+		err := shutdown.Do(task)
+		if errors.Is(err, ErrShuttingDown) {
+			return
+		}
+		if err != nil {
+			log.Println(err)
+		}
+		if health != nil {
+			health.Record(err)
+		}
+		delay := NextDelay(interval, jitter, rng)
+		log.Printf("Next renewal in %s", delay)
+		select {
+		case <-time.After(delay):
+		case <-shutdown.Stopped():
+			return
+		}
+	}
+}
@@ -0,0 +1,47 @@
+package daemon
+
+import (
+	"math/rand"
+	"testing"
+	"time"
+)
+
+// Test that delay is equal to interval when jitter is not configured
+func TestNextDelayWithoutJitter(t *testing.T) {
+	rng := rand.New(rand.NewSource(1))
+	interval := time.Hour
+	for i := 0; i < 100; i++ {
+		if got := NextDelay(interval, 0, rng); got != interval {
+			t.Fatalf("Bad delay. Want: %s. Got: %s", interval, got)
+		}
+	}
+}
+
+// Test that delay stays within configured bounds
+func TestNextDelayWithinBounds(t *testing.T) {
+	rng := rand.New(rand.NewSource(1))
+	interval := time.Hour
+	jitter := 10 * time.Minute
+	shorter, longer := false, false
+	for i := 0; i < 10000; i++ {
+		got := NextDelay(interval, jitter, rng)
+		if got < interval-jitter || got > interval+jitter {
+			t.Fatalf("Delay out of bounds: %s", got)
+		}
+		shorter = shorter || got < interval
+		longer = longer || got > interval
+	}
+	if !shorter || !longer {
+		t.Errorf("Expected delays to spread on both sides of interval")
+	}
+}
+
+// Test that delay is never negative
+func TestNextDelayNeverNegative(t *testing.T) {
+	rng := rand.New(rand.NewSource(1))
+	for i := 0; i < 1000; i++ {
+		if got := NextDelay(time.Minute, time.Hour, rng); got < 0 {
+			t.Fatalf("Negative delay: %s", got)
+		}
+	}
+}
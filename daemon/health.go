@@ -0,0 +1,80 @@
+package daemon
+
+import (
+	"errors"
+	"fmt"
+	"log"
+	"net"
+	"net/http"
+	"sync"
+	"time"
+)
+
+// State of the renewal loop reported by health endpoint
+type Health struct {
+	// Return expiry of the earliest expiring managed certificate
+	Expiry func() (time.Time, error)
+
+	mu   sync.Mutex
+	done bool
+	err  error
+}
+
+// Record outcome of a renewal cycle
+func (h *Health) Record(err error) {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	h.done = true
+	h.err = err
+}
+
+// Return an error unless last renewal cycle succeeded and managed
+// certificates are not expired at given time
+func (h *Health) Check(now time.Time) error {
+	h.mu.Lock()
+	done, err := h.done, h.err
+	h.mu.Unlock()
+	if !done {
+		return errors.New("No renewal cycle completed yet")
+	}
+	if err != nil {
+		return errors.New(fmt.Sprintf("Last renewal cycle failed: %s", err))
+	}
+	if h.Expiry == nil {
+		return nil
+	}
+	notAfter, err := h.Expiry()
+	if err != nil {
+		return err
+	}
+	if !now.Before(notAfter) {
+		return errors.New(fmt.Sprintf("Certificate expired at %s", notAfter.UTC().Format(time.RFC3339)))
+	}
+	return nil
+}
+
+// Respond with 200 when healthy and 503 otherwise
+func (h *Health) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	if err := h.Check(time.Now()); err != nil {
+		http.Error(w, err.Error(), http.StatusServiceUnavailable)
+		return
+	}
+	fmt.Fprintln(w, "ok")
+}
+
+// Serve /healthz endpoint on addr in background
+func ServeHealth(addr string, health *Health) error {
+	listener, err := net.Listen("tcp", addr)
+	if err != nil {
+		return err
+	}
+	mux := http.NewServeMux()
+	mux.Handle("/healthz", health)
+	go func() {
+		if err := http.Serve(listener, mux); err != nil {
+			log.Println(err)
+		}
+	}()
+	log.Printf("Serving health endpoint on %s", listener.Addr())
+	return nil
+}
@@ -0,0 +1,45 @@
+package daemon
+
+import (
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+)
+
+// Send a request to health endpoint and return status code
+func getHealth(t *testing.T, url string) int {
+	res, err := http.Get(url)
+	if err != nil {
+		t.Fatalf(err.Error())
+	}
+	res.Body.Close()
+	return res.StatusCode
+}
+
+// Test that health endpoint reflects outcome of last renewal cycle
+func TestHealthEndpoint(t *testing.T) {
+	notAfter := time.Now().Add(time.Hour)
+	health := &Health{Expiry: func() (time.Time, error) { return notAfter, nil }}
+	server := httptest.NewServer(health)
+	defer server.Close()
+
+	if got := getHealth(t, server.URL); got != http.StatusServiceUnavailable {
+		t.Errorf("Bad status before first cycle. Want: %d. Got: %d", http.StatusServiceUnavailable, got)
+	}
+	health.Record(nil)
+	if got := getHealth(t, server.URL); got != http.StatusOK {
+		t.Errorf("Bad status after success. Want: %d. Got: %d", http.StatusOK, got)
+	}
+	health.Record(errors.New("rate limited"))
+	if got := getHealth(t, server.URL); got != http.StatusServiceUnavailable {
+		t.Errorf("Bad status after failure. Want: %d. Got: %d", http.StatusServiceUnavailable, got)
+	}
+	// Expired certificate is unhealthy even when last cycle succeeded
+	health.Record(nil)
+	notAfter = time.Now().Add(-time.Hour)
+	if got := getHealth(t, server.URL); got != http.StatusServiceUnavailable {
+		t.Errorf("Bad status with expired certificate. Want: %d. Got: %d", http.StatusServiceUnavailable, got)
+	}
+}
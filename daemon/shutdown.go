@@ -0,0 +1,68 @@
+package daemon
+
+import (
+	"errors"
+	"sync"
+	"time"
+)
+
+// Error returned when a renewal cycle is not started because process is shutting down
+var ErrShuttingDown = errors.New("Shutting down, renewal cycle not started")
+
+// Coordinate process shutdown with in-flight renewal cycles.
+//
+// Once draining, no new renewal cycle is started, while a cycle already
+// running is allowed to complete so that no ACME order is abandoned.
+type Shutdown struct {
+	mu       sync.Mutex
+	draining bool
+	stopped  chan struct{}
+	inflight sync.WaitGroup
+}
+
+// Create shutdown coordinator, not draining
+func NewShutdown() *Shutdown {
+	return &Shutdown{stopped: make(chan struct{})}
+}
+
+// Run task unless shutdown is draining
+func (s *Shutdown) Do(task func() error) error {
+	s.mu.Lock()
+	if s.draining {
+		s.mu.Unlock()
+		return ErrShuttingDown
+	}
+	s.inflight.Add(1)
+	s.mu.Unlock()
+	defer s.inflight.Done()
+	return task()
+}
+
+// Channel closed once shutdown starts draining
+func (s *Shutdown) Stopped() <-chan struct{} {
+	return s.stopped
+}
+
+// Stop starting new tasks and wait up to grace for in-flight task to complete.
+//
+// Return false when grace period expired before in-flight task completed,
+// in which case in-flight task must be cancelled by caller.
+func (s *Shutdown) Drain(grace time.Duration) bool {
+	s.mu.Lock()
+	if !s.draining {
+		s.draining = true
+		close(s.stopped)
+	}
+	s.mu.Unlock()
+	done := make(chan struct{})
+	go func() {
+		s.inflight.Wait()
+		close(done)
+	}()
+	select {
+	case <-done:
+		return true
+	case <-time.After(grace):
+		return false
+	}
+}
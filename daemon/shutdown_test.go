@@ -0,0 +1,71 @@
+package daemon
+
+import (
+	"testing"
+	"time"
+)
+
+// Test that draining waits for in-flight task and prevents new tasks
+func TestShutdownDrain(t *testing.T) {
+	shutdown := NewShutdown()
+	started := make(chan struct{})
+	completed := false
+	go shutdown.Do(func() error {
+		close(started)
+		time.Sleep(50 * time.Millisecond)
+		completed = true
+		return nil
+	})
+	<-started
+	if !shutdown.Drain(time.Second) {
+		t.Fatalf("Expected in-flight task to complete within grace period")
+	}
+	if !completed {
+		t.Errorf("Expected in-flight task to be completed once drained")
+	}
+	err := shutdown.Do(func() error { return nil })
+	if err != ErrShuttingDown {
+		t.Errorf("Bad error. Want: %s. Got: %v", ErrShuttingDown, err)
+	}
+}
+
+// Test that draining gives up once grace period expires
+func TestShutdownDrainExpired(t *testing.T) {
+	shutdown := NewShutdown()
+	started := make(chan struct{})
+	release := make(chan struct{})
+	defer close(release)
+	go shutdown.Do(func() error {
+		close(started)
+		<-release
+		return nil
+	})
+	<-started
+	if shutdown.Drain(10 * time.Millisecond) {
+		t.Errorf("Expected grace period to expire")
+	}
+}
+
+// Test that renewal loop returns once shutdown is draining
+func TestRunStopsWhenDraining(t *testing.T) {
+	shutdown := NewShutdown()
+	runs := 0
+	done := make(chan struct{})
+	go func() {
+		Run(time.Hour, 0, nil, shutdown, func() error {
+			runs++
+			return nil
+		})
+		close(done)
+	}()
+	time.Sleep(10 * time.Millisecond)
+	shutdown.Drain(time.Second)
+	select {
+	case <-done:
+	case <-time.After(time.Second):
+		t.Fatalf("Expected renewal loop to return")
+	}
+	if runs != 1 {
+		t.Errorf("Bad number of runs. Want: 1. Got: %d", runs)
+	}
+}
@@ -0,0 +1,56 @@
+package main
+
+import (
+	"errors"
+	"flag"
+	"io"
+	"log"
+	"net"
+
+	"github.com/charbonnierg/letsgo/client"
+	"github.com/charbonnierg/letsgo/configuration"
+	"github.com/charbonnierg/letsgo/constants"
+)
+
+// Map error to exit code of its category.
+//
+// Errors which do not belong to any category exit with code 1.
+func exitCode(err error) int {
+	var configErr *configuration.ConfigError
+	var storeErr *configuration.StoreError
+	var domainErr *client.DomainError
+	var accountErr *client.AccountError
+	var netErr net.Error
+	switch {
+	case err == nil:
+		return constants.EXIT_CODE_SUCCESS
+	case errors.As(err, &storeErr):
+		return constants.EXIT_CODE_AUTH
+	case errors.As(err, &configErr):
+		return constants.EXIT_CODE_CONFIG
+	case client.IsRateLimited(err):
+		return constants.EXIT_CODE_RATE_LIMITED
+	case client.IsUnauthorized(err), errors.As(err, &accountErr):
+		return constants.EXIT_CODE_AUTH
+	case errors.As(err, &domainErr):
+		return constants.EXIT_CODE_CHALLENGE
+	case errors.As(err, &netErr):
+		return constants.EXIT_CODE_NETWORK
+	default:
+		return constants.EXIT_CODE_FAILURE
+	}
+}
+
+// Run subcommand and return exit code of process.
+//
+// Errors are logged before returning.
+func execute(args []string, mode string, commands map[string]command, out io.Writer) int {
+	err := dispatch(args, mode, commands, out)
+	if errors.Is(err, flag.ErrHelp) {
+		return constants.EXIT_CODE_SUCCESS
+	}
+	if err != nil {
+		log.Print(err)
+	}
+	return exitCode(err)
+}
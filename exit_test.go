@@ -0,0 +1,54 @@
+package main
+
+import (
+	"bytes"
+	"errors"
+	"io"
+	"net"
+	"testing"
+
+	"github.com/charbonnierg/letsgo/client"
+	"github.com/charbonnierg/letsgo/configuration"
+	"github.com/go-acme/lego/v4/acme"
+)
+
+// Test that execute returns exit code of error category
+func TestExecute(t *testing.T) {
+	for name, test := range map[string]struct {
+		err  error
+		want int
+	}{
+		"success":      {err: nil, want: 0},
+		"unknown":      {err: errors.New("unexpected"), want: 1},
+		"config":       {err: &configuration.ConfigError{Err: errors.New("Invalid DNS_TTL")}, want: 2},
+		"token":        {err: &configuration.StoreError{Err: errors.New("secret not found")}, want: 3},
+		"unauthorized": {err: &acme.ProblemDetails{Type: "urn:ietf:params:acme:error:unauthorized", HTTPStatus: 403}, want: 3},
+		"account":      {err: &client.AccountError{AccountURI: "https://ca.example.com/acct/1", Err: errors.New("account is deactivated")}, want: 3},
+		"rate limited": {err: &acme.ProblemDetails{Type: "urn:ietf:params:acme:error:rateLimited", HTTPStatus: 429}, want: 4},
+		"challenge": {err: errors.Join(&client.DomainError{
+			Domain: "example.com",
+			Err:    &acme.ProblemDetails{Type: "urn:ietf:params:acme:error:unauthorized", HTTPStatus: 403},
+		}), want: 5},
+		"network": {err: &net.OpError{Op: "dial", Net: "tcp", Err: errors.New("connection refused")}, want: 6},
+	} {
+		err := test.err
+		commands := map[string]command{"obtain": {description: "obtain", run: func(out io.Writer) error {
+			return err
+		}}}
+		got := execute([]string{}, "", commands, &bytes.Buffer{})
+		if got != test.want {
+			t.Errorf("Bad exit code for %s. Want: %d. Got: %d", name, test.want, got)
+		}
+	}
+}
+
+// Test that invalid subcommands and help are mapped to exit codes
+func TestExecuteUsage(t *testing.T) {
+	ran := ""
+	if got := execute([]string{"unknown"}, "", newTestCommands(&ran), &bytes.Buffer{}); got != 2 {
+		t.Errorf("Bad exit code for invalid mode. Want: 2. Got: %d", got)
+	}
+	if got := execute([]string{"obtain", "-h"}, "", newTestCommands(&ran), &bytes.Buffer{}); got != 0 {
+		t.Errorf("Bad exit code for help. Want: 0. Got: %d", got)
+	}
+}
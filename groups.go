@@ -0,0 +1,88 @@
+package main
+
+import (
+	"errors"
+	"fmt"
+	"sort"
+	"strings"
+	"sync"
+
+	"github.com/charbonnierg/letsgo/configuration"
+	"golang.org/x/exp/slices"
+)
+
+// Locks preventing concurrent challenges for the same domain.
+//
+// A wildcard domain and its parent domain share the same
+// challenge record, so they share the same lock.
+type domainLocks struct {
+	mutex sync.Mutex
+	locks map[string]*sync.Mutex
+}
+
+// Lock all domains and return a function releasing locks
+func (l *domainLocks) lock(domains []string) func() {
+	keys := []string{}
+	for _, domain := range domains {
+		key := strings.TrimPrefix(strings.ToLower(domain), "*.")
+		if !slices.Contains(keys, key) {
+			keys = append(keys, key)
+		}
+	}
+	// Always acquire locks in the same order to avoid deadlocks
+	sort.Strings(keys)
+	l.mutex.Lock()
+	if l.locks == nil {
+		l.locks = map[string]*sync.Mutex{}
+	}
+	mutexes := []*sync.Mutex{}
+	for _, key := range keys {
+		if _, ok := l.locks[key]; !ok {
+			l.locks[key] = &sync.Mutex{}
+		}
+		mutexes = append(mutexes, l.locks[key])
+	}
+	l.mutex.Unlock()
+	for _, mutex := range mutexes {
+		mutex.Lock()
+	}
+	return func() {
+		for _, mutex := range mutexes {
+			mutex.Unlock()
+		}
+	}
+}
+
+// Obtain all certificate groups using a pool of workers.
+//
+// Errors are aggregated so that a failing group does not
+// prevent other groups from being obtained.
+func obtainGroups(config *configuration.UserConfig, obtain func(*configuration.UserConfig) error) error {
+	// Groups are sent to workers by index
+	indexes := make(chan int)
+	errs := make([]error, len(config.Certificates))
+	locks := &domainLocks{}
+	wg := sync.WaitGroup{}
+	for worker := 0; worker < config.Concurrency; worker++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for idx := range indexes {
+				group := config.Certificates[idx]
+				groupConfig := config.ForGroup(group)
+				unlock := locks.lock(group.Domains)
+				err := obtain(&groupConfig)
+				unlock()
+				if err != nil {
+					errs[idx] = fmt.Errorf("%s: %w", group.Filename, err)
+				}
+			}
+		}()
+	}
+	for idx := range config.Certificates {
+		indexes <- idx
+	}
+	close(indexes)
+	wg.Wait()
+	return errors.Join(errs...)
+}
@@ -0,0 +1,193 @@
+package main
+
+import (
+	"crypto"
+	"errors"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/charbonnierg/letsgo/configuration"
+	"github.com/charbonnierg/letsgo/stores"
+)
+
+// Fake DNS provider recording challenge records presented concurrently
+type fakeProvider struct {
+	mutex   sync.Mutex
+	records map[string]bool
+	active  int32
+	maximum int32
+}
+
+func (p *fakeProvider) present(t *testing.T, domains []string) {
+	p.mutex.Lock()
+	defer p.mutex.Unlock()
+	for _, domain := range domains {
+		record := "_acme-challenge." + strings.TrimPrefix(domain, "*.")
+		if p.records[record] {
+			t.Errorf("Challenge record %s presented concurrently", record)
+		}
+		p.records[record] = true
+	}
+}
+
+func (p *fakeProvider) cleanUp(domains []string) {
+	p.mutex.Lock()
+	defer p.mutex.Unlock()
+	for _, domain := range domains {
+		delete(p.records, "_acme-challenge."+strings.TrimPrefix(domain, "*."))
+	}
+}
+
+// Obtain function using the fake provider
+func (p *fakeProvider) obtain(t *testing.T, failing string) func(*configuration.UserConfig) error {
+	return func(config *configuration.UserConfig) error {
+		active := atomic.AddInt32(&p.active, 1)
+		defer atomic.AddInt32(&p.active, -1)
+		for {
+			maximum := atomic.LoadInt32(&p.maximum)
+			if active <= maximum || atomic.CompareAndSwapInt32(&p.maximum, maximum, active) {
+				break
+			}
+		}
+		p.present(t, config.Domains)
+		time.Sleep(10 * time.Millisecond)
+		p.cleanUp(config.Domains)
+		if config.Filename == failing {
+			return errors.New("challenge failed")
+		}
+		return nil
+	}
+}
+
+// Test that groups are obtained concurrently and errors are aggregated
+func TestObtainGroups(t *testing.T) {
+	provider := &fakeProvider{records: map[string]bool{}}
+	config := &configuration.UserConfig{
+		Concurrency: 3,
+		Certificates: []configuration.CertificateGroup{
+			{Domains: []string{"a.com"}, Filename: "a.com"},
+			{Domains: []string{"b.com"}, Filename: "b.com"},
+			{Domains: []string{"c.com"}, Filename: "c.com"},
+			{Domains: []string{"*.a.com"}, Filename: "_.a.com"},
+			{Domains: []string{"d.com", "a.com"}, Filename: "d.com"},
+		},
+	}
+	err := obtainGroups(config, provider.obtain(t, "b.com"))
+	if err == nil {
+		t.Fatalf("Expected error for failing group")
+	}
+	if err.Error() != "b.com: challenge failed" {
+		t.Errorf("Bad error. Want: b.com: challenge failed. Got: %s", err.Error())
+	}
+	if provider.maximum < 2 {
+		t.Errorf("Expected groups to be obtained concurrently")
+	}
+	if provider.maximum > 3 {
+		t.Errorf("Concurrency limit exceeded: %d", provider.maximum)
+	}
+}
+
+// Test that one certificate is obtained per domain when separate certificates are enabled
+func TestObtainSeparateCerts(t *testing.T) {
+	storage := stores.TestStores("")
+	t.Setenv("DOMAINS", "a.com,b.com")
+	t.Setenv("SEPARATE_CERTS", "true")
+	t.Setenv("ACCOUNT_EMAIL", "support@example.com")
+	t.Setenv("ACCOUNT_KEY_FILE", filepath.Join(t.TempDir(), "account.key"))
+	t.Setenv("DNS_AUTH_TOKEN", "XXXXX")
+	config, err := configuration.NewUserConfig(&storage)
+	if err != nil {
+		t.Fatalf(err.Error())
+	}
+	if len(config.Certificates) != 2 {
+		t.Fatalf("Expected 2 certificate groups but got: %+v", config.Certificates)
+	}
+	obtained := []string{}
+	mutex := sync.Mutex{}
+	provider := &fakeProvider{records: map[string]bool{}}
+	obtain := provider.obtain(t, "b.com")
+	err = obtainGroups(config, func(config *configuration.UserConfig) error {
+		err := obtain(config)
+		if err == nil {
+			mutex.Lock()
+			obtained = append(obtained, config.Domains...)
+			mutex.Unlock()
+		}
+		return err
+	})
+	if err == nil || err.Error() != "b.com: challenge failed" {
+		t.Errorf("Bad error. Want: b.com: challenge failed. Got: %v", err)
+	}
+	if len(obtained) != 1 || obtained[0] != "a.com" {
+		t.Errorf("Bad obtained domains. Want: [a.com]. Got: %s", obtained)
+	}
+}
+
+// Test that groups paired with an account key file are obtained with their own account
+func TestObtainGroupsWithAccountKeys(t *testing.T) {
+	storage := stores.TestStores("")
+	dir := t.TempDir()
+	t.Setenv("CERTIFICATES", "a.com@"+filepath.Join(dir, "a.key")+";b.com@"+filepath.Join(dir, "b.key")+";c.com")
+	t.Setenv("ACCOUNT_EMAIL", "support@example.com")
+	t.Setenv("ACCOUNT_KEY_FILE", filepath.Join(dir, "account.key"))
+	t.Setenv("ACCOUNT_URI", "https://acme.example.com/acct/1")
+	t.Setenv("DNS_AUTH_TOKEN", "XXXXX")
+	config, err := configuration.NewUserConfig(&storage)
+	if err != nil {
+		t.Fatalf(err.Error())
+	}
+	keys := map[string]crypto.PrivateKey{}
+	uris := map[string]string{}
+	mutex := sync.Mutex{}
+	err = obtainGroups(config, func(config *configuration.UserConfig) error {
+		mutex.Lock()
+		defer mutex.Unlock()
+		keys[config.Filename] = config.Key
+		uris[config.Filename] = config.AccountURI
+		return nil
+	})
+	if err != nil {
+		t.Fatalf(err.Error())
+	}
+	type equaler interface {
+		Equal(crypto.PrivateKey) bool
+	}
+	if keys["a.com"].(equaler).Equal(keys["b.com"]) {
+		t.Errorf("Expected groups a.com and b.com to use different account keys")
+	}
+	if !keys["c.com"].(equaler).Equal(config.Key) || keys["a.com"].(equaler).Equal(config.Key) {
+		t.Errorf("Expected only group c.com to use shared account key")
+	}
+	if uris["a.com"] != "" || uris["c.com"] != "https://acme.example.com/acct/1" {
+		t.Errorf("Bad account URIs: %v", uris)
+	}
+	for _, name := range []string{"a.key", "b.key"} {
+		if _, err := os.Stat(filepath.Join(dir, name)); err != nil {
+			t.Errorf("Expected account key %s to be created: %s", name, err)
+		}
+	}
+}
+
+// Test that groups are obtained sequentially by default
+func TestObtainGroupsSequential(t *testing.T) {
+	provider := &fakeProvider{records: map[string]bool{}}
+	config := &configuration.UserConfig{
+		Concurrency: 1,
+		Certificates: []configuration.CertificateGroup{
+			{Domains: []string{"a.com"}, Filename: "a.com"},
+			{Domains: []string{"b.com"}, Filename: "b.com"},
+		},
+	}
+	err := obtainGroups(config, provider.obtain(t, ""))
+	if err != nil {
+		t.Fatalf(err.Error())
+	}
+	if provider.maximum != 1 {
+		t.Errorf("Expected groups to be obtained sequentially")
+	}
+}
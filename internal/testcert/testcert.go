@@ -0,0 +1,106 @@
+// Package testcert generates certificates used as test fixtures.
+package testcert
+
+import (
+	"crypto"
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"encoding/pem"
+	"math/big"
+	"net"
+	"testing"
+	"time"
+)
+
+// Options of a test certificate. Zero values are replaced with defaults.
+type Options struct {
+	// Subject common name, first name is used by default
+	CommonName string
+	// DNS names, names which are IP addresses are included as IP address SANs
+	Names []string
+	// Serial number, 1 by default
+	Serial int64
+	// Validity period, from an hour ago to 90 days from now by default
+	NotBefore time.Time
+	NotAfter  time.Time
+	// Authority key identifier, used to identify certificate in ARI requests
+	AuthorityKeyID []byte
+	// Key certified by certificate, an ECDSA P-256 key is generated by default
+	Key crypto.Signer
+	// When set, certificate is signed by a generated issuer with this common name,
+	// else certificate is self-signed
+	IssuerCN string
+}
+
+// Generate an ECDSA P-256 key
+func newKey(t testing.TB) crypto.Signer {
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if err != nil {
+		t.Fatalf(err.Error())
+	}
+	return key
+}
+
+// Generate a parsed certificate according to options
+func New(t testing.TB, opts Options) *x509.Certificate {
+	template := &x509.Certificate{
+		SerialNumber:   big.NewInt(opts.Serial),
+		Subject:        pkix.Name{CommonName: opts.CommonName},
+		AuthorityKeyId: opts.AuthorityKeyID,
+		NotBefore:      opts.NotBefore,
+		NotAfter:       opts.NotAfter,
+	}
+	if opts.Serial == 0 {
+		template.SerialNumber = big.NewInt(1)
+	}
+	if template.Subject.CommonName == "" && len(opts.Names) > 0 {
+		template.Subject.CommonName = opts.Names[0]
+	}
+	if template.NotBefore.IsZero() {
+		template.NotBefore = time.Now().Add(-time.Hour)
+	}
+	if template.NotAfter.IsZero() {
+		template.NotAfter = time.Now().Add(90 * 24 * time.Hour)
+	}
+	for _, name := range opts.Names {
+		if ip := net.ParseIP(name); ip != nil {
+			template.IPAddresses = append(template.IPAddresses, ip)
+		} else {
+			template.DNSNames = append(template.DNSNames, name)
+		}
+	}
+	key := opts.Key
+	if key == nil {
+		key = newKey(t)
+	}
+	// Self-signed by default
+	issuer, issuerKey := template, key
+	if opts.IssuerCN != "" {
+		issuerKey = newKey(t)
+		issuer = &x509.Certificate{
+			SerialNumber:          big.NewInt(1),
+			Subject:               pkix.Name{CommonName: opts.IssuerCN},
+			NotBefore:             time.Now().Add(-time.Hour),
+			NotAfter:              time.Now().Add(24 * time.Hour),
+			IsCA:                  true,
+			BasicConstraintsValid: true,
+		}
+	}
+	der, err := x509.CreateCertificate(rand.Reader, template, issuer, key.Public(), issuerKey)
+	if err != nil {
+		t.Fatalf(err.Error())
+	}
+	cert, err := x509.ParseCertificate(der)
+	if err != nil {
+		t.Fatalf(err.Error())
+	}
+	return cert
+}
+
+// Encode certificate as PEM
+func PEM(cert *x509.Certificate) []byte {
+	return pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: cert.Raw})
+}
@@ -1,42 +1,169 @@
 package main
 
 import (
+	"errors"
+	"fmt"
 	"log"
 	"os"
-	"path/filepath"
+	"time"
 
 	"github.com/charbonnierg/letsgo/client"
 	"github.com/charbonnierg/letsgo/configuration"
-	"github.com/charbonnierg/letsgo/stores"
+	"github.com/charbonnierg/letsgo/constants"
+	"github.com/charbonnierg/letsgo/notify"
+	"github.com/charbonnierg/letsgo/output"
+	"github.com/charbonnierg/letsgo/storage"
+	"github.com/go-acme/lego/v4/certificate"
 )
 
-func main() {
-	// Create stores
-	stores := stores.DefaultStores()
-	// Generate config for user
-	config, err := configuration.NewUserConfig(&stores)
+// Print result as JSON when enabled
+func report(config *configuration.UserConfig, files []string, changed []string, cert []byte, renewed bool, skipped bool) error {
+	if !config.OutputJSON {
+		return nil
+	}
+	result, err := output.NewResult(config.Domains, config.Filename, files, cert)
+	if err != nil {
+		return err
+	}
+	if changed != nil {
+		result.Changed = changed
+	}
+	result.Renewed = renewed
+	result.Skipped = skipped
+	return output.PrintResult(result)
+}
+
+// Request or renew certificate and write certificate files
+func obtain(config *configuration.UserConfig) error {
+	// Load previous certificate
+	previous, err := output.LoadCertificates(config.OutputDirectory, config.Filename)
 	if err != nil {
-		log.Fatal(err)
+		return err
+	}
+	// Keep previous certificate when it is not due for renewal
+	if previous != nil && !client.NeedsRenewal(*config, *previous) {
+		log.Printf("Certificate %s is not due for renewal", config.Filename)
+		return report(config, nil, nil, previous.Certificate, false, true)
 	}
 	// Generate certificate
-	resource, err := client.RequestCertificate(*config)
+	var resource *certificate.Resource
+	if previous != nil {
+		resource, err = client.RenewCertificate(*config, *previous)
+	} else {
+		resource, err = client.RequestCertificate(*config)
+	}
 	if err != nil {
-		log.Fatal(err)
+		return err
 	}
-	// Write certificate to file
-	certPath := filepath.Join(config.OutputDirectory, config.Filename+".crt")
-	keyPath := filepath.Join(config.OutputDirectory, config.Filename+".key")
-	issuerPath := filepath.Join(config.OutputDirectory, config.Filename+".issuer.crt")
-	err = os.WriteFile(certPath, resource.Certificate, 0o600)
+	// Check that certificate holds all requested domains before writing it
+	err = client.VerifyCertificate(*config, resource.Certificate)
 	if err != nil {
-		log.Fatal(err)
+		return err
+	}
+	// Write certificate to file
+	outputOptions := output.OutputOptions{
+		Metadata:      config.OutputMetadata,
+		KeyPassphrase: config.KeyPassphrase,
+		SkipIssuer:    !config.OutputIssuer,
+		DER:           config.OutputDER,
+		P7B:           config.OutputP7B,
+		OnlyChanged:   config.OutputOnlyChanged,
+		Layout:        config.OutputLayout,
 	}
-	err = os.WriteFile(keyPath, resource.PrivateKey, 0o600)
+	files, changed, err := output.WriteCertificates(config.OutputDirectory, config.Filename, resource, outputOptions)
 	if err != nil {
-		log.Fatal(err)
+		return err
 	}
-	err = os.WriteFile(issuerPath, resource.IssuerCertificate, 0o600)
+	// Archive certificate under a name expanded from issued certificate
+	if config.FilenameTemplate != "" {
+		archive, err := output.ExpandFilename(config.FilenameTemplate, resource.Certificate)
+		if err != nil {
+			return err
+		}
+		if archive != config.Filename {
+			archived, archivedChanged, err := output.WriteCertificates(config.OutputDirectory, archive, resource, outputOptions)
+			if err != nil {
+				return err
+			}
+			files = append(files, archived...)
+			changed = append(changed, archivedChanged...)
+		}
+	}
+	// Write every chain offered by CA server
+	if config.OutputAllChains {
+		chains, err := client.FetchAllChains(*config, resource.CertURL)
+		if err != nil {
+			return err
+		}
+		chainFiles, chainChanged, err := output.WriteChains(config.OutputDirectory, config.Filename, chains, config.OutputOnlyChanged)
+		if err != nil {
+			return err
+		}
+		files = append(files, chainFiles...)
+		changed = append(changed, chainChanged...)
+	}
+	// Hand written files over to configured owner
+	err = output.ChownFiles(files, config.OutputUID, config.OutputGID)
 	if err != nil {
-		log.Fatal(err)
+		return err
 	}
+	return report(config, files, changed, resource.Certificate, previous != nil, false)
+}
+
+// Notify webhook of the outcome of obtaining each certificate
+func withNotifier(notifier *notify.Notifier, obtain func(*configuration.UserConfig) error) func(*configuration.UserConfig) error {
+	return func(config *configuration.UserConfig) error {
+		err := obtain(config)
+		notifier.Notify(config.DisplayDomains, err)
+		return err
+	}
+}
+
+// Request or renew all certificate groups, then remove stale certificate files when enabled
+func run(config *configuration.UserConfig) error {
+	task := obtain
+	if config.NotifyWebhookURL != "" {
+		task = withNotifier(notify.NewNotifier(config.NotifyWebhookURL, config.NotifyAlways), obtain)
+	}
+	err := obtainGroups(config, task)
+	if err != nil || !config.Prune {
+		return err
+	}
+	keep := []string{}
+	for _, group := range config.Certificates {
+		keep = append(keep, group.Filename)
+	}
+	removed, err := storage.PruneCertificates(config.OutputDirectory, keep)
+	for _, path := range removed {
+		log.Printf("Removed stale certificate file %s", path)
+	}
+	return err
+}
+
+// Return expiry of the earliest expiring certificate found in output directory
+func earliestExpiry(config *configuration.UserConfig) (time.Time, error) {
+	var earliest time.Time
+	for _, group := range config.Certificates {
+		resource, err := output.LoadCertificates(config.OutputDirectory, group.Filename)
+		if err != nil {
+			return earliest, err
+		}
+		if resource == nil {
+			return earliest, errors.New(fmt.Sprintf("Certificate %s not found", group.Filename))
+		}
+		metadata, err := output.NewMetadata(resource.Certificate)
+		if err != nil {
+			return earliest, err
+		}
+		if earliest.IsZero() || metadata.NotAfter.Before(earliest) {
+			earliest = metadata.NotAfter
+		}
+	}
+	return earliest, nil
+}
+
+func main() {
+	// Warn about misspelled environment variables
+	configuration.WarnUnknownVariables(os.Environ())
+	os.Exit(execute(os.Args[1:], os.Getenv(constants.MODE), commands, os.Stdout))
 }
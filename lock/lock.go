@@ -0,0 +1,66 @@
+package lock
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"strconv"
+	"time"
+)
+
+// Interval between attempts to acquire a lock held by another instance
+const pollInterval = 100 * time.Millisecond
+
+// Error returned when lock is held by another instance
+var ErrLocked = errors.New("lock is held by another instance")
+
+// Exclusive lock backed by a lock file.
+//
+// Lock is held by an open file handle, so operating system releases it
+// when process exits, even when it is killed or crashes. Lock file itself
+// is left in place.
+type Lock struct {
+	file *os.File
+}
+
+// Acquire lock of lock file.
+//
+// When lock is held by another instance, acquisition is attempted
+// again until timeout expires. A zero timeout fails immediately.
+func Acquire(path string, timeout time.Duration) (*Lock, error) {
+	deadline := time.Now().Add(timeout)
+	for {
+		file, err := lockFile(path)
+		if err == nil {
+			// Record process ID to help operators identify lock holder
+			err = writePID(file)
+			if err != nil {
+				file.Close()
+				return nil, err
+			}
+			return &Lock{file: file}, nil
+		}
+		if !errors.Is(err, ErrLocked) {
+			return nil, err
+		}
+		if !time.Now().Before(deadline) {
+			return nil, fmt.Errorf("%w: %s", ErrLocked, path)
+		}
+		time.Sleep(pollInterval)
+	}
+}
+
+// Replace content of lock file with current process ID
+func writePID(file *os.File) error {
+	err := file.Truncate(0)
+	if err != nil {
+		return err
+	}
+	_, err = file.WriteAt([]byte(strconv.Itoa(os.Getpid())), 0)
+	return err
+}
+
+// Release lock by closing lock file
+func (l *Lock) Release() error {
+	return l.file.Close()
+}
@@ -0,0 +1,105 @@
+package lock
+
+import (
+	"errors"
+	"os"
+	"path/filepath"
+	"strconv"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+// Test that only one of two contending goroutines acquires the lock
+func TestAcquireContention(t *testing.T) {
+	path := filepath.Join(t.TempDir(), ".letsgo.lock")
+	var acquired int32
+	var locked int32
+	var wg sync.WaitGroup
+	start := make(chan struct{})
+	locks := make(chan *Lock, 2)
+	for i := 0; i < 2; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			<-start
+			lock, err := Acquire(path, 0)
+			if err == nil {
+				atomic.AddInt32(&acquired, 1)
+				locks <- lock
+			} else if errors.Is(err, ErrLocked) {
+				atomic.AddInt32(&locked, 1)
+			} else {
+				t.Errorf(err.Error())
+			}
+		}()
+	}
+	close(start)
+	wg.Wait()
+	if acquired != 1 || locked != 1 {
+		t.Fatalf("Expected exactly one goroutine to acquire lock. Acquired: %d. Locked: %d", acquired, locked)
+	}
+	lock := <-locks
+	err := lock.Release()
+	if err != nil {
+		t.Fatalf(err.Error())
+	}
+	// Lock can be acquired again once released
+	lock, err = Acquire(path, 0)
+	if err != nil {
+		t.Fatalf(err.Error())
+	}
+	lock.Release()
+}
+
+// Test that acquisition waits for lock to be released until timeout
+func TestAcquireTimeout(t *testing.T) {
+	path := filepath.Join(t.TempDir(), ".letsgo.lock")
+	first, err := Acquire(path, 0)
+	if err != nil {
+		t.Fatalf(err.Error())
+	}
+	done := make(chan error)
+	go func() {
+		second, err := Acquire(path, 5*time.Second)
+		if err == nil {
+			second.Release()
+		}
+		done <- err
+	}()
+	time.Sleep(200 * time.Millisecond)
+	first.Release()
+	err = <-done
+	if err != nil {
+		t.Errorf("Expected lock to be acquired once released. Got: %s", err.Error())
+	}
+	first, _ = Acquire(path, 0)
+	defer first.Release()
+	_, err = Acquire(path, 200*time.Millisecond)
+	if !errors.Is(err, ErrLocked) {
+		t.Errorf("Bad error. Want: %s. Got: %v", ErrLocked, err)
+	}
+}
+
+// Test that lock file left behind by a killed instance does not hold lock
+func TestAcquireLeftoverLockFile(t *testing.T) {
+	path := filepath.Join(t.TempDir(), ".letsgo.lock")
+	err := os.WriteFile(path, []byte("999999"), 0o600)
+	if err != nil {
+		t.Fatalf(err.Error())
+	}
+	lock, err := Acquire(path, 0)
+	if err != nil {
+		t.Fatalf("Expected leftover lock file to be acquired. Got: %s", err.Error())
+	}
+	defer lock.Release()
+	content, err := os.ReadFile(path)
+	if err != nil {
+		t.Fatalf(err.Error())
+	}
+	want := strconv.Itoa(os.Getpid())
+	if string(content) != want {
+		t.Errorf("Bad lock file content. Want: %s. Got: %s", want, content)
+	}
+}
@@ -0,0 +1,29 @@
+//go:build windows
+
+package lock
+
+import (
+	"os"
+	"syscall"
+)
+
+// Windows error returned when file is already opened without sharing
+const errorSharingViolation syscall.Errno = 32
+
+// Open lock file without sharing it.
+//
+// ErrLocked is returned when file is already opened by another handle.
+func lockFile(path string) (*os.File, error) {
+	name, err := syscall.UTF16PtrFromString(path)
+	if err != nil {
+		return nil, err
+	}
+	handle, err := syscall.CreateFile(name, syscall.GENERIC_READ|syscall.GENERIC_WRITE, 0, nil, syscall.OPEN_ALWAYS, syscall.FILE_ATTRIBUTE_NORMAL, 0)
+	if err == errorSharingViolation {
+		return nil, ErrLocked
+	}
+	if err != nil {
+		return nil, err
+	}
+	return os.NewFile(uintptr(handle), path), nil
+}
@@ -0,0 +1,85 @@
+package notify
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"log"
+	"net/http"
+	"strings"
+	"time"
+)
+
+// Timeout of a single notification request
+const notifyTimeout = 10 * time.Second
+
+// Message card accepted by both Slack and Microsoft Teams incoming webhooks.
+//
+// Slack only reads `text` field, other fields are used by Teams.
+type message struct {
+	Type       string `json:"@type"`
+	Context    string `json:"@context"`
+	Summary    string `json:"summary"`
+	ThemeColor string `json:"themeColor"`
+	Title      string `json:"title"`
+	Text       string `json:"text"`
+}
+
+// Notifier posting a message to an incoming webhook
+type Notifier struct {
+	// URL of incoming webhook
+	URL string
+	// Notify successes as well as failures
+	Always bool
+	// HTTP client used to post messages
+	HTTPClient *http.Client
+}
+
+// Create notifier posting to incoming webhook
+func NewNotifier(url string, always bool) *Notifier {
+	return &Notifier{URL: url, Always: always, HTTPClient: &http.Client{Timeout: notifyTimeout}}
+}
+
+// Build message describing outcome of obtaining certificate for domains
+func newMessage(domains []string, err error) message {
+	msg := message{
+		Type:    "MessageCard",
+		Context: "https://schema.org/extensions",
+	}
+	names := strings.Join(domains, ", ")
+	if err != nil {
+		msg.Title = fmt.Sprintf("Certificate renewal failed for %s", names)
+		msg.Text = fmt.Sprintf("letsgo failed to obtain certificate for %s: %s", names, err.Error())
+		msg.ThemeColor = "D70000"
+	} else {
+		msg.Title = fmt.Sprintf("Certificate renewal succeeded for %s", names)
+		msg.Text = fmt.Sprintf("letsgo obtained certificate for %s", names)
+		msg.ThemeColor = "2EB886"
+	}
+	msg.Summary = msg.Title
+	return msg
+}
+
+// Post message describing outcome of obtaining certificate for domains.
+//
+// Successes are only notified when Always is set. Notification
+// failures are logged and never returned to the caller.
+func (n *Notifier) Notify(domains []string, err error) {
+	if err == nil && !n.Always {
+		return
+	}
+	body, jsonErr := json.Marshal(newMessage(domains, err))
+	if jsonErr != nil {
+		log.Printf("Failed to encode notification: %s", jsonErr.Error())
+		return
+	}
+	response, postErr := n.HTTPClient.Post(n.URL, "application/json", bytes.NewReader(body))
+	if postErr != nil {
+		log.Printf("Failed to send notification: %s", postErr.Error())
+		return
+	}
+	response.Body.Close()
+	if response.StatusCode < 200 || response.StatusCode >= 300 {
+		log.Printf("Failed to send notification: webhook returned %s", response.Status)
+	}
+}
@@ -0,0 +1,63 @@
+package notify
+
+import (
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+)
+
+// Test that a message card describing the failure is posted
+func TestNotifyFailure(t *testing.T) {
+	received := []message{}
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if ct := r.Header.Get("Content-Type"); ct != "application/json" {
+			t.Errorf("Bad content type. Want: application/json. Got: %s", ct)
+		}
+		msg := message{}
+		if err := json.NewDecoder(r.Body).Decode(&msg); err != nil {
+			t.Errorf(err.Error())
+		}
+		received = append(received, msg)
+		w.WriteHeader(http.StatusInternalServerError)
+	}))
+	defer server.Close()
+
+	notifier := NewNotifier(server.URL, false)
+	notifier.Notify([]string{"example.com", "*.example.com"}, nil)
+	if len(received) != 0 {
+		t.Fatalf("Expected success not to be notified but got: %+v", received)
+	}
+	// Non-2xx responses are only logged
+	notifier.Notify([]string{"example.com", "*.example.com"}, errors.New("rate limited"))
+	if len(received) != 1 {
+		t.Fatalf("Expected 1 notification but got: %d", len(received))
+	}
+	msg := received[0]
+	if msg.Type != "MessageCard" {
+		t.Errorf("Bad message type. Want: MessageCard. Got: %s", msg.Type)
+	}
+	if msg.Title != "Certificate renewal failed for example.com, *.example.com" {
+		t.Errorf("Bad title: %s", msg.Title)
+	}
+	if !strings.Contains(msg.Text, "rate limited") {
+		t.Errorf("Expected text to contain error but got: %s", msg.Text)
+	}
+}
+
+// Test that successes are notified when always is set
+func TestNotifyAlways(t *testing.T) {
+	received := 0
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		received++
+	}))
+	defer server.Close()
+
+	notifier := NewNotifier(server.URL, true)
+	notifier.Notify([]string{"example.com"}, nil)
+	if received != 1 {
+		t.Errorf("Expected 1 notification but got: %d", received)
+	}
+}
@@ -0,0 +1,74 @@
+package output
+
+import (
+	"crypto/sha256"
+	"os"
+)
+
+// File to be written with its content
+type pendingFile struct {
+	path    string
+	content []byte
+}
+
+// Write file content. Replaced in tests to inject failures.
+var writeFile = os.WriteFile
+
+// Check whether content differs from content of existing file, using
+// content hashes. Missing or unreadable files are considered changed.
+func contentChanged(path string, content []byte) bool {
+	existing, err := os.ReadFile(path)
+	if err != nil {
+		return true
+	}
+	return sha256.Sum256(existing) != sha256.Sum256(content)
+}
+
+// Select files to write and paths of files whose content changed.
+//
+// When onlyChanged is set, files whose content is unchanged are not
+// rewritten so that their modification time is preserved.
+func selectChanged(files []pendingFile, onlyChanged bool) ([]pendingFile, []string) {
+	selected := []pendingFile{}
+	changed := []string{}
+	for _, file := range files {
+		if contentChanged(file.path, file.content) {
+			changed = append(changed, file.path)
+		} else if onlyChanged {
+			continue
+		}
+		selected = append(selected, file)
+	}
+	return selected, changed
+}
+
+// Write all files to temporary names, then rename them into place once all
+// writes succeeded, so that a failure never leaves a mix of new and previous files.
+//
+// Temporary files are removed on failure and existing files are left untouched.
+func writeAll(files []pendingFile) error {
+	temps := []string{}
+	removeTemps := func() {
+		for _, temp := range temps {
+			os.Remove(temp)
+		}
+	}
+	for _, file := range files {
+		temp := file.path + ".tmp"
+		err := writeFile(temp, file.content, 0o600)
+		if err != nil {
+			os.Remove(temp)
+			removeTemps()
+			return err
+		}
+		temps = append(temps, temp)
+	}
+	for i, file := range files {
+		err := os.Rename(temps[i], file.path)
+		if err != nil {
+			removeTemps()
+			return err
+		}
+	}
+	return nil
+}
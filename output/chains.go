@@ -0,0 +1,47 @@
+package output
+
+import (
+	"path/filepath"
+	"regexp"
+	"sort"
+)
+
+// Match characters which are not allowed in chain filenames
+var unsafeFilenameCharacters = regexp.MustCompile(`[^A-Za-z0-9_-]+`)
+
+// Get filename of chain issued by root certificate with given common name.
+//
+// Root common name is sanitized so that it cannot escape output directory.
+func chainFilename(alias string, rootCN string) string {
+	name := unsafeFilenameCharacters.ReplaceAllString(rootCN, "_")
+	if name == "" || name == "_" {
+		name = "unknown"
+	}
+	return alias + ".chain." + name + ".crt"
+}
+
+// Write certificate chains indexed by common name of their root certificate
+// into directory and return paths of chain files, along with paths of files
+// whose content changed. Unchanged files are not rewritten when onlyChanged is set.
+//
+// Files are named `<alias>.chain.<root-cn>.crt`.
+func WriteChains(dir string, alias string, chains map[string][]byte, onlyChanged bool) ([]string, []string, error) {
+	roots := []string{}
+	for root := range chains {
+		roots = append(roots, root)
+	}
+	sort.Strings(roots)
+	files := []pendingFile{}
+	paths := []string{}
+	for _, root := range roots {
+		path := filepath.Join(dir, chainFilename(alias, root))
+		files = append(files, pendingFile{path: path, content: chains[root]})
+		paths = append(paths, path)
+	}
+	selected, changed := selectChanged(files, onlyChanged)
+	err := writeAll(selected)
+	if err != nil {
+		return nil, nil, err
+	}
+	return paths, changed, nil
+}
@@ -0,0 +1,17 @@
+package output
+
+import "testing"
+
+// Test that root common name cannot escape output directory
+func TestChainFilename(t *testing.T) {
+	for rootCN, want := range map[string]string{
+		"ISRG Root X1":   "example.com.chain.ISRG_Root_X1.crt",
+		"../../etc/cert": "example.com.chain._etc_cert.crt",
+		"":               "example.com.chain.unknown.crt",
+		"..":             "example.com.chain.unknown.crt",
+	} {
+		if got := chainFilename("example.com", rootCN); got != want {
+			t.Errorf("Bad filename for %q. Want: %s. Got: %s", rootCN, want, got)
+		}
+	}
+}
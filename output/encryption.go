@@ -0,0 +1,42 @@
+package output
+
+import (
+	"crypto"
+	"encoding/pem"
+	"errors"
+
+	"github.com/go-acme/lego/v4/certcrypto"
+	"github.com/youmark/pkcs8"
+)
+
+// Encrypt PEM-encoded private key using passphrase.
+//
+// Key is encoded as PKCS#8 and encrypted using PBES2
+// (PBKDF2 with HMAC-SHA256 and AES-256-CBC).
+func encryptPrivateKey(key []byte, passphrase string) ([]byte, error) {
+	privateKey, err := certcrypto.ParsePEMPrivateKey(key)
+	if err != nil {
+		return nil, err
+	}
+	der, err := pkcs8.MarshalPrivateKey(privateKey, []byte(passphrase), nil)
+	if err != nil {
+		return nil, err
+	}
+	return pem.EncodeToMemory(&pem.Block{Type: "ENCRYPTED PRIVATE KEY", Bytes: der}), nil
+}
+
+// Parse PEM-encoded private key, decrypting it using passphrase
+// when it was written as an encrypted PKCS#8 PEM.
+func ParsePrivateKey(key []byte, passphrase string) (crypto.PrivateKey, error) {
+	block, _ := pem.Decode(key)
+	if block == nil {
+		return nil, errors.New("Invalid private key: no PEM data found")
+	}
+	if block.Type != "ENCRYPTED PRIVATE KEY" {
+		return certcrypto.ParsePEMPrivateKey(key)
+	}
+	if passphrase == "" {
+		return nil, errors.New("Invalid private key: key is encrypted but no passphrase was provided")
+	}
+	return pkcs8.ParsePKCS8PrivateKey(block.Bytes, []byte(passphrase))
+}
@@ -0,0 +1,69 @@
+package output
+
+import (
+	"crypto/ecdsa"
+	"encoding/pem"
+	"testing"
+
+	"github.com/go-acme/lego/v4/certcrypto"
+	"github.com/youmark/pkcs8"
+)
+
+// Test that encrypted private key can be decrypted using passphrase
+func TestEncryptPrivateKey(t *testing.T) {
+	privateKey, err := certcrypto.GeneratePrivateKey(certcrypto.EC256)
+	if err != nil {
+		t.Fatalf(err.Error())
+	}
+	passphrase := "correct horse battery staple"
+	encrypted, err := encryptPrivateKey(certcrypto.PEMEncode(privateKey), passphrase)
+	if err != nil {
+		t.Fatalf(err.Error())
+	}
+	block, _ := pem.Decode(encrypted)
+	if block == nil || block.Type != "ENCRYPTED PRIVATE KEY" {
+		t.Fatalf("Bad PEM block: %s", encrypted)
+	}
+	_, err = pkcs8.ParsePKCS8PrivateKey(block.Bytes, []byte("wrong passphrase"))
+	if err == nil {
+		t.Errorf("Expected error when decrypting with wrong passphrase")
+	}
+	decrypted, err := pkcs8.ParsePKCS8PrivateKey(block.Bytes, []byte(passphrase))
+	if err != nil {
+		t.Fatalf(err.Error())
+	}
+	if !privateKey.(*ecdsa.PrivateKey).Equal(decrypted) {
+		t.Errorf("Decrypted private key does not match original private key")
+	}
+}
+
+// Test that private key is parsed whether it is encrypted or not
+func TestParsePrivateKey(t *testing.T) {
+	privateKey, err := certcrypto.GeneratePrivateKey(certcrypto.EC256)
+	if err != nil {
+		t.Fatalf(err.Error())
+	}
+	parsed, err := ParsePrivateKey(certcrypto.PEMEncode(privateKey), "")
+	if err != nil {
+		t.Fatalf(err.Error())
+	}
+	if !privateKey.(*ecdsa.PrivateKey).Equal(parsed) {
+		t.Errorf("Parsed private key does not match original private key")
+	}
+	passphrase := "correct horse battery staple"
+	encrypted, err := encryptPrivateKey(certcrypto.PEMEncode(privateKey), passphrase)
+	if err != nil {
+		t.Fatalf(err.Error())
+	}
+	parsed, err = ParsePrivateKey(encrypted, passphrase)
+	if err != nil {
+		t.Fatalf(err.Error())
+	}
+	if !privateKey.(*ecdsa.PrivateKey).Equal(parsed) {
+		t.Errorf("Decrypted private key does not match original private key")
+	}
+	_, err = ParsePrivateKey(encrypted, "")
+	if err == nil {
+		t.Errorf("Expected error when parsing encrypted key without passphrase")
+	}
+}
@@ -0,0 +1,57 @@
+package output
+
+import (
+	"errors"
+	"fmt"
+	"regexp"
+	"strings"
+
+	"github.com/charbonnierg/letsgo/certinfo"
+)
+
+// Match placeholders of filename templates
+var filenamePlaceholder = regexp.MustCompile(`\{[^{}]*\}`)
+
+// Placeholders supported in filename templates
+var filenamePlaceholders = []string{"{domain}", "{date}", "{serial}"}
+
+// Check that filename template only holds supported placeholders
+func CheckFilenameTemplate(template string) error {
+	for _, placeholder := range filenamePlaceholder.FindAllString(template, -1) {
+		supported := false
+		for _, name := range filenamePlaceholders {
+			supported = supported || placeholder == name
+		}
+		if !supported {
+			return errors.New(fmt.Sprintf("Unsupported placeholder %s. Allowed placeholders are %s.", placeholder, strings.Join(filenamePlaceholders, ", ")))
+		}
+	}
+	return nil
+}
+
+// Expand filename template using PEM-encoded certificate or bundle.
+//
+// Supported placeholders are:
+//   - {domain}: first domain of certificate, with wildcard replaced by "_"
+//   - {date}: issuance date of certificate (YYYY-MM-DD)
+//   - {serial}: serial number of certificate in hexadecimal
+func ExpandFilename(template string, certificate []byte) (string, error) {
+	leaf, err := certinfo.ParseLeaf(certificate)
+	if err != nil {
+		return "", err
+	}
+	domain := ""
+	if len(leaf.DNSNames) > 0 {
+		domain = strings.ReplaceAll(leaf.DNSNames[0], "*", "_")
+	}
+	name := strings.NewReplacer(
+		"{domain}", domain,
+		"{date}", leaf.NotBefore.UTC().Format("2006-01-02"),
+		"{serial}", leaf.SerialNumber.Text(16),
+	).Replace(template)
+	// Expanded name must stay within output directory
+	if name == "" || name == "." || name == ".." || strings.ContainsAny(name, "/\\\x00") {
+		return "", errors.New(fmt.Sprintf("Filename template %s expands to an unsafe filename: %q", template, name))
+	}
+	return name, nil
+}
@@ -0,0 +1,50 @@
+package output
+
+import (
+	"testing"
+)
+
+// Test that each placeholder is expanded from issued certificate
+func TestExpandFilename(t *testing.T) {
+	certificate := newTestCertificate(t, "*.example.com", "example.com")
+	for template, want := range map[string]string{
+		"{domain}":                 "_.example.com",
+		"{date}":                   "2022-11-01",
+		"{serial}":                 "1234abcd",
+		"{domain}.{date}.{serial}": "_.example.com.2022-11-01.1234abcd",
+		"archive":                  "archive",
+	} {
+		got, err := ExpandFilename(template, certificate)
+		if err != nil {
+			t.Errorf(err.Error())
+			continue
+		}
+		if got != want {
+			t.Errorf("Bad filename for %s. Want: %s. Got: %s", template, want, got)
+		}
+	}
+}
+
+// Test that expanded filenames cannot escape output directory
+func TestExpandFilenameUnsafe(t *testing.T) {
+	certificate := newTestCertificate(t, "example.com")
+	for _, template := range []string{"../{domain}", "{domain}/{serial}", ".."} {
+		_, err := ExpandFilename(template, certificate)
+		if err == nil {
+			t.Errorf("Expected error for unsafe template %s", template)
+		}
+	}
+}
+
+// Test that unsupported placeholders are rejected
+func TestCheckFilenameTemplate(t *testing.T) {
+	err := CheckFilenameTemplate("{domain}.{date}.{serial}")
+	if err != nil {
+		t.Errorf(err.Error())
+	}
+	err = CheckFilenameTemplate("{domain}.{time}")
+	err_want := "Unsupported placeholder {time}. Allowed placeholders are {domain}, {date}, {serial}."
+	if err == nil || err.Error() != err_want {
+		t.Errorf("Bad error. Want: %s. Got: %v", err_want, err)
+	}
+}
@@ -0,0 +1,86 @@
+package output
+
+import (
+	"encoding/pem"
+	"errors"
+	"fmt"
+	"path/filepath"
+
+	"github.com/charbonnierg/letsgo/constants"
+)
+
+// PEM blocks which can be written into a layout file
+const (
+	leafBlocks  = "leaf"
+	chainBlocks = "chain"
+	keyBlocks   = "key"
+)
+
+// File written by a layout, holding blocks in the given order
+type layoutFile struct {
+	suffix string
+	blocks []string
+}
+
+// Files expected by each server, written in addition to standard files.
+//
+// Standard files are always written since they are used for renewal.
+var layouts = map[string][]layoutFile{
+	constants.OUTPUT_LAYOUT_STANDARD: {},
+	// ssl_certificate expects leaf followed by chain, key is read from <alias>.key
+	constants.OUTPUT_LAYOUT_NGINX: {
+		{suffix: ".fullchain.pem", blocks: []string{leafBlocks, chainBlocks}},
+	},
+	// SSLCertificateFile and SSLCertificateChainFile expect separate files
+	constants.OUTPUT_LAYOUT_APACHE: {
+		{suffix: ".cert.pem", blocks: []string{leafBlocks}},
+		{suffix: ".chain.pem", blocks: []string{chainBlocks}},
+	},
+	// crt expects leaf, chain and key in a single file
+	constants.OUTPUT_LAYOUT_HAPROXY: {
+		{suffix: ".combined.pem", blocks: []string{leafBlocks, chainBlocks, keyBlocks}},
+	},
+	// certFile and keyFile of file provider
+	constants.OUTPUT_LAYOUT_TRAEFIK: {
+		{suffix: ".fullchain.pem", blocks: []string{leafBlocks, chainBlocks}},
+		{suffix: ".key.pem", blocks: []string{keyBlocks}},
+	},
+}
+
+// Generate files of layout.
+//
+// Leaf certificate is the first certificate of the bundle, chain holds
+// the remaining certificates of bundle and issuer certificate.
+func layoutFiles(dir string, alias string, layout string, certificate []byte, issuer []byte, privateKey []byte) ([]pendingFile, error) {
+	if layout == "" {
+		layout = constants.OUTPUT_LAYOUT_STANDARD
+	}
+	definitions, ok := layouts[layout]
+	if !ok {
+		return nil, errors.New(fmt.Sprintf("Unknown output layout: %s", layout))
+	}
+	if len(definitions) == 0 {
+		return nil, nil
+	}
+	certs := pemCertificates(certificate, issuer)
+	if len(certs) == 0 {
+		return nil, errors.New("No certificate found")
+	}
+	contents := map[string][]byte{
+		leafBlocks:  pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: certs[0]}),
+		chainBlocks: {},
+		keyBlocks:   privateKey,
+	}
+	for _, cert := range certs[1:] {
+		contents[chainBlocks] = append(contents[chainBlocks], pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: cert})...)
+	}
+	files := []pendingFile{}
+	for _, definition := range definitions {
+		content := []byte{}
+		for _, blocks := range definition.blocks {
+			content = append(content, contents[blocks]...)
+		}
+		files = append(files, pendingFile{path: filepath.Join(dir, alias+definition.suffix), content: content})
+	}
+	return files, nil
+}
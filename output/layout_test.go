@@ -0,0 +1,89 @@
+package output
+
+import (
+	"crypto/x509"
+	"encoding/pem"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"golang.org/x/exp/slices"
+)
+
+// Describe PEM blocks of file: common name of certificates, type of other blocks
+func blockOrder(t *testing.T, path string) []string {
+	content, err := os.ReadFile(path)
+	if err != nil {
+		t.Fatalf(err.Error())
+	}
+	order := []string{}
+	for {
+		var block *pem.Block
+		block, content = pem.Decode(content)
+		if block == nil {
+			break
+		}
+		if block.Type != "CERTIFICATE" {
+			order = append(order, block.Type)
+			continue
+		}
+		cert, err := x509.ParseCertificate(block.Bytes)
+		if err != nil {
+			t.Fatalf(err.Error())
+		}
+		order = append(order, cert.Subject.CommonName)
+	}
+	return order
+}
+
+// Test that each layout writes the files expected by server, with blocks in the expected order
+func TestWriteCertificatesLayout(t *testing.T) {
+	res := newTestResource(t)
+	// Bundled certificates already hold the issuer, which must not be duplicated
+	res.Certificate = append(res.Certificate, res.IssuerCertificate...)
+	res.PrivateKey = pem.EncodeToMemory(&pem.Block{Type: "EC PRIVATE KEY", Bytes: []byte("private key")})
+	for layout, want := range map[string]map[string][]string{
+		"standard": {},
+		"nginx": {
+			"example.com.fullchain.pem": {"example.com", "issuer"},
+		},
+		"apache": {
+			"example.com.cert.pem":  {"example.com"},
+			"example.com.chain.pem": {"issuer"},
+		},
+		"haproxy": {
+			"example.com.combined.pem": {"example.com", "issuer", "EC PRIVATE KEY"},
+		},
+		"traefik": {
+			"example.com.fullchain.pem": {"example.com", "issuer"},
+			"example.com.key.pem":       {"EC PRIVATE KEY"},
+		},
+	} {
+		dir := t.TempDir()
+		paths, _, err := WriteCertificates(dir, "example.com", res, OutputOptions{Layout: layout})
+		if err != nil {
+			t.Fatalf(err.Error())
+		}
+		if len(paths) != 4+len(want) {
+			t.Errorf("Bad written files for %s layout. Want %d files. Got: %s", layout, 4+len(want), paths)
+		}
+		for name, blocks := range want {
+			if !slices.Contains(paths, filepath.Join(dir, name)) {
+				t.Errorf("Bad written files for %s layout. Missing: %s", layout, name)
+			}
+			got := blockOrder(t, filepath.Join(dir, name))
+			if !slices.Equal(got, blocks) {
+				t.Errorf("Bad blocks of %s for %s layout. Want: %s. Got: %s", name, layout, blocks, got)
+			}
+		}
+	}
+}
+
+// Test that unknown layouts are rejected
+func TestWriteCertificatesUnknownLayout(t *testing.T) {
+	_, _, err := WriteCertificates(t.TempDir(), "example.com", newTestResource(t), OutputOptions{Layout: "iis"})
+	err_want := "Unknown output layout: iis"
+	if err == nil || err.Error() != err_want {
+		t.Errorf("Bad error. Want: %s. Got: %v", err_want, err)
+	}
+}
@@ -0,0 +1,55 @@
+package output
+
+import (
+	"encoding/json"
+	"os"
+	"time"
+
+	"github.com/charbonnierg/letsgo/certinfo"
+)
+
+// Machine-readable summary of an issued certificate
+type Metadata struct {
+	SerialNumber string    `json:"serial_number"`
+	Fingerprint  string    `json:"fingerprint"`
+	Subject      string    `json:"subject"`
+	SANs         []string  `json:"sans"`
+	Issuer       string    `json:"issuer"`
+	NotBefore    time.Time `json:"not_before"`
+	NotAfter     time.Time `json:"not_after"`
+}
+
+// Generate metadata from PEM-encoded certificate or bundle
+func NewMetadata(certificate []byte) (*Metadata, error) {
+	info, err := certinfo.InspectCertificate(certificate)
+	if err != nil {
+		return nil, err
+	}
+	return &Metadata{
+		SerialNumber: info.SerialNumber,
+		Fingerprint:  info.Fingerprint,
+		Subject:      info.Subject,
+		SANs:         append(append([]string{}, info.DNSNames...), info.IPAddresses...),
+		Issuer:       info.Issuer,
+		NotBefore:    info.NotBefore,
+		NotAfter:     info.NotAfter,
+	}, nil
+}
+
+// Write certificate metadata as JSON to file
+func WriteMetadata(path string, certificate []byte) error {
+	content, err := encodeMetadata(certificate)
+	if err != nil {
+		return err
+	}
+	return os.WriteFile(path, content, 0o600)
+}
+
+// Encode metadata of PEM-encoded certificate or bundle as JSON
+func encodeMetadata(certificate []byte) ([]byte, error) {
+	metadata, err := NewMetadata(certificate)
+	if err != nil {
+		return nil, err
+	}
+	return json.MarshalIndent(metadata, "", "  ")
+}
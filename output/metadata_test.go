@@ -0,0 +1,87 @@
+package output
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"encoding/pem"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/charbonnierg/letsgo/internal/testcert"
+	"golang.org/x/exp/slices"
+)
+
+var testNotBefore = time.Date(2022, 11, 1, 0, 0, 0, 0, time.UTC)
+var testNotAfter = time.Date(2023, 1, 30, 0, 0, 0, 0, time.UTC)
+
+// Generate a self-signed PEM-encoded certificate used as fixture.
+//
+// Names which are IP addresses are included as IP address SANs.
+func newTestCertificate(t *testing.T, names ...string) []byte {
+	return testcert.PEM(testcert.New(t, testcert.Options{Names: names, Serial: 0x1234abcd, NotBefore: testNotBefore, NotAfter: testNotAfter}))
+}
+
+// Test that metadata fields match fixture certificate
+func TestWriteMetadata(t *testing.T) {
+	certificate := newTestCertificate(t, "example.com", "*.example.com")
+	path := filepath.Join(t.TempDir(), "example.com.meta.json")
+	err := WriteMetadata(path, certificate)
+	if err != nil {
+		t.Fatalf(err.Error())
+	}
+	content, err := os.ReadFile(path)
+	if err != nil {
+		t.Fatalf(err.Error())
+	}
+	metadata := Metadata{}
+	err = json.Unmarshal(content, &metadata)
+	if err != nil {
+		t.Fatalf(err.Error())
+	}
+	block, _ := pem.Decode(certificate)
+	fingerprint := sha256.Sum256(block.Bytes)
+	if metadata.SerialNumber != "1234abcd" {
+		t.Errorf("Bad serial number. Want: 1234abcd. Got: %s", metadata.SerialNumber)
+	}
+	if metadata.Fingerprint != hex.EncodeToString(fingerprint[:]) {
+		t.Errorf("Bad fingerprint. Want: %s. Got: %s", hex.EncodeToString(fingerprint[:]), metadata.Fingerprint)
+	}
+	if metadata.Subject != "CN=example.com" {
+		t.Errorf("Bad subject. Want: CN=example.com. Got: %s", metadata.Subject)
+	}
+	if metadata.Issuer != "CN=example.com" {
+		t.Errorf("Bad issuer. Want: CN=example.com. Got: %s", metadata.Issuer)
+	}
+	if !slices.Equal(metadata.SANs, []string{"example.com", "*.example.com"}) {
+		t.Errorf("Bad SANs. Got: %s", metadata.SANs)
+	}
+	if !metadata.NotBefore.Equal(testNotBefore) {
+		t.Errorf("Bad not before. Want: %s. Got: %s", testNotBefore, metadata.NotBefore)
+	}
+	if !metadata.NotAfter.Equal(testNotAfter) {
+		t.Errorf("Bad not after. Want: %s. Got: %s", testNotAfter, metadata.NotAfter)
+	}
+}
+
+// Test that IP address SANs are included in metadata
+func TestNewMetadataIPAddresses(t *testing.T) {
+	certificate := newTestCertificate(t, "example.com", "192.0.2.1")
+	metadata, err := NewMetadata(certificate)
+	if err != nil {
+		t.Fatalf(err.Error())
+	}
+	if !slices.Equal(metadata.SANs, []string{"example.com", "192.0.2.1"}) {
+		t.Errorf("Bad SANs. Want: [example.com 192.0.2.1]. Got: %s", metadata.SANs)
+	}
+}
+
+// Test that invalid certificate is rejected
+func TestNewMetadataInvalidCertificate(t *testing.T) {
+	_, err := NewMetadata([]byte("not a certificate"))
+	if err == nil {
+		t.Errorf("Expected error for invalid certificate")
+	}
+}
@@ -0,0 +1,125 @@
+package output
+
+import (
+	"encoding/pem"
+	"errors"
+	"path/filepath"
+
+	"github.com/go-acme/lego/v4/certificate"
+)
+
+// Options used when writing certificate files
+type OutputOptions struct {
+	// Write certificate metadata as JSON
+	Metadata bool
+	// Encrypt private key using passphrase when not empty
+	KeyPassphrase string
+	// Do not write issuer certificate
+	SkipIssuer bool
+	// Write DER-encoded leaf certificate and private key
+	DER bool
+	// Write certificate chain as PKCS#7
+	P7B bool
+	// Do not rewrite files whose content is unchanged
+	OnlyChanged bool
+	// Layout of files expected by a server, e.g. nginx
+	Layout string
+}
+
+// Decode first PEM block of content into DER
+func pemToDER(content []byte) ([]byte, error) {
+	block, _ := pem.Decode(content)
+	if block == nil {
+		return nil, errors.New("No PEM block found")
+	}
+	return block.Bytes, nil
+}
+
+// Write certificate files into directory and return paths of certificate
+// files, along with paths of files whose content changed.
+//
+// Files are named after alias:
+//   - <alias>.crt: PEM-encoded certificate
+//   - <alias>.key: PEM-encoded private key (encrypted when a passphrase is provided)
+//   - <alias>.issuer.crt: PEM-encoded issuer certificate (unless skipped)
+//   - <alias>.resource.json: certificate resource used for renewal
+//   - <alias>.meta.json: certificate metadata (optional)
+//   - <alias>.cert.der: DER-encoded leaf certificate (optional)
+//   - <alias>.key.der: DER-encoded private key, as written into <alias>.key (optional)
+//   - <alias>.p7b: DER-encoded PKCS#7 structure holding leaf and issuer certificates (optional)
+//
+// Files expected by a server are also written according to layout:
+//   - nginx: <alias>.fullchain.pem (leaf and chain)
+//   - apache: <alias>.cert.pem (leaf) and <alias>.chain.pem (chain)
+//   - haproxy: <alias>.combined.pem (leaf, chain and private key)
+//   - traefik: <alias>.fullchain.pem (leaf and chain) and <alias>.key.pem (private key)
+func WriteCertificates(dir string, alias string, res *certificate.Resource, opts OutputOptions) ([]string, []string, error) {
+	privateKey := res.PrivateKey
+	if opts.KeyPassphrase != "" {
+		var err error
+		privateKey, err = encryptPrivateKey(privateKey, opts.KeyPassphrase)
+		if err != nil {
+			return nil, nil, err
+		}
+	}
+	files := []pendingFile{
+		{path: filepath.Join(dir, alias+".crt"), content: res.Certificate},
+		{path: filepath.Join(dir, alias+".key"), content: privateKey},
+	}
+	if !opts.SkipIssuer {
+		files = append(files, pendingFile{path: filepath.Join(dir, alias+".issuer.crt"), content: res.IssuerCertificate})
+	}
+	resource, err := encodeResource(res)
+	if err != nil {
+		return nil, nil, err
+	}
+	files = append(files, pendingFile{path: filepath.Join(dir, alias+".resource.json"), content: resource})
+	// Write certificate metadata to file
+	if opts.Metadata {
+		metadata, err := encodeMetadata(res.Certificate)
+		if err != nil {
+			return nil, nil, err
+		}
+		files = append(files, pendingFile{path: filepath.Join(dir, alias+".meta.json"), content: metadata})
+	}
+	// Write DER-encoded certificate and private key
+	if opts.DER {
+		certDER, err := pemToDER(res.Certificate)
+		if err != nil {
+			return nil, nil, err
+		}
+		keyDER, err := pemToDER(privateKey)
+		if err != nil {
+			return nil, nil, err
+		}
+		files = append(files,
+			pendingFile{path: filepath.Join(dir, alias+".cert.der"), content: certDER},
+			pendingFile{path: filepath.Join(dir, alias+".key.der"), content: keyDER},
+		)
+	}
+	// Write certificate chain as PKCS#7
+	if opts.P7B {
+		p7b, err := encodePKCS7(res.Certificate, res.IssuerCertificate)
+		if err != nil {
+			return nil, nil, err
+		}
+		files = append(files, pendingFile{path: filepath.Join(dir, alias+".p7b"), content: p7b})
+	}
+	// Write files expected by server
+	layout, err := layoutFiles(dir, alias, opts.Layout, res.Certificate, res.IssuerCertificate, privateKey)
+	if err != nil {
+		return nil, nil, err
+	}
+	files = append(files, layout...)
+	// Previous files are only replaced once all new files are written
+	selected, changed := selectChanged(files, opts.OnlyChanged)
+	err = writeAll(selected)
+	if err != nil {
+		return nil, nil, err
+	}
+	paths := []string{}
+	for _, file := range files {
+		paths = append(paths, file.path)
+	}
+	return paths, changed, nil
+}
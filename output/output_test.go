@@ -0,0 +1,216 @@
+package output
+
+import (
+	"bytes"
+	"crypto/x509"
+	"errors"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/go-acme/lego/v4/certcrypto"
+	"github.com/go-acme/lego/v4/certificate"
+	"github.com/youmark/pkcs8"
+)
+
+// Generate a certificate resource used as fixture
+func newTestResource(t *testing.T) *certificate.Resource {
+	return &certificate.Resource{
+		Domain:            "example.com",
+		Certificate:       newTestCertificate(t, "example.com"),
+		PrivateKey:        []byte("private key"),
+		IssuerCertificate: newTestCertificate(t, "issuer"),
+	}
+}
+
+// Test that certificate files are written into directory
+func TestWriteCertificates(t *testing.T) {
+	dir := t.TempDir()
+	res := newTestResource(t)
+	paths, _, err := WriteCertificates(dir, "example.com", res, OutputOptions{})
+	if err != nil {
+		t.Fatalf(err.Error())
+	}
+	if len(paths) != 4 {
+		t.Errorf("Bad written files. Want 4 files. Got: %s", paths)
+	}
+	for name, want := range map[string][]byte{
+		"example.com.crt":        res.Certificate,
+		"example.com.key":        res.PrivateKey,
+		"example.com.issuer.crt": res.IssuerCertificate,
+	} {
+		got, err := os.ReadFile(filepath.Join(dir, name))
+		if err != nil {
+			t.Errorf(err.Error())
+			continue
+		}
+		if !bytes.Equal(got, want) {
+			t.Errorf("Bad content for %s", name)
+		}
+	}
+	if fileExists(filepath.Join(dir, "example.com.meta.json")) {
+		t.Errorf("Metadata file was written but metadata option is disabled")
+	}
+}
+
+// Test that issuer certificate is not written when skipped
+func TestWriteCertificatesWithoutIssuer(t *testing.T) {
+	dir := t.TempDir()
+	paths, _, err := WriteCertificates(dir, "example.com", newTestResource(t), OutputOptions{SkipIssuer: true})
+	if err != nil {
+		t.Fatalf(err.Error())
+	}
+	if len(paths) != 3 {
+		t.Errorf("Bad written files. Want 3 files. Got: %s", paths)
+	}
+	if fileExists(filepath.Join(dir, "example.com.issuer.crt")) {
+		t.Errorf("Issuer file was written but issuer option is disabled")
+	}
+	if !fileExists(filepath.Join(dir, "example.com.crt")) {
+		t.Errorf("Certificate file was not written")
+	}
+}
+
+// Test that metadata file is written when enabled
+func TestWriteCertificatesWithMetadata(t *testing.T) {
+	dir := t.TempDir()
+	paths, _, err := WriteCertificates(dir, "example.com", newTestResource(t), OutputOptions{Metadata: true})
+	if err != nil {
+		t.Fatalf(err.Error())
+	}
+	if len(paths) != 5 || paths[4] != filepath.Join(dir, "example.com.meta.json") {
+		t.Errorf("Bad written files: %s", paths)
+	}
+	if !fileExists(filepath.Join(dir, "example.com.meta.json")) {
+		t.Errorf("Metadata file was not written")
+	}
+}
+
+// Test that previous files are left intact when a write fails
+func TestWriteCertificatesFailure(t *testing.T) {
+	dir := t.TempDir()
+	previous := newTestResource(t)
+	_, _, err := WriteCertificates(dir, "example.com", previous, OutputOptions{})
+	if err != nil {
+		t.Fatalf(err.Error())
+	}
+	// Fail writing the private key of next certificate
+	writeFile = func(name string, data []byte, perm os.FileMode) error {
+		if strings.HasSuffix(name, ".key.tmp") {
+			return errors.New("disk full")
+		}
+		return os.WriteFile(name, data, perm)
+	}
+	defer func() { writeFile = os.WriteFile }()
+	next := newTestResource(t)
+	next.PrivateKey = []byte("new private key")
+	_, _, err = WriteCertificates(dir, "example.com", next, OutputOptions{})
+	if err == nil || err.Error() != "disk full" {
+		t.Fatalf("Bad error. Want: disk full. Got: %v", err)
+	}
+	for name, want := range map[string][]byte{
+		"example.com.crt":        previous.Certificate,
+		"example.com.key":        previous.PrivateKey,
+		"example.com.issuer.crt": previous.IssuerCertificate,
+	} {
+		got, err := os.ReadFile(filepath.Join(dir, name))
+		if err != nil || !bytes.Equal(got, want) {
+			t.Errorf("Expected %s to be left intact", name)
+		}
+	}
+	entries, _ := os.ReadDir(dir)
+	for _, entry := range entries {
+		if strings.HasSuffix(entry.Name(), ".tmp") {
+			t.Errorf("Temporary file was not removed: %s", entry.Name())
+		}
+	}
+}
+
+// Test that only files whose content changed are reported, and rewritten when enabled
+func TestWriteCertificatesOnlyChanged(t *testing.T) {
+	dir := t.TempDir()
+	previous := newTestResource(t)
+	_, changed, err := WriteCertificates(dir, "example.com", previous, OutputOptions{OnlyChanged: true})
+	if err != nil {
+		t.Fatalf(err.Error())
+	}
+	if len(changed) != 4 {
+		t.Errorf("Bad changed files. Want 4 files. Got: %s", changed)
+	}
+	issuer := filepath.Join(dir, "example.com.issuer.crt")
+	past := time.Now().Add(-time.Hour).Truncate(time.Second)
+	err = os.Chtimes(issuer, past, past)
+	if err != nil {
+		t.Fatalf(err.Error())
+	}
+	// Leaf certificate is renewed by the same issuer
+	next := newTestResource(t)
+	next.IssuerCertificate = previous.IssuerCertificate
+	next.PrivateKey = []byte("new private key")
+	paths, changed, err := WriteCertificates(dir, "example.com", next, OutputOptions{OnlyChanged: true})
+	if err != nil {
+		t.Fatalf(err.Error())
+	}
+	if len(paths) != 4 {
+		t.Errorf("Bad written files. Want 4 files. Got: %s", paths)
+	}
+	want := []string{
+		filepath.Join(dir, "example.com.crt"),
+		filepath.Join(dir, "example.com.key"),
+	}
+	if len(changed) != len(want) || changed[0] != want[0] || changed[1] != want[1] {
+		t.Errorf("Bad changed files. Want: %s. Got: %s", want, changed)
+	}
+	info, err := os.Stat(issuer)
+	if err != nil {
+		t.Fatalf(err.Error())
+	}
+	if !info.ModTime().Equal(past) {
+		t.Errorf("Unchanged issuer certificate was rewritten")
+	}
+}
+
+// Test that DER-encoded certificate and private key are written when enabled
+func TestWriteCertificatesDER(t *testing.T) {
+	key, err := certcrypto.GeneratePrivateKey(certcrypto.EC256)
+	if err != nil {
+		t.Fatalf(err.Error())
+	}
+	res := newTestResource(t)
+	res.PrivateKey = certcrypto.PEMEncode(key)
+	dir := t.TempDir()
+	_, _, err = WriteCertificates(dir, "example.com", res, OutputOptions{DER: true})
+	if err != nil {
+		t.Fatalf(err.Error())
+	}
+	certDER, _ := os.ReadFile(filepath.Join(dir, "example.com.cert.der"))
+	cert, err := x509.ParseCertificate(certDER)
+	if err != nil {
+		t.Fatalf("DER certificate does not parse: %s", err.Error())
+	}
+	if cert.Subject.CommonName != "example.com" {
+		t.Errorf("Bad DER certificate. Want: example.com. Got: %s", cert.Subject.CommonName)
+	}
+	keyDER, _ := os.ReadFile(filepath.Join(dir, "example.com.key.der"))
+	if _, err := x509.ParseECPrivateKey(keyDER); err != nil {
+		t.Errorf("DER private key does not parse: %s", err.Error())
+	}
+
+	// Private key is encrypted with passphrase
+	_, _, err = WriteCertificates(dir, "example.com", res, OutputOptions{DER: true, KeyPassphrase: "correct horse battery"})
+	if err != nil {
+		t.Fatalf(err.Error())
+	}
+	keyDER, _ = os.ReadFile(filepath.Join(dir, "example.com.key.der"))
+	if _, err := pkcs8.ParsePKCS8PrivateKey(keyDER, []byte("correct horse battery")); err != nil {
+		t.Errorf("Encrypted DER private key does not parse: %s", err.Error())
+	}
+}
+
+// Check if a file exists
+func fileExists(path string) bool {
+	_, err := os.Stat(path)
+	return err == nil
+}
@@ -0,0 +1,33 @@
+package output
+
+import (
+	"errors"
+	"io/fs"
+	"log"
+	"os"
+)
+
+// Change owner of file. Replaced in tests to record calls.
+var chown = os.Chown
+
+// Change owner and group of written files.
+//
+// A negative uid or gid is left unchanged. Changing owner requires
+// privileges, so a warning is logged instead of failing when
+// permission is denied.
+func ChownFiles(paths []string, uid int, gid int) error {
+	if uid < 0 && gid < 0 {
+		return nil
+	}
+	for _, path := range paths {
+		err := chown(path, uid, gid)
+		if errors.Is(err, fs.ErrPermission) {
+			log.Printf("Cannot change owner of %s: %s", path, err.Error())
+			continue
+		}
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
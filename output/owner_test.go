@@ -0,0 +1,59 @@
+package output
+
+import (
+	"os"
+	"path/filepath"
+	"syscall"
+	"testing"
+)
+
+// Test that owner of every file is changed, and that permission errors are ignored
+func TestChownFiles(t *testing.T) {
+	defer func(original func(string, int, int) error) { chown = original }(chown)
+	attempted := []string{}
+	chown = func(path string, uid int, gid int) error {
+		attempted = append(attempted, path)
+		if uid != 1000 || gid != -1 {
+			t.Errorf("Bad owner. Want: 1000:-1. Got: %d:%d", uid, gid)
+		}
+		return &os.PathError{Op: "chown", Path: path, Err: syscall.EPERM}
+	}
+	err := ChownFiles([]string{"example.com.crt", "example.com.key"}, 1000, -1)
+	if err != nil {
+		t.Errorf("Expected permission error to be ignored but got: %s", err.Error())
+	}
+	if len(attempted) != 2 {
+		t.Errorf("Bad chown attempts. Want: [example.com.crt example.com.key]. Got: %s", attempted)
+	}
+
+	// Nothing is attempted when owner is not configured
+	attempted = []string{}
+	err = ChownFiles([]string{"example.com.crt"}, -1, -1)
+	if err != nil || len(attempted) != 0 {
+		t.Errorf("Expected no chown attempt but got: %s (%v)", attempted, err)
+	}
+}
+
+// Test that owner of written files is changed when running as root
+func TestChownFilesAsRoot(t *testing.T) {
+	if os.Geteuid() != 0 {
+		t.Skip("Changing owner of files requires root")
+	}
+	path := filepath.Join(t.TempDir(), "example.com.key")
+	os.WriteFile(path, []byte("key"), 0o600)
+	err := ChownFiles([]string{path}, 1234, 5678)
+	if err != nil {
+		t.Fatalf(err.Error())
+	}
+	info, err := os.Stat(path)
+	if err != nil {
+		t.Fatalf(err.Error())
+	}
+	stat, ok := info.Sys().(*syscall.Stat_t)
+	if !ok {
+		t.Skip("File owner is not available on this platform")
+	}
+	if stat.Uid != 1234 || stat.Gid != 5678 {
+		t.Errorf("Bad owner. Want: 1234:5678. Got: %d:%d", stat.Uid, stat.Gid)
+	}
+}
@@ -0,0 +1,58 @@
+package output
+
+import (
+	"bytes"
+	"encoding/pem"
+	"errors"
+	"fmt"
+
+	"go.mozilla.org/pkcs7"
+)
+
+// Decode all certificates found in PEM-encoded bundles, without duplicates
+func pemCertificates(bundles ...[]byte) [][]byte {
+	certs := [][]byte{}
+	for _, bundle := range bundles {
+		for {
+			var block *pem.Block
+			block, bundle = pem.Decode(bundle)
+			if block == nil {
+				break
+			}
+			if block.Type != "CERTIFICATE" {
+				continue
+			}
+			duplicate := false
+			for _, cert := range certs {
+				duplicate = duplicate || bytes.Equal(cert, block.Bytes)
+			}
+			if !duplicate {
+				certs = append(certs, block.Bytes)
+			}
+		}
+	}
+	return certs
+}
+
+// Encode leaf certificate and issuer chain into a DER-encoded
+// PKCS#7 degenerate SignedData structure (.p7b).
+//
+// Encoded structure is parsed again to check that it holds the whole chain.
+func encodePKCS7(cert []byte, issuer []byte) ([]byte, error) {
+	certs := pemCertificates(cert, issuer)
+	if len(certs) == 0 {
+		return nil, errors.New("No certificate found to encode as PKCS#7")
+	}
+	p7b, err := pkcs7.DegenerateCertificate(bytes.Join(certs, nil))
+	if err != nil {
+		return nil, err
+	}
+	parsed, err := pkcs7.Parse(p7b)
+	if err != nil {
+		return nil, err
+	}
+	if len(parsed.Certificates) != len(certs) {
+		return nil, errors.New(fmt.Sprintf("PKCS#7 structure holds %d certificates instead of %d", len(parsed.Certificates), len(certs)))
+	}
+	return p7b, nil
+}
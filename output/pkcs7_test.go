@@ -0,0 +1,44 @@
+package output
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+
+	"go.mozilla.org/pkcs7"
+)
+
+// Test that leaf and issuer certificates round-trip through the PKCS#7 file
+func TestWriteCertificatesP7B(t *testing.T) {
+	res := newTestResource(t)
+	// Bundled certificates already hold the issuer, which must not be duplicated
+	res.Certificate = append(res.Certificate, res.IssuerCertificate...)
+	dir := t.TempDir()
+	_, _, err := WriteCertificates(dir, "example.com", res, OutputOptions{P7B: true})
+	if err != nil {
+		t.Fatalf(err.Error())
+	}
+	content, err := os.ReadFile(filepath.Join(dir, "example.com.p7b"))
+	if err != nil {
+		t.Fatalf(err.Error())
+	}
+	p7, err := pkcs7.Parse(content)
+	if err != nil {
+		t.Fatalf("PKCS#7 file does not parse: %s", err.Error())
+	}
+	if len(p7.Certificates) != 2 {
+		t.Fatalf("Bad number of certificates. Want: 2. Got: %d", len(p7.Certificates))
+	}
+	if p7.Certificates[0].Subject.CommonName != "example.com" || p7.Certificates[1].Subject.CommonName != "issuer" {
+		t.Errorf("Bad certificates. Want: [example.com issuer]. Got: [%s %s]", p7.Certificates[0].Subject.CommonName, p7.Certificates[1].Subject.CommonName)
+	}
+}
+
+// Test that encoding fails when no certificate is found
+func TestEncodePKCS7Empty(t *testing.T) {
+	_, err := encodePKCS7([]byte("not a certificate"), nil)
+	err_want := "No certificate found to encode as PKCS#7"
+	if err == nil || err.Error() != err_want {
+		t.Errorf("Bad error. Want: %s. Got: %v", err_want, err)
+	}
+}
@@ -0,0 +1,75 @@
+package output
+
+import (
+	"encoding/json"
+	"errors"
+	"os"
+	"path/filepath"
+
+	"github.com/charbonnierg/letsgo/certinfo"
+	"github.com/go-acme/lego/v4/certificate"
+)
+
+// Encode certificate resource as JSON
+func encodeResource(res *certificate.Resource) ([]byte, error) {
+	return json.MarshalIndent(res, "", "  ")
+}
+
+// Load certificate resource from JSON
+func loadResource(path string) (*certificate.Resource, error) {
+	content, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	res := &certificate.Resource{}
+	err = json.Unmarshal(content, res)
+	if err != nil {
+		return nil, err
+	}
+	return res, nil
+}
+
+// Load certificate files previously written into directory.
+//
+// Returns nil when no certificate exists for alias.
+// When the saved resource is missing, resource is reconstructed
+// from the PEM-encoded certificate.
+func LoadCertificates(dir string, alias string) (*certificate.Resource, error) {
+	cert, err := os.ReadFile(filepath.Join(dir, alias+".crt"))
+	if errors.Is(err, os.ErrNotExist) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	res, err := loadResource(filepath.Join(dir, alias+".resource.json"))
+	if errors.Is(err, os.ErrNotExist) {
+		res, err = reconstructResource(cert)
+	}
+	if err != nil {
+		return nil, err
+	}
+	res.Certificate = cert
+	res.PrivateKey, err = os.ReadFile(filepath.Join(dir, alias+".key"))
+	if err != nil && !errors.Is(err, os.ErrNotExist) {
+		return nil, err
+	}
+	res.IssuerCertificate, err = os.ReadFile(filepath.Join(dir, alias+".issuer.crt"))
+	if err != nil && !errors.Is(err, os.ErrNotExist) {
+		return nil, err
+	}
+	return res, nil
+}
+
+// Reconstruct certificate resource from PEM-encoded certificate
+func reconstructResource(cert []byte) (*certificate.Resource, error) {
+	leaf, err := certinfo.ParseLeaf(cert)
+	if err != nil {
+		return nil, err
+	}
+	res := &certificate.Resource{Domain: leaf.Subject.CommonName}
+	if res.Domain == "" && len(leaf.DNSNames) > 0 {
+		res.Domain = leaf.DNSNames[0]
+	}
+	return res, nil
+}
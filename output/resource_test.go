@@ -0,0 +1,79 @@
+package output
+
+import (
+	"bytes"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+// Test that certificate resource is saved as JSON and loaded back
+func TestWriteLoadResource(t *testing.T) {
+	dir := t.TempDir()
+	want := newTestResource(t)
+	want.CertURL = "https://acme.example.com/cert/1"
+	want.CertStableURL = "https://acme.example.com/cert/1/stable"
+	_, _, err := WriteCertificates(dir, "example.com", want, OutputOptions{})
+	if err != nil {
+		t.Fatalf(err.Error())
+	}
+	got, err := loadResource(filepath.Join(dir, "example.com.resource.json"))
+	if err != nil {
+		t.Fatalf(err.Error())
+	}
+	if got.Domain != want.Domain || got.CertURL != want.CertURL || got.CertStableURL != want.CertStableURL {
+		t.Errorf("Bad resource. Want: %+v. Got: %+v", want, got)
+	}
+}
+
+// Test that certificates written to directory can be loaded back
+func TestLoadCertificatesRoundTrip(t *testing.T) {
+	dir := t.TempDir()
+	res := newTestResource(t)
+	res.CertURL = "https://acme.example.com/cert/1"
+	_, _, err := WriteCertificates(dir, "example.com", res, OutputOptions{})
+	if err != nil {
+		t.Fatalf(err.Error())
+	}
+	got, err := LoadCertificates(dir, "example.com")
+	if err != nil {
+		t.Fatalf(err.Error())
+	}
+	if got.CertURL != res.CertURL || got.Domain != res.Domain {
+		t.Errorf("Bad resource. Want: %+v. Got: %+v", res, got)
+	}
+	if !bytes.Equal(got.Certificate, res.Certificate) || !bytes.Equal(got.PrivateKey, res.PrivateKey) || !bytes.Equal(got.IssuerCertificate, res.IssuerCertificate) {
+		t.Errorf("Bad PEM-encoded content loaded from directory")
+	}
+}
+
+// Test that resource is reconstructed from certificate when missing
+func TestLoadCertificatesWithoutResource(t *testing.T) {
+	dir := t.TempDir()
+	_, _, err := WriteCertificates(dir, "example.com", newTestResource(t), OutputOptions{})
+	if err != nil {
+		t.Fatalf(err.Error())
+	}
+	os.Remove(filepath.Join(dir, "example.com.resource.json"))
+	got, err := LoadCertificates(dir, "example.com")
+	if err != nil {
+		t.Fatalf(err.Error())
+	}
+	if got.Domain != "example.com" {
+		t.Errorf("Bad domain. Want: example.com. Got: %s", got.Domain)
+	}
+	if got.CertURL != "" {
+		t.Errorf("Expected empty certificate URL but got: %s", got.CertURL)
+	}
+}
+
+// Test that nothing is loaded when certificate does not exist
+func TestLoadCertificatesMissing(t *testing.T) {
+	got, err := LoadCertificates(t.TempDir(), "example.com")
+	if err != nil {
+		t.Fatalf(err.Error())
+	}
+	if got != nil {
+		t.Errorf("Expected nil resource but got: %+v", got)
+	}
+}
@@ -0,0 +1,49 @@
+package output
+
+import (
+	"encoding/json"
+	"io"
+	"os"
+	"sync"
+	"time"
+
+	"github.com/charbonnierg/letsgo/certinfo"
+)
+
+// Result of processing a certificate, printed as JSON for scripting
+type Result struct {
+	Domains  []string  `json:"domains"`
+	Alias    string    `json:"alias"`
+	Files    []string  `json:"files"`
+	Changed  []string  `json:"changed"`
+	NotAfter time.Time `json:"not_after"`
+	Renewed  bool      `json:"renewed"`
+	Skipped  bool      `json:"skipped"`
+}
+
+// Results of concurrently processed certificates must not be interleaved
+var stdoutMutex sync.Mutex
+
+// Create result from PEM-encoded certificate
+func NewResult(domains []string, alias string, files []string, certificate []byte) (*Result, error) {
+	leaf, err := certinfo.ParseLeaf(certificate)
+	if err != nil {
+		return nil, err
+	}
+	if files == nil {
+		files = []string{}
+	}
+	return &Result{Domains: domains, Alias: alias, Files: files, Changed: []string{}, NotAfter: leaf.NotAfter.UTC()}, nil
+}
+
+// Write result as a single line of JSON
+func WriteResult(w io.Writer, result *Result) error {
+	return json.NewEncoder(w).Encode(result)
+}
+
+// Print result as a single line of JSON to stdout
+func PrintResult(result *Result) error {
+	stdoutMutex.Lock()
+	defer stdoutMutex.Unlock()
+	return WriteResult(os.Stdout, result)
+}
@@ -0,0 +1,49 @@
+package output
+
+import (
+	"encoding/json"
+	"io"
+	"os"
+	"testing"
+)
+
+// Test that result is printed to stdout as a single JSON object
+func TestPrintResult(t *testing.T) {
+	result, err := NewResult([]string{"example.com"}, "example.com", []string{"example.com.crt"}, newTestCertificate(t, "example.com"))
+	if err != nil {
+		t.Fatalf(err.Error())
+	}
+	result.Renewed = true
+	// Capture stdout
+	reader, writer, err := os.Pipe()
+	if err != nil {
+		t.Fatalf(err.Error())
+	}
+	stdout := os.Stdout
+	os.Stdout = writer
+	err = PrintResult(result)
+	os.Stdout = stdout
+	writer.Close()
+	if err != nil {
+		t.Fatalf(err.Error())
+	}
+	content, err := io.ReadAll(reader)
+	if err != nil {
+		t.Fatalf(err.Error())
+	}
+	got := map[string]interface{}{}
+	err = json.Unmarshal(content, &got)
+	if err != nil {
+		t.Fatalf("Stdout is not valid JSON: %s", content)
+	}
+	if got["alias"] != "example.com" || got["renewed"] != true || got["skipped"] != false {
+		t.Errorf("Bad result: %s", content)
+	}
+	if got["not_after"] != result.NotAfter.Format("2006-01-02T15:04:05Z07:00") {
+		t.Errorf("Bad not_after. Want: %s. Got: %v", result.NotAfter, got["not_after"])
+	}
+	files, ok := got["files"].([]interface{})
+	if !ok || len(files) != 1 || files[0] != "example.com.crt" {
+		t.Errorf("Bad files: %v", got["files"])
+	}
+}
@@ -0,0 +1,26 @@
+//go:build selftest
+
+package main
+
+import (
+	"bytes"
+	"strings"
+	"testing"
+)
+
+// Test that a certificate is obtained from local Boulder test CA.
+//
+// Boulder must be running locally with its mock DNS server, see
+// https://github.com/letsencrypt/boulder#setting-up-boulder
+//
+// Run with: go test -tags selftest -run TestSelfTest .
+func TestSelfTest(t *testing.T) {
+	out := &bytes.Buffer{}
+	err := runSelfTest(out)
+	if err != nil {
+		t.Fatalf(err.Error())
+	}
+	if !strings.Contains(out.String(), "Self test succeeded") {
+		t.Errorf("Bad output: %s", out.String())
+	}
+}
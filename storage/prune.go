@@ -0,0 +1,108 @@
+package storage
+
+import (
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+)
+
+// Suffixes of files written for each certificate alias.
+//
+// Longest suffixes come first so that `<alias>.issuer.crt`
+// is not mistaken for the certificate of `<alias>.issuer`.
+var certificateSuffixes = []string{
+	".resource.json",
+	".order.json",
+	".meta.json",
+	".cert.der",
+	".key.der",
+	".p7b",
+	".fullchain.pem",
+	".combined.pem",
+	".chain.pem",
+	".cert.pem",
+	".key.pem",
+	".issuer.crt",
+	".crt",
+	".key",
+}
+
+// Aliases that a file may belong to, according to its suffix
+func aliasesOf(name string) []string {
+	aliases := []string{}
+	for _, suffix := range certificateSuffixes {
+		if strings.HasSuffix(name, suffix) && len(name) > len(suffix) {
+			aliases = append(aliases, strings.TrimSuffix(name, suffix))
+		}
+	}
+	// Chains are written as `<alias>.chain.<root-cn>.crt`
+	if index := strings.LastIndex(name, ".chain."); index > 0 && strings.HasSuffix(name, ".crt") {
+		aliases = append(aliases, name[:index])
+	}
+	return aliases
+}
+
+// List certificate aliases found in directory
+func ListCertificates(dir string) ([]string, error) {
+	entries, err := os.ReadDir(dir)
+	if err != nil {
+		return nil, err
+	}
+	found := map[string]bool{}
+	for _, entry := range entries {
+		if entry.IsDir() {
+			continue
+		}
+		if alias := strings.TrimSuffix(entry.Name(), ".crt"); alias != entry.Name() && !strings.HasSuffix(alias, ".issuer") && !strings.Contains(alias, ".chain.") {
+			found[alias] = true
+		}
+	}
+	aliases := []string{}
+	for alias := range found {
+		aliases = append(aliases, alias)
+	}
+	sort.Strings(aliases)
+	return aliases, nil
+}
+
+// Remove certificate files whose alias is not in keep.
+//
+// Only files written by letsgo for a certificate alias are considered,
+// other files are left untouched. A file which may belong to a kept alias
+// is never removed. Removed paths are returned in lexical order.
+func PruneCertificates(dir string, keep []string) ([]string, error) {
+	kept := map[string]bool{}
+	for _, alias := range keep {
+		kept[alias] = true
+	}
+	entries, err := os.ReadDir(dir)
+	if err != nil {
+		return nil, err
+	}
+	removed := []string{}
+	for _, entry := range entries {
+		if entry.IsDir() {
+			continue
+		}
+		aliases := aliasesOf(entry.Name())
+		if len(aliases) == 0 {
+			continue
+		}
+		stale := true
+		for _, alias := range aliases {
+			if kept[alias] {
+				stale = false
+			}
+		}
+		if !stale {
+			continue
+		}
+		path := filepath.Join(dir, entry.Name())
+		if err := os.Remove(path); err != nil {
+			return removed, err
+		}
+		removed = append(removed, path)
+	}
+	return removed, nil
+}
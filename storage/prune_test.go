@@ -0,0 +1,105 @@
+package storage
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+
+	"golang.org/x/exp/slices"
+)
+
+// Write empty certificate files for alias
+func writeAlias(t *testing.T, dir string, alias string) {
+	for _, suffix := range certificateSuffixes {
+		err := os.WriteFile(filepath.Join(dir, alias+suffix), []byte{}, 0o600)
+		if err != nil {
+			t.Fatalf(err.Error())
+		}
+	}
+}
+
+// Test that certificate aliases are listed
+func TestListCertificates(t *testing.T) {
+	dir := t.TempDir()
+	writeAlias(t, dir, "example.com")
+	writeAlias(t, dir, "_.example.net")
+	os.WriteFile(filepath.Join(dir, ".letsgo.lock"), []byte{}, 0o600)
+	aliases, err := ListCertificates(dir)
+	if err != nil {
+		t.Fatalf(err.Error())
+	}
+	want := []string{"_.example.net", "example.com"}
+	if !slices.Equal(aliases, want) {
+		t.Errorf("Bad aliases. Want: %s. Got: %s", want, aliases)
+	}
+}
+
+// Test that files of aliases which are not kept are removed
+func TestPruneCertificates(t *testing.T) {
+	dir := t.TempDir()
+	writeAlias(t, dir, "example.com")
+	writeAlias(t, dir, "old.example.com")
+	writeAlias(t, dir, "_.example.net")
+	os.WriteFile(filepath.Join(dir, ".letsgo.lock"), []byte{}, 0o600)
+	os.WriteFile(filepath.Join(dir, "notes.txt"), []byte{}, 0o600)
+
+	removed, err := PruneCertificates(dir, []string{"example.com", "_.example.net"})
+	if err != nil {
+		t.Fatalf(err.Error())
+	}
+	want := []string{}
+	for _, name := range []string{"old.example.com.cert.der", "old.example.com.cert.pem", "old.example.com.chain.pem", "old.example.com.combined.pem", "old.example.com.crt", "old.example.com.fullchain.pem", "old.example.com.issuer.crt", "old.example.com.key", "old.example.com.key.der", "old.example.com.key.pem", "old.example.com.meta.json", "old.example.com.order.json", "old.example.com.p7b", "old.example.com.resource.json"} {
+		want = append(want, filepath.Join(dir, name))
+	}
+	if !slices.Equal(removed, want) {
+		t.Errorf("Bad removed files. Want: %s. Got: %s", want, removed)
+	}
+	aliases, err := ListCertificates(dir)
+	if err != nil {
+		t.Fatalf(err.Error())
+	}
+	if !slices.Equal(aliases, []string{"_.example.net", "example.com"}) {
+		t.Errorf("Bad remaining aliases: %s", aliases)
+	}
+	for _, name := range []string{".letsgo.lock", "notes.txt", "example.com.issuer.crt"} {
+		if _, err := os.Stat(filepath.Join(dir, name)); err != nil {
+			t.Errorf("Expected %s to be kept", name)
+		}
+	}
+}
+
+// Test that issuer certificate of a kept alias is not mistaken for another alias
+func TestPruneCertificatesIssuer(t *testing.T) {
+	dir := t.TempDir()
+	writeAlias(t, dir, "example.com")
+	removed, err := PruneCertificates(dir, []string{"example.com"})
+	if err != nil {
+		t.Fatalf(err.Error())
+	}
+	if len(removed) != 0 {
+		t.Errorf("Expected no file to be removed but got: %s", removed)
+	}
+}
+
+// Test that chain files are pruned along with their alias
+func TestPruneCertificatesChains(t *testing.T) {
+	dir := t.TempDir()
+	writeAlias(t, dir, "example.com")
+	os.WriteFile(filepath.Join(dir, "example.com.chain.ISRG_Root_X1.crt"), []byte{}, 0o600)
+	os.WriteFile(filepath.Join(dir, "old.example.com.chain.ISRG_Root_X1.crt"), []byte{}, 0o600)
+	aliases, err := ListCertificates(dir)
+	if err != nil {
+		t.Fatalf(err.Error())
+	}
+	if !slices.Equal(aliases, []string{"example.com"}) {
+		t.Errorf("Bad aliases. Want: [example.com]. Got: %s", aliases)
+	}
+	removed, err := PruneCertificates(dir, []string{"example.com"})
+	if err != nil {
+		t.Fatalf(err.Error())
+	}
+	want := []string{filepath.Join(dir, "old.example.com.chain.ISRG_Root_X1.crt")}
+	if !slices.Equal(removed, want) {
+		t.Errorf("Bad removed files. Want: %s. Got: %s", want, removed)
+	}
+}
@@ -2,28 +2,140 @@ package stores
 
 import (
 	"context"
+	"errors"
+	"net"
+	"net/http"
 	"strings"
+	"time"
 
+	"github.com/Azure/azure-sdk-for-go/sdk/azcore"
 	"github.com/Azure/azure-sdk-for-go/sdk/azidentity"
 	"github.com/Azure/azure-sdk-for-go/sdk/keyvault/azsecrets"
 )
 
+// Error returned when a secret does not exist in keyvault
+var ErrSecretNotFound = errors.New("secret not found")
+
+// Default timeout of a single keyvault request
+const DefaultVaultTimeout = 15 * time.Second
+
+// Number of attempts and delay between attempts for transient failures
+const vaultAttempts = 3
+const vaultBackoff = time.Second
+
+// Subset of azsecrets client used by keyvault store
+type secretsClient interface {
+	GetSecret(ctx context.Context, name string, version string, options *azsecrets.GetSecretOptions) (azsecrets.GetSecretResponse, error)
+	SetSecret(ctx context.Context, name string, parameters azsecrets.SetSecretParameters, options *azsecrets.SetSecretOptions) (azsecrets.SetSecretResponse, error)
+}
+
 // Azure Keyvault store implementation to fetch token from azure keyvault
-type KeyVault struct{}
+type KeyVault struct {
+	// Timeout of a single request. DefaultVaultTimeout is used when zero.
+	Timeout time.Duration
+	// Initial delay between attempts, doubled after each attempt
+	backoff time.Duration
+	// Create secrets client, used in tests
+	newSecretsClient func(uri string) (secretsClient, error)
+}
+
+// Create keyvault store using request timeout
+func NewKeyVault(timeout time.Duration) *KeyVault {
+	return &KeyVault{Timeout: timeout}
+}
 
-func (k *KeyVault) GetToken(uri string, secret string) (string, error) {
+// Create client to interact with key vault
+func (k *KeyVault) newClient(uri string) (secretsClient, error) {
+	if k.newSecretsClient != nil {
+		return k.newSecretsClient(uri)
+	}
 	// Generate azure credentials
 	cred, err := azidentity.NewDefaultAzureCredential(nil)
+	if err != nil {
+		return nil, err
+	}
+	return azsecrets.NewClient(uri, cred, nil), nil
+}
+
+// Check whether a keyvault request failed because of a transient failure
+func isTransient(err error) bool {
+	var authErr *azidentity.AuthenticationFailedError
+	if errors.As(err, &authErr) {
+		return false
+	}
+	var respErr *azcore.ResponseError
+	if errors.As(err, &respErr) {
+		code := respErr.StatusCode
+		return code == http.StatusRequestTimeout || code == http.StatusTooManyRequests || code >= 500
+	}
+	if errors.Is(err, context.DeadlineExceeded) {
+		return true
+	}
+	var netErr net.Error
+	return errors.As(err, &netErr)
+}
+
+// Run a keyvault request with a timeout, retrying transient failures
+// until parent context is cancelled
+func (k *KeyVault) do(parent context.Context, request func(ctx context.Context) error) error {
+	timeout := k.Timeout
+	if timeout <= 0 {
+		timeout = DefaultVaultTimeout
+	}
+	backoff := k.backoff
+	if backoff <= 0 {
+		backoff = vaultBackoff
+	}
+	var err error
+	for attempt := 1; attempt <= vaultAttempts; attempt++ {
+		ctx, cancel := context.WithTimeout(parent, timeout)
+		err = request(ctx)
+		cancel()
+		if err == nil || !isTransient(err) || parent.Err() != nil {
+			return err
+		}
+		if attempt < vaultAttempts {
+			select {
+			case <-parent.Done():
+				return parent.Err()
+			case <-time.After(backoff):
+			}
+			backoff *= 2
+		}
+	}
+	return err
+}
+
+func (k *KeyVault) GetToken(ctx context.Context, uri string, secret string) (string, error) {
+	client, err := k.newClient(uri)
 	if err != nil {
 		return "", err
 	}
-	// Create client to interact with key vault
-	client := azsecrets.NewClient(uri, cred, nil)
 	// Fetch the token
-	resp, err := client.GetSecret(context.TODO(), secret, "", nil)
+	var resp azsecrets.GetSecretResponse
+	err = k.do(ctx, func(ctx context.Context) error {
+		resp, err = client.GetSecret(ctx, secret, "", nil)
+		return err
+	})
 	if err != nil {
+		var respErr *azcore.ResponseError
+		if errors.As(err, &respErr) && respErr.StatusCode == http.StatusNotFound {
+			return "", ErrSecretNotFound
+		}
 		return "", err
 	}
 	// Return the token (secret value)
 	return strings.TrimSuffix(*resp.Value, "\n"), nil
 }
+
+func (k *KeyVault) SetToken(ctx context.Context, uri string, secret string, value string) error {
+	client, err := k.newClient(uri)
+	if err != nil {
+		return err
+	}
+	// Store the token
+	return k.do(ctx, func(ctx context.Context) error {
+		_, err := client.SetSecret(ctx, secret, azsecrets.SetSecretParameters{Value: &value}, nil)
+		return err
+	})
+}
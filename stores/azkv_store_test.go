@@ -0,0 +1,108 @@
+package stores
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"testing"
+	"time"
+
+	"github.com/Azure/azure-sdk-for-go/sdk/azcore"
+	"github.com/Azure/azure-sdk-for-go/sdk/keyvault/azsecrets"
+)
+
+// Secrets client mock which hangs a number of times before succeeding
+type hangingSecretsClient struct {
+	hangs int
+	calls int
+	err   error
+}
+
+func (c *hangingSecretsClient) GetSecret(ctx context.Context, name string, version string, options *azsecrets.GetSecretOptions) (azsecrets.GetSecretResponse, error) {
+	c.calls += 1
+	if c.err != nil {
+		return azsecrets.GetSecretResponse{}, c.err
+	}
+	if c.calls <= c.hangs {
+		<-ctx.Done()
+		return azsecrets.GetSecretResponse{}, ctx.Err()
+	}
+	value := "XXXXX"
+	resp := azsecrets.GetSecretResponse{}
+	resp.Value = &value
+	return resp, nil
+}
+
+func (c *hangingSecretsClient) SetSecret(ctx context.Context, name string, parameters azsecrets.SetSecretParameters, options *azsecrets.SetSecretOptions) (azsecrets.SetSecretResponse, error) {
+	return azsecrets.SetSecretResponse{}, nil
+}
+
+func newTestKeyVault(client secretsClient) *KeyVault {
+	return &KeyVault{
+		Timeout: 10 * time.Millisecond,
+		backoff: time.Millisecond,
+		newSecretsClient: func(uri string) (secretsClient, error) {
+			return client, nil
+		},
+	}
+}
+
+// Test that a request timing out is retried
+func TestKeyVaultGetTokenRetry(t *testing.T) {
+	client := &hangingSecretsClient{hangs: 1}
+	token, err := newTestKeyVault(client).GetToken(context.Background(), "https://vault.vault.azure.net", "secret")
+	if err != nil {
+		t.Fatalf(err.Error())
+	}
+	if token != "XXXXX" {
+		t.Errorf("Bad token. Want: XXXXX. Got: %s", token)
+	}
+	if client.calls != 2 {
+		t.Errorf("Bad number of calls. Want: 2. Got: %d", client.calls)
+	}
+}
+
+// Test that retries are limited
+func TestKeyVaultGetTokenTimeout(t *testing.T) {
+	client := &hangingSecretsClient{hangs: 10}
+	_, err := newTestKeyVault(client).GetToken(context.Background(), "https://vault.vault.azure.net", "secret")
+	if !errors.Is(err, context.DeadlineExceeded) {
+		t.Errorf("Bad error. Want: %s. Got: %v", context.DeadlineExceeded, err)
+	}
+	if client.calls != vaultAttempts {
+		t.Errorf("Bad number of calls. Want: %d. Got: %d", vaultAttempts, client.calls)
+	}
+}
+
+// Test that permanent errors are not retried
+func TestKeyVaultGetTokenPermanentError(t *testing.T) {
+	client := &hangingSecretsClient{err: &azcore.ResponseError{StatusCode: http.StatusForbidden}}
+	_, err := newTestKeyVault(client).GetToken(context.Background(), "https://vault.vault.azure.net", "secret")
+	if err == nil {
+		t.Fatalf("Expected error")
+	}
+	if client.calls != 1 {
+		t.Errorf("Bad number of calls. Want: 1. Got: %d", client.calls)
+	}
+	client = &hangingSecretsClient{err: &azcore.ResponseError{StatusCode: http.StatusNotFound}}
+	_, err = newTestKeyVault(client).GetToken(context.Background(), "https://vault.vault.azure.net", "secret")
+	if err != ErrSecretNotFound {
+		t.Errorf("Bad error. Want: %s. Got: %v", ErrSecretNotFound, err)
+	}
+}
+
+// Test that keyvault requests are not retried once parent context is cancelled
+func TestKeyVaultGetTokenCancel(t *testing.T) {
+	client := &hangingSecretsClient{hangs: vaultAttempts}
+	store := newTestKeyVault(client)
+	store.Timeout = time.Minute
+	ctx, cancel := context.WithCancel(context.Background())
+	time.AfterFunc(10*time.Millisecond, cancel)
+	_, err := store.GetToken(ctx, "https://vault.vault.azure.net", "secret")
+	if !errors.Is(err, context.Canceled) {
+		t.Errorf("Bad error. Want: %s. Got: %v", context.Canceled, err)
+	}
+	if client.calls != 1 {
+		t.Errorf("Expected a single call but got %d calls", client.calls)
+	}
+}
@@ -0,0 +1,81 @@
+package stores
+
+import (
+	"context"
+	"sync"
+)
+
+// Keyvault store wrapper which memoizes tokens for the process lifetime.
+//
+// Only keyvault stores are wrapped, since keyvault is the only remote store
+// whose tokens are fetched again by each certificate group. Only successful
+// results are cached, so a failed fetch is retried on next call.
+type CachingStore struct {
+	store  KeyvaultStoreProtocol
+	mutex  sync.Mutex
+	tokens map[string]*cachedToken
+}
+
+// Token of a single secret, locked while it is fetched so that
+// concurrent fetches of other secrets are not serialized
+type cachedToken struct {
+	mutex sync.Mutex
+	value string
+	found bool
+}
+
+// Wrap a keyvault store into a caching store
+func NewCachingStore(store KeyvaultStoreProtocol) *CachingStore {
+	return &CachingStore{
+		store:  store,
+		tokens: map[string]*cachedToken{},
+	}
+}
+
+// Get cache entry of secret, tokens are cached per vault and secret
+func (s *CachingStore) entry(uri string, secret string) *cachedToken {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+	key := uri + "/" + secret
+	entry, ok := s.tokens[key]
+	if !ok {
+		entry = &cachedToken{}
+		s.tokens[key] = entry
+	}
+	return entry
+}
+
+func (s *CachingStore) GetToken(ctx context.Context, uri string, secret string) (string, error) {
+	entry := s.entry(uri, secret)
+	entry.mutex.Lock()
+	defer entry.mutex.Unlock()
+	if entry.found {
+		return entry.value, nil
+	}
+	// Fetch the token from wrapped store
+	token, err := s.store.GetToken(ctx, uri, secret)
+	if err != nil {
+		return "", err
+	}
+	entry.value = token
+	entry.found = true
+	return token, nil
+}
+
+func (s *CachingStore) SetToken(ctx context.Context, uri string, secret string, value string) error {
+	writer, ok := s.store.(KeyvaultWriter)
+	if !ok {
+		return ErrReadOnlyKeyvault
+	}
+	entry := s.entry(uri, secret)
+	entry.mutex.Lock()
+	defer entry.mutex.Unlock()
+	err := writer.SetToken(ctx, uri, secret, value)
+	if err != nil {
+		return err
+	}
+	// Keep cache in sync with stored value
+	entry.value = value
+	entry.found = true
+	return nil
+}
@@ -0,0 +1,109 @@
+package stores
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+)
+
+// Keyvault store counting calls to GetToken
+type countingKeyVault struct {
+	Token string
+	Err   error
+	Calls int
+}
+
+func (k *countingKeyVault) GetToken(ctx context.Context, uri string, secret string) (string, error) {
+	k.Calls += 1
+	return k.Token, k.Err
+}
+
+func (k *countingKeyVault) SetToken(ctx context.Context, uri string, secret string, value string) error {
+	k.Token = value
+	return k.Err
+}
+
+// Test that the wrapped store is called only once
+func TestCachingStoreCallsStoreOnce(t *testing.T) {
+	wrapped := &countingKeyVault{Token: "XXXXX"}
+	store := NewCachingStore(wrapped)
+	for i := 0; i < 3; i++ {
+		token, err := store.GetToken(context.Background(), "https://test.vault.azure.net/", "secret")
+		if err != nil {
+			t.Errorf(err.Error())
+		}
+		if token != "XXXXX" {
+			t.Errorf("Bad token. Want: XXXXX. Got: %s", token)
+		}
+	}
+	if wrapped.Calls != 1 {
+		t.Errorf("Expected wrapped store to be called once but got %d calls", wrapped.Calls)
+	}
+	// A different secret is fetched from wrapped store
+	store.GetToken(context.Background(), "https://test.vault.azure.net/", "other-secret")
+	if wrapped.Calls != 2 {
+		t.Errorf("Expected wrapped store to be called twice but got %d calls", wrapped.Calls)
+	}
+}
+
+// Test that errors are not cached
+func TestCachingStoreDoesNotCacheErrors(t *testing.T) {
+	wrapped := &countingKeyVault{Err: errors.New("unreachable")}
+	store := NewCachingStore(wrapped)
+	_, err := store.GetToken(context.Background(), "https://test.vault.azure.net/", "secret")
+	if err == nil {
+		t.Errorf("Expected error but got nil")
+	}
+	wrapped.Err = nil
+	wrapped.Token = "XXXXX"
+	token, err := store.GetToken(context.Background(), "https://test.vault.azure.net/", "secret")
+	if err != nil {
+		t.Errorf(err.Error())
+	}
+	if token != "XXXXX" {
+		t.Errorf("Bad token. Want: XXXXX. Got: %s", token)
+	}
+	if wrapped.Calls != 2 {
+		t.Errorf("Expected wrapped store to be called twice but got %d calls", wrapped.Calls)
+	}
+}
+
+// Keyvault store blocking fetches of a secret until released
+type blockingKeyVault struct {
+	secret  string
+	release chan struct{}
+}
+
+func (k *blockingKeyVault) GetToken(ctx context.Context, uri string, secret string) (string, error) {
+	if secret == k.secret {
+		<-k.release
+	}
+	return secret, nil
+}
+
+// Test that a slow fetch does not block fetches of other secrets
+func TestCachingStoreDoesNotSerializeSecrets(t *testing.T) {
+	wrapped := &blockingKeyVault{secret: "slow", release: make(chan struct{})}
+	defer close(wrapped.release)
+	store := NewCachingStore(wrapped)
+	go store.GetToken(context.Background(), "https://test.vault.azure.net/", "slow")
+	done := make(chan struct{})
+	go func() {
+		store.GetToken(context.Background(), "https://test.vault.azure.net/", "fast")
+		close(done)
+	}()
+	select {
+	case <-done:
+	case <-time.After(time.Second):
+		t.Errorf("Fetch of a secret was blocked by fetch of another secret")
+	}
+}
+
+// Test that default stores cache keyvault tokens
+func TestDefaultStoresUseCachingStore(t *testing.T) {
+	s := DefaultStores(DefaultVaultTimeout)
+	if _, ok := s.GetKeyvaultStore().(*CachingStore); !ok {
+		t.Errorf("Expected caching keyvault store but got %T", s.GetKeyvaultStore())
+	}
+}
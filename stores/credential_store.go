@@ -0,0 +1,42 @@
+package stores
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+)
+
+// Environment variable set by systemd to the directory holding unit credentials
+const CredentialsDirectoryVariable = "CREDENTIALS_DIRECTORY"
+
+// Credential store implementation to fetch token from systemd credentials,
+// loaded by units using LoadCredential= or SetCredential=.
+type CredentialStore struct{}
+
+func (s *CredentialStore) GetToken(ctx context.Context, name string) (string, error) {
+	if err := ctx.Err(); err != nil {
+		return "", err
+	}
+	dir := os.Getenv(CredentialsDirectoryVariable)
+	if dir == "" {
+		return "", errors.New(fmt.Sprintf("Cannot read credential %s: %s environment variable is not set", name, CredentialsDirectoryVariable))
+	}
+	// Credential names cannot point outside of credentials directory
+	if name == "" || name != filepath.Base(name) || name == "." || name == ".." {
+		return "", errors.New(fmt.Sprintf("Invalid credential name: %s", name))
+	}
+	path := filepath.Join(dir, name)
+	content, err := os.ReadFile(path)
+	if err != nil {
+		return "", err
+	}
+	// Credentials are commonly written with a trailing newline
+	token := strings.TrimRight(string(content), "\r\n")
+	if token == "" {
+		return "", errors.New(fmt.Sprintf("Invalid token found in %s", path))
+	}
+	return token, nil
+}
@@ -0,0 +1,46 @@
+package stores
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+// Test that credential store reads token from systemd credentials directory
+func TestCredentialStoreGetToken(t *testing.T) {
+	dir := t.TempDir()
+	err := os.WriteFile(filepath.Join(dir, "dns-token"), []byte("XXXXX\n\n"), 0o600)
+	if err != nil {
+		t.Fatalf(err.Error())
+	}
+	t.Setenv("CREDENTIALS_DIRECTORY", dir)
+	store := &CredentialStore{}
+	token, err := store.GetToken(context.Background(), "dns-token")
+	if err != nil {
+		t.Fatalf(err.Error())
+	}
+	if token != "XXXXX" {
+		t.Errorf("Bad token. Want: XXXXX. Got: %q", token)
+	}
+}
+
+// Test that credential store rejects missing directory, invalid names and empty credentials
+func TestCredentialStoreGetTokenInvalid(t *testing.T) {
+	dir := t.TempDir()
+	os.WriteFile(filepath.Join(dir, "empty"), []byte("\n"), 0o600)
+	store := &CredentialStore{}
+	t.Setenv("CREDENTIALS_DIRECTORY", "")
+	_, err := store.GetToken(context.Background(), "dns-token")
+	err_want := "Cannot read credential dns-token: CREDENTIALS_DIRECTORY environment variable is not set"
+	if err == nil || err.Error() != err_want {
+		t.Errorf("Bad error. Want: %s. Got: %v", err_want, err)
+	}
+	t.Setenv("CREDENTIALS_DIRECTORY", dir)
+	for _, name := range []string{"missing", "empty", "../dns-token", ".."} {
+		_, err = store.GetToken(context.Background(), name)
+		if err == nil {
+			t.Errorf("Expected error for credential %s", name)
+		}
+	}
+}
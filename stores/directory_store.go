@@ -0,0 +1,32 @@
+package stores
+
+import (
+	"context"
+	"path/filepath"
+)
+
+// Name of file holding token within a secret directory
+const DefaultTokenFilename = "token"
+
+// Directory store implementation to fetch token from a secret directory,
+// such as a Kubernetes secret mounted as a volume.
+//
+// Token file is resolved on each read, so that secret updates performed
+// by swapping a symlink atomically are picked up.
+type DirectoryStore struct {
+	Filename string
+}
+
+func (s *DirectoryStore) GetToken(ctx context.Context, dir string) (string, error) {
+	filename := s.Filename
+	if filename == "" {
+		filename = DefaultTokenFilename
+	}
+	// Resolve symlinks to read the current version of the secret
+	path, err := filepath.EvalSymlinks(filepath.Join(dir, filename))
+	if err != nil {
+		return "", err
+	}
+	store := &FileStore{}
+	return store.GetToken(ctx, path)
+}
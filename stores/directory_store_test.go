@@ -0,0 +1,73 @@
+package stores
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+// Write a secret version and point the ..data symlink to it,
+// the same way Kubernetes updates mounted secrets
+func writeSecretVersion(t *testing.T, dir string, version string, token string) {
+	versionDir := filepath.Join(dir, version)
+	err := os.Mkdir(versionDir, 0o700)
+	if err != nil {
+		t.Fatalf(err.Error())
+	}
+	err = os.WriteFile(filepath.Join(versionDir, "token"), []byte(token), 0o600)
+	if err != nil {
+		t.Fatalf(err.Error())
+	}
+	tmpLink := filepath.Join(dir, "..data_tmp")
+	err = os.Symlink(version, tmpLink)
+	if err != nil {
+		t.Fatalf(err.Error())
+	}
+	err = os.Rename(tmpLink, filepath.Join(dir, "..data"))
+	if err != nil {
+		t.Fatalf(err.Error())
+	}
+}
+
+// Test that directory store follows symlinks swapped atomically
+func TestDirectoryStoreGetToken(t *testing.T) {
+	dir := t.TempDir()
+	writeSecretVersion(t, dir, "..2022_11_20_10_00_00.1", "XXXXX\n")
+	err := os.Symlink(filepath.Join("..data", "token"), filepath.Join(dir, "token"))
+	if err != nil {
+		t.Fatalf(err.Error())
+	}
+	store := &DirectoryStore{}
+	token, err := store.GetToken(context.Background(), dir)
+	if err != nil {
+		t.Fatalf(err.Error())
+	}
+	if token != "XXXXX" {
+		t.Errorf("Bad token. Want: XXXXX. Got: %q", token)
+	}
+	// Secret is updated
+	writeSecretVersion(t, dir, "..2022_11_20_11_00_00.2", "YYYYY\n")
+	token, err = store.GetToken(context.Background(), dir)
+	if err != nil {
+		t.Fatalf(err.Error())
+	}
+	if token != "YYYYY" {
+		t.Errorf("Bad token. Want: YYYYY. Got: %q", token)
+	}
+}
+
+// Test that directory store rejects missing and empty tokens
+func TestDirectoryStoreGetTokenInvalid(t *testing.T) {
+	dir := t.TempDir()
+	store := &DirectoryStore{}
+	_, err := store.GetToken(context.Background(), dir)
+	if err == nil {
+		t.Errorf("Expected error for missing token")
+	}
+	os.WriteFile(filepath.Join(dir, "token"), []byte("\n"), 0o600)
+	_, err = store.GetToken(context.Background(), dir)
+	if err == nil {
+		t.Errorf("Expected error for empty token")
+	}
+}
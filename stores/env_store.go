@@ -0,0 +1,26 @@
+package stores
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"os"
+	"strings"
+)
+
+// Environment store implementation to fetch token from environment variable
+type EnvStore struct{}
+
+func (s *EnvStore) GetToken(ctx context.Context, variable string) (string, error) {
+	if err := ctx.Err(); err != nil {
+		return "", err
+	}
+	// Read environment variable and strip whitespace and line breaks
+	token := strings.TrimSpace(os.Getenv(variable))
+	// Check that token is not empty
+	if token == "" {
+		return "", errors.New(fmt.Sprintf("Invalid token found in %s environment variable", variable))
+	}
+	// Return token
+	return token, nil
+}
@@ -1,28 +1,54 @@
 package stores
 
 import (
+	"context"
 	"errors"
 	"fmt"
 	"io/ioutil"
+	"os"
 	"strings"
 )
 
+// Suffix of environment variables holding path of credential files
+const FileSuffix = "_FILE"
+
 // File store implementation to fetch token from file
 type FileStore struct{}
 
-func (s *FileStore) GetToken(path string) (string, error) {
+func (s *FileStore) GetToken(ctx context.Context, path string) (string, error) {
+	if err := ctx.Err(); err != nil {
+		return "", err
+	}
 	// Read file
 	rawToken, err := ioutil.ReadFile(path)
 	// Or return an error
 	if err != nil {
 		return "", err
 	}
-	// Convert to string and strip line break
-	token := strings.TrimSuffix(string(rawToken), "\n")
+	// Convert to string, strip UTF-8 BOM and surrounding whitespace
+	token := strings.TrimSpace(strings.TrimPrefix(string(rawToken), "\ufeff"))
 	// Check that token is not empty
 	if token == "" {
 		return "", errors.New(fmt.Sprintf("Invalid token found in %s", path))
 	}
+	// Check that token file is not a key or certificate file
+	if strings.HasPrefix(token, "-----BEGIN") {
+		return "", errors.New(fmt.Sprintf("Token file %s appears to contain a PEM block, expected a plain token", path))
+	}
 	// Return token
 	return token, nil
 }
+
+// Get value of a named credential.
+//
+// Value is read from the file whose path is found in the environment
+// variable named after credential with a _FILE suffix, e.g.
+// AWS_SECRET_ACCESS_KEY is read from AWS_SECRET_ACCESS_KEY_FILE.
+func (s *FileStore) GetValue(ctx context.Context, name string) (string, error) {
+	variable := name + FileSuffix
+	path := os.Getenv(variable)
+	if path == "" {
+		return "", errors.New(fmt.Sprintf("No file configured for %s. Set %s environment variable", name, variable))
+	}
+	return s.GetToken(ctx, path)
+}
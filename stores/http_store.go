@@ -0,0 +1,67 @@
+package stores
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"strings"
+	"time"
+)
+
+// Timeout of requests sent by default HTTP store
+const DefaultHTTPTimeout = 10 * time.Second
+
+// Maximum size of token fetched over HTTP
+const maxHTTPTokenSize = 64 * 1024
+
+// HTTP store implementation to fetch token from a metadata or secret endpoint
+type HTTPStore struct {
+	client *http.Client
+}
+
+// Create a new HTTP store whose requests are cancelled after timeout
+func NewHTTPStore(timeout time.Duration) *HTTPStore {
+	return &HTTPStore{client: &http.Client{Timeout: timeout}}
+}
+
+// Get token found in body of response to a GET request sent to url.
+//
+// Header is optional and must be formatted as `Name: value`.
+// Header value is never included in errors since it usually holds credentials.
+func (s *HTTPStore) GetToken(ctx context.Context, url string, header string) (string, error) {
+	request, err := http.NewRequestWithContext(ctx, http.MethodGet, url, nil)
+	if err != nil {
+		return "", err
+	}
+	if header != "" {
+		name, value, ok := strings.Cut(header, ":")
+		name = strings.TrimSpace(name)
+		if !ok || name == "" {
+			return "", errors.New("Invalid header, expected 'Name: value'")
+		}
+		request.Header.Set(name, strings.TrimSpace(value))
+	}
+	client := s.client
+	if client == nil {
+		client = &http.Client{Timeout: DefaultHTTPTimeout}
+	}
+	response, err := client.Do(request)
+	if err != nil {
+		return "", err
+	}
+	defer response.Body.Close()
+	if response.StatusCode < 200 || response.StatusCode > 299 {
+		return "", errors.New(fmt.Sprintf("Cannot fetch token from %s: %s", request.URL.Redacted(), response.Status))
+	}
+	body, err := io.ReadAll(io.LimitReader(response.Body, maxHTTPTokenSize))
+	if err != nil {
+		return "", err
+	}
+	token := strings.TrimSpace(string(body))
+	if token == "" {
+		return "", errors.New(fmt.Sprintf("Invalid token found at %s", request.URL.Redacted()))
+	}
+	return token, nil
+}
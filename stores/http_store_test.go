@@ -0,0 +1,65 @@
+package stores
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+)
+
+// Test that token is fetched from endpoint, sending configured header
+func TestHTTPStoreGetToken(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Header.Get("Metadata-Flavor") != "Google" {
+			w.WriteHeader(http.StatusForbidden)
+			return
+		}
+		w.Write([]byte("  XXXXX\n"))
+	}))
+	defer server.Close()
+	store := NewHTTPStore(time.Second)
+	token, err := store.GetToken(context.Background(), server.URL, "Metadata-Flavor: Google")
+	if err != nil {
+		t.Fatalf(err.Error())
+	}
+	if token != "XXXXX" {
+		t.Errorf("Bad token. Want: XXXXX. Got: %s", token)
+	}
+	_, err = store.GetToken(context.Background(), server.URL, "")
+	err_want := "Cannot fetch token from " + server.URL + ": 403 Forbidden"
+	if err == nil || err.Error() != err_want {
+		t.Errorf("Bad error. Want: %s. Got: %v", err_want, err)
+	}
+	_, err = store.GetToken(context.Background(), server.URL, "Metadata-Flavor")
+	err_want = "Invalid header, expected 'Name: value'"
+	if err == nil || err.Error() != err_want {
+		t.Errorf("Bad error. Want: %s. Got: %v", err_want, err)
+	}
+}
+
+// Test that empty responses are rejected
+func TestHTTPStoreEmptyToken(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Write([]byte("\n"))
+	}))
+	defer server.Close()
+	_, err := NewHTTPStore(time.Second).GetToken(context.Background(), server.URL, "")
+	err_want := "Invalid token found at " + server.URL
+	if err == nil || err.Error() != err_want {
+		t.Errorf("Bad error. Want: %s. Got: %v", err_want, err)
+	}
+}
+
+// Test that slow endpoints are abandoned once timeout expires
+func TestHTTPStoreTimeout(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		time.Sleep(200 * time.Millisecond)
+		w.Write([]byte("XXXXX"))
+	}))
+	defer server.Close()
+	_, err := NewHTTPStore(50*time.Millisecond).GetToken(context.Background(), server.URL, "")
+	if err == nil {
+		t.Errorf("Expected timeout error")
+	}
+}
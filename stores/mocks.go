@@ -1,17 +1,123 @@
 package stores
 
+import (
+	"context"
+	"time"
+)
+
+// Keyvault mock returning a single token.
+//
+// When Delay is set, fetching token takes that long unless
+// context is cancelled first.
 type KeyVaultMock struct {
 	Token string
+	Delay time.Duration
 }
 
-func (k *KeyVaultMock) GetToken(uri string, secret string) (string, error) {
-	return k.Token, nil
+func (k *KeyVaultMock) GetToken(ctx context.Context, uri string, secret string) (string, error) {
+	select {
+	case <-ctx.Done():
+		return "", ctx.Err()
+	case <-time.After(k.Delay):
+		return k.Token, nil
+	}
+}
+
+func (k *KeyVaultMock) SetToken(ctx context.Context, uri string, secret string, value string) error {
+	if err := ctx.Err(); err != nil {
+		return err
+	}
+	k.Token = value
+	return nil
+}
+
+// Keyvault mock holding several secrets
+//
+// ErrSecretNotFound is returned for missing secrets.
+type KeyVaultSecretsMock struct {
+	Secrets map[string]string
+}
+
+func (k *KeyVaultSecretsMock) GetToken(ctx context.Context, uri string, secret string) (string, error) {
+	if err := ctx.Err(); err != nil {
+		return "", err
+	}
+	value, ok := k.Secrets[secret]
+	if !ok {
+		return "", ErrSecretNotFound
+	}
+	return value, nil
+}
+
+func (k *KeyVaultSecretsMock) SetToken(ctx context.Context, uri string, secret string, value string) error {
+	if err := ctx.Err(); err != nil {
+		return err
+	}
+	if k.Secrets == nil {
+		k.Secrets = map[string]string{}
+	}
+	k.Secrets[secret] = value
+	return nil
 }
 
 type FileStoreMock struct {
 	Token string
 }
 
-func (k *FileStoreMock) GetToken(path string) (string, error) {
+func (k *FileStoreMock) GetToken(ctx context.Context, path string) (string, error) {
+	if err := ctx.Err(); err != nil {
+		return "", err
+	}
+	return k.Token, nil
+}
+
+func (k *FileStoreMock) GetValue(ctx context.Context, name string) (string, error) {
+	if err := ctx.Err(); err != nil {
+		return "", err
+	}
+	return k.Token, nil
+}
+
+type DirectoryStoreMock struct {
+	Token string
+}
+
+func (k *DirectoryStoreMock) GetToken(ctx context.Context, dir string) (string, error) {
+	if err := ctx.Err(); err != nil {
+		return "", err
+	}
+	return k.Token, nil
+}
+
+type CredentialStoreMock struct {
+	Token string
+}
+
+func (k *CredentialStoreMock) GetToken(ctx context.Context, name string) (string, error) {
+	if err := ctx.Err(); err != nil {
+		return "", err
+	}
+	return k.Token, nil
+}
+
+type HTTPStoreMock struct {
+	Token string
+}
+
+func (k *HTTPStoreMock) GetToken(ctx context.Context, url string, header string) (string, error) {
+	if err := ctx.Err(); err != nil {
+		return "", err
+	}
+	return k.Token, nil
+}
+
+type EnvStoreMock struct {
+	Token string
+}
+
+func (k *EnvStoreMock) GetToken(ctx context.Context, variable string) (string, error) {
+	if err := ctx.Err(); err != nil {
+		return "", err
+	}
 	return k.Token, nil
 }
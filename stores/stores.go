@@ -1,23 +1,126 @@
 package stores
 
+import (
+	"context"
+	"errors"
+	"time"
+)
+
 // A store expose the GetToken() method
-// This method may return an error
+// This method may return an error, and must return
+// early when context is cancelled
+//
+// The GetValue() method reads a named credential from the
+// file whose path is found in <NAME>_FILE environment variable
 type FileStoreProtocol interface {
-	GetToken(path string) (string, error)
+	GetToken(ctx context.Context, path string) (string, error)
+	GetValue(ctx context.Context, name string) (string, error)
+}
+
+// A directory store reads token from a file within a directory
+type DirectoryStoreProtocol interface {
+	GetToken(ctx context.Context, dir string) (string, error)
+}
+
+// A credential store reads token from a systemd credential
+type CredentialStoreProtocol interface {
+	GetToken(ctx context.Context, name string) (string, error)
+}
+
+// An HTTP store reads token from an HTTP endpoint, sending an optional header
+type HTTPStoreProtocol interface {
+	GetToken(ctx context.Context, url string, header string) (string, error)
 }
 
 type EnvStoreProtocol interface {
-	GetToken(variable string) (string, error)
+	GetToken(ctx context.Context, variable string) (string, error)
 }
 
+// A keyvault store reads token from a keyvault secret
 type KeyvaultStoreProtocol interface {
-	GetToken(uri string, secret string) (string, error)
+	GetToken(ctx context.Context, uri string, secret string) (string, error)
+}
+
+// A keyvault store can also store secrets when it implements the SetToken() method
+type KeyvaultWriter interface {
+	SetToken(ctx context.Context, uri string, secret string, value string) error
 }
 
+// Error returned when a secret is stored into a keyvault store which is read-only
+var ErrReadOnlyKeyvault = errors.New("keyvault store is read-only")
+
 // Stores used to find DNS auth token
 type Stores struct {
-	Files    FileStoreProtocol
-	Keyvault KeyvaultStoreProtocol
+	Files       FileStoreProtocol
+	Directory   DirectoryStoreProtocol
+	Credentials CredentialStoreProtocol
+	HTTP        HTTPStoreProtocol
+	Env         EnvStoreProtocol
+	Keyvault    KeyvaultStoreProtocol
+}
+
+// Option used to configure stores created with NewStores()
+type StoreOption func(*Stores)
+
+// Use a custom keyvault store
+func WithKeyvault(store KeyvaultStoreProtocol) StoreOption {
+	return func(s *Stores) {
+		s.Keyvault = store
+	}
+}
+
+// Use a custom file store
+func WithFileStore(store FileStoreProtocol) StoreOption {
+	return func(s *Stores) {
+		s.Files = store
+	}
+}
+
+// Use a custom directory store
+func WithDirectoryStore(store DirectoryStoreProtocol) StoreOption {
+	return func(s *Stores) {
+		s.Directory = store
+	}
+}
+
+// Use a custom credential store
+func WithCredentialStore(store CredentialStoreProtocol) StoreOption {
+	return func(s *Stores) {
+		s.Credentials = store
+	}
+}
+
+// Use a custom HTTP store
+func WithHTTPStore(store HTTPStoreProtocol) StoreOption {
+	return func(s *Stores) {
+		s.HTTP = store
+	}
+}
+
+// Use a custom environment store
+func WithEnvStore(store EnvStoreProtocol) StoreOption {
+	return func(s *Stores) {
+		s.Env = store
+	}
+}
+
+// Create new stores.
+//
+// Default implementations are used unless
+// overridden using options.
+func NewStores(opts ...StoreOption) Stores {
+	stores := Stores{
+		Keyvault:    &KeyVault{},
+		Files:       &FileStore{},
+		Directory:   &DirectoryStore{Filename: DefaultTokenFilename},
+		Credentials: &CredentialStore{},
+		HTTP:        NewHTTPStore(DefaultHTTPTimeout),
+		Env:         &EnvStore{},
+	}
+	for _, opt := range opts {
+		opt(&stores)
+	}
+	return stores
 }
 
 // Access the file store
@@ -25,27 +128,47 @@ func (s *Stores) GetFileStore() FileStoreProtocol {
 	return s.Files
 }
 
+// Access the directory store
+func (s *Stores) GetDirectoryStore() DirectoryStoreProtocol {
+	return s.Directory
+}
+
+// Access the credential store
+func (s *Stores) GetCredentialStore() CredentialStoreProtocol {
+	return s.Credentials
+}
+
+// Access the HTTP store
+func (s *Stores) GetHTTPStore() HTTPStoreProtocol {
+	return s.HTTP
+}
+
+// Access the environment store
+func (s *Stores) GetEnvStore() EnvStoreProtocol {
+	return s.Env
+}
+
 // Access the keyvault store
 func (s *Stores) GetKeyvaultStore() KeyvaultStoreProtocol {
 	return s.Keyvault
 }
 
 // Default stores
-func DefaultStores() Stores {
-	return Stores{
-		Keyvault: &KeyVault{},
-		Files:    &FileStore{},
-	}
+//
+// Tokens fetched from keyvault are cached for the process lifetime.
+// Keyvault requests are cancelled after vaultTimeout.
+func DefaultStores(vaultTimeout time.Duration) Stores {
+	return NewStores(WithKeyvault(NewCachingStore(NewKeyVault(vaultTimeout))))
 }
 
 // Stores used in tests
 func TestStores(token string) Stores {
-	return Stores{
-		Keyvault: &KeyVaultMock{
-			Token: token,
-		},
-		Files: &FileStoreMock{
-			Token: token,
-		},
-	}
+	return NewStores(
+		WithKeyvault(&KeyVaultMock{Token: token}),
+		WithFileStore(&FileStoreMock{Token: token}),
+		WithDirectoryStore(&DirectoryStoreMock{Token: token}),
+		WithCredentialStore(&CredentialStoreMock{Token: token}),
+		WithHTTPStore(&HTTPStoreMock{Token: token}),
+		WithEnvStore(&EnvStoreMock{Token: token}),
+	)
 }
@@ -0,0 +1,91 @@
+package stores
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+)
+
+// Test that NewStores uses default implementations when no option is given
+func TestNewStoresDefaults(t *testing.T) {
+	s := NewStores()
+	if _, ok := s.GetKeyvaultStore().(*KeyVault); !ok {
+		t.Errorf("Expected default keyvault store but got %T", s.GetKeyvaultStore())
+	}
+	if _, ok := s.GetFileStore().(*FileStore); !ok {
+		t.Errorf("Expected default file store but got %T", s.GetFileStore())
+	}
+	if _, ok := s.GetEnvStore().(*EnvStore); !ok {
+		t.Errorf("Expected default env store but got %T", s.GetEnvStore())
+	}
+	if _, ok := s.GetHTTPStore().(*HTTPStore); !ok {
+		t.Errorf("Expected default HTTP store but got %T", s.GetHTTPStore())
+	}
+}
+
+// Test that NewStores uses implementations provided as options
+func TestNewStoresWithOptions(t *testing.T) {
+	keyvault := &KeyVaultMock{Token: "vault"}
+	files := &FileStoreMock{Token: "file"}
+	env := &EnvStoreMock{Token: "env"}
+	s := NewStores(WithKeyvault(keyvault), WithFileStore(files), WithEnvStore(env))
+	if s.GetKeyvaultStore() != keyvault {
+		t.Errorf("Keyvault store option was not applied")
+	}
+	if s.GetFileStore() != files {
+		t.Errorf("File store option was not applied")
+	}
+	if s.GetEnvStore() != env {
+		t.Errorf("Env store option was not applied")
+	}
+}
+
+// Test that a single option only overrides a single store
+func TestNewStoresWithSingleOption(t *testing.T) {
+	keyvault := &KeyVaultMock{Token: "vault"}
+	s := NewStores(WithKeyvault(keyvault))
+	if s.GetKeyvaultStore() != keyvault {
+		t.Errorf("Keyvault store option was not applied")
+	}
+	if _, ok := s.GetFileStore().(*FileStore); !ok {
+		t.Errorf("Expected default file store but got %T", s.GetFileStore())
+	}
+}
+
+// Test that the env store reads token from environment variable
+func TestEnvStoreGetToken(t *testing.T) {
+	t.Setenv("TEST_TOKEN", "XXXXX\n")
+	store := &EnvStore{}
+	token, err := store.GetToken(context.Background(), "TEST_TOKEN")
+	if err != nil {
+		t.Errorf(err.Error())
+	}
+	if token != "XXXXX" {
+		t.Errorf("Bad token. Want: XXXXX. Got: %s", token)
+	}
+	t.Setenv("TEST_TOKEN", "  XXXXX \t")
+	token, err = store.GetToken(context.Background(), "TEST_TOKEN")
+	if err != nil || token != "XXXXX" {
+		t.Errorf("Bad token. Want: XXXXX. Got: %q (%v)", token, err)
+	}
+	_, err = store.GetToken(context.Background(), "TEST_TOKEN_MISSING")
+	if err == nil {
+		t.Errorf("Expected error for missing environment variable")
+	}
+}
+
+// Test that fetching a token stops when context is cancelled mid-fetch
+func TestKeyVaultMockCancel(t *testing.T) {
+	store := &KeyVaultMock{Token: "XXXXX", Delay: time.Minute}
+	ctx, cancel := context.WithCancel(context.Background())
+	time.AfterFunc(10*time.Millisecond, cancel)
+	start := time.Now()
+	_, err := store.GetToken(ctx, "https://test.vault.azure.net/", "secret")
+	if !errors.Is(err, context.Canceled) {
+		t.Errorf("Bad error. Want: %s. Got: %v", context.Canceled, err)
+	}
+	if time.Since(start) > time.Second {
+		t.Errorf("Expected fetch to stop when context is cancelled")
+	}
+}
@@ -0,0 +1,37 @@
+package main
+
+import (
+	"errors"
+	"fmt"
+	"io"
+
+	"github.com/charbonnierg/letsgo/client"
+	"github.com/charbonnierg/letsgo/configuration"
+	"github.com/charbonnierg/letsgo/stores"
+)
+
+// Parse configuration from environment and print effective configuration.
+// The CA server is never contacted, nothing is written to stores nor to
+// filesystem, and DNS providers are only contacted when DNS credentials
+// verification is enabled.
+func validate(storage *stores.Stores, out io.Writer) error {
+	// Account keys are not generated and output directory is not created
+	config, err := configuration.LoadUserConfig(storage)
+	if err != nil {
+		return err
+	}
+	fmt.Fprintln(out, config.Summary())
+	if !config.VerifyDNSCredentials {
+		return nil
+	}
+	err = client.VerifyDNSCredentials(*config)
+	if errors.Is(err, client.ErrDNSVerificationUnsupported) {
+		fmt.Fprintf(out, "DNS credentials: not verified, %s\n", err)
+		return nil
+	}
+	if err != nil {
+		return err
+	}
+	fmt.Fprintln(out, "DNS credentials: verified")
+	return nil
+}
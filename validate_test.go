@@ -0,0 +1,124 @@
+package main
+
+import (
+	"bytes"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/charbonnierg/letsgo/stores"
+)
+
+// Test that a valid environment prints effective configuration
+func TestValidate(t *testing.T) {
+	t.Setenv("DOMAINS", "example.com,www.example.com")
+	t.Setenv("ACCOUNT_EMAIL", "support@example.com")
+	t.Setenv("ACCOUNT_KEY_FILE", filepath.Join(t.TempDir(), "account.key"))
+	t.Setenv("DNS_AUTH_TOKEN", "XXXXX")
+	storage := stores.TestStores("")
+	out := &bytes.Buffer{}
+	err := validate(&storage, out)
+	if err != nil {
+		t.Fatalf(err.Error())
+	}
+	want := "Certificate example.com: example.com,www.example.com"
+	if !strings.Contains(out.String(), want) {
+		t.Errorf("Bad summary. Want: %s. Got: %s", want, out.String())
+	}
+	if strings.Contains(out.String(), "XXXXX") {
+		t.Errorf("Summary must not include auth token")
+	}
+}
+
+// Test that DNS credentials are verified against DNS provider when enabled
+func TestValidateDNSCredentials(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Header.Get("Authorization") != "Bearer XXXXX" {
+			w.WriteHeader(http.StatusUnauthorized)
+			return
+		}
+		w.Write([]byte(`{"domains": [{"name": "example.com"}]}`))
+	}))
+	defer server.Close()
+	t.Setenv("DOMAINS", "example.com,www.example.com")
+	t.Setenv("ACCOUNT_EMAIL", "support@example.com")
+	t.Setenv("ACCOUNT_KEY_FILE", filepath.Join(t.TempDir(), "account.key"))
+	t.Setenv("DNS_AUTH_TOKEN", "XXXXX")
+	t.Setenv("DO_API_URL", server.URL)
+	t.Setenv("VERIFY_DNS_CREDENTIALS", "true")
+	storage := stores.TestStores("")
+	out := &bytes.Buffer{}
+	err := validate(&storage, out)
+	if err != nil {
+		t.Fatalf(err.Error())
+	}
+	if !strings.Contains(out.String(), "DNS credentials: verified") {
+		t.Errorf("Expected DNS credentials to be verified. Got: %s", out.String())
+	}
+	t.Setenv("DNS_AUTH_TOKEN", "YYYYY")
+	err = validate(&storage, &bytes.Buffer{})
+	if err == nil {
+		t.Errorf("Expected error for rejected DNS auth token")
+	}
+}
+
+// Test that first configuration problem is reported
+func TestValidateInvalid(t *testing.T) {
+	t.Setenv("DOMAINS", "example.com")
+	t.Setenv("ACCOUNT_EMAIL", "support@example.com")
+	t.Setenv("ACCOUNT_KEY_FILE", filepath.Join(t.TempDir(), "account.key"))
+	t.Setenv("DNS_AUTH_TOKEN", "XXXXX")
+	t.Setenv("LE_CRT_KEY_TYPE", "RSA1024")
+	storage := stores.TestStores("")
+	out := &bytes.Buffer{}
+	err := validate(&storage, out)
+	if err == nil {
+		t.Fatalf("Expected error for invalid key type")
+	}
+	if out.Len() > 0 {
+		t.Errorf("Expected no summary. Got: %s", out.String())
+	}
+}
+
+// Test that validation never generates account keys nor creates output directory
+func TestValidateHasNoSideEffects(t *testing.T) {
+	dir := t.TempDir()
+	t.Setenv("DOMAINS", "example.com")
+	t.Setenv("ACCOUNT_EMAIL", "support@example.com")
+	t.Setenv("DNS_AUTH_TOKEN", "XXXXX")
+	t.Setenv("OUTPUT_DIRECTORY", filepath.Join(dir, "certs"))
+	// Account key stored in keyvault
+	t.Setenv("ACCOUNT_KEY_VAULT", "vault")
+	keyvault := &stores.KeyVaultSecretsMock{}
+	storage := stores.NewStores(stores.WithKeyvault(keyvault), stores.WithEnvStore(&stores.EnvStoreMock{Token: "XXXXX"}))
+	out := &bytes.Buffer{}
+	err := validate(&storage, out)
+	if err != nil {
+		t.Fatalf(err.Error())
+	}
+	if len(keyvault.Secrets) > 0 {
+		t.Errorf("Expected no secret to be saved. Got: %v", keyvault.Secrets)
+	}
+	want := "Account key: not found, generated on first run"
+	if !strings.Contains(out.String(), want) {
+		t.Errorf("Bad summary. Want: %s. Got: %s", want, out.String())
+	}
+	// Account key stored in file
+	t.Setenv("ACCOUNT_KEY_VAULT", "")
+	t.Setenv("ACCOUNT_KEY_FILE", filepath.Join(dir, "account.key"))
+	storage = stores.TestStores("XXXXX")
+	err = validate(&storage, &bytes.Buffer{})
+	if err != nil {
+		t.Fatalf(err.Error())
+	}
+	entries, err := os.ReadDir(dir)
+	if err != nil {
+		t.Fatalf(err.Error())
+	}
+	if len(entries) > 0 {
+		t.Errorf("Expected no file to be created. Got: %v", entries)
+	}
+}
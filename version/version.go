@@ -0,0 +1,46 @@
+package version
+
+import (
+	"fmt"
+	"runtime/debug"
+)
+
+// Build information injected using -ldflags:
+//
+//	go build -ldflags "-X github.com/charbonnierg/letsgo/version.Version=v0.7.0"
+var (
+	Version = "dev"
+	Commit  = "unknown"
+	Date    = "unknown"
+)
+
+const legoModule = "github.com/go-acme/lego/v4"
+
+// Get user agent identifying letsgo
+func UserAgent() string {
+	return "letsgo/" + Version
+}
+
+// Get version of lego module letsgo was built with
+func LegoVersion() string {
+	info, ok := debug.ReadBuildInfo()
+	if !ok {
+		return "unknown"
+	}
+	return legoVersion(info)
+}
+
+// Find version of lego module within build information
+func legoVersion(info *debug.BuildInfo) string {
+	for _, dep := range info.Deps {
+		if dep.Path == legoModule {
+			return dep.Version
+		}
+	}
+	return "unknown"
+}
+
+// Get a human-readable summary of build information
+func Info() string {
+	return fmt.Sprintf("letsgo %s (commit: %s, built: %s, lego: %s)", Version, Commit, Date, LegoVersion())
+}
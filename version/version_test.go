@@ -0,0 +1,43 @@
+package version
+
+import (
+	"runtime/debug"
+	"strings"
+	"testing"
+)
+
+// Test that lego version is read from build info
+func TestLegoVersion(t *testing.T) {
+	info := &debug.BuildInfo{
+		Deps: []*debug.Module{
+			{Path: "golang.org/x/net", Version: "v0.1.0"},
+			{Path: "github.com/go-acme/lego/v4", Version: "v4.9.0"},
+		},
+	}
+	if got := legoVersion(info); got != "v4.9.0" {
+		t.Errorf("Bad lego version. Want: v4.9.0. Got: %s", got)
+	}
+	if got := legoVersion(&debug.BuildInfo{}); got != "unknown" {
+		t.Errorf("Bad lego version. Want: unknown. Got: %s", got)
+	}
+}
+
+// Test that build info of running binary can be read back
+func TestReadBuildInfo(t *testing.T) {
+	if _, ok := debug.ReadBuildInfo(); !ok {
+		t.Errorf("Build info is not available")
+	}
+}
+
+// Test that build information is included into summary
+func TestInfo(t *testing.T) {
+	Version, Commit, Date = "v1.2.3", "abcdef", "2022-11-20"
+	got := Info()
+	want := "letsgo v1.2.3 (commit: abcdef, built: 2022-11-20, lego: " + LegoVersion() + ")"
+	if got != want {
+		t.Errorf("Bad build info. Want: %s. Got: %s", want, got)
+	}
+	if !strings.HasSuffix(UserAgent(), "/v1.2.3") {
+		t.Errorf("Bad user agent. Want: letsgo/v1.2.3. Got: %s", UserAgent())
+	}
+}